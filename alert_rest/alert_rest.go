@@ -0,0 +1,269 @@
+package alert_rest
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/LovePelmeni/Infrastructure/alerting"
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/policy"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of Rest API Controllers, for Managing Alert Rules and Notification Channels of the Projects
+// Rules can be Managed by the Operators, Channels, that may Contain the Secret Webhook URLs, only by the Admins
+
+var (
+	Logger *zap.Logger
+)
+
+const (
+	MaxAlerts = 100 // Number of the Latest Alerts, Returned by the List Controller
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("AlertRestLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+func GetAuthorizedProject(RequestContext *gin.Context, Action string) (int, int, bool) {
+	// Returns the Project, specified in the `ProjectId` Param, and the Customer's ID
+	// Responds with the Error and Returns false, if the Customer's Role does not Allow to Perform the Action in the Project
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return 0, 0, false
+	}
+
+	ProjectId, ParseError := strconv.Atoi(RequestContext.Request.FormValue("ProjectId"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Project ID"})
+		return 0, 0, false
+	}
+	if AuthorizationError := policy.AuthorizeInProject(jwtCredentials.UserId, ProjectId, Action); AuthorizationError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": AuthorizationError.Error()})
+		return 0, 0, false
+	}
+	return ProjectId, jwtCredentials.UserId, true
+}
+
+// Alert Rules Rest API Endpoints
+
+func CreateAlertRuleRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Creates new Alert Rule of the Project
+	// Rule Applies to every Virtual Machine of the Project, unless the `VirtualMachineId` is Specified
+
+	ProjectId, CustomerId, Authorized := GetAuthorizedProject(RequestContext, policy.ActionOperate)
+	if !Authorized {
+		return
+	}
+
+	Name := RequestContext.PostForm("Name")
+	if len(Name) == 0 || len(Name) > 100 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Rule Name should be between 1 and 100 Characters"})
+		return
+	}
+	Metric, Comparator := RequestContext.PostForm("Metric"), RequestContext.PostForm("Comparator")
+	if ValidationError := alerting.ValidateRule(Metric, Comparator); ValidationError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": ValidationError.Error()})
+		return
+	}
+	Threshold, ThresholdError := strconv.ParseFloat(RequestContext.PostForm("Threshold"), 64)
+	if ThresholdError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Threshold"})
+		return
+	}
+	Duration, DurationError := config.ParseDuration(RequestContext.DefaultPostForm("Duration", "0"))
+	if DurationError != nil || Duration < 0 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Duration, Seconds or the Duration like `5m` is Expected"})
+		return
+	}
+
+	VirtualMachineId := 0
+	if Value := RequestContext.PostForm("VirtualMachineId"); len(Value) != 0 {
+		ParsedId, ParseError := strconv.Atoi(Value)
+		var Count int64
+		if ParseError == nil {
+			models.Database.Model(&models.VirtualMachine{}).Where(
+				"id = ? AND project_id = ?", ParsedId, ProjectId).Count(&Count)
+		}
+		if Count == 0 {
+			RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Virtual Machine Does Not Exist in the Project"})
+			return
+		}
+		VirtualMachineId = ParsedId
+	}
+
+	Rule := models.NewAlertRule(ProjectId, VirtualMachineId, Name, Metric, Comparator, Threshold, Duration, CustomerId)
+	if _, CreationError := Rule.Create(); CreationError != nil {
+		Logger.Error("Failed to Create Alert Rule", zap.Error(CreationError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Create Alert Rule"})
+		return
+	}
+	RequestContext.JSON(http.StatusCreated, gin.H{"Rule": Rule})
+}
+
+func ListAlertRulesRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns Alert Rules of the Project along with the Metrics, the Rules can be Defined for
+
+	ProjectId, _, Authorized := GetAuthorizedProject(RequestContext, policy.ActionView)
+	if !Authorized {
+		return
+	}
+
+	Rules, RulesError := models.GetProjectAlertRules(ProjectId)
+	if RulesError != nil {
+		Logger.Error("Failed to Receive Alert Rules", zap.Error(RulesError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Receive Alert Rules"})
+		return
+	}
+	Metrics := make(map[string]string, len(alerting.Metrics))
+	for Name, Metric := range alerting.Metrics {
+		Metrics[Name] = Metric.Description
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": Rules, "Metrics": Metrics})
+}
+
+func RemoveAlertRuleRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Removes the Alert Rule, Active Alerts of it are being Resolved without the Notifications
+
+	ProjectId, _, Authorized := GetAuthorizedProject(RequestContext, policy.ActionOperate)
+	if !Authorized {
+		return
+	}
+	RuleId, ParseError := strconv.Atoi(RequestContext.Request.FormValue("RuleId"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Rule ID"})
+		return
+	}
+	Rule, Exists := models.GetProjectAlertRule(ProjectId, RuleId)
+	if !Exists {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Alert Rule Does Not Exist"})
+		return
+	}
+
+	if _, DeleteError := Rule.Delete(); DeleteError != nil {
+		Logger.Error("Failed to Remove Alert Rule", zap.Error(DeleteError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Remove Alert Rule"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Removed"})
+}
+
+// Notification Channels Rest API Endpoints
+
+func CreateAlertChannelRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Creates new Notification Channel of the Project
+
+	ProjectId, _, Authorized := GetAuthorizedProject(RequestContext, policy.ActionManage)
+	if !Authorized {
+		return
+	}
+
+	Name := RequestContext.PostForm("Name")
+	if len(Name) == 0 || len(Name) > 100 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Channel Name should be between 1 and 100 Characters"})
+		return
+	}
+	Type, Target := RequestContext.PostForm("Type"), RequestContext.PostForm("Target")
+	if ValidationError := alerting.ValidateChannel(Type, Target); ValidationError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": ValidationError.Error()})
+		return
+	}
+
+	Channel := models.NewAlertChannel(ProjectId, Name, Type, Target)
+	if _, CreationError := Channel.Create(); CreationError != nil {
+		Logger.Error("Failed to Create Alert Channel", zap.Error(CreationError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Create Alert Channel"})
+		return
+	}
+	RequestContext.JSON(http.StatusCreated, gin.H{"Channel": Channel})
+}
+
+func ListAlertChannelsRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns Notification Channels of the Project
+
+	ProjectId, _, Authorized := GetAuthorizedProject(RequestContext, policy.ActionManage)
+	if !Authorized {
+		return
+	}
+
+	Channels, ChannelsError := models.GetProjectAlertChannels(ProjectId)
+	if ChannelsError != nil {
+		Logger.Error("Failed to Receive Alert Channels", zap.Error(ChannelsError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Receive Alert Channels"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": Channels})
+}
+
+func RemoveAlertChannelRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Removes the Notification Channel of the Project
+
+	ProjectId, _, Authorized := GetAuthorizedProject(RequestContext, policy.ActionManage)
+	if !Authorized {
+		return
+	}
+	ChannelId, ParseError := strconv.Atoi(RequestContext.Request.FormValue("ChannelId"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Channel ID"})
+		return
+	}
+	Channel, Exists := models.GetProjectAlertChannel(ProjectId, ChannelId)
+	if !Exists {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Alert Channel Does Not Exist"})
+		return
+	}
+
+	if _, DeleteError := Channel.Delete(); DeleteError != nil {
+		Logger.Error("Failed to Remove Alert Channel", zap.Error(DeleteError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Remove Alert Channel"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Removed"})
+}
+
+// Alerts Rest API Endpoints
+
+func ListAlertsRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns the Latest Alerts of the Project, Optionally Filtered by the `State`
+
+	ProjectId, _, Authorized := GetAuthorizedProject(RequestContext, policy.ActionView)
+	if !Authorized {
+		return
+	}
+
+	State := RequestContext.Query("State")
+	switch State {
+	case "", models.AlertStatePending, models.AlertStateFiring, models.AlertStateResolved:
+	default:
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid State, Supported ones are Pending, Firing, Resolved"})
+		return
+	}
+
+	Alerts, AlertsError := models.GetProjectAlerts(ProjectId, State, MaxAlerts)
+	if AlertsError != nil {
+		Logger.Error("Failed to Receive Alerts", zap.Error(AlertsError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Receive Alerts"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": Alerts})
+}
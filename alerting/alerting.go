@@ -0,0 +1,522 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/egress"
+	"github.com/LovePelmeni/Infrastructure/healthcheck"
+	"github.com/LovePelmeni/Infrastructure/mailer"
+	"github.com/LovePelmeni/Infrastructure/metrics"
+	"github.com/LovePelmeni/Infrastructure/models"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Alert Rules Evaluator and the Notifiers of the Channels
+// Rules are being Evaluated against the Health of the Virtual Machines after every Collection of the Metrics Collector,
+// Alert becomes Firing, once the Condition has been Met for the Duration of the Rule, and Resolved, once it's not Met anymore
+// Channels of the Project are being Notified only on these Transitions, the Transitions are being Made Conditionally in the Database,
+// so the Replicas, that Evaluate the same Rules, do not Notify the Channels twice
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	NotificationTimeout = 10 * time.Second
+)
+
+var (
+	ErrUnknownMetric      = errors.New("Unknown Metric")
+	ErrUnknownComparator  = errors.New("Unknown Comparator, Supported ones are >, >=, <, <=, ==, !=")
+	ErrUnknownChannelType = errors.New("Unknown Channel Type, Supported ones are Email, Webhook, Slack")
+	ErrInvalidEmail       = errors.New("Invalid Email Address")
+	ErrInvalidWebhookURL  = errors.New("Invalid Webhook URL, Absolute HTTP or HTTPS URL is Expected")
+)
+
+var (
+	Notifications = metrics.NewCounterVec("alert_notifications_total", "Number of the Alert Notifications by the Channel Type", "channel", "status")
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("AlertingLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+	metrics.Register(Notifications)
+}
+
+func Configure(Config config.AlertingConfig) {
+	NotificationTimeout = Config.NotificationTimeout
+}
+
+// METRICS
+
+type Metric struct {
+	// Metric of the Virtual Machine Health, the Rules can be Defined for
+	// Value is not Available, if the vSphere does not Report it (e.g the Heartbeat of the Guest without the VMware Tools)
+	Description string
+	GetValue    func(Manager *healthcheck.VirtualMachineHealthCheckManager) (float64, bool)
+}
+
+var (
+	// Severity of the Guest Heartbeat Status, Gray one stands for the Unknown Status
+	HeartbeatSeverities = map[string]float64{
+		string(types.ManagedEntityStatusGreen):  0,
+		string(types.ManagedEntityStatusYellow): 1,
+		string(types.ManagedEntityStatusRed):    2,
+	}
+)
+
+func GetPercent(Value int32, Max int32) (float64, bool) {
+	if Max <= 0 {
+		return 0, false
+	}
+	return float64(Value) / float64(Max) * 100, true
+}
+
+var Metrics = map[string]Metric{
+	"cpu_usage": {"CPU Usage, MHz", func(Manager *healthcheck.VirtualMachineHealthCheckManager) (float64, bool) {
+		return float64(Manager.GetCpuMetrics().OverallCpuUsage), true
+	}},
+	"cpu_usage_percent": {"CPU Usage, Percent of the Limit", func(Manager *healthcheck.VirtualMachineHealthCheckManager) (float64, bool) {
+		Cpu := Manager.GetCpuMetrics()
+		return GetPercent(Cpu.OverallCpuUsage, Cpu.MaxCpuUsage)
+	}},
+	"cpu_readiness": {"CPU Readiness, Percent of the Time, the Virtual Machine has been Waiting for the CPU", func(Manager *healthcheck.VirtualMachineHealthCheckManager) (float64, bool) {
+		return float64(Manager.GetCpuMetrics().OverallCpuReadness), true
+	}},
+	"memory_active": {"Active Memory, MB", func(Manager *healthcheck.VirtualMachineHealthCheckManager) (float64, bool) {
+		return float64(Manager.GetMemoryUsageMetrics().Active), true
+	}},
+	"memory_active_percent": {"Active Memory, Percent of the Limit", func(Manager *healthcheck.VirtualMachineHealthCheckManager) (float64, bool) {
+		Memory := Manager.GetMemoryUsageMetrics()
+		return GetPercent(Memory.Active, Memory.MaxMemoryUsage)
+	}},
+	"memory_granted": {"Granted Memory, MB", func(Manager *healthcheck.VirtualMachineHealthCheckManager) (float64, bool) {
+		return float64(Manager.GetMemoryUsageMetrics().Granted), true
+	}},
+	"guest_memory_usage": {"Memory Usage, Reported by the Guest, MB", func(Manager *healthcheck.VirtualMachineHealthCheckManager) (float64, bool) {
+		return float64(Manager.GetHostSystemHealthMetrics().GuestOsMemoryUsage), true
+	}},
+	"guest_heartbeat": {"Guest Heartbeat Severity, 0 - Green, 1 - Yellow, 2 - Red", func(Manager *healthcheck.VirtualMachineHealthCheckManager) (float64, bool) {
+		Severity, Known := HeartbeatSeverities[Manager.GetHostSystemHealthMetrics().GuestOsHeartbeat]
+		return Severity, Known
+	}},
+}
+
+func GetMetricNames() []string {
+	Names := make([]string, 0, len(Metrics))
+	for Name := range Metrics {
+		Names = append(Names, Name)
+	}
+	sort.Strings(Names)
+	return Names
+}
+
+var Comparators = map[string]func(Value float64, Threshold float64) bool{
+	">":  func(Value float64, Threshold float64) bool { return Value > Threshold },
+	">=": func(Value float64, Threshold float64) bool { return Value >= Threshold },
+	"<":  func(Value float64, Threshold float64) bool { return Value < Threshold },
+	"<=": func(Value float64, Threshold float64) bool { return Value <= Threshold },
+	"==": func(Value float64, Threshold float64) bool { return Value == Threshold },
+	"!=": func(Value float64, Threshold float64) bool { return Value != Threshold },
+}
+
+func ValidateRule(Metric string, Comparator string) error {
+	if _, Exists := Metrics[Metric]; !Exists {
+		return fmt.Errorf("%w, Supported ones are %s", ErrUnknownMetric, strings.Join(GetMetricNames(), ", "))
+	}
+	if _, Exists := Comparators[Comparator]; !Exists {
+		return ErrUnknownComparator
+	}
+	return nil
+}
+
+func ValidateChannel(Type string, Target string) error {
+	// Checks, that the Target can be Notified by the Channel of the Type
+	switch Type {
+	case models.ChannelTypeEmail:
+		if Address, ParseError := mail.ParseAddress(Target); ParseError != nil || Address.Address != Target {
+			return ErrInvalidEmail
+		}
+	case models.ChannelTypeWebhook, models.ChannelTypeSlack:
+		Parsed, ParseError := url.Parse(Target)
+		if ParseError != nil || (Parsed.Scheme != "http" && Parsed.Scheme != "https") || len(Parsed.Hostname()) == 0 {
+			return ErrInvalidWebhookURL
+		}
+		if HostError := egress.ValidateHost(Parsed.Hostname()); HostError != nil {
+			return fmt.Errorf("Invalid Webhook URL, %w", HostError)
+		}
+	default:
+		return ErrUnknownChannelType
+	}
+	return nil
+}
+
+// EVALUATOR
+
+type Sample struct {
+	// Values of the Metrics of the Virtual Machine, the Metrics, that are not Available, are being Omitted
+	VirtualMachine models.VirtualMachine
+	Values         map[string]float64
+}
+
+func NewSample(VirtualMachine models.VirtualMachine, Manager *healthcheck.VirtualMachineHealthCheckManager) Sample {
+	Values := make(map[string]float64, len(Metrics))
+	for Name, Metric := range Metrics {
+		if Value, Available := Metric.GetValue(Manager); Available {
+			Values[Name] = Value
+		}
+	}
+	return Sample{VirtualMachine: VirtualMachine, Values: Values}
+}
+
+func GetSamples(Records []models.VirtualMachine, VirtualMachines map[string]mo.VirtualMachine) []Sample {
+	// Returns Samples of the Records, that have been Found in the vSphere Inventory by the Managed Object ID
+	Samples := make([]Sample, 0, len(Records))
+	for _, Record := range Records {
+		if VirtualMachine, Exists := VirtualMachines[Record.ManagedObjectId]; Exists {
+			Samples = append(Samples, NewSample(Record, healthcheck.NewVirtualMachineHealthCheckManager(&VirtualMachine)))
+		}
+	}
+	return Samples
+}
+
+func IsMatching(Rule models.AlertRule, VirtualMachine models.VirtualMachine) bool {
+	// Checks, that the Rule Applies to the Virtual Machine, either Directly or by the Project
+	if Rule.VirtualMachineId != 0 {
+		return Rule.VirtualMachineId == VirtualMachine.ID
+	}
+	return Rule.ProjectId == VirtualMachine.ProjectId
+}
+
+const (
+	TransitionNone    = ""
+	TransitionOpen    = "Open"    // Condition has been Met, new Alert is being Created
+	TransitionFire    = "Fire"    // Condition has been Met for the Duration of the Rule
+	TransitionResolve = "Resolve" // Condition of the Firing Alert is not being Met anymore
+	TransitionDiscard = "Discard" // Condition of the Pending Alert is not being Met anymore, the Channels are not being Notified
+)
+
+func GetTransition(Rule models.AlertRule, Alert *models.Alert, Met bool, Now time.Time) string {
+	// Returns the Transition of the Alert of the Rule (nil, if there is no Active one) for the Current Evaluation
+	switch {
+	case Alert == nil && Met:
+		return TransitionOpen
+	case Alert == nil:
+		return TransitionNone
+	case Alert.State == models.AlertStatePending && !Met:
+		return TransitionDiscard
+	case Alert.State == models.AlertStatePending && !Now.Before(Alert.StartedAt.Add(Rule.GetDuration())):
+		return TransitionFire
+	case Alert.State == models.AlertStateFiring && !Met:
+		return TransitionResolve
+	default:
+		return TransitionNone
+	}
+}
+
+type AlertKey struct {
+	RuleId           int
+	VirtualMachineId int
+}
+
+type Evaluator struct {
+	// Evaluates the Alert Rules against the Samples and Notifies the Channels of the Projects about the Transitions
+	Notifiers map[string]Notifier
+}
+
+func NewEvaluator(Notifiers map[string]Notifier) *Evaluator {
+	return &Evaluator{Notifiers: Notifiers}
+}
+
+func (this *Evaluator) Observe(Records []models.VirtualMachine, VirtualMachines map[string]mo.VirtualMachine, Timestamp time.Time) {
+	// Evaluates the Rules after the Collection of the Region, Subscribed to the Metrics Collector
+	if EvaluateError := this.Evaluate(GetSamples(Records, VirtualMachines), Timestamp); EvaluateError != nil {
+		Logger.Error("Failed to Evaluate Alert Rules", zap.Error(EvaluateError))
+	}
+}
+
+func (this *Evaluator) Evaluate(Samples []Sample, Now time.Time) error {
+	// Evaluates every Enabled Rule against the Samples of the Virtual Machines, it Applies to
+	// Virtual Machines without the Samples (e.g of the Unavailable Region) keep the State of their Alerts
+
+	Rules, RulesError := models.GetEnabledAlertRules()
+	if RulesError != nil || len(Rules) == 0 {
+		return RulesError
+	}
+	Alerts, AlertsError := models.GetActiveAlerts()
+	if AlertsError != nil {
+		return AlertsError
+	}
+	Active := make(map[AlertKey]*models.Alert, len(Alerts))
+	for Index := range Alerts {
+		Active[AlertKey{Alerts[Index].RuleId, Alerts[Index].VirtualMachineId}] = &Alerts[Index]
+	}
+
+	for _, Sample := range Samples {
+		for _, Rule := range Rules {
+			if !IsMatching(Rule, Sample.VirtualMachine) {
+				continue
+			}
+			Value, Available := Sample.Values[Rule.Metric]
+			Compare, Known := Comparators[Rule.Comparator]
+			if !Available || !Known {
+				continue
+			}
+			Alert := Active[AlertKey{Rule.ID, Sample.VirtualMachine.ID}]
+			this.Apply(Rule, Alert, Sample.VirtualMachine, Value, GetTransition(Rule, Alert, Compare(Value, Rule.Threshold), Now), Now)
+		}
+	}
+	return nil
+}
+
+func (this *Evaluator) Apply(Rule models.AlertRule, Alert *models.Alert, VirtualMachine models.VirtualMachine, Value float64, Transition string, Now time.Time) {
+	// Applies the Transition to the Alert, the Channels are being Notified only by the Replica, that has Applied it
+
+	switch Transition {
+	case TransitionOpen:
+		// Rules without the Duration Fire immediately
+		Opened := models.NewAlert(Rule.ID, VirtualMachine.ID, models.AlertStatePending, Value, Now)
+		if Rule.Duration == 0 {
+			Opened.State, Opened.FiredAt = models.AlertStateFiring, &Now
+		}
+		if Opened.Create() && Opened.State == models.AlertStateFiring {
+			this.Notify(NewNotification(Rule, *Opened, VirtualMachine, Now))
+		}
+
+	case TransitionFire:
+		if Alert.Transit(models.AlertStatePending, models.AlertStateFiring, Value, Now) {
+			this.Notify(NewNotification(Rule, *Alert, VirtualMachine, Now))
+		}
+
+	case TransitionResolve:
+		if Alert.Transit(models.AlertStateFiring, models.AlertStateResolved, Value, Now) {
+			this.Notify(NewNotification(Rule, *Alert, VirtualMachine, Now))
+		}
+
+	case TransitionDiscard:
+		Alert.Discard()
+	}
+}
+
+func (this *Evaluator) Notify(Notification Notification) {
+	// Notifies every Channel of the Project, Failed Notifications are being Logged and not Retried
+
+	Channels, ChannelsError := models.GetProjectAlertChannels(Notification.ProjectId)
+	if ChannelsError != nil {
+		Logger.Error("Failed to Receive Alert Channels", zap.Int("ProjectId", Notification.ProjectId), zap.Error(ChannelsError))
+		return
+	}
+	for _, Channel := range Channels {
+		Notifier, Exists := this.Notifiers[Channel.Type]
+		if !Exists {
+			Logger.Warn("Alert Channel has Unknown Type", zap.Int("ChannelId", Channel.ID), zap.String("Type", Channel.Type))
+			continue
+		}
+		TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), NotificationTimeout)
+		NotifyError := Notifier.Notify(TimeoutContext, Channel.Target, Notification)
+		CancelFunc()
+
+		if NotifyError != nil {
+			Notifications.Inc(Channel.Type, "failed")
+			Logger.Error("Failed to Send Alert Notification", zap.Int("ChannelId", Channel.ID),
+				zap.Int("RuleId", Notification.RuleId), zap.Error(NotifyError))
+			continue
+		}
+		Notifications.Inc(Channel.Type, "sent")
+	}
+}
+
+// NOTIFIERS
+
+type Notification struct {
+	// Transition of the Alert, the Channels are being Notified about
+	State              string    `json:"State"`
+	RuleId             int       `json:"RuleId"`
+	RuleName           string    `json:"RuleName"`
+	ProjectId          int       `json:"ProjectId"`
+	VirtualMachineId   int       `json:"VirtualMachineId"`
+	VirtualMachineName string    `json:"VirtualMachineName"`
+	Metric             string    `json:"Metric"`
+	Comparator         string    `json:"Comparator"`
+	Threshold          float64   `json:"Threshold"`
+	Value              float64   `json:"Value"`
+	StartedAt          time.Time `json:"StartedAt"`
+	At                 time.Time `json:"At"`
+}
+
+func NewNotification(Rule models.AlertRule, Alert models.Alert, VirtualMachine models.VirtualMachine, At time.Time) Notification {
+	return Notification{
+		State:              Alert.State,
+		RuleId:             Rule.ID,
+		RuleName:           Rule.Name,
+		ProjectId:          Rule.ProjectId,
+		VirtualMachineId:   VirtualMachine.ID,
+		VirtualMachineName: VirtualMachine.VirtualMachineName,
+		Metric:             Rule.Metric,
+		Comparator:         Rule.Comparator,
+		Threshold:          Rule.Threshold,
+		Value:              Alert.Value,
+		StartedAt:          Alert.StartedAt,
+		At:                 At,
+	}
+}
+
+func (this Notification) GetSummary() string {
+	// Returns Single Line Summary, e.g "[Firing] High CPU: cpu_usage_percent of the Virtual Machine `web` (ID 1) is 97.5, Rule is > 90"
+	return fmt.Sprintf("[%s] %s: %s of the Virtual Machine `%s` (ID %v) is %v, Rule is %s %v", this.State, this.RuleName,
+		this.Metric, this.VirtualMachineName, this.VirtualMachineId, this.Value, this.Comparator, this.Threshold)
+}
+
+type Notifier interface {
+	// Interface, that Delivers the Notification to the Target of the Channel
+	Notify(Context context.Context, Target string, Notification Notification) error
+}
+
+type EmailNotifier struct{}
+
+func (this EmailNotifier) Notify(Context context.Context, Target string, Notification Notification) error {
+	// Sends the Notification with the Mailer of the Application
+	Body := fmt.Sprintf("%s\n\nCondition has been Met since %s\nProject: %v\nRule: %v\n", Notification.GetSummary(),
+		Notification.StartedAt.UTC().Format(time.RFC3339), Notification.ProjectId, Notification.RuleId)
+	return mailer.Send(Target, Notification.GetSummary(), Body)
+}
+
+type WebhookNotifier struct {
+	// Posts the Notification as JSON
+	Client *http.Client
+}
+
+func NewWebhookNotifier() *WebhookNotifier {
+	// Addresses of the Channels are being Checked once again on every Connection, since their DNS Records might have been Changed
+	return &WebhookNotifier{Client: &http.Client{Transport: egress.NewTransport()}}
+}
+
+func (this *WebhookNotifier) Notify(Context context.Context, Target string, Notification Notification) error {
+	return PostJSON(Context, this.Client, Target, Notification)
+}
+
+type SlackNotifier struct {
+	// Posts the Summary of the Notification to the Slack Compatible Incoming Webhook
+	Client *http.Client
+}
+
+func NewSlackNotifier() *SlackNotifier {
+	return &SlackNotifier{Client: &http.Client{Transport: egress.NewTransport()}}
+}
+
+func (this *SlackNotifier) Notify(Context context.Context, Target string, Notification Notification) error {
+	return PostJSON(Context, this.Client, Target, map[string]string{"text": Notification.GetSummary()})
+}
+
+func PostJSON(Context context.Context, Client *http.Client, URL string, Payload interface{}) error {
+	Body, EncodeError := json.Marshal(Payload)
+	if EncodeError != nil {
+		return EncodeError
+	}
+	Request, RequestError := http.NewRequestWithContext(Context, http.MethodPost, URL, bytes.NewReader(Body))
+	if RequestError != nil {
+		return RequestError
+	}
+	Request.Header.Set("Content-Type", "application/json")
+
+	Response, ResponseError := Client.Do(Request)
+	if ResponseError != nil {
+		return ResponseError
+	}
+	defer Response.Body.Close()
+	io.Copy(io.Discard, Response.Body)
+
+	if Response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("Webhook has Responded with the Status %v", Response.StatusCode)
+	}
+	return nil
+}
+
+func NewNotifiers() map[string]Notifier {
+	// Returns Notifiers of every Channel Type
+	return map[string]Notifier{
+		models.ChannelTypeEmail:   EmailNotifier{},
+		models.ChannelTypeWebhook: NewWebhookNotifier(),
+		models.ChannelTypeSlack:   NewSlackNotifier(),
+	}
+}
+
+// HTTP SINK
+
+type SinkRequest struct {
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+type HttpSink struct {
+	// Local HTTP Server, that Records the Webhook Notifications, used by the Tests and the Local Development
+	Server     *httptest.Server
+	Mutex      sync.Mutex
+	Requests   []SinkRequest
+	StatusCode int // Status, the Sink Responds with
+}
+
+func NewHttpSink() *HttpSink {
+	Sink := &HttpSink{StatusCode: http.StatusOK}
+	Sink.Server = httptest.NewServer(http.HandlerFunc(func(Writer http.ResponseWriter, Request *http.Request) {
+		Body, _ := io.ReadAll(Request.Body)
+		Sink.Mutex.Lock()
+		Sink.Requests = append(Sink.Requests, SinkRequest{Path: Request.URL.Path, Header: Request.Header.Clone(), Body: Body})
+		StatusCode := Sink.StatusCode
+		Sink.Mutex.Unlock()
+		Writer.WriteHeader(StatusCode)
+	}))
+	return Sink
+}
+
+func (this *HttpSink) GetURL() string {
+	return this.Server.URL
+}
+
+func (this *HttpSink) SetStatusCode(StatusCode int) {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.StatusCode = StatusCode
+}
+
+func (this *HttpSink) GetRequests() []SinkRequest {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	return append([]SinkRequest(nil), this.Requests...)
+}
+
+func (this *HttpSink) Close() {
+	this.Server.Close()
+}
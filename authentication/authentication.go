@@ -1,11 +1,22 @@
 package authentication
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/models"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -14,7 +25,26 @@ import (
 var (
 	Logger *zap.Logger
 )
-var secretKey = []byte(os.Getenv("JWT_SECRET_KEY"))
+var secretKey []byte
+
+var (
+	AccessTokenLifetime            = 15 * time.Minute
+	RefreshTokenLifetime           = 14 * 24 * time.Hour
+	PasswordResetTokenLifetime     = time.Hour
+	EmailVerificationTokenLifetime = 48 * time.Hour
+)
+
+const CredentialsContextKey = "Credentials"
+
+var (
+	ErrInvalidApiKey        = errors.New("Invalid or Expired API Key")
+	ErrInvalidRefreshToken  = errors.New("Invalid or Expired Refresh Token")
+	ErrRefreshTokenReused   = errors.New("Refresh Token has been Used already, Session has been Revoked")
+	ErrInvalidCustomerToken = errors.New("Invalid, Used or Expired Token")
+	ErrMfaPending           = errors.New("Second Factor of the Login has not been Verified")
+	ErrInvalidMfaToken      = errors.New("Invalid or Expired MFA Token")
+	ErrTooManyMfaAttempts   = errors.New("Too many MFA Attempts, Please Login again")
+)
 
 func InitializeProductionLogger() {
 
@@ -32,6 +62,15 @@ func init() {
 	InitializeProductionLogger()
 }
 
+func Configure(Config config.AuthenticationConfig) {
+	// Applies the Signing Key of the Tokens and their Lifetimes
+	secretKey = []byte(Config.SecretKey)
+	AccessTokenLifetime = Config.AccessTokenLifetime
+	RefreshTokenLifetime = Config.RefreshTokenLifetime
+	PasswordResetTokenLifetime = Config.PasswordResetTokenLifetime
+	EmailVerificationTokenLifetime = Config.EmailVerificationTokenLifetime
+}
+
 // Packege, that Is Responsible for handling Customer Authentication Policy
 
 func InvalidJwt() error {
@@ -44,17 +83,43 @@ type JwtToken struct {
 	UserId   int
 	Username string
 	Email    string
+
+	// Filled only, if the Customer has been Authorized by the API Key, instead of the Jwt Token
+	ApiKeyId int      `json:"-"`
+	Scopes   []string `json:"-"`
+
+	// Token, that has been Issued after the Password Check, but before the Second Factor has been Verified
+	// It can only be Exchanged for the Access Token, along with the Code of the Authenticator App
+	MfaPending bool `json:",omitempty"`
 }
 
-func CreateJwtToken(UserId int, Username string, Email string) (string, error) {
+func (this *JwtToken) HasScope(Scope string) bool {
+	// Checks, that the API Key Allows the Scope, Jwt Tokens are not being Limited by the Scopes
+	if this.ApiKeyId == 0 {
+		return true
+	}
+	for _, Allowed := range this.Scopes {
+		if Allowed == Scope {
+			return true
+		}
+	}
+	return false
+}
 
-	newToken := jwt.New(jwt.SigningMethodHS256)
-	newTokenClaims := newToken.Claims.(jwt.MapClaims)
+func CreateJwtToken(UserId int, Username string, Email string) (string, error) {
+	// Returns Short Lived Access Token, every Token has Unique ID (`jti`), so it can be Revoked before it Expires
 
-	newTokenClaims["UserId"] = UserId
-	newTokenClaims["Username"] = Username
-	newTokenClaims["Email"] = Email
-	newTokenClaims["exp"] = time.Now().Add(10000 * time.Minute).Unix()
+	IssuedAt := time.Now()
+	newToken := jwt.NewWithClaims(jwt.SigningMethodHS256, JwtToken{
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			IssuedAt:  IssuedAt.Unix(),
+			ExpiresAt: IssuedAt.Add(AccessTokenLifetime).Unix(),
+		},
+		UserId:   UserId,
+		Username: Username,
+		Email:    Email,
+	})
 	stringToken, Error := newToken.SignedString(secretKey)
 	if Error != nil {
 		Logger.Error("Failed to Stringify JWT Token. Error: %s", zap.Error(Error))
@@ -90,6 +155,7 @@ func CheckValidJwtToken(token string) error {
 func GetCustomerJwtCredentials(token string) (*JwtToken, error) {
 	// Returns Decoded Customer Credentials from the Jwt Auth Token
 
+	token = strings.TrimPrefix(token, "Bearer ")
 	if len(token) == 0 {
 		return nil, errors.New("Invalid Jwt Token")
 	}
@@ -100,5 +166,350 @@ func GetCustomerJwtCredentials(token string) (*JwtToken, error) {
 	if Error != nil {
 		return nil, Error
 	}
+	if DecodedData.MfaPending {
+		return nil, ErrMfaPending
+	}
+	return DecodedData, nil
+}
+
+func GetRequestCredentials(RequestContext *gin.Context) (*JwtToken, error) {
+	// Returns Credentials of the Customer, who has Sent the Request
+	// Credentials of the API Key are being Stored in the Context by the `AuthorizationRequiredMiddleware`
+	if Credentials, Exists := RequestContext.Get(CredentialsContextKey); Exists {
+		return Credentials.(*JwtToken), nil
+	}
+	return GetCustomerJwtCredentials(RequestContext.GetHeader("Authorization"))
+}
+
+// Refresh Tokens
+
+type TokenPair struct {
+	// Access Token along with the Refresh Token, that can be Exchanged for the new Pair, once the Access Token Expires
+	AccessToken  string `json:"AccessToken"`
+	RefreshToken string `json:"RefreshToken"`
+	ExpiresIn    int64  `json:"ExpiresIn"` // Lifetime of the Access Token in Seconds
+}
+
+func HashToken(Token string) string {
+	// Returns SHA-256 Hash of the Refresh Token or API Key, that is being Stored in the Database instead of it
+	Hash := sha256.Sum256([]byte(Token))
+	return hex.EncodeToString(Hash[:])
+}
+
+func GenerateRandomToken() (string, error) {
+	// Returns new Random Opaque Token, used for the Refresh Tokens and API Keys
+	Random := make([]byte, 32)
+	if _, RandomError := rand.Read(Random); RandomError != nil {
+		return "", RandomError
+	}
+	return base64.RawURLEncoding.EncodeToString(Random), nil
+}
+
+func IssueTokenPair(Customer models.Customer, FamilyId string) (*TokenPair, error) {
+	// Issues new Access and Refresh Token for the Customer
+	// Empty Family ID Starts new Session, Rotated Refresh Tokens Keep the Family of the Previous one
+
+	AccessToken, AccessError := CreateJwtToken(Customer.ID, Customer.Username, Customer.Email)
+	if AccessError != nil {
+		return nil, AccessError
+	}
+	RefreshToken, RefreshError := GenerateRandomToken()
+	if RefreshError != nil {
+		return nil, RefreshError
+	}
+
+	if len(FamilyId) == 0 {
+		FamilyId = uuid.NewString()
+	}
+	NewRefreshToken := models.NewRefreshToken(Customer.ID, FamilyId,
+		HashToken(RefreshToken), time.Now().Add(RefreshTokenLifetime))
+	if _, CreationError := NewRefreshToken.Create(); CreationError != nil {
+		Logger.Error("Failed to Store Refresh Token", zap.Error(CreationError))
+		return nil, CreationError
+	}
+	return &TokenPair{
+		AccessToken:  AccessToken,
+		RefreshToken: RefreshToken,
+		ExpiresIn:    int64(AccessTokenLifetime.Seconds()),
+	}, nil
+}
+
+func RotateRefreshToken(Token string) (*TokenPair, error) {
+	// Exchanges the Refresh Token for the new Token Pair, the Refresh Token can't be Used after that
+	// Reusing the Rotated Refresh Token means, that it has been Stolen, so the whole Session is being Revoked
+
+	var StoredToken models.RefreshToken
+	models.Database.Model(&models.RefreshToken{}).Where("token_hash = ?", HashToken(Token)).Find(&StoredToken)
+
+	if StoredToken.ID == 0 || time.Now().After(StoredToken.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if StoredToken.RevokedAt != nil || !StoredToken.Revoke() {
+		Logger.Warn("Revoked Refresh Token has been Reused, Revoking the Session",
+			zap.Int("CustomerId", StoredToken.CustomerId), zap.String("FamilyId", StoredToken.FamilyId))
+		models.RevokeRefreshTokenFamily(StoredToken.FamilyId)
+		return nil, ErrRefreshTokenReused
+	}
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("id = ?", StoredToken.CustomerId).Find(&Customer)
+	if Customer.ID == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+	return IssueTokenPair(Customer, StoredToken.FamilyId)
+}
+
+func RevokeRefreshToken(Token string) error {
+	// Revokes the Session, the Refresh Token Belongs to
+	var StoredToken models.RefreshToken
+	models.Database.Model(&models.RefreshToken{}).Where("token_hash = ?", HashToken(Token)).Find(&StoredToken)
+	if StoredToken.ID == 0 {
+		return ErrInvalidRefreshToken
+	}
+	return models.RevokeRefreshTokenFamily(StoredToken.FamilyId)
+}
+
+// Single Use Tokens of the Password Reset and Email Verification
+
+func GetCustomerTokenLifetime(Purpose string) time.Duration {
+	if Purpose == models.TokenPurposePasswordReset {
+		return PasswordResetTokenLifetime
+	}
+	return EmailVerificationTokenLifetime
+}
+
+func IssueCustomerToken(CustomerId int, Purpose string) (string, error) {
+	// Issues new Single Use Token of the Customer, Tokens of the same Purpose, Issued before, are being Invalidated
+	Token, GenerateError := GenerateRandomToken()
+	if GenerateError != nil {
+		return "", GenerateError
+	}
+	NewToken := models.NewCustomerToken(CustomerId, Purpose,
+		HashToken(Token), time.Now().Add(GetCustomerTokenLifetime(Purpose)))
+	if _, CreationError := NewToken.Create(); CreationError != nil {
+		Logger.Error("Failed to Store Customer Token", zap.String("Purpose", Purpose), zap.Error(CreationError))
+		return "", CreationError
+	}
+	return Token, nil
+}
+
+func ConsumeCustomerToken(Token string, Purpose string) (*models.Customer, error) {
+	// Marks the Token as Used and Returns the Customer, it has been Issued for
+
+	var StoredToken models.CustomerToken
+	models.Database.Model(&models.CustomerToken{}).Where(
+		"token_hash = ? AND purpose = ?", HashToken(Token), Purpose).Find(&StoredToken)
+	if StoredToken.ID == 0 || !StoredToken.IsActive() || !StoredToken.Use() {
+		return nil, ErrInvalidCustomerToken
+	}
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("id = ?", StoredToken.CustomerId).Find(&Customer)
+	if Customer.ID == 0 {
+		return nil, ErrInvalidCustomerToken
+	}
+	return &Customer, nil
+}
+
+// Access Tokens Denylist
+// Access Tokens can't be Revoked by themselves, so IDs of the Revoked ones are being Stored in Redis, until they Expire
+
+func GetDenylistKey(TokenId string) string {
+	return fmt.Sprintf("jwt:denylist:%s", TokenId)
+}
+
+func GetRevokedBeforeKey(UserId int) string {
+	return fmt.Sprintf("jwt:revoked-before:%v", UserId)
+}
+
+func RevokeAccessToken(Client *redis.Client, Credentials *JwtToken) error {
+	// Adds the Access Token to the Denylist, until it Expires
+	TimeToLive := time.Until(time.Unix(Credentials.ExpiresAt, 0))
+	if TimeToLive <= 0 {
+		return nil
+	}
+	return Client.Set(GetDenylistKey(Credentials.Id), Credentials.UserId, TimeToLive).Err()
+}
+
+func RevokeCustomerSessions(Client *redis.Client, UserId int) error {
+	// Revokes every Session of the Customer: Refresh Tokens are being Revoked in the Database,
+	// Access Tokens, that have been Issued before, are being Rejected, until the last of them Expires
+	if RevokeError := models.RevokeCustomerRefreshTokens(UserId); RevokeError != nil {
+		return RevokeError
+	}
+	return Client.Set(GetRevokedBeforeKey(UserId), time.Now().Unix(), AccessTokenLifetime).Err()
+}
+
+func IsAccessTokenRevoked(Client *redis.Client, Credentials *JwtToken) (bool, error) {
+	// Checks, that the Access Token has been Revoked, either by Itself or along with all the Customer's Sessions
+
+	Denied, DenylistError := Client.Exists(GetDenylistKey(Credentials.Id)).Result()
+	if DenylistError != nil {
+		return false, DenylistError
+	}
+	if Denied != 0 {
+		return true, nil
+	}
+
+	RevokedBefore, RevokedError := Client.Get(GetRevokedBeforeKey(Credentials.UserId)).Int64()
+	switch {
+	case RevokedError == redis.Nil:
+		return false, nil
+	case RevokedError != nil:
+		return false, RevokedError
+	default:
+		// Tokens, Issued within the same Second, as the Sessions have been Revoked, belong to the new Session (e.g after the Password Reset)
+		return Credentials.IssuedAt < RevokedBefore, nil
+	}
+}
+
+func IsCredentialsRevoked(Client *redis.Client, Credentials *JwtToken) (bool, error) {
+	// Checks, that the Credentials can't be Used anymore: the API Key has been Revoked or has Expired, or the Access Token has been Revoked
+	if Credentials.ApiKeyId != 0 {
+		var StoredKey models.ApiKey
+		if Found := models.Database.Model(&models.ApiKey{}).Where("id = ?", Credentials.ApiKeyId).Find(&StoredKey); Found.Error != nil {
+			return false, Found.Error
+		}
+		return StoredKey.ID == 0 || !StoredKey.IsActive(), nil
+	}
+	return IsAccessTokenRevoked(Client, Credentials)
+}
+
+// Second Factor Login
+
+var (
+	MfaPendingTokenLifetime = 5 * time.Minute
+	MaxMfaAttempts          = int64(5) // Number of the Codes, that can be Tried with the Single MFA Token
+)
+
+func CreateMfaPendingToken(UserId int, Username string, Email string) (string, error) {
+	// Returns Short Lived Token, that Confirms the Password of the Customer has been Checked
+	IssuedAt := time.Now()
+	newToken := jwt.NewWithClaims(jwt.SigningMethodHS256, JwtToken{
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			IssuedAt:  IssuedAt.Unix(),
+			ExpiresAt: IssuedAt.Add(MfaPendingTokenLifetime).Unix(),
+		},
+		UserId:     UserId,
+		Username:   Username,
+		Email:      Email,
+		MfaPending: true,
+	})
+	return newToken.SignedString(secretKey)
+}
+
+func GetMfaPendingCredentials(token string) (*JwtToken, error) {
+	// Returns Credentials of the MFA Token, Access Tokens are not being Accepted
+	DecodedData := &JwtToken{}
+	if _, Error := jwt.ParseWithClaims(token, DecodedData,
+		func(token *jwt.Token) (interface{}, error) { return secretKey, nil }); Error != nil {
+		return nil, ErrInvalidMfaToken
+	}
+	if !DecodedData.MfaPending {
+		return nil, ErrInvalidMfaToken
+	}
 	return DecodedData, nil
-}
\ No newline at end of file
+}
+
+func GetMfaAttemptsKey(TokenId string) string {
+	return fmt.Sprintf("mfa:attempts:%s", TokenId)
+}
+
+func RegisterMfaAttempt(Client *redis.Client, Credentials *JwtToken) error {
+	// Counts Attempts of the MFA Token, so the Codes can't be Brute Forced
+	Attempts, IncrementError := Client.Incr(GetMfaAttemptsKey(Credentials.Id)).Result()
+	if IncrementError != nil {
+		return IncrementError
+	}
+	if Attempts == 1 {
+		Client.Expire(GetMfaAttemptsKey(Credentials.Id), MfaPendingTokenLifetime)
+	}
+	if Attempts > MaxMfaAttempts {
+		return ErrTooManyMfaAttempts
+	}
+	return nil
+}
+
+// Personal API Keys
+
+const ApiKeyPrefix = "ak_"
+
+const (
+	ScopeVirtualMachinesRead  = "vm:read"  // Reading Virtual Machines, their Metrics, Snapshots and Jobs
+	ScopeVirtualMachinesWrite = "vm:write" // Creating, Operating and Removing Virtual Machines
+	ScopeSshRead              = "ssh:read" // Downloading SSH Credentials of the Virtual Machines
+)
+
+var (
+	ApiKeyScopes      = []string{ScopeVirtualMachinesRead, ScopeVirtualMachinesWrite, ScopeSshRead}
+	MaxApiKeyLifetime = 365 * 24 * time.Hour
+)
+
+func IsValidScope(Scope string) bool {
+	for _, Valid := range ApiKeyScopes {
+		if Valid == Scope {
+			return true
+		}
+	}
+	return false
+}
+
+func IsApiKey(Key string) bool {
+	return strings.HasPrefix(Key, ApiKeyPrefix)
+}
+
+func GetRequestApiKey(RequestContext *gin.Context) string {
+	// Returns API Key, passed either in the `X-API-Key` Header or as the `Bearer` Token
+	if Key := RequestContext.GetHeader("X-API-Key"); len(Key) != 0 {
+		return Key
+	}
+	if Key := strings.TrimPrefix(RequestContext.GetHeader("Authorization"), "Bearer "); IsApiKey(Key) {
+		return Key
+	}
+	return ""
+}
+
+func CreateApiKey(CustomerId int, Name string, Scopes []string, Lifetime time.Duration) (string, *models.ApiKey, error) {
+	// Creates new API Key of the Customer, Returns the Key itself, it can't be Received later
+	Secret, GenerateError := GenerateRandomToken()
+	if GenerateError != nil {
+		return "", nil, GenerateError
+	}
+	Key := ApiKeyPrefix + Secret
+
+	NewApiKey := models.NewApiKey(CustomerId, Name, Key[:len(ApiKeyPrefix)+6],
+		HashToken(Key), Scopes, time.Now().Add(Lifetime))
+	if _, CreationError := NewApiKey.Create(); CreationError != nil {
+		Logger.Error("Failed to Store API Key", zap.Error(CreationError))
+		return "", nil, CreationError
+	}
+	return Key, NewApiKey, nil
+}
+
+func AuthenticateApiKey(Key string) (*JwtToken, error) {
+	// Returns Credentials of the Customer, the API Key Belongs to
+
+	var StoredKey models.ApiKey
+	models.Database.Model(&models.ApiKey{}).Where("key_hash = ?", HashToken(Key)).Find(&StoredKey)
+	if StoredKey.ID == 0 || !StoredKey.IsActive() {
+		return nil, ErrInvalidApiKey
+	}
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("id = ?", StoredKey.CustomerId).Find(&Customer)
+	if Customer.ID == 0 {
+		return nil, ErrInvalidApiKey
+	}
+
+	if TouchError := StoredKey.Touch(); TouchError != nil {
+		Logger.Error("Failed to Record API Key Usage", zap.Error(TouchError))
+	}
+	return &JwtToken{
+		UserId:   Customer.ID,
+		Username: Customer.Username,
+		Email:    Customer.Email,
+		ApiKeyId: StoredKey.ID,
+		Scopes:   StoredKey.GetScopes(),
+	}, nil
+}
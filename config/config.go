@@ -0,0 +1,735 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v2"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Typed Configuration of the Application, that is being Loaded once at the Startup
+// and Passed Explicitly into every Subsystem
+
+// Every Value is being Taken from the first Source, that has it:
+//   1. Command Line Flag, e.g `--database-host` for the `DATABASE_HOST`
+//   2. Environment Variable `DATABASE_HOST`, or the File, Specified in the `DATABASE_HOST_FILE` one (Mounted Secrets)
+//   3. YAML / TOML Configuration File, Specified in the `--config` Flag or the `CONFIG_FILE` Variable
+//   4. Default Value of the Field
+
+var (
+	Logger *zap.Logger
+)
+
+const (
+	ConfigFileKey    = "CONFIG_FILE"
+	ConfigFileFlag   = "config"
+	SecretFileSuffix = "_FILE"
+	RateLimitPrefix  = "RATE_LIMIT_"
+	DefaultRegion    = "default"
+)
+
+var (
+	MinSecretKeyLength = 32
+	MailerBackends     = []string{"smtp", "file", "memory"}
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("Configuration File should be either `.yaml`, `.yml` or `.toml`")
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("ConfigLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+// Sections of the Configuration
+// Fields are being Described with the Tags:
+//   `env`      - Key of the Value, used as is in the Environment and the Files, and as the Flag in lower case with dashes
+//   `default`  - Value, used if there is no one in any Source
+//   `required` - Value should not be Empty
+//   `secret`   - Value is being Hidden, once the Configuration is being Printed
+//   `min`      - Lowest Allowed Number (Seconds for the Durations), 1 by Default
+// Durations can be Specified either in Seconds, or as `15m`, `1h30m` and so on
+
+type ApplicationConfig struct {
+	Host      string `env:"APPLICATION_HOST" default:"localhost"`
+	Port      int    `env:"APPLICATION_PORT" default:"8001"`
+	FrontHost string `env:"FRONT_APPLICATION_HOST" default:"localhost"`
+	FrontPort int    `env:"FRONT_APPLICATION_PORT" default:"3000"`
+}
+
+func (this ApplicationConfig) GetFrontApplicationURL() string {
+	// Returns URL of the Front Application, the Links in the Emails are being Pointed to
+	return fmt.Sprintf("http://%s:%v", this.FrontHost, this.FrontPort)
+}
+
+type DatabaseConfig struct {
+	Host     string `env:"DATABASE_HOST" required:"true"`
+	Port     int    `env:"DATABASE_PORT" default:"5432"`
+	User     string `env:"DATABASE_USER" required:"true"`
+	Password string `env:"DATABASE_PASSWORD" secret:"true"`
+	Name     string `env:"DATABASE_NAME" required:"true"`
+}
+
+type CacheConfig struct {
+	Host           string `env:"CACHE_STORAGE_HOST" required:"true"`
+	Port           int    `env:"CACHE_STORAGE_PORT" default:"6379"`
+	Password       string `env:"CACHE_STORAGE_PASSWORD" secret:"true"`
+	DatabaseNumber int    `env:"CACHE_STORAGE_DATABASE_NUMBER" default:"0" min:"0"`
+}
+
+func (this CacheConfig) GetAddress() string {
+	return fmt.Sprintf("%s:%v", this.Host, this.Port)
+}
+
+type AuthenticationConfig struct {
+	SecretKey                      string        `env:"JWT_SECRET_KEY" required:"true" secret:"true"`
+	AccessTokenLifetime            time.Duration `env:"ACCESS_TOKEN_LIFETIME" default:"900"`
+	RefreshTokenLifetime           time.Duration `env:"REFRESH_TOKEN_LIFETIME" default:"1209600"`
+	PasswordResetTokenLifetime     time.Duration `env:"PASSWORD_RESET_TOKEN_LIFETIME" default:"3600"`
+	EmailVerificationTokenLifetime time.Duration `env:"EMAIL_VERIFICATION_TOKEN_LIFETIME" default:"172800"`
+}
+
+type MfaConfig struct {
+	Issuer string `env:"MFA_ISSUER" default:"KubeLagoon"`
+}
+
+type OidcConfig struct {
+	IssuerURL    string   `env:"OIDC_ISSUER_URL"`
+	ClientId     string   `env:"OIDC_CLIENT_ID"`
+	ClientSecret string   `env:"OIDC_CLIENT_SECRET" secret:"true"`
+	RedirectURL  string   `env:"OIDC_REDIRECT_URL"`
+	Scopes       []string `env:"OIDC_SCOPES" default:"openid email profile"`
+}
+
+func (this OidcConfig) IsEnabled() bool {
+	// Single Sign On is being Enabled, once the Identity Provider has been Specified
+	return len(this.IssuerURL) != 0 || len(this.ClientId) != 0
+}
+
+type MailerConfig struct {
+	Backend  string `env:"MAILER_BACKEND" default:"smtp"`
+	FilePath string `env:"MAILER_FILE_PATH" default:"Mails.json"`
+	Address  string `env:"SUPPORT_EMAIL_ADDRESS"`
+	Password string `env:"SUPPORT_EMAIL_ADDRESS_PASSWORD" secret:"true"`
+	SmtpHost string `env:"SUPPORT_EMAIL_SMTP_HOST"`
+	SmtpPort int    `env:"SUPPORT_EMAIL_SMTP_PORT" default:"587"`
+}
+
+type LimitConfig struct {
+	// Rate Limit of the Route Group, Specified as `<Requests>/<Seconds>`
+	Requests int
+	Period   time.Duration
+}
+
+func ParseLimit(Value string) (LimitConfig, error) {
+	Parts := strings.Split(strings.TrimSpace(Value), "/")
+	if len(Parts) != 2 {
+		return LimitConfig{}, fmt.Errorf("`%s` should be Specified as `<Requests>/<Seconds>`", Value)
+	}
+	Requests, RequestsError := strconv.Atoi(Parts[0])
+	Seconds, SecondsError := strconv.Atoi(Parts[1])
+	if RequestsError != nil || SecondsError != nil || Requests <= 0 || Seconds <= 0 {
+		return LimitConfig{}, fmt.Errorf("`%s` should be Specified as `<Requests>/<Seconds>`", Value)
+	}
+	return LimitConfig{Requests: Requests, Period: time.Duration(Seconds) * time.Second}, nil
+}
+
+type RateLimitConfig struct {
+	MaxFailedLogins int           `env:"LOGIN_MAX_FAILED_ATTEMPTS" default:"5"`
+	BaseLockout     time.Duration `env:"LOGIN_LOCKOUT_BASE" default:"30"`
+	MaxLockout      time.Duration `env:"LOGIN_LOCKOUT_MAX" default:"3600"`
+
+	// `RATE_LIMIT_<GROUP>` Overrides of the Default Limits, by the lower case Name of the Route Group
+	Limits map[string]LimitConfig
+}
+
+type JobsConfig struct {
+	Workers        int           `env:"JOB_WORKERS_NUMBER" default:"5"`
+	QueueSize      int           `env:"JOB_QUEUE_SIZE" default:"1000"`
+	LockTimeToLive time.Duration `env:"VM_LOCK_TIME_TO_LIVE" default:"60"`
+}
+
+type IdempotencyConfig struct {
+	KeyTimeToLive time.Duration `env:"IDEMPOTENCY_KEY_TTL" default:"86400"`
+}
+
+type ReconcilerConfig struct {
+	Interval time.Duration `env:"RECONCILE_INTERVAL" default:"60"`
+}
+
+type MetricsConfig struct {
+	CollectInterval time.Duration `env:"METRICS_COLLECT_INTERVAL" default:"60"`
+	Token           string        `env:"METRICS_TOKEN" secret:"true"` // Bearer Token, Required to Scrape the Metrics, if it is not Empty
+
+	// Health History of the Virtual Machines, Raw Samples are being Rolled up into the 5 Minutes Averages
+	RawRetention    time.Duration `env:"METRICS_HISTORY_RAW_RETENTION" default:"86400"`
+	RollupRetention time.Duration `env:"METRICS_HISTORY_ROLLUP_RETENTION" default:"2592000"`
+}
+
+type AlertingConfig struct {
+	NotificationTimeout time.Duration `env:"ALERT_NOTIFICATION_TIMEOUT" default:"10"`
+}
+
+type StreamConfig struct {
+	KeepAliveInterval time.Duration `env:"STREAM_KEEPALIVE_INTERVAL" default:"15"`   // Interval of the Comments, that Keep the Idle Streams Open behind the Proxies
+	BufferSize        int           `env:"STREAM_BUFFER_SIZE" default:"100" min:"1"` // Events, Buffered for the Slow Client, before the Newer ones are being Dropped
+}
+
+type WebhooksConfig struct {
+	Timeout      time.Duration `env:"WEBHOOK_TIMEOUT" default:"10"`
+	MaxAttempts  int           `env:"WEBHOOK_MAX_ATTEMPTS" default:"8" min:"1"`
+	BackoffBase  time.Duration `env:"WEBHOOK_BACKOFF_BASE" default:"30"`  // Delay after the First Failed Attempt, Doubled after every next one
+	BackoffMax   time.Duration `env:"WEBHOOK_BACKOFF_MAX" default:"3600"` // Longest Delay between the Attempts
+	PollInterval time.Duration `env:"WEBHOOK_POLL_INTERVAL" default:"5"`  // Interval of Checking the Due Deliveries
+}
+
+type CustomersConfig struct {
+	VirtualMachinesLimit int `env:"CUSTOMER_VIRTUAL_MACHINES_LIMIT" default:"10"`
+}
+
+type EndpointConfig struct {
+	// vCenter, Serving the Region, Credentials are being Taken from the `<Credentials>_USERNAME`
+	// and `<Credentials>_PASSWORD` Keys
+	Name        string `json:"Name"`
+	Region      string `json:"Region"`
+	Host        string `json:"Host"`
+	Credentials string `json:"Credentials"`
+	Username    string `json:"-"`
+	Password    string `json:"-"`
+}
+
+type VsphereConfig struct {
+	Host     string `env:"VMWARE_SOURCE_IP"`
+	Username string `env:"VMWARE_SOURCE_USERNAME"`
+	Password string `env:"VMWARE_SOURCE_PASSWORD" secret:"true"`
+
+	// JSON Array of the vCenters, e.g `[{"Name": "vc-eu", "Region": "eu", "Host": "10.0.0.1", "Credentials": "VSPHERE_EU"}]`,
+	// Single vCenter of the `VMWARE_SOURCE_*` Keys is being used, if it is Empty
+	RawEndpoints string `env:"VSPHERE_ENDPOINTS"`
+
+	KeepAliveInterval time.Duration `env:"VSPHERE_KEEPALIVE_INTERVAL" default:"300"`
+	BreakerFailures   int           `env:"VSPHERE_BREAKER_FAILURES" default:"3"`
+	BreakerCooldown   time.Duration `env:"VSPHERE_BREAKER_COOLDOWN" default:"30"`
+
+	Endpoints []EndpointConfig
+}
+
+type Config struct {
+	Application    ApplicationConfig
+	Database       DatabaseConfig
+	Cache          CacheConfig
+	Authentication AuthenticationConfig
+	Mfa            MfaConfig
+	Oidc           OidcConfig
+	Mailer         MailerConfig
+	RateLimit      RateLimitConfig
+	Jobs           JobsConfig
+	Idempotency    IdempotencyConfig
+	Reconciler     ReconcilerConfig
+	Metrics        MetricsConfig
+	Alerting       AlertingConfig
+	Stream         StreamConfig
+	Webhooks       WebhooksConfig
+	Customers      CustomersConfig
+	Vsphere        VsphereConfig
+
+	File      string   // Configuration File, the Values have been Loaded from
+	Arguments []string // Command Line Arguments, left after the Flags
+}
+
+type ValidationError struct {
+	// All the Problems of the Configuration, so they can be Fixed at once
+	Problems []string
+}
+
+func (this *ValidationError) Error() string {
+	return "Invalid Configuration:\n  - " + strings.Join(this.Problems, "\n  - ")
+}
+
+func (this *ValidationError) Add(Format string, Arguments ...interface{}) {
+	this.Problems = append(this.Problems, fmt.Sprintf(Format, Arguments...))
+}
+
+type Field struct {
+	// Configuration Field, Described by the Tags
+	Key      string
+	Default  string
+	Required bool
+	Secret   bool
+	Min      *int64
+	Value    reflect.Value
+}
+
+func GetFields(Config *Config) []Field {
+	// Returns Fields of all the Sections, that have the `env` Tag
+	Fields := []Field{}
+	Sections := reflect.ValueOf(Config).Elem()
+	for SectionIndex := 0; SectionIndex < Sections.NumField(); SectionIndex++ {
+		Section := Sections.Field(SectionIndex)
+		if Section.Kind() != reflect.Struct {
+			continue
+		}
+		for Index := 0; Index < Section.NumField(); Index++ {
+			Tag := Section.Type().Field(Index).Tag
+			Key, Ok := Tag.Lookup("env")
+			if !Ok {
+				continue
+			}
+			Described := Field{
+				Key:      Key,
+				Default:  Tag.Get("default"),
+				Required: Tag.Get("required") == "true",
+				Secret:   Tag.Get("secret") == "true",
+				Value:    Section.Field(Index),
+			}
+			if Min, ParseError := strconv.ParseInt(Tag.Get("min"), 10, 64); ParseError == nil {
+				Described.Min = &Min
+			}
+			Fields = append(Fields, Described)
+		}
+	}
+	return Fields
+}
+
+func GetFlagName(Key string) string {
+	// Returns Command Line Flag of the Key, e.g `--database-host` for the `DATABASE_HOST`
+	return strings.ToLower(strings.ReplaceAll(Key, "_", "-"))
+}
+
+func ParseDuration(Value string) (time.Duration, error) {
+	if Seconds, ParseError := strconv.ParseInt(Value, 10, 64); ParseError == nil {
+		return time.Duration(Seconds) * time.Second, nil
+	}
+	return time.ParseDuration(Value)
+}
+
+func (this Field) Set(Value string) error {
+	// Parses the Value according to the Type of the Field
+	switch {
+	case this.Value.Type() == reflect.TypeOf(time.Duration(0)):
+		Duration, ParseError := ParseDuration(Value)
+		if ParseError != nil {
+			return fmt.Errorf("%s should be a Duration in Seconds or like `1m30s`, got `%s`", this.Key, Value)
+		}
+		if Min := time.Duration(this.GetMin()) * time.Second; Duration < Min {
+			return fmt.Errorf("%s should not be less than %s, got `%s`", this.Key, Min, Value)
+		}
+		this.Value.SetInt(int64(Duration))
+	case this.Value.Kind() == reflect.Int:
+		Number, ParseError := strconv.ParseInt(Value, 10, 64)
+		if ParseError != nil {
+			return fmt.Errorf("%s should be an Integer, got `%s`", this.Key, Value)
+		}
+		if Number < this.GetMin() {
+			return fmt.Errorf("%s should not be less than %v, got `%s`", this.Key, this.GetMin(), Value)
+		}
+		this.Value.SetInt(Number)
+	case this.Value.Kind() == reflect.Slice:
+		this.Value.Set(reflect.ValueOf(strings.Fields(Value)))
+	default:
+		this.Value.SetString(Value)
+	}
+	return nil
+}
+
+func (this Field) GetMin() int64 {
+	if this.Min == nil {
+		return 1
+	}
+	return *this.Min
+}
+
+type Source struct {
+	// Values of the Keys, Collected from the Flags, the Environment and the Configuration File
+	Flags       map[string]string
+	Environment map[string]string
+	File        map[string]string
+}
+
+func (this *Source) Lookup(Key string) (string, bool, error) {
+	// Returns the Value of the Key from the Source with the highest Precedence
+	// Empty Variables, such as the Placeholders of the `.env` Files, are not being Considered
+	if Value, Ok := this.Flags[Key]; Ok {
+		return Value, true, nil
+	}
+	Value := this.Environment[Key]
+	InEnvironment := len(Value) != 0
+	SecretFile := this.Environment[Key+SecretFileSuffix]
+	InSecretFile := len(SecretFile) != 0
+	if InEnvironment && InSecretFile {
+		return "", false, fmt.Errorf("Only one of the %s and the %s should be Specified", Key, Key+SecretFileSuffix)
+	}
+	if InEnvironment {
+		return Value, true, nil
+	}
+	if !InSecretFile {
+		SecretFile = this.File[Key+SecretFileSuffix]
+		InSecretFile = len(SecretFile) != 0
+	}
+	if InSecretFile {
+		Content, ReadError := os.ReadFile(SecretFile)
+		if ReadError != nil {
+			return "", false, fmt.Errorf("Failed to Read %s: %s", Key+SecretFileSuffix, ReadError)
+		}
+		return strings.TrimRight(string(Content), "\r\n"), true, nil
+	}
+	Value, Ok := this.File[Key]
+	return Value, Ok, nil
+}
+
+func (this *Source) GetKeys(Prefix string) []string {
+	// Returns Keys of the Environment and the File, that Start with the Prefix
+	Found := map[string]bool{}
+	for _, Values := range []map[string]string{this.Environment, this.File} {
+		for Key := range Values {
+			if strings.HasPrefix(Key, Prefix) && !strings.HasSuffix(Key, SecretFileSuffix) {
+				Found[Key] = true
+			}
+		}
+	}
+	Keys := make([]string, 0, len(Found))
+	for Key := range Found {
+		Keys = append(Keys, Key)
+	}
+	sort.Strings(Keys)
+	return Keys
+}
+
+func Flatten(Prefix string, Value interface{}, Values map[string]string) {
+	// Flattens the Nested Sections of the File into the Keys, e.g `database: {host: ...}` into the `DATABASE_HOST`
+	Key := strings.ToUpper(Prefix)
+	switch Typed := Value.(type) {
+	case map[string]interface{}:
+		for Name, Nested := range Typed {
+			Flatten(JoinKey(Prefix, Name), Nested, Values)
+		}
+	case map[interface{}]interface{}:
+		for Name, Nested := range Typed {
+			Flatten(JoinKey(Prefix, fmt.Sprint(Name)), Nested, Values)
+		}
+	case []interface{}:
+		if IsScalars(Typed) {
+			Items := make([]string, 0, len(Typed))
+			for _, Item := range Typed {
+				Items = append(Items, fmt.Sprint(Item))
+			}
+			Values[Key] = strings.Join(Items, " ")
+			return
+		}
+		// Lists of the Sections, such as the vSphere Endpoints, are being Kept as JSON
+		Serialized, _ := json.Marshal(Stringify(Typed))
+		Values[Key] = string(Serialized)
+	case nil:
+		Values[Key] = ""
+	default:
+		Values[Key] = fmt.Sprint(Typed)
+	}
+}
+
+func JoinKey(Prefix string, Name string) string {
+	if len(Prefix) == 0 {
+		return Name
+	}
+	return Prefix + "_" + Name
+}
+
+func IsScalars(Items []interface{}) bool {
+	for _, Item := range Items {
+		switch Item.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+func Stringify(Value interface{}) interface{} {
+	// Converts YAML Maps into the ones, that can be Encoded into JSON
+	switch Typed := Value.(type) {
+	case map[interface{}]interface{}:
+		Converted := make(map[string]interface{}, len(Typed))
+		for Name, Nested := range Typed {
+			Converted[fmt.Sprint(Name)] = Stringify(Nested)
+		}
+		return Converted
+	case map[string]interface{}:
+		for Name, Nested := range Typed {
+			Typed[Name] = Stringify(Nested)
+		}
+		return Typed
+	case []interface{}:
+		for Index, Item := range Typed {
+			Typed[Index] = Stringify(Item)
+		}
+		return Typed
+	default:
+		return Typed
+	}
+}
+
+func ReadFile(Path string) (map[string]string, error) {
+	// Reads the YAML or the TOML Configuration File into the Flat Keys
+	Content, ReadError := os.ReadFile(Path)
+	if ReadError != nil {
+		return nil, ReadError
+	}
+	Parsed := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(Path)) {
+	case ".yaml", ".yml":
+		ReadError = yaml.Unmarshal(Content, &Parsed)
+	case ".toml":
+		ReadError = toml.Unmarshal(Content, &Parsed)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if ReadError != nil {
+		return nil, ReadError
+	}
+	Values := map[string]string{}
+	Flatten("", Parsed, Values)
+	return Values, nil
+}
+
+func GetEnvironment(Environ []string) map[string]string {
+	Environment := map[string]string{}
+	for _, Variable := range Environ {
+		if Key, Value, Ok := strings.Cut(Variable, "="); Ok {
+			Environment[Key] = Value
+		}
+	}
+	return Environment
+}
+
+func Load(Arguments []string) (*Config, error) {
+	// Loads the Configuration of the Application from the Command Line Arguments, the Environment and the Files
+	return LoadFrom(Arguments, os.Environ())
+}
+
+func LoadFrom(Arguments []string, Environ []string) (*Config, error) {
+	Config := &Config{}
+	Fields := GetFields(Config)
+
+	Flags := flag.NewFlagSet("infrastructure", flag.ContinueOnError)
+	ConfigFile := Flags.String(ConfigFileFlag, "", "YAML / TOML Configuration File")
+	for _, Field := range Fields {
+		Flags.String(GetFlagName(Field.Key), Field.Default, Field.Key)
+	}
+	if ParseError := Flags.Parse(Arguments); ParseError != nil {
+		return nil, ParseError
+	}
+	Source := &Source{
+		Flags:       map[string]string{},
+		Environment: GetEnvironment(Environ),
+		File:        map[string]string{},
+	}
+	FlagKeys := map[string]string{}
+	for _, Field := range Fields {
+		FlagKeys[GetFlagName(Field.Key)] = Field.Key
+	}
+	Flags.Visit(func(Flag *flag.Flag) {
+		if Key, Ok := FlagKeys[Flag.Name]; Ok {
+			Source.Flags[Key] = Flag.Value.String()
+		}
+	})
+
+	Config.File = *ConfigFile
+	if len(Config.File) == 0 {
+		Config.File = Source.Environment[ConfigFileKey]
+	}
+	if len(Config.File) != 0 {
+		Values, ReadError := ReadFile(Config.File)
+		if ReadError != nil {
+			return nil, fmt.Errorf("Failed to Read the Configuration File `%s`: %w", Config.File, ReadError)
+		}
+		Source.File = Values
+	}
+	Config.Arguments = Flags.Args()
+
+	Problems := &ValidationError{}
+	for _, Field := range Fields {
+		Value, Ok, LookupError := Source.Lookup(Field.Key)
+		if LookupError != nil {
+			Problems.Add("%s", LookupError)
+			continue
+		}
+		if !Ok || len(strings.TrimSpace(Value)) == 0 {
+			Value = Field.Default
+		}
+		if len(strings.TrimSpace(Value)) == 0 {
+			if Field.Required {
+				Problems.Add("%s is Required", Field.Key)
+			}
+			continue
+		}
+		if SetError := Field.Set(strings.TrimSpace(Value)); SetError != nil {
+			Problems.Add("%s", SetError)
+		}
+	}
+	Config.RateLimit.Limits = LoadLimits(Source, Problems)
+	Config.Vsphere.Endpoints = LoadEndpoints(Config.Vsphere, Source, Problems)
+	Config.Validate(Problems)
+
+	if len(Problems.Problems) != 0 {
+		return nil, Problems
+	}
+	Logger.Debug("Configuration has been Loaded", zap.String("File", Config.File), zap.String("Values", Config.String()))
+	return Config, nil
+}
+
+func LoadLimits(Source *Source, Problems *ValidationError) map[string]LimitConfig {
+	// Collects the `RATE_LIMIT_<GROUP>` Overrides of the Route Groups
+	Limits := map[string]LimitConfig{}
+	for _, Key := range Source.GetKeys(RateLimitPrefix) {
+		Value, _, LookupError := Source.Lookup(Key)
+		if LookupError != nil {
+			Problems.Add("%s", LookupError)
+			continue
+		}
+		if len(Value) == 0 {
+			continue
+		}
+		Limit, ParseError := ParseLimit(Value)
+		if ParseError != nil {
+			Problems.Add("%s %s", Key, ParseError)
+			continue
+		}
+		Limits[strings.ToLower(strings.TrimPrefix(Key, RateLimitPrefix))] = Limit
+	}
+	return Limits
+}
+
+func LoadEndpoints(Vsphere VsphereConfig, Source *Source, Problems *ValidationError) []EndpointConfig {
+	// Returns vCenters of the Regions along with their Credentials
+	if len(strings.TrimSpace(Vsphere.RawEndpoints)) == 0 {
+		if len(Vsphere.Host) == 0 {
+			Problems.Add("Either VMWARE_SOURCE_IP or VSPHERE_ENDPOINTS is Required")
+			return nil
+		}
+		return []EndpointConfig{{
+			Name:        DefaultRegion,
+			Region:      DefaultRegion,
+			Host:        Vsphere.Host,
+			Credentials: "VMWARE_SOURCE",
+			Username:    Vsphere.Username,
+			Password:    Vsphere.Password,
+		}}
+	}
+
+	var Endpoints []EndpointConfig
+	if DecodeError := json.Unmarshal([]byte(Vsphere.RawEndpoints), &Endpoints); DecodeError != nil {
+		Problems.Add("VSPHERE_ENDPOINTS should be a JSON Array of the Endpoints: %s", DecodeError)
+		return nil
+	}
+	if len(Endpoints) == 0 {
+		Problems.Add("VSPHERE_ENDPOINTS should have at least one Endpoint")
+		return nil
+	}
+	Regions := map[string]bool{}
+	for Index := range Endpoints {
+		Endpoint := &Endpoints[Index]
+		if len(Endpoint.Region) == 0 || len(Endpoint.Host) == 0 {
+			Problems.Add("VSPHERE_ENDPOINTS Endpoint #%v should have both the Region and the Host", Index+1)
+			continue
+		}
+		if Regions[Endpoint.Region] {
+			Problems.Add("VSPHERE_ENDPOINTS Region `%s` has more than one Endpoint", Endpoint.Region)
+			continue
+		}
+		Regions[Endpoint.Region] = true
+		if len(Endpoint.Name) == 0 {
+			Endpoint.Name = Endpoint.Host
+		}
+		if len(Endpoint.Credentials) == 0 {
+			continue
+		}
+		for Suffix, Target := range map[string]*string{"_USERNAME": &Endpoint.Username, "_PASSWORD": &Endpoint.Password} {
+			Value, _, LookupError := Source.Lookup(Endpoint.Credentials + Suffix)
+			if LookupError != nil {
+				Problems.Add("%s", LookupError)
+			}
+			*Target = Value
+		}
+	}
+	return Endpoints
+}
+
+func (this *Config) Validate(Problems *ValidationError) {
+	// Checks the Values, that Depend on each other
+	if Key := this.Authentication.SecretKey; len(Key) != 0 && len(Key) < MinSecretKeyLength {
+		Problems.Add("JWT_SECRET_KEY should be at least %v Characters long", MinSecretKeyLength)
+	}
+	if this.RateLimit.BaseLockout > this.RateLimit.MaxLockout {
+		Problems.Add("LOGIN_LOCKOUT_BASE should not be greater than the LOGIN_LOCKOUT_MAX")
+	}
+	if this.Metrics.RawRetention > this.Metrics.RollupRetention {
+		Problems.Add("METRICS_HISTORY_RAW_RETENTION should not be greater than the METRICS_HISTORY_ROLLUP_RETENTION")
+	}
+	if this.Webhooks.BackoffBase > this.Webhooks.BackoffMax {
+		Problems.Add("WEBHOOK_BACKOFF_BASE should not be greater than the WEBHOOK_BACKOFF_MAX")
+	}
+
+	Supported := false
+	for _, Backend := range MailerBackends {
+		Supported = Supported || this.Mailer.Backend == Backend
+	}
+	if !Supported {
+		Problems.Add("MAILER_BACKEND should be one of %s, got `%s`", strings.Join(MailerBackends, ", "), this.Mailer.Backend)
+	}
+
+	if this.Oidc.IsEnabled() {
+		for Key, Value := range map[string]string{
+			"OIDC_ISSUER_URL": this.Oidc.IssuerURL, "OIDC_CLIENT_ID": this.Oidc.ClientId, "OIDC_REDIRECT_URL": this.Oidc.RedirectURL,
+		} {
+			if len(Value) == 0 {
+				Problems.Add("%s is Required, once the Single Sign On is Enabled", Key)
+				continue
+			}
+			if Parsed, ParseError := url.Parse(Value); Key != "OIDC_CLIENT_ID" && (ParseError != nil || !Parsed.IsAbs()) {
+				Problems.Add("%s should be an Absolute URL, got `%s`", Key, Value)
+			}
+		}
+	}
+	sort.Strings(Problems.Problems)
+}
+
+func (this *Config) String() string {
+	// Returns the Configuration with the Secrets Hidden
+	Lines := []string{}
+	for _, Field := range GetFields(this) {
+		Value := fmt.Sprint(Field.Value.Interface())
+		if Slice, Ok := Field.Value.Interface().([]string); Ok {
+			Value = strings.Join(Slice, " ")
+		}
+		if Field.Secret && len(Value) != 0 {
+			Value = "******"
+		}
+		Lines = append(Lines, Field.Key+"="+Value)
+	}
+	return strings.Join(Lines, "\n")
+}
@@ -9,10 +9,17 @@ import (
 	"strconv"
 
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/mailer"
+	"github.com/LovePelmeni/Infrastructure/mfa"
+	"github.com/LovePelmeni/Infrastructure/middlewares"
 	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/oidc"
+	"github.com/LovePelmeni/Infrastructure/ratelimit"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -30,6 +37,15 @@ var (
 	Logger *zap.Logger
 )
 
+var (
+	Application config.ApplicationConfig // Links in the Emails are being Pointed to the Front Application
+)
+
+var (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72 // Longer Passwords are being Truncated by the bcrypt
+)
+
 func InitializeProductionLogger() {
 
 	config := zap.NewProductionEncoderConfig()
@@ -46,6 +62,10 @@ func init() {
 	InitializeProductionLogger()
 }
 
+func Configure(Config config.ApplicationConfig) {
+	Application = Config
+}
+
 // Authorization Rest API Endpoints
 
 func LoginRestController(RequestContext *gin.Context) {
@@ -54,6 +74,10 @@ func LoginRestController(RequestContext *gin.Context) {
 	Username := RequestContext.PostForm("Username")
 	Password := RequestContext.PostForm("Password")
 
+	if IsAccountLocked(RequestContext, Username) {
+		return
+	}
+
 	var Customer models.Customer
 	customer := models.Database.Model(&models.Customer{}).Where(
 		"username = ?", Username).Find(&Customer)
@@ -66,31 +90,596 @@ func LoginRestController(RequestContext *gin.Context) {
 
 	if EqualsError := bcrypt.CompareHashAndPassword(
 		[]byte(Customer.Password), []byte(Password)); EqualsError != nil {
-		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Password"})
+		RespondFailedLogin(RequestContext, Username, "Invalid Password")
+		return
+	}
+
+	if !Customer.EmailVerified {
+		RequestContext.JSON(http.StatusForbidden, gin.H{
+			"Error": "Email Address has not been Verified, Please Follow the Link from the Verification Email", "EmailVerified": false})
+		return
+	}
+
+	CompleteLogin(RequestContext, Customer)
+}
+
+func IsAccountLocked(RequestContext *gin.Context, Username string) bool {
+	// Responds with the Error and Returns true, if the Account is Locked after too many Failed Logins
+	Lockout, LockoutError := ratelimit.GetLockout(middlewares.RedisClient, Username)
+	if LockoutError != nil {
+		Logger.Error("Failed to Check Account Lockout", zap.Error(LockoutError))
+		return false
+	}
+	if Lockout <= 0 {
+		return false
+	}
+	Seconds := int(Lockout.Seconds() + 0.999)
+	RequestContext.Header("Retry-After", strconv.Itoa(Seconds))
+	RequestContext.JSON(http.StatusTooManyRequests, gin.H{
+		"Error": fmt.Sprintf("Too many Failed Logins, Account is Locked for %v Seconds", Seconds)})
+	return true
+}
+
+func RespondFailedLogin(RequestContext *gin.Context, Username string, Message string) {
+	// Counts the Failed Login of the Account, so it is being Locked after too many of them
+	Lockout, RegisterError := ratelimit.RegisterFailedLogin(middlewares.RedisClient, Username)
+	if RegisterError != nil {
+		Logger.Error("Failed to Register Failed Login", zap.Error(RegisterError))
+	}
+	if Lockout > 0 {
+		Seconds := int(Lockout.Seconds() + 0.999)
+		RequestContext.Header("Retry-After", strconv.Itoa(Seconds))
+		RequestContext.JSON(http.StatusTooManyRequests, gin.H{
+			"Error": fmt.Sprintf("%s, Account is Locked for %v Seconds", Message, Seconds)})
+		return
+	}
+	RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": Message})
+}
+
+func CompleteLogin(RequestContext *gin.Context, Customer models.Customer) {
+	// Issues Tokens of the Customer, whose Identity has been Confirmed either by the Password or by the Single Sign-On
+
+	// Customers with the Second Factor Receive the Short Lived MFA Token, that is being Exchanged for the
+	// Access and Refresh Tokens at the `/customer/login/mfa/` Endpoint, along with the Code of the Authenticator App
+	if Customer.MfaEnabled {
+		MfaToken, MfaError := authentication.CreateMfaPendingToken(Customer.ID, Customer.Username, Customer.Email)
+		if MfaError != nil {
+			Logger.Error("Failed to Initialize New MFA Token", zap.Error(MfaError))
+			RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Login Error"})
+			return
+		}
+		RequestContext.JSON(http.StatusOK, gin.H{"MfaRequired": true, "MfaToken": MfaToken})
+		return
+	}
+	// Failed Logins are being Forgotten only after the Complete Login, so the Second Factor can't be Guessed endlessly
+	ratelimit.ResetFailedLogins(middlewares.RedisClient, Customer.Username)
+
+	// Generating New Access and Refresh Tokens
+	Tokens, JwtError := authentication.IssueTokenPair(Customer, "")
+	if JwtError != nil {
+		Logger.Error("Failed to Initialize New Jwt Token", zap.Error(JwtError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Login Error"})
+		return
+	}
+
+	// Setting UP New Generated Auth Tokens
+	SetTokenCookies(RequestContext, Tokens)
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Logged In", "Tokens": Tokens})
+}
+
+// Single Sign-On Rest API Endpoints
+// Customers are being Linked by the Verified Email Claim of the ID Token, or Provisioned, if there is no such Customer yet
+
+var (
+	ErrOidcEmailNotVerified = errors.New("Identity Provider has not Verified the Email Address")
+)
+
+func GetOidcUsername(Claims *oidc.IdTokenClaims) string {
+	// Returns Free Username for the Provisioned Customer, based on the Preferred Username or the Email
+	Username := Claims.PreferredUsername
+	if len(Username) == 0 {
+		Username = strings.Split(Claims.Email, "@")[0]
+	}
+	if len(Username) > 90 {
+		Username = Username[:90]
+	}
+	Candidate := Username
+	for Attempt := 1; ; Attempt++ {
+		var Taken int64
+		models.Database.Model(&models.Customer{}).Where("username = ?", Candidate).Count(&Taken)
+		if Taken == 0 {
+			return Candidate
+		}
+		Candidate = fmt.Sprintf("%s-%v", Username, Attempt)
+	}
+}
+
+func GetOrProvisionOidcCustomer(Claims *oidc.IdTokenClaims) (*models.Customer, error) {
+	// Returns the Customer of the ID Token, Links the Identity to the Existing Customer with the same Email,
+	// or Creates new Customer, that has no Password and can only Login with the Single Sign-On
+
+	if Customer, Exists := models.GetCustomerByOidcIdentity(Claims.Issuer, Claims.Subject); Exists {
+		return Customer, nil
+	}
+	if len(Claims.Email) == 0 || !Claims.EmailVerified {
+		return nil, ErrOidcEmailNotVerified
+	}
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("LOWER(email) = LOWER(?)", Claims.Email).Find(&Customer)
+	if Customer.ID != 0 {
+		if LinkError := Customer.LinkOidcIdentity(Claims.Issuer, Claims.Subject); LinkError != nil {
+			return nil, LinkError
+		}
+		Logger.Info("OIDC Identity has been Linked to the Customer", zap.Int("CustomerId", Customer.ID))
+		return &Customer, nil
+	}
+
+	// Random Password is not being Revealed to anyone, the Customer can Set it with the Password Reset
+	Password, PasswordError := authentication.GenerateRandomToken()
+	if PasswordError != nil {
+		return nil, PasswordError
+	}
+	NewCustomer := models.NewCustomer(GetOidcUsername(Claims), Password, Claims.Email, "", "", "", "")
+	NewCustomer.EmailVerified = true
+	NewCustomer.OidcIssuer = Claims.Issuer
+	NewCustomer.OidcSubject = Claims.Subject
+	if _, CreationError := NewCustomer.Create(); CreationError != nil {
+		return nil, CreationError
+	}
+	Logger.Info("Customer has been Provisioned with the Single Sign-On", zap.Int("CustomerId", NewCustomer.ID))
+	return NewCustomer, nil
+}
+
+func OidcLoginRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Redirects the Customer to the Login Page of the Identity Provider
+
+	Provider, ProviderError := oidc.GetProvider(RequestContext.Request.Context())
+	if ProviderError != nil {
+		if ProviderError == oidc.ErrNotConfigured {
+			RequestContext.JSON(http.StatusNotFound, gin.H{"Error": ProviderError.Error()})
+			return
+		}
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Identity Provider is not Available, Try a bit Later"})
+		return
+	}
+
+	AuthorizationRequest, RequestError := oidc.NewAuthorizationRequest()
+	if RequestError == nil {
+		RequestError = AuthorizationRequest.Save(middlewares.RedisClient)
+	}
+	if RequestError != nil {
+		Logger.Error("Failed to Initialize OIDC Login", zap.Error(RequestError))
+		RequestContext.JSON(http.StatusServiceUnavailable, gin.H{"Error": "Login Error, Try a bit Later"})
+		return
+	}
+
+	// Binding the Login to the Browser, the Provider Redirects the Customer back to the Callback with the Top Level Navigation,
+	// so the Cookie should be `Lax`, the `Strict` one is not being Sent along with it
+	RequestContext.SetSameSite(http.SameSiteLaxMode)
+	RequestContext.SetCookie(oidc.StateCookieName, AuthorizationRequest.GetStateBinding(),
+		int(oidc.AuthorizationRequestLifetime.Seconds()), oidc.StateCookiePath, "", true, true)
+
+	RequestContext.Redirect(http.StatusFound, Provider.GetAuthorizationURL(
+		AuthorizationRequest.State, AuthorizationRequest.Nonce, AuthorizationRequest.CodeVerifier))
+}
+
+func OidcCallbackRestController(RequestContext *gin.Context) {
+	// Rest Controller, the Identity Provider Redirects the Customer back to, along with the Authorization Code
+
+	if ProviderError := RequestContext.Query("error"); len(ProviderError) != 0 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": fmt.Sprintf(
+			"Identity Provider has Rejected the Login: %s", RequestContext.Query("error_description"))})
+		return
+	}
+
+	Provider, ProviderError := oidc.GetProvider(RequestContext.Request.Context())
+	if ProviderError != nil {
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Identity Provider is not Available, Try a bit Later"})
+		return
+	}
+
+	// State should be Returned to the same Browser, the Login has been Started from, otherwise the Request is not being Consumed,
+	// so the Attacker can't Complete the Login Attempt of his own in the Customer's Browser
+	Binding, _ := RequestContext.Cookie(oidc.StateCookieName)
+	if !oidc.IsStateBound(RequestContext.Query("state"), Binding) {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": oidc.ErrStateMismatch.Error()})
 		return
 	}
+	RequestContext.SetSameSite(http.SameSiteLaxMode)
+	RequestContext.SetCookie(oidc.StateCookieName, "", -1, oidc.StateCookiePath, "", true, true)
 
-	// Generating New Jwt Authentication Token
-	NewJwtToken, JwtError := authentication.CreateJwtToken(int(Customer.ID), Customer.Username, Customer.Email)
+	AuthorizationRequest, StateError := oidc.PopAuthorizationRequest(middlewares.RedisClient, RequestContext.Query("state"))
+	if StateError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": StateError.Error()})
+		return
+	}
+
+	Tokens, ExchangeError := Provider.ExchangeCode(RequestContext.Request.Context(),
+		RequestContext.Query("code"), AuthorizationRequest.CodeVerifier)
+	if ExchangeError != nil {
+		Logger.Error("Failed to Exchange OIDC Authorization Code", zap.Error(ExchangeError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Complete the Login with the Identity Provider"})
+		return
+	}
+
+	Claims, VerifyError := Provider.VerifyIdToken(RequestContext.Request.Context(), Tokens.IdToken, AuthorizationRequest.Nonce)
+	if VerifyError != nil {
+		Logger.Warn("Invalid OIDC ID Token has been Received", zap.Error(VerifyError))
+		RequestContext.JSON(http.StatusUnauthorized, gin.H{"Error": VerifyError.Error()})
+		return
+	}
+
+	Customer, CustomerError := GetOrProvisionOidcCustomer(Claims)
+	switch {
+	case CustomerError == ErrOidcEmailNotVerified:
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": CustomerError.Error()})
+		return
+	case CustomerError != nil:
+		Logger.Error("Failed to Receive OIDC Customer", zap.Error(CustomerError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Login Error"})
+		return
+	}
+	CompleteLogin(RequestContext, *Customer)
+}
+
+func LoginMfaRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Completes the Login of the Customer with the Second Factor
+	// Accepts the MFA Token, Received from the Login Endpoint, and either the Code of the Authenticator App or the Recovery Code
+
+	Credentials, TokenError := authentication.GetMfaPendingCredentials(RequestContext.PostForm("MfaToken"))
+	if TokenError != nil {
+		RequestContext.JSON(http.StatusUnauthorized, gin.H{"Error": TokenError.Error()})
+		return
+	}
+
+	// MFA Token can only be Exchanged once, and only Limited Number of the Codes can be Tried with it
+	Revoked, RevokedError := authentication.IsAccessTokenRevoked(middlewares.RedisClient, Credentials)
+	if RevokedError == nil {
+		RevokedError = authentication.RegisterMfaAttempt(middlewares.RedisClient, Credentials)
+	}
+	switch {
+	case Revoked:
+		RequestContext.JSON(http.StatusUnauthorized, gin.H{"Error": authentication.ErrInvalidMfaToken.Error()})
+		return
+	case RevokedError == authentication.ErrTooManyMfaAttempts:
+		authentication.RevokeAccessToken(middlewares.RedisClient, Credentials)
+		RequestContext.JSON(http.StatusTooManyRequests, gin.H{"Error": RevokedError.Error()})
+		return
+	case RevokedError != nil:
+		Logger.Error("Failed to Check MFA Token", zap.Error(RevokedError))
+		RequestContext.JSON(http.StatusServiceUnavailable, gin.H{"Error": "Login Error, Try a bit Later"})
+		return
+	}
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("id = ?", Credentials.UserId).Find(&Customer)
+	if Customer.ID == 0 || !Customer.MfaEnabled {
+		RequestContext.JSON(http.StatusUnauthorized, gin.H{"Error": authentication.ErrInvalidMfaToken.Error()})
+		return
+	}
+	if IsAccountLocked(RequestContext, Customer.Username) {
+		return
+	}
+
+	// Invalid Codes are being Counted along with the Invalid Passwords, so they can't be Guessed with new MFA Tokens
+	Code := RequestContext.PostForm("Code")
+	if len(Code) == 0 {
+		Code = RequestContext.PostForm("RecoveryCode")
+	}
+	if !mfa.VerifyCustomer(&Customer, Code) {
+		RespondFailedLogin(RequestContext, Customer.Username, "Invalid Code")
+		return
+	}
+	ratelimit.ResetFailedLogins(middlewares.RedisClient, Customer.Username)
+	authentication.RevokeAccessToken(middlewares.RedisClient, Credentials)
+
+	Tokens, JwtError := authentication.IssueTokenPair(Customer, "")
 	if JwtError != nil {
 		Logger.Error("Failed to Initialize New Jwt Token", zap.Error(JwtError))
 		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Login Error"})
 		return
 	}
+	SetTokenCookies(RequestContext, Tokens)
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Logged In", "Tokens": Tokens})
+}
 
-	// Setting UP New Generated Auth Token
-	RequestContext.SetCookie("jwt-token", NewJwtToken, int(time.Now().Add(time.Minute*10000).Unix()), "/", "", true, false)
-	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Logged In"})
+func SetTokenCookies(RequestContext *gin.Context, Tokens *authentication.TokenPair) {
+	// Sets Access and Refresh Tokens Cookies, Refresh Token is only being Sent to the Refresh Endpoint
+	RequestContext.SetCookie("jwt-token", Tokens.AccessToken,
+		int(authentication.AccessTokenLifetime.Seconds()), "/", "", true, false)
+	RequestContext.SetCookie("refresh-token", Tokens.RefreshToken,
+		int(authentication.RefreshTokenLifetime.Seconds()), "/customer/token/", "", true, true)
+}
+
+func ClearTokenCookies(RequestContext *gin.Context) {
+	RequestContext.SetCookie("jwt-token", "", -1, "/", "", true, false)
+	RequestContext.SetCookie("refresh-token", "", -1, "/customer/token/", "", true, true)
+}
+
+func GetRefreshToken(RequestContext *gin.Context) string {
+	// Returns Refresh Token, passed either in the Form or in the Cookie
+	if Token := RequestContext.PostForm("RefreshToken"); len(Token) != 0 {
+		return Token
+	}
+	Token, _ := RequestContext.Cookie("refresh-token")
+	return Token
+}
+
+func RefreshTokenRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Exchanges the Refresh Token for the new Access and Refresh Tokens
+	// Refresh Token can only be Used once, the Session is being Revoked, if it is Reused
+
+	RefreshToken := GetRefreshToken(RequestContext)
+	if len(RefreshToken) == 0 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Refresh Token is Required"})
+		return
+	}
+
+	Tokens, RefreshError := authentication.RotateRefreshToken(RefreshToken)
+	switch RefreshError {
+
+	case nil:
+		SetTokenCookies(RequestContext, Tokens)
+		RequestContext.JSON(http.StatusOK, gin.H{"Tokens": Tokens})
+
+	case authentication.ErrInvalidRefreshToken, authentication.ErrRefreshTokenReused:
+		ClearTokenCookies(RequestContext)
+		RequestContext.JSON(http.StatusUnauthorized, gin.H{"Error": RefreshError.Error()})
+
+	default:
+		Logger.Error("Failed to Refresh Tokens", zap.Error(RefreshError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Refresh Tokens"})
+	}
 }
 
 func LogoutRestController(RequestContext *gin.Context) {
 	// Rest Controller, that is responsible to let users Log out from their existing account
+	// Revokes the Access Token and the Session of the Refresh Token, passed along
+
+	Credentials, JwtError := authentication.GetCustomerJwtCredentials(RequestContext.GetHeader("Authorization"))
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	if RevokeError := authentication.RevokeAccessToken(middlewares.RedisClient, Credentials); RevokeError != nil {
+		Logger.Error("Failed to Revoke Access Token", zap.Error(RevokeError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Logout, Try a bit Later"})
+		return
+	}
+	if RefreshToken := GetRefreshToken(RequestContext); len(RefreshToken) != 0 {
+		authentication.RevokeRefreshToken(RefreshToken)
+	}
+
+	ClearTokenCookies(RequestContext)
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Logged out"})
+}
+
+func LogoutAllSessionsRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Logs the Customer out from every Device, the Customer has Logged in from
+
+	Credentials, JwtError := authentication.GetCustomerJwtCredentials(RequestContext.GetHeader("Authorization"))
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	if RevokeError := authentication.RevokeCustomerSessions(middlewares.RedisClient, Credentials.UserId); RevokeError != nil {
+		Logger.Error("Failed to Revoke Customer Sessions", zap.Error(RevokeError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Logout, Try a bit Later"})
+		return
+	}
+
+	ClearTokenCookies(RequestContext)
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Logged out from all Sessions"})
+}
+
+// Two Factor Authentication Rest API Endpoints
+// Second Factor can only be Managed by the Customer, Logged in with the Password, API Keys are not Allowed
+
+func EnrollMfaRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Generates new Secret of the Authenticator App
+	// The Second Factor is not being Enabled, until the Code of the Secret is Verified
+
+	Credentials, JwtError := authentication.GetCustomerJwtCredentials(RequestContext.GetHeader("Authorization"))
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("id = ?", Credentials.UserId).Find(&Customer)
+	if Customer.MfaEnabled {
+		RequestContext.JSON(http.StatusConflict, gin.H{"Error": "Two Factor Authentication is already Enabled"})
+		return
+	}
+
+	Secret, SecretError := mfa.GenerateSecret()
+	if SecretError != nil {
+		Logger.Error("Failed to Generate MFA Secret", zap.Error(SecretError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Enroll"})
+		return
+	}
+	Customer.MfaSecret = Secret
+	Customer.MfaLastUsedStep = 0
+	if SaveError := Customer.SaveMfa(); SaveError != nil {
+		Logger.Error("Failed to Save MFA Secret", zap.Error(SaveError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Enroll"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{
+		"Secret":          Secret,
+		"ProvisioningURI": mfa.GetProvisioningURI(Customer.Email, Secret),
+	})
+}
+
+func VerifyMfaRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Enables the Second Factor, once the Customer Confirms the Code of the Authenticator App
+	// Returns Recovery Codes, they are being Shown only once
+
+	Credentials, JwtError := authentication.GetCustomerJwtCredentials(RequestContext.GetHeader("Authorization"))
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("id = ?", Credentials.UserId).Find(&Customer)
+	if Customer.MfaEnabled || len(Customer.MfaSecret) == 0 {
+		RequestContext.JSON(http.StatusConflict, gin.H{"Error": "Two Factor Authentication is Enabled or has not been Enrolled"})
+		return
+	}
+
+	Step, Valid := mfa.ValidateCode(Customer.MfaSecret, RequestContext.PostForm("Code"), Customer.MfaLastUsedStep, time.Now())
+	if !Valid {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Code"})
+		return
+	}
+
+	RecoveryCodes, Hashes, GenerateError := mfa.GenerateRecoveryCodes()
+	if GenerateError != nil {
+		Logger.Error("Failed to Generate MFA Recovery Codes", zap.Error(GenerateError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Enable Two Factor Authentication"})
+		return
+	}
+	Customer.MfaEnabled = true
+	Customer.MfaLastUsedStep = Step
+	Customer.MfaRecoveryCodes = strings.Join(Hashes, ",")
+	if SaveError := Customer.SaveMfa(); SaveError != nil {
+		Logger.Error("Failed to Enable MFA", zap.Error(SaveError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Enable Two Factor Authentication"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Two Factor Authentication has been Enabled", "RecoveryCodes": RecoveryCodes})
+}
+
+func DisableMfaRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Disables the Second Factor, Requires either the Code of the Authenticator App or the Recovery Code
+
+	Credentials, JwtError := authentication.GetCustomerJwtCredentials(RequestContext.GetHeader("Authorization"))
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("id = ?", Credentials.UserId).Find(&Customer)
+	if !Customer.MfaEnabled {
+		RequestContext.JSON(http.StatusConflict, gin.H{"Error": "Two Factor Authentication is not Enabled"})
+		return
+	}
+
+	Code := RequestContext.PostForm("Code")
+	if len(Code) == 0 {
+		Code = RequestContext.PostForm("RecoveryCode")
+	}
+	if !mfa.VerifyCustomer(&Customer, Code) {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Code"})
+		return
+	}
+
+	Customer.MfaEnabled = false
+	Customer.MfaSecret = ""
+	Customer.MfaRecoveryCodes = ""
+	Customer.MfaLastUsedStep = 0
+	if SaveError := Customer.SaveMfa(); SaveError != nil {
+		Logger.Error("Failed to Disable MFA", zap.Error(SaveError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Disable Two Factor Authentication"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Two Factor Authentication has been Disabled"})
+}
+
+// Personal API Keys Rest API Endpoints
+// API Keys can't Manage other API Keys, so only the Customer, Logged in with the Password, can Create or Revoke them
+
+var (
+	DefaultApiKeyLifetimeDays = 90
+)
+
+func CreateApiKeyRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Creates new API Key with the Scopes, the Key is being Returned only once
+
+	Credentials, JwtError := authentication.GetCustomerJwtCredentials(RequestContext.GetHeader("Authorization"))
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	Name := RequestContext.PostForm("Name")
+	if len(Name) == 0 || len(Name) > 100 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "API Key Name should be between 1 and 100 Characters"})
+		return
+	}
+
+	Scopes := strings.Split(RequestContext.PostForm("Scopes"), ",")
+	for _, Scope := range Scopes {
+		if !authentication.IsValidScope(Scope) {
+			RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": fmt.Sprintf(
+				"Invalid Scope `%s`, Available Scopes are: %s", Scope, strings.Join(authentication.ApiKeyScopes, ", "))})
+			return
+		}
+	}
+
+	MaxLifetimeDays := int(authentication.MaxApiKeyLifetime.Hours() / 24)
+	LifetimeDays, ParseError := strconv.Atoi(RequestContext.DefaultPostForm("ExpiresInDays", strconv.Itoa(DefaultApiKeyLifetimeDays)))
+	if ParseError != nil || LifetimeDays < 1 || LifetimeDays > MaxLifetimeDays {
+		RequestContext.JSON(http.StatusBadRequest,
+			gin.H{"Error": fmt.Sprintf("API Key should Expire in 1 to %v Days", MaxLifetimeDays)})
+		return
+	}
+
+	Key, ApiKey, CreationError := authentication.CreateApiKey(Credentials.UserId, Name, Scopes, time.Duration(LifetimeDays)*24*time.Hour)
+	if CreationError != nil {
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Create API Key"})
+		return
+	}
+	RequestContext.JSON(http.StatusCreated, gin.H{"ApiKey": ApiKey, "Key": Key})
+}
+
+func ListApiKeysRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns API Keys of the Customer, including Revoked and Expired ones
+
+	Credentials, JwtError := authentication.GetCustomerJwtCredentials(RequestContext.GetHeader("Authorization"))
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	var ApiKeys []models.ApiKey
+	if Gorm := models.Database.Model(&models.ApiKey{}).Where(
+		"customer_id = ?", Credentials.UserId).Order("created_at DESC").Find(&ApiKeys); Gorm.Error != nil {
+		Logger.Error("Failed to Receive Customer API Keys", zap.Error(Gorm.Error))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Receive API Keys"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": ApiKeys})
+}
 
-	if Cookie, Error := RequestContext.Cookie("jwt-token"); len(Cookie) != 0 && Error == nil {
-		Cookie, _ := RequestContext.Request.Cookie("jwt-token")
-		Cookie.Expires.Add(-1)
-		RequestContext.JSON(http.StatusOK, gin.H{"Status": "Logged out"})
+func RevokeApiKeyRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Revokes the API Key, specified in the `ApiKeyId` Query Param
+
+	Credentials, JwtError := authentication.GetCustomerJwtCredentials(RequestContext.GetHeader("Authorization"))
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	var ApiKey models.ApiKey
+	models.Database.Model(&models.ApiKey{}).Where(
+		"id = ? AND customer_id = ?", RequestContext.Query("ApiKeyId"), Credentials.UserId).Find(&ApiKey)
+	if ApiKey.ID == 0 {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "API Key Does Not Exist"})
+		return
 	}
+
+	if RevokeError := ApiKey.Revoke(); RevokeError != nil {
+		Logger.Error("Failed to Revoke API Key", zap.Error(RevokeError))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Revoke API Key"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Revoked"})
 }
 
 // Customers Rest API Endpoints
@@ -106,6 +695,11 @@ func CreateCustomerRestController(RequestContext *gin.Context) {
 	ZipCode := RequestContext.PostForm("ZipCode")
 	Street := RequestContext.PostForm("Street")
 
+	if ValidationError := ValidatePassword(Password); ValidationError != nil {
+		RequestContext.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"Error": ValidationError.Error()})
+		return
+	}
+
 	// Checking If Customer is Already Exists...
 	var Customer models.Customer
 	if Transact := models.Database.Model(
@@ -117,11 +711,11 @@ func CreateCustomerRestController(RequestContext *gin.Context) {
 		return
 	}
 
-	NewCustomer := models.NewCustomer(Username, Password, Email)
-	NewCustomer.BillAddress = BillingAddress
-	NewCustomer.Country = Country
-	NewCustomer.ZipCode = ZipCode
-	NewCustomer.Street = Street
+	NewCustomer := models.NewCustomer(Username, Password, Email, BillingAddress, Country, ZipCode, Street)
+	if NewCustomer == nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Create Customer"})
+		return
+	}
 
 	Created, Error := NewCustomer.Create()
 
@@ -166,51 +760,182 @@ func CreateCustomerRestController(RequestContext *gin.Context) {
 			return
 		}
 	} else {
-		NewJwtToken, JwtError := authentication.CreateJwtToken(
-			int(NewCustomer.ID), NewCustomer.Username, NewCustomer.Email)
+		// Customer can Login only after Confirming the Email Address
+		go SendVerificationEmail(*NewCustomer)
+		RequestContext.JSON(http.StatusCreated, gin.H{
+			"Operation": "Success", "Status": "Verification Email has been Sent"})
+	}
+}
 
-		if JwtError != nil {
-			RequestContext.JSON(http.StatusBadGateway,
-				gin.H{"Error": "Failed to Generate Auth Token"})
-			return
-		}
-		Created.Commit()
-		RequestContext.SetCookie("jwt-token", NewJwtToken, int(time.Now().Add(10000*time.Minute).Unix()), "/", "", false, false)
-		RequestContext.JSON(http.StatusCreated, gin.H{"Operation": "Success"})
+func ValidatePassword(Password string) error {
+	if len(Password) < MinPasswordLength || len(Password) > MaxPasswordLength {
+		return fmt.Errorf("Password should be between %v and %v Characters", MinPasswordLength, MaxPasswordLength)
 	}
+	return nil
 }
 
 func ResetPasswordRestController(RequestContext *gin.Context) {
-	// Rest Controller, Responsible for Resetting Password
+	// Rest Controller, Responsible for Changing Password of the Logged in Customer
+	// Every other Session of the Customer is being Revoked, the Current one Receives new Tokens
+
+	Credentials, JwtError := authentication.GetCustomerJwtCredentials(RequestContext.GetHeader("Authorization"))
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("id = ?", Credentials.UserId).Find(&Customer)
+	if EqualsError := bcrypt.CompareHashAndPassword(
+		[]byte(Customer.Password), []byte(RequestContext.PostForm("Password"))); Customer.ID == 0 || EqualsError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Password"})
+		return
+	}
 
 	NewPassword := RequestContext.PostForm("NewPassword")
-	CustomerId := RequestContext.PostForm("CustomerId")
+	if ValidationError := ValidatePassword(NewPassword); ValidationError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": ValidationError.Error()})
+		return
+	}
+	if UpdateError := UpdateCustomerPassword(&Customer, NewPassword); UpdateError != nil {
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Oops, Failed to Apply New Password"})
+		return
+	}
+
+	Tokens, TokenError := authentication.IssueTokenPair(Customer, "")
+	if TokenError != nil {
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Generate Auth Token"})
+		return
+	}
+	SetTokenCookies(RequestContext, Tokens)
+	RequestContext.JSON(http.StatusCreated, gin.H{"Status": "Applied", "Tokens": Tokens})
+}
 
+func UpdateCustomerPassword(Customer *models.Customer, NewPassword string) error {
+	// Applies new Password of the Customer and Revokes every Session of it
 	NewPasswordHash, GenerateError := bcrypt.GenerateFromPassword([]byte(NewPassword), 14)
 	if GenerateError != nil {
-		RequestContext.JSON(http.StatusBadRequest,
-			gin.H{"Error": "Failed to Apply new Password"})
+		return GenerateError
+	}
+	if Updated := models.Database.Model(&models.Customer{}).Where(
+		"id = ?", Customer.ID).Update("password", string(NewPasswordHash)); Updated.Error != nil {
+		Logger.Error("Failed to Apply New Password", zap.Int("CustomerId", Customer.ID), zap.Error(Updated.Error))
+		return Updated.Error
 	}
-	Updated := models.Database.Model(&models.Customer{}).Where(
-		"id = ?", CustomerId).Update("Password", NewPasswordHash)
+	Customer.Password = string(NewPasswordHash)
 
-	if Updated.Error != nil {
-		Updated.Rollback()
-		RequestContext.JSON(
-			http.StatusBadGateway, gin.H{"Error": "Oops, Failed to Apply New Password"})
+	if RevokeError := authentication.RevokeCustomerSessions(middlewares.RedisClient, Customer.ID); RevokeError != nil {
+		Logger.Error("Failed to Revoke Customer Sessions", zap.Int("CustomerId", Customer.ID), zap.Error(RevokeError))
+		return RevokeError
+	}
+	return nil
+}
+
+// Password Recovery and Email Verification Rest API Endpoints
+// Tokens are being Delivered to the Customer's Email, Responses does not tell, whether the Email is Registered
+
+func GetFrontApplicationURL() string {
+	return Application.GetFrontApplicationURL()
+}
+
+func SendVerificationEmail(Customer models.Customer) {
+	// Sends Email with the Link, that Confirms the Email Address of the Customer
+	Token, TokenError := authentication.IssueCustomerToken(Customer.ID, models.TokenPurposeEmailVerification)
+	if TokenError != nil {
+		return
+	}
+	Body := fmt.Sprintf("Hello, %s!\n\nPlease Confirm your Email Address by Following the Link: %s/email/verify/?Token=%s\n\n"+
+		"The Link Expires in %v. If you have not Signed up, just Ignore this Email.",
+		Customer.Username, GetFrontApplicationURL(), Token, authentication.EmailVerificationTokenLifetime)
+	if SendError := mailer.Send(Customer.Email, "Confirm your Email Address", Body); SendError != nil {
+		Logger.Error("Failed to Send Verification Email", zap.Int("CustomerId", Customer.ID), zap.Error(SendError))
+	}
+}
+
+func SendPasswordResetEmail(Customer models.Customer) {
+	// Sends Email with the Link, that Allows to Set new Password of the Customer
+	Token, TokenError := authentication.IssueCustomerToken(Customer.ID, models.TokenPurposePasswordReset)
+	if TokenError != nil {
+		return
+	}
+	Body := fmt.Sprintf("Hello, %s!\n\nYou can Set new Password by Following the Link: %s/password/reset/?Token=%s\n\n"+
+		"The Link Expires in %v. If you have not Requested the Password Reset, just Ignore this Email.",
+		Customer.Username, GetFrontApplicationURL(), Token, authentication.PasswordResetTokenLifetime)
+	if SendError := mailer.Send(Customer.Email, "Reset your Password", Body); SendError != nil {
+		Logger.Error("Failed to Send Password Reset Email", zap.Int("CustomerId", Customer.ID), zap.Error(SendError))
+	}
+}
+
+func ForgotPasswordRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Sends the Password Reset Link to the Email of the Customer
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("email = ?", RequestContext.PostForm("Email")).Find(&Customer)
+	if Customer.ID != 0 {
+		go SendPasswordResetEmail(Customer)
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "If the Email is Registered, the Reset Link has been Sent to it"})
+}
+
+func ResetForgottenPasswordRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Sets new Password of the Customer, using the Token from the Password Reset Email
+	// Following the Link Confirms the Email Address as well
+
+	NewPassword := RequestContext.PostForm("NewPassword")
+	if ValidationError := ValidatePassword(NewPassword); ValidationError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": ValidationError.Error()})
+		return
+	}
+
+	Customer, TokenError := authentication.ConsumeCustomerToken(RequestContext.PostForm("Token"), models.TokenPurposePasswordReset)
+	if TokenError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": TokenError.Error()})
+		return
+	}
+	if UpdateError := UpdateCustomerPassword(Customer, NewPassword); UpdateError != nil {
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Oops, Failed to Apply New Password"})
+		return
+	}
+	models.Database.Model(&models.Customer{}).Where("id = ?", Customer.ID).Update("email_verified", true)
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Password has been Reset, Please Login"})
+}
+
+func VerifyEmailRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Confirms the Email Address of the Customer, using the Token from the Verification Email
+
+	Customer, TokenError := authentication.ConsumeCustomerToken(RequestContext.Query("Token"), models.TokenPurposeEmailVerification)
+	if TokenError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": TokenError.Error()})
 		return
 	}
+	if Updated := models.Database.Model(&models.Customer{}).Where(
+		"id = ?", Customer.ID).Update("email_verified", true); Updated.Error != nil {
+		Logger.Error("Failed to Verify Email", zap.Int("CustomerId", Customer.ID), zap.Error(Updated.Error))
+		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Verify Email"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Email has been Verified"})
+}
 
-	Updated.Unscoped().Update("Password", NewPasswordHash)
-	RequestContext.JSON(http.StatusCreated, gin.H{"Status": "Applied"})
+func ResendVerificationEmailRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Sends the Verification Email once again, the Link of the Previous one Stops Working
 
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("email = ?", RequestContext.PostForm("Email")).Find(&Customer)
+	if Customer.ID != 0 && !Customer.EmailVerified {
+		go SendVerificationEmail(Customer)
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "If the Email is Registered and not Verified, the Link has been Sent to it"})
 }
 
 func DeleteCustomerRestController(RequestContext *gin.Context) {
 	// Rest Controller, Responsible for Deleting Customer Profiles
 
-	token := RequestContext.Request.Header.Get("Authorization")
-	Credentials, _ := authentication.GetCustomerJwtCredentials(token)
+	Credentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
 
 	Deleted, Error := Customer.Delete(Credentials.UserId)
 
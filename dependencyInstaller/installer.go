@@ -173,7 +173,7 @@ type Dependency struct {
 	InstallUrl  *url.URL `json:"InstallUrl"`
 }
 
-func (this *Dependency) UploadToVm(DependencyCommands []string, SshConnection ssh.Client) string {
+func (this *Dependency) UploadToVm(DependencyCommands []string, SshConnection *ssh.Client) string {
 	// Uploads package to the Virtual Machine, Returns Output of the Command
 	NewSshSession, SshError := SshConnection.NewSession()
 	if SshError != nil {
@@ -247,7 +247,7 @@ func (this *EnviromentDependencyInstaller) GetDependency(PackageName string, Ins
 	return NewDependency(PackageName, url.URL{Path: InstallUrl}), nil
 }
 
-func (this *EnviromentDependencyInstaller) InstallDeploymentDependencies(SshConnection ssh.Client) []error {
+func (this *EnviromentDependencyInstaller) InstallDeploymentDependencies(SshConnection *ssh.Client) []error {
 	// Installs deployment Dependencies such as Docker and Docker-Compose and Kubectl
 
 	var InstallationErrors []error
@@ -265,7 +265,7 @@ func (this *EnviromentDependencyInstaller) InstallDeploymentDependencies(SshConn
 	for _, Command := range InstallationCommands {
 		ResponseError := NewSession.Run(Command)
 		if len(stdOut.String()) != 0 {
-			Responses = append(Responses)
+			Responses = append(Responses, stdOut.String())
 		}
 		if strings.Contains(stdOut.String(), "error") || ResponseError != nil {
 			InstallationErrors = append(InstallationErrors, ResponseError)
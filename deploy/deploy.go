@@ -5,23 +5,27 @@ import (
 	"encoding/json"
 
 	"errors"
+	"fmt"
 
 	"os"
 	"time"
 
 	"github.com/LovePelmeni/Infrastructure/exceptions"
+	"github.com/LovePelmeni/Infrastructure/network"
 	"github.com/LovePelmeni/Infrastructure/parsers"
+	resource_config "github.com/LovePelmeni/Infrastructure/resource_config"
 	"github.com/LovePelmeni/Infrastructure/ssh_config"
+	storage_config "github.com/LovePelmeni/Infrastructure/storage_config"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/webhooks"
 
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/property"
 
-	"github.com/vmware/govmomi/simulator/esx"
 	"github.com/vmware/govmomi/vim25"
 
 	"github.com/vmware/govmomi/vim25/mo"
@@ -76,11 +80,11 @@ type VirtualMachineResourceKeyManager struct {
 	// Class, that is responsible for Obtaining Credentials, to the Storage/Network
 	// To Get the Permissions to use that Resources.
 
-	Client rest.Client
+	Client *rest.Client
 }
 
-func NewVirtualMachineResourceKeyManager() *VirtualMachineResourceKeyManager {
-	return &VirtualMachineResourceKeyManager{}
+func NewVirtualMachineResourceKeyManager(Client *rest.Client) *VirtualMachineResourceKeyManager {
+	return &VirtualMachineResourceKeyManager{Client: Client}
 }
 
 func (this *VirtualMachineResourceKeyManager) GetLibraryItem(Context context.Context) (*library.Item, error) {
@@ -94,7 +98,7 @@ func (this *VirtualMachineResourceKeyManager) GetLibraryItem(Context context.Con
 	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*10)
 	defer CancelFunc()
 
-	m := library.NewManager(&this.Client)
+	m := library.NewManager(this.Client)
 
 	libraries, Error := m.FindLibrary(TimeoutContext, library.Find{Name: libName})
 	if Error != nil {
@@ -140,7 +144,7 @@ func (this *VirtualMachineResourceKeyManager) GetResourceKeys(
 	defer CancelFunc()
 
 	LibraryItem, LibError := this.GetLibraryItem(TimeoutContext)
-	DatacenterManager := vcenter.NewManager(&this.Client)
+	DatacenterManager := vcenter.NewManager(this.Client)
 
 	FilterRequest := vcenter.FilterRequest{Target: vcenter.Target{
 		ResourcePoolID: Resource.Reference().Value,
@@ -170,18 +174,34 @@ type VirtualMachineManager struct {
 	// It is responsible for Deploying/ Starting / Stopping / Updating Virtual Machines
 	// Owned by Customers
 	// Provides Following Methods in order to Fullfill the Needs and make the Process comfortable and easier
+	// Power Operations and the Destruction are being Reported to the Webhooks by the Manager, including the ones,
+	// it Performs Internally (e.g Power Cycle of the Resize)
 	VimClient vim25.Client
+
+	Records   map[string]int         // IDs of the Records of the Virtual Machines, Received by the `GetVirtualMachine`, by their Managed Object IDs
+	EventData map[string]interface{} // Data, Attached to every Webhook Event (e.g ID of the Job, that Performs the Operation)
 }
 
 func NewVirtualMachineManager(Client vim25.Client) *VirtualMachineManager {
 	return &VirtualMachineManager{
 		VimClient: Client,
+		Records:   make(map[string]int),
+		EventData: make(map[string]interface{}),
+	}
+}
+
+func (this *VirtualMachineManager) EmitEvent(EventType string, VirtualMachine *object.VirtualMachine) {
+	// Emits Lifecycle Event of the Virtual Machine to the Webhooks of the Customers
+	// Virtual Machines without the Record (e.g the Discarded ones) are not being Reported
+	if VirtualMachineId, Recorded := this.Records[VirtualMachine.Reference().Value]; Recorded {
+		webhooks.EmitVirtualMachine(EventType, VirtualMachineId, this.EventData)
 	}
 }
 
-func (this *VirtualMachineManager) GetVirtualMachine(VmId string, CustomerId string) (*object.VirtualMachine, error) {
+func (this *VirtualMachineManager) GetVirtualMachine(VmId string) (*object.VirtualMachine, error) {
 
-	// Method Retunrs Prepared Virtual Machine Instance, (That Already Exists, and has been created by Customer)
+	// Method Retunrs Prepared Virtual Machine Instance, (That Already Exists)
+	// Access to the Virtual Machine should be Checked by the Caller, using the `policy` package
 
 	// Initializing Timeout Context
 	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*10)
@@ -191,14 +211,12 @@ func (this *VirtualMachineManager) GetVirtualMachine(VmId string, CustomerId str
 
 	var VirtualMachineObj models.VirtualMachine
 
-	VirtualMachineGormRefError := models.Database.Model(
-		&models.VirtualMachine{}).Where(
-		"owner_id = ? AND id = ?",
-		CustomerId, VmId).Find(&VirtualMachineObj)
+	VirtualMachineGormRef := models.Database.Model(
+		&models.VirtualMachine{}).Where("id = ?", VmId).Find(&VirtualMachineObj)
 
-	if VirtualMachineGormRefError != nil {
+	if VirtualMachineGormRef.Error != nil || VirtualMachineObj.ID == 0 {
 		Logger.Error("Failed to Find Virtual Machine",
-			zap.String("Virtual Machine ID", VmId), zap.String("Customer ID", CustomerId))
+			zap.String("Virtual Machine ID", VmId))
 		return nil, exceptions.ItemDoesNotExist()
 	}
 
@@ -210,10 +228,11 @@ func (this *VirtualMachineManager) GetVirtualMachine(VmId string, CustomerId str
 	switch {
 	case FindError != nil:
 		Logger.Error("Failed to Find Virtual Machine",
-			zap.String("Virtual Machine ID", VmId), zap.String("Customer ID", CustomerId))
+			zap.String("Virtual Machine ID", VmId))
 		return nil, exceptions.ItemDoesNotExist()
 
 	case FindError == nil:
+		this.Records[VirtualRef.Reference().Value] = VirtualMachineObj.ID
 		return VirtualRef.(*object.VirtualMachine), nil
 
 	default:
@@ -223,6 +242,7 @@ func (this *VirtualMachineManager) GetVirtualMachine(VmId string, CustomerId str
 
 func (this *VirtualMachineManager) InitializeNewVirtualMachine(
 	VimClient vim25.Client,
+	RestClient *rest.Client, // vAPI Client of the same Region, Content Library is being Accessed through
 	VirtualMachineName string,
 	DataStore *object.Datastore, // Name, Customer Decided to set up for this Virtual Server
 	DatacenterNetwork *object.Network,
@@ -246,7 +266,7 @@ func (this *VirtualMachineManager) InitializeNewVirtualMachine(
 	// Initializing Virtual Machine Resource Key Manager, that Is Going to Obtain Necessasy Keys
 	// In order to Get Access to Resource Pools
 
-	ResourceAllocationManager := NewVirtualMachineResourceKeyManager()
+	ResourceAllocationManager := NewVirtualMachineResourceKeyManager(RestClient)
 	ResourceCredentials, ResourceKeysError := ResourceAllocationManager.GetResourceKeys(
 		ClusterResourcePool, DatacenterFolder)
 
@@ -294,7 +314,7 @@ func (this *VirtualMachineManager) InitializeNewVirtualMachine(
 			return nil, exceptions.ItemDoesNotExist()
 		}
 
-		VirtualMachineInstanceReference, DeployError := vcenter.NewManager(rest.NewClient(&VimClient)).DeployLibraryItem(DeployTimeoutContext, Item.ID, Deployment)
+		VirtualMachineInstanceReference, DeployError := vcenter.NewManager(RestClient).DeployLibraryItem(DeployTimeoutContext, Item.ID, Deployment)
 
 		switch {
 
@@ -329,18 +349,66 @@ func (this *VirtualMachineManager) InitializeNewVirtualMachine(
 	return nil, exceptions.VMDeployFailure()
 }
 
-func (this *VirtualMachineManager) ApplyConfiguration(VirtualMachine *object.VirtualMachine, Configuration parsers.VirtualMachineCustomSpec) (
+type DeployContext struct {
+	// Outputs of the Deploy Steps, that are being Persisted along with the Deployment Progress
+	// Original Values are being used by the Undo Actions to Restore the Virtual Machine
+
+	OriginalCpuNum   int32   `json:"OriginalCpuNum"`
+	OriginalCoresNum int32   `json:"OriginalCoresNum"`
+	OriginalMemoryMB int32   `json:"OriginalMemoryMB"`
+	OriginalGuestId  string  `json:"OriginalGuestId"`
+	OriginalDiskKeys []int32 `json:"OriginalDiskKeys"` // Disks of the Virtual Machine before the Data Disk has been Attached
+	OriginalHostname string  `json:"OriginalHostname"`
+	DiskKey          int32   `json:"DiskKey"`
+	IPAddress        string  `json:"IPAddress"`
+	SshType          string  `json:"SshType"`
+	SshInfo          string  `json:"-"` // SSH Credentials, including the Root Password, are not being Stored along with the Progress
+}
+
+func (this *VirtualMachineManager) ReconfigureVirtualMachine(VirtualMachine *object.VirtualMachine, Specification types.VirtualMachineConfigSpec) error {
+	// Applies Configuration Specification to the Virtual Machine and Waits until it's Applied
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*5)
+	defer CancelFunc()
+
+	ConfigureTask, ConfigureError := VirtualMachine.Reconfigure(TimeoutContext, Specification)
+	if ConfigureError != nil {
+		return ConfigureError
+	}
+	return ConfigureTask.Wait(TimeoutContext)
+}
+
+func (this *VirtualMachineManager) CustomizeVirtualMachine(VirtualMachine *object.VirtualMachine, Specification types.CustomizationSpec) error {
+	// Applies Guest Customization to the Virtual Machine and Waits until it's Applied
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*5)
+	defer CancelFunc()
+
+	CustomizeTask, CustomizeError := VirtualMachine.Customize(TimeoutContext, Specification)
+	if CustomizeError != nil {
+		return CustomizeError
+	}
+	return CustomizeTask.Wait(TimeoutContext)
+}
+
+func (this *VirtualMachineManager) ApplyConfiguration(VirtualMachine *object.VirtualMachine, Configuration parsers.VirtualMachineCustomSpec, Deployment *models.Deployment) (
 
 	*struct {
 		SshType   string `json:"SshType"`
 		IPAddress string `json:"IPAddress"`
 		SshInfo   string `json:"SshInfo"`
+		DiskKey   int32  `json:"DiskKey"`
 	},
 	error) {
 
 	// Applies Custom Configuration: Num's of CPU's, Memory etc... onto the Initialized Virtual Machine
-
-	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*1)
+	// Configuration is being Applied Step by Step using the Deploy Pipeline, so if any of the Steps Fails
+	// The Virtual Machine is being Restored to the State it has been before the Deploy
+	// except for the Guest OS Customization, that can't be Reverted and gets Overridden by the next Deploy
+
+	// Restoring Outputs of the Steps, if the Deployment is being Resumed
+	var DeployContext DeployContext
+	if len(Deployment.Context) != 0 {
+		json.Unmarshal([]byte(Deployment.Context), &DeployContext)
+	}
 
 	// Receiving Virtual Machine Configurations to Apply
 
@@ -355,220 +423,317 @@ func (this *VirtualMachineManager) ApplyConfiguration(VirtualMachine *object.Vir
 		return nil, ResourceError
 	}
 
-	// Getting Disk Storage Config
-	DiskStorageConfig, DiskError := Configuration.GetDiskStorageConfig(this.VimClient) // Disk Storage Configuration for the VM
-	if DiskError != nil {
-		return nil, DiskError
-	}
-
 	// Getting Network Config
 	NetworkConfig, NetworkError := Configuration.GetNetworkConfig(this.VimClient)
 	if NetworkError != nil {
 		return nil, NetworkError
 	}
 
-	// Getting Extra Tools Configuration, that is going to be Installed on the VM
-	_, ExtraToolsError := Configuration.GetExtraToolsConfig(this.VimClient)
-	if ExtraToolsError != nil {
-		Logger.Error("Failed to Obtain Extra Tools that Should be Installed on the VM",
-			zap.Error(ExtraToolsError))
-	}
-
-	// Retrieving Mo Entity of the Virtual Machine
-	var vm mo.VirtualMachine
 	Collector := property.DefaultCollector(&this.VimClient)
-	RetrieveError := Collector.RetrieveOne(TimeoutContext, VirtualMachine.Reference(), []string{"*"}, &vm)
-	if RetrieveError != nil {
-		return nil, errors.New("VM Server not found")
-	}
 
-	ResourceSpecification := types.ResourceConfigSpec{
-		CpuAllocation: types.ResourceAllocationInfo{
-			Limit:         &Configuration.Resources.MaxCpuUsage,
-			OverheadLimit: types.NewInt64(Configuration.Resources.MaxCpuUsage + int64(5)), // if Max CPU is 0.5 (half of One Processor), if memory overhead occurs, it would add 0.5 to existing limit
-		},
-		MemoryAllocation: types.ResourceAllocationInfo{
-			Limit:         &Configuration.Resources.MaxMemoryUsage,
-			OverheadLimit: types.NewInt64(Configuration.Resources.MaxMemoryUsage + int64(50)), // adds 50 Mb
-		},
-	}
+	// Steps Persist their Context before they Change the Virtual Machine, so the Rollback can Undo the Change,
+	// even if the Step Fails or the Server is being Restarted right after it
+	var Pipeline *DeployPipeline
 
-	vm.Guest = &types.GuestInfo{GuestId: HostSystemConfig.GuestId}
-	vm.Config = &types.VirtualMachineConfigInfo{
-		ExtraConfig:        []types.BaseOptionValue{&types.OptionValue{Key: "govcsim", Value: "TRUE"}},
-		MemoryAllocation:   &ResourceSpecification.MemoryAllocation,
-		CpuAllocation:      &ResourceSpecification.CpuAllocation,
-		LatencySensitivity: &types.LatencySensitivity{Level: types.LatencySensitivitySensitivityLevelNormal},
-		BootOptions:        &types.VirtualMachineBootOptions{BootRetryEnabled: types.NewBool(true)},
-		CreateDate:         types.NewTime(time.Now()),
-		InitialOverhead: &types.VirtualMachineConfigInfoOverheadInfo{
-			InitialMemoryReservation: 100, // In Megabytes
-			InitialSwapReservation:   100, // In Megabytes
-		},
-	}
-	vm.Layout = &types.VirtualMachineFileLayout{}
-	vm.LayoutEx = &types.VirtualMachineFileLayoutEx{
-		Timestamp: time.Now(),
-	}
-	vm.Snapshot = nil // intentionally set to nil until a snapshot is created
-	vm.Storage = &types.VirtualMachineStorageInfo{
-		Timestamp: time.Now(),
-	}
-	vm.Summary.Guest = &HostSystemConfig
-	vm.Summary.Vm = &vm.Self
-	vm.Summary.Storage = &types.VirtualMachineStorageSummary{
-		Timestamp: time.Now(),
-	}
+	// Step 1: CPU, Memory and Guest OS Type of the Virtual Machine
 
-	// Applying Max CPU/Memory Usage to the Virtual Machine Server
+	ResourcesStep := NewDeployStep("Resources", func() error {
+		TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*1)
+		defer CancelFunc()
 
-	if Configuration.Resources.MaxCpuUsage != 0 {
-		vm.Summary.Runtime.MaxCpuUsage = int32(Configuration.Resources.MaxCpuUsage)
-	}
+		var vm mo.VirtualMachine
+		if RetrieveError := Collector.RetrieveOne(TimeoutContext, VirtualMachine.Reference(), []string{"config"}, &vm); RetrieveError != nil || vm.Config == nil {
+			return errors.New("VM Server not found")
+		}
 
-	if Configuration.Resources.MaxMemoryUsage != 0 {
-		vm.Summary.Runtime.MaxMemoryUsage = int32(Configuration.Resources.MaxMemoryUsage)
-	}
+		// Remembering Original Resources, so they can be Restored on Rollback
+		DeployContext.OriginalCpuNum = vm.Config.Hardware.NumCPU
+		DeployContext.OriginalCoresNum = vm.Config.Hardware.NumCoresPerSocket
+		DeployContext.OriginalMemoryMB = vm.Config.Hardware.MemoryMB
+		DeployContext.OriginalGuestId = vm.Config.GuestId
+		Pipeline.Persist()
+
+		Specification := types.VirtualMachineConfigSpec{
+			GuestId:             HostSystemConfig.GuestId,
+			NumCPUs:             ResourceConfig.NumCPUs,
+			NumCoresPerSocket:   ResourceConfig.NumCoresPerSocket,
+			MemoryMB:            ResourceConfig.MemoryMB,
+			CpuHotAddEnabled:    ResourceConfig.CpuHotAddEnabled,
+			MemoryHotAddEnabled: ResourceConfig.MemoryHotAddEnabled,
+			LatencySensitivity:  &types.LatencySensitivity{Level: types.LatencySensitivitySensitivityLevelNormal},
+			BootOptions:         &types.VirtualMachineBootOptions{BootRetryEnabled: types.NewBool(true)},
+		}
 
-	// Initializing New Devices
+		// Applying Max CPU/Memory Usage to the Virtual Machine Server
+		if Configuration.Resources.MaxCpuUsage != 0 {
+			Specification.CpuAllocation = &types.ResourceAllocationInfo{
+				Limit: types.NewInt64(Configuration.Resources.MaxCpuUsage),
+			}
+		}
+		if Configuration.Resources.MaxMemoryUsage != 0 {
+			Specification.MemoryAllocation = &types.ResourceAllocationInfo{
+				Limit: types.NewInt64(Configuration.Resources.MaxMemoryUsage),
+			}
+		}
+		return this.ReconfigureVirtualMachine(VirtualMachine, Specification)
 
-	Devices, DeviceError := VirtualMachine.Device(TimeoutContext)
+	}, func() error {
+		// Virtual Machine has not been Reconfigured, if the Original Resources has not been Recorded
+		if DeployContext.OriginalCpuNum == 0 {
+			return nil
+		}
+		return this.ReconfigureVirtualMachine(VirtualMachine, types.VirtualMachineConfigSpec{
+			GuestId:           DeployContext.OriginalGuestId,
+			NumCPUs:           DeployContext.OriginalCpuNum,
+			NumCoresPerSocket: DeployContext.OriginalCoresNum,
+			MemoryMB:          int64(DeployContext.OriginalMemoryMB),
+		})
+	})
+
+	// Step 2: Disk Storage of the Virtual Machine
+
+	DetachDisks := func() error {
+		// Detaches and Destroys the Disks, that have been Attached after the Original Disks of the Virtual Machine have been Recorded
+		if DeployContext.OriginalDiskKeys == nil {
+			return nil
+		}
+		TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*1)
+		defer CancelFunc()
 
-	if DeviceError != nil {
-		Logger.Error("Failed to Receive Available Devices for Virtual Machine", zap.Error(DeviceError))
-		return nil, errors.New("Failed to Receive Available Devices for the Virtual Machine")
+		Devices, DeviceError := VirtualMachine.Device(TimeoutContext)
+		if DeviceError != nil {
+			return DeviceError
+		}
+		Changes := []types.BaseVirtualDeviceConfigSpec{}
+		for _, Disk := range Devices.SelectByType((*types.VirtualDisk)(nil)) {
+			if !ContainsDeviceKey(DeployContext.OriginalDiskKeys, Disk.GetVirtualDevice().Key) {
+				Changes = append(Changes, &types.VirtualDeviceConfigSpec{
+					Operation:     types.VirtualDeviceConfigSpecOperationRemove,
+					FileOperation: types.VirtualDeviceConfigSpecFileOperationDestroy,
+					Device:        Disk,
+				})
+			}
+		}
+		if len(Changes) != 0 {
+			if ConfigureError := this.ReconfigureVirtualMachine(VirtualMachine,
+				types.VirtualMachineConfigSpec{DeviceChange: Changes}); ConfigureError != nil {
+				return ConfigureError
+			}
+		}
+		DeployContext.OriginalDiskKeys = nil
+		DeployContext.DiskKey = 0
+		return nil
 	}
-	DiskController, ControllerError := Devices.FindDiskController("scsi")
 
-	if ControllerError != nil {
-		Logger.Error("Failed to Receive Disk Controller for the Virtual Machine, To Allocate Storage", zap.Error(ControllerError))
-		return nil, errors.New("Failed to Receive DiskController for the Virtual Machine")
-	}
+	DiskStep := NewDeployStep("Disk", func() error {
+		if Configuration.Disk.CapacityInKB == 0 {
+			return nil
+		}
+		// Disk, Attached by the Interrupted Attempt of the Step, is being Detached, so the Disk is not being Attached twice
+		if DetachError := DetachDisks(); DetachError != nil {
+			return DetachError
+		}
+		TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*1)
+		defer CancelFunc()
 
-	// Assigning Resource Configurations
-	Devices.AssignController(DiskStorageConfig.Device, DiskController)
-	defaults := types.VirtualMachineConfigSpec{
-		GuestId:             vm.Guest.GuestId,
-		NumCPUs:             ResourceConfig.NumCPUs,
-		NumCoresPerSocket:   ResourceConfig.NumCoresPerSocket,
-		MemoryMB:            ResourceConfig.MemoryMB,
-		Version:             esx.HardwareVersion,
-		CpuHotAddEnabled:    ResourceConfig.CpuHotAddEnabled,
-		MemoryHotAddEnabled: ResourceConfig.MemoryHotAddEnabled,
-		Firmware:            string(types.GuestOsDescriptorFirmwareTypeBios),
-		DeviceChange:        []types.BaseVirtualDeviceConfigSpec{DiskStorageConfig},
-	}
+		var vm mo.VirtualMachine
+		if RetrieveError := Collector.RetrieveOne(TimeoutContext, VirtualMachine.Reference(),
+			[]string{"config.hardware.device", "datastore"}, &vm); RetrieveError != nil || len(vm.Datastore) == 0 {
+			return errors.New("VM Server not found")
+		}
+		Devices := object.VirtualDeviceList(vm.Config.Hardware.Device)
 
-	// Setting up Configure Response Timeout on 5 mins...
-	ConfigureTimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*5)
-	defer CancelFunc()
+		DiskController, ControllerError := Devices.FindDiskController("scsi")
+		if ControllerError != nil {
+			Logger.Error("Failed to Receive Disk Controller for the Virtual Machine, To Allocate Storage", zap.Error(ControllerError))
+			return errors.New("Failed to Receive DiskController for the Virtual Machine")
+		}
 
-	// Applying Configurations to the VM Server
+		Datastore := object.NewDatastore(&this.VimClient, vm.Datastore[0])
+		DiskStorageConfig, DiskError := storage_config.NewVirtualMachineStorageManager().SetupStorageDisk(
+			*storage_config.NewVirtualMachineStorage(Configuration.Disk.CapacityInKB), *Datastore)
+		if DiskError != nil {
+			return DiskError
+		}
+		Devices.AssignController(DiskStorageConfig.Device, DiskController)
 
-	ConfigureTask, ConfiguredError := object.NewReference(&this.VimClient, vm.Reference()).(*object.VirtualMachine).Reconfigure(ConfigureTimeoutContext, defaults)
-	HostSystemCustomizationTask, HostSystemCustomizationError := object.NewReference(&this.VimClient, vm.Reference()).(*object.VirtualMachine).Customize(ConfigureTimeoutContext, HostSystemCustomizationConfig)
-	NetworkCustomizationTask, NetworkCustomizationError := object.NewReference(&this.VimClient, vm.Reference()).(*object.VirtualMachine).Customize(ConfigureTimeoutContext, *NetworkConfig)
+		// Remembering the Disks, the Virtual Machine has before the new one is Attached, so the Rollback can Find and Detach it
+		DeployContext.OriginalDiskKeys = []int32{}
+		for _, Disk := range Devices.SelectByType((*types.VirtualDisk)(nil)) {
+			DeployContext.OriginalDiskKeys = append(DeployContext.OriginalDiskKeys, Disk.GetVirtualDevice().Key)
+		}
+		Pipeline.Persist()
 
-	// Checking If there is Any Errors, while Configuration was Applying
+		if ConfigureError := this.ReconfigureVirtualMachine(VirtualMachine, types.VirtualMachineConfigSpec{
+			DeviceChange: []types.BaseVirtualDeviceConfigSpec{DiskStorageConfig},
+		}); ConfigureError != nil {
+			return ConfigureError
+		}
 
-	if ConfiguredError != nil {
-		// If Failing To Apply First Configuration, Destroying Virtual Machine
-		Logger.Error("Failed to Configure Virtual Machine, Error has Occurred", zap.Error(ConfiguredError))
-		_, Error := this.DestroyVirtualMachine(VirtualMachine)
-		return nil, Error
-	}
+		// Remembering the Key of the Attached Disk, so it can be Grown by the Resize
+		UpdatedDevices, DeviceError := VirtualMachine.Device(TimeoutContext)
+		if DeviceError != nil {
+			return DeviceError
+		}
+		for _, Disk := range UpdatedDevices.SelectByType((*types.VirtualDisk)(nil)) {
+			if !ContainsDeviceKey(DeployContext.OriginalDiskKeys, Disk.GetVirtualDevice().Key) {
+				DeployContext.DiskKey = Disk.GetVirtualDevice().Key
+			}
+		}
+		return nil
 
-	if HostSystemCustomizationError != nil {
-		Logger.Error("Failed to Apply Customization Specification to the VM Server with OS Specifications", zap.Error(HostSystemCustomizationError))
-		return nil, HostSystemCustomizationError
-	}
+	}, DetachDisks)
 
-	if NetworkCustomizationError != nil {
-		Logger.Error("Failed to Setup Customized Network", zap.Error(NetworkCustomizationError))
-		return nil, NetworkCustomizationError
-	}
+	// Step 3: Guest OS Customization
+	// Customization can't be Reverted, it gets Overridden by the next Deploy
 
-	// Waiting for Hardware and Resource Configuration to Apply
-	WaitResponseError := ConfigureTask.Wait(ConfigureTimeoutContext)
-	if WaitResponseError != nil {
-		Logger.Error("Failed to Configure Virtual Machine", zap.Error(WaitResponseError))
-		return nil, WaitResponseError
-	}
+	HostSystemStep := NewDeployStep("HostSystem", func() error {
+		return this.CustomizeVirtualMachine(VirtualMachine, HostSystemCustomizationConfig)
+	}, nil)
 
-	// Waiting for OS Customization Response
-	WaitCustomizationResponseError := HostSystemCustomizationTask.Wait(ConfigureTimeoutContext)
-	if WaitCustomizationResponseError != nil {
-		Logger.Error("Failed to Configure OS Customization Specification for the VM Server", zap.Error(WaitCustomizationResponseError))
-		return nil, WaitCustomizationResponseError
-	}
+	// Step 4: Network Customization
 
-	// Waiting for the Network Customization Response
-	WaitNetworkCustomizationError := NetworkCustomizationTask.Wait(ConfigureTimeoutContext)
-	if WaitNetworkCustomizationError != nil {
-		Logger.Error("Failed to Apply Network Configuration", zap.Error(WaitNetworkCustomizationError))
-		return nil, WaitNetworkCustomizationError
-	}
+	NetworkStep := NewDeployStep("Network", func() error {
+		TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*1)
+		defer CancelFunc()
 
-	NewVirtualMachine := object.NewVirtualMachine(&this.VimClient, vm.Reference())
+		// Remembering the Hostname of the Guest, so the Rollback can Return it along with the Dynamic Address
+		var vm mo.VirtualMachine
+		if RetrieveError := Collector.RetrieveOne(TimeoutContext, VirtualMachine.Reference(), []string{"name", "guest"}, &vm); RetrieveError != nil {
+			return errors.New("VM Server not found")
+		}
+		DeployContext.OriginalHostname = vm.Name
+		if vm.Guest != nil && len(vm.Guest.HostName) != 0 {
+			DeployContext.OriginalHostname = vm.Guest.HostName
+		}
+		Pipeline.Persist()
 
-	VmIPAddress, VmIPError := NewVirtualMachine.WaitForIP(TimeoutContext, true)
-	if VmIPError != nil {
-		Logger.Error("Failed to Fetch IP Addresses for the VM", zap.Error(VmIPError))
-	}
+		if CustomizeError := this.CustomizeVirtualMachine(VirtualMachine, *NetworkConfig); CustomizeError != nil {
+			return CustomizeError
+		}
+		VmIPAddress, VmIPError := VirtualMachine.WaitForIP(TimeoutContext, true)
+		if VmIPError != nil {
+			Logger.Error("Failed to Fetch IP Addresses for the VM", zap.Error(VmIPError))
+		}
+		DeployContext.IPAddress = VmIPAddress
+		return nil
 
-	// Setting up SSH Credentials for the Virtual Machine
+	}, func() error {
+		// Fixed Address of the Configuration is being Released, the Guest Receives the Address from the DHCP back
+		if len(DeployContext.OriginalHostname) == 0 {
+			return nil
+		}
+		Specification, SetupError := network.NewVirtualMachinePublicNetworkManager().SetupDynamicNetwork(DeployContext.OriginalHostname)
+		if SetupError != nil {
+			return SetupError
+		}
+		if CustomizeError := this.CustomizeVirtualMachine(VirtualMachine, *Specification); CustomizeError != nil {
+			return CustomizeError
+		}
+		DeployContext.OriginalHostname = ""
+		DeployContext.IPAddress = ""
+		return nil
+	})
 
-	SshCredentials, ApplyError := Configuration.ApplySshConfig(this.VimClient, VirtualMachine)
-	if ApplyError != nil {
-		Logger.Error("Failed to Apply SSH to the VM", zap.Error(ApplyError))
-	}
+	// Step 5: SSH Credentials for the Virtual Machine
 
-	// Defining which type of the SSH has been Applied to Virtual Machine,
-	// If the Customer has chosen the Type: "By Root Credentials", It would return `Username` and `Password`
-	// Or If Customer has chosen the Type: "By SSL Certificate" It would return the Generated SSL Certificate with Into About it
-	// By Root Credentials or By SSL Certificate
+	GenerateSshCredentials := func() error {
+		SshCredentials, ApplyError := Configuration.ApplySshConfig(this.VimClient, VirtualMachine)
+		if ApplyError != nil {
+			return ApplyError
+		}
 
-	var SshType string
-	var SshInfo []byte
+		// Defining which type of the SSH has been Applied to Virtual Machine,
+		// If the Customer has chosen the Type: "By Root Credentials", It would return `Username` and `Password`
+		// Or If Customer has chosen the Type: "By SSL Certificate" It would return the Generated SSL Certificate with Into About it
+
+		var SshInfo []byte
+		switch Credentials := SshCredentials.(type) {
+
+		case *types.NamePasswordAuthentication:
+			DeployContext.SshType = models.TypeByRootCredentials
+			SshInfo, _ = json.Marshal(struct {
+				Username string `json:"Username"`
+				Password string `json:"Password"`
+			}{Username: Credentials.Username, Password: Credentials.Password})
+
+		case *ssh_config.SshCertificateCredentials:
+			DeployContext.SshType = models.TypeByRootCertificate
+			SshInfo, _ = json.Marshal(struct {
+				KeyContent []byte `json:"KeyContent"`
+				Filename   string `json:"Filename"`
+			}{KeyContent: Credentials.Content, Filename: Credentials.FileName})
+
+		default:
+			return errors.New("Unknown SSH Credentials Type")
+		}
+		DeployContext.SshInfo = string(SshInfo)
+		return nil
+	}
 
-	switch {
+	// Credentials are only Generated and Returned to the Caller, nothing is being Installed on the Guest,
+	// so Discarding them Compensates the Step
+	SshStep := NewDeployStep("Ssh", GenerateSshCredentials, func() error {
+		DeployContext.SshType = ""
+		DeployContext.SshInfo = ""
+		return nil
+	})
 
-	case SshCredentials.(*ssh_config.SshRootCredentials) != nil:
-		RootCredentials := SshCredentials.(*ssh_config.SshRootCredentials)
-		SshType = models.TypeByRootCredentials
-		SshInfo, _ = json.Marshal(struct {
-			Username string `json:"Username"`
-			Password string `json:"Password"`
-		}{Username: RootCredentials.Username, Password: RootCredentials.Password})
+	Pipeline = NewDeployPipeline(Deployment, &DeployContext,
+		ResourcesStep, DiskStep, HostSystemStep, NetworkStep, SshStep)
 
-	case SshCredentials.(*ssh_config.SshCertificateCredentials) != nil:
-		CertificateCredentials := SshCredentials.(*ssh_config.SshCertificateCredentials)
-		SshType = models.TypeByRootCertificate
-		SshInfo, _ = json.Marshal(struct {
-			KeyContent []byte `json:"KeyContent"`
-			Filename   string `json:"Filename"`
-			FilePath   string `json:"FilePath"`
-		}{KeyContent: CertificateCredentials.Content, Filename: CertificateCredentials.FileName, FilePath: CertificateCredentials.FilePath})
+	if DeployError := Pipeline.Execute(); DeployError != nil {
+		return nil, DeployError
 	}
 
-	// Installing Initial Dependencies on the Virtual Machine
-
-	// DepInstaller := dependency_installer.NewEnviromentDependencyInstaller()
-	// SshConnection := DepInstaller.GetSshConnection(IPAddress)
-	// Installed, InstallError := DepInstaller.InstallDependencies(VirtualMachine)
-	// if InstallError != nil {return nil, InstallError}
+	// Credentials are not being Persisted along with the Progress, so they are being Generated again,
+	// if the Deployment has been Resumed after the Ssh Step has been Completed
+	if len(DeployContext.SshInfo) == 0 {
+		if SshError := GenerateSshCredentials(); SshError != nil {
+			return nil, SshError
+		}
+	}
 
 	return &struct {
 		SshType   string "json:\"SshType\""
 		IPAddress string "json:\"IPAddress\""
 		SshInfo   string "json:\"SshInfo\""
+		DiskKey   int32  "json:\"DiskKey\""
 	}{
-		SshType:   SshType,
-		IPAddress: VmIPAddress,
-		SshInfo:   string(SshInfo),
+		SshType:   DeployContext.SshType,
+		IPAddress: DeployContext.IPAddress,
+		SshInfo:   DeployContext.SshInfo,
+		DiskKey:   DeployContext.DiskKey,
 	}, nil
 }
 
+func ContainsDeviceKey(Keys []int32, Key int32) bool {
+	for _, Existing := range Keys {
+		if Existing == Key {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *VirtualMachineManager) RollbackDeployment(VirtualMachine *object.VirtualMachine, Deployment *models.Deployment, Cause error) error {
+	// Undoes Completed Steps of the Unfinished Deployment, e.g before the new Configuration is Applied instead of it
+	// Returns nil, only if the Virtual Machine has been Restored to the State it has been before the Deployment
+
+	Configuration, ParseError := parsers.NewCustomConfig(Deployment.Configuration)
+	if ParseError != nil {
+		return ParseError
+	}
+	Deployment.State = models.DeploymentStateRollingBack
+	Deployment.Error = Cause.Error()
+
+	this.ApplyConfiguration(VirtualMachine, *Configuration, Deployment)
+	if Deployment.State != models.DeploymentStateRolledBack {
+		return fmt.Errorf("Failed to Roll Back Deployment %v: %s", Deployment.ID, Deployment.Error)
+	}
+	return nil
+}
+
 func (this *VirtualMachineManager) StartVirtualMachine(VirtualMachine *object.VirtualMachine) error {
 
 	// Starts Virtual Machine Server..
@@ -577,7 +742,10 @@ func (this *VirtualMachineManager) StartVirtualMachine(VirtualMachine *object.Vi
 	defer CancelFunc()
 
 	Newtask, DeployError := VirtualMachine.PowerOn(TimeoutContext)
-	AppliedError := Newtask.Wait(TimeoutContext)
+	var AppliedError error
+	if DeployError == nil {
+		AppliedError = Newtask.Wait(TimeoutContext)
+	}
 
 	switch {
 	case DeployError != nil || AppliedError != nil:
@@ -588,6 +756,7 @@ func (this *VirtualMachineManager) StartVirtualMachine(VirtualMachine *object.Vi
 	case DeployError == nil && AppliedError == nil:
 		Logger.Debug("Virtual Machine has been Started Successfully",
 			zap.String("ItemPath", VirtualMachine.InventoryPath))
+		this.EmitEvent(webhooks.EventStarted, VirtualMachine)
 		return nil
 	default:
 		return nil
@@ -604,9 +773,10 @@ func (this *VirtualMachineManager) RebootVirtualMachine(VirtualMachine *object.V
 	if RebootError != nil {
 		Logger.Error("Failed to Reboot Guest OS, on VM: %s",
 			zap.Error(RebootError))
-		return true
-	} else {
 		return false
+	} else {
+		this.EmitEvent(webhooks.EventRebooted, VirtualMachine)
+		return true
 	}
 }
 
@@ -617,7 +787,10 @@ func (this *VirtualMachineManager) ShutdownVirtualMachine(VirtualMachine *object
 	defer CancelFunc()
 
 	Newtask, DeployError := VirtualMachine.PowerOff(TimeoutContext)
-	AppliedError := Newtask.Wait(TimeoutContext)
+	var AppliedError error
+	if DeployError == nil {
+		AppliedError = Newtask.Wait(TimeoutContext)
+	}
 
 	switch {
 	case DeployError != nil || AppliedError != nil:
@@ -630,6 +803,7 @@ func (this *VirtualMachineManager) ShutdownVirtualMachine(VirtualMachine *object
 	case DeployError == nil && AppliedError == nil:
 		Logger.Debug("Virtual Machine has been Shutdown.",
 			zap.String("ItemPath", VirtualMachine.InventoryPath))
+		this.EmitEvent(webhooks.EventStopped, VirtualMachine)
 		return nil
 	default:
 		Logger.Error("Unknown State has been Occurred, while Shutting Down Virtual Machine",
@@ -638,6 +812,27 @@ func (this *VirtualMachineManager) ShutdownVirtualMachine(VirtualMachine *object
 	}
 }
 
+func (this *VirtualMachineManager) ShutdownGuestVirtualMachine(VirtualMachine *object.VirtualMachine) error {
+	// Shuts Down the Guest OS of the Virtual Machine and Waits until the Virtual Machine is Powered Off
+	// Unlike the Power Off, the Guest can Stop it's Services and Flush the File System Buffers, so the Data is not being Lost
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), GuestShutdownTimeout)
+	defer CancelFunc()
+
+	ShutdownError := VirtualMachine.ShutdownGuest(TimeoutContext)
+	if ShutdownError == nil {
+		ShutdownError = VirtualMachine.WaitForPowerState(TimeoutContext, types.VirtualMachinePowerStatePoweredOff)
+	}
+	if ShutdownError != nil {
+		Logger.Error("Failed to Shutdown Guest OS of the Virtual Machine",
+			zap.String("ItemPath", VirtualMachine.InventoryPath), zap.Error(ShutdownError))
+		return ErrPowerCycleRequired
+	}
+	Logger.Debug("Guest OS of the Virtual Machine has been Shutdown", zap.String("ItemPath", VirtualMachine.InventoryPath))
+	this.EmitEvent(webhooks.EventStopped, VirtualMachine)
+	return nil
+}
+
 func (this *VirtualMachineManager) DestroyVirtualMachine(VirtualMachine *object.VirtualMachine) (bool, error) {
 	// Destroys Virtual Machine, Customer Decided to get rid of...
 
@@ -645,7 +840,10 @@ func (this *VirtualMachineManager) DestroyVirtualMachine(VirtualMachine *object.
 	defer CancelFunc()
 
 	DestroyTask, DestroyError := VirtualMachine.Destroy(TimeoutContext)
-	TaskError := DestroyTask.Wait(TimeoutContext)
+	var TaskError error
+	if DestroyError == nil {
+		TaskError = DestroyTask.Wait(TimeoutContext)
+	}
 
 	if DestroyError != nil || TaskError != nil {
 		Logger.Error("Failed to Destroy Virtual Machine",
@@ -656,9 +854,375 @@ func (this *VirtualMachineManager) DestroyVirtualMachine(VirtualMachine *object.
 	}
 	Logger.Info("Virtual Machine has been Destroyed",
 		zap.String("ItemPath", VirtualMachine.InventoryPath))
+	this.EmitEvent(webhooks.EventDestroyed, VirtualMachine)
 	return true, nil
 }
 
-func (this *VirtualMachineManager) ReplicateVirtualMachine(VirtualMachine *object.VirtualMachine) {
+func (this *VirtualMachineManager) DiscardVirtualMachine(VirtualMachine *object.VirtualMachine) error {
+	// Powers Off and Destroys the Virtual Machine, that has not been Recorded in the Database (e.g the Copy, that Failed to be Saved)
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*10)
+	defer CancelFunc()
+
+	PowerState, StateError := VirtualMachine.PowerState(TimeoutContext)
+	if StateError == nil && PowerState == types.VirtualMachinePowerStatePoweredOn {
+		if ShutdownError := this.ShutdownVirtualMachine(VirtualMachine); ShutdownError != nil {
+			return ShutdownError
+		}
+	}
+	_, DestroyError := this.DestroyVirtualMachine(VirtualMachine)
+	return DestroyError
+}
+
+var (
+	ErrPlacementOutsideDatacenter = errors.New("Location of the Copy should be within the Datacenter of the Source Virtual Machine")
+)
+
+func (this *VirtualMachineManager) GetDatacenter(Context context.Context, Reference types.ManagedObjectReference) (*types.ManagedObjectReference, error) {
+	// Returns Datacenter, the Inventory Item Belongs to
+	Ancestors, AncestorsError := mo.Ancestors(Context, this.VimClient.RoundTripper, this.VimClient.ServiceContent.PropertyCollector, Reference)
+	if AncestorsError != nil {
+		return nil, AncestorsError
+	}
+	for _, Ancestor := range Ancestors {
+		if Ancestor.Self.Type == "Datacenter" {
+			return &Ancestor.Self, nil
+		}
+	}
+	return nil, exceptions.ComponentDoesNotExist("Datacenter")
+}
+
+func (this *VirtualMachineManager) FindClonePlacement(VirtualMachine *object.VirtualMachine, ItemPath string, Type string) (*types.ManagedObjectReference, error) {
+	// Returns the Datastore or the Folder (depending on the `Type`), the Copy of the Virtual Machine is going to be Placed in
+	// Items of the other Datacenters are being Rejected, so the Customer can't Place the Copy to the Infrastructure, it has no Access to
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*1)
+	defer CancelFunc()
+
+	Reference, FindError := object.NewSearchIndex(&this.VimClient).FindByInventoryPath(TimeoutContext, ItemPath)
+	if FindError != nil || Reference == nil || Reference.Reference().Type != Type {
+		return nil, exceptions.ComponentDoesNotExist(Type)
+	}
+	Placement := Reference.Reference()
+
+	SourceDatacenter, SourceError := this.GetDatacenter(TimeoutContext, VirtualMachine.Reference())
+	if SourceError != nil {
+		return nil, SourceError
+	}
+	Datacenter, DatacenterError := this.GetDatacenter(TimeoutContext, Placement)
+	if DatacenterError != nil || *Datacenter != *SourceDatacenter {
+		return nil, ErrPlacementOutsideDatacenter
+	}
+	return &Placement, nil
+}
+
+type VirtualMachineCloneSpec struct {
+	// Options of the Virtual Machine Server Copy
+
+	VirtualMachineName string                          // Name of the New Virtual Machine Server
+	Datastore          *object.Datastore               // Datastore, the Copy is going to be Placed on, (Source Datastore if nil)
+	Folder             *object.Folder                  // Folder, the Copy is going to be Placed in, (Source Folder if nil)
+	Linked             bool                            // Linked Clone Shares the Disks with the Source, instead of Copying them
+	IPAddress          network.VirtualMachineIPAddress // Fresh IP Address and Hostname for the Copy
+}
+
+func NewVirtualMachineCloneSpec(
+	VirtualMachineName string,
+	Datastore *object.Datastore,
+	Folder *object.Folder,
+	Linked bool,
+	IPAddress network.VirtualMachineIPAddress,
+) *VirtualMachineCloneSpec {
+	return &VirtualMachineCloneSpec{
+		VirtualMachineName: VirtualMachineName,
+		Datastore:          Datastore,
+		Folder:             Folder,
+		Linked:             Linked,
+		IPAddress:          IPAddress,
+	}
+}
+
+func (this *VirtualMachineManager) GetCloneBaseSnapshot(VirtualMachine *object.VirtualMachine, MoVirtualMachine mo.VirtualMachine) (*types.ManagedObjectReference, error) {
+	// Returns Snapshot, the Linked Clone is going to be based on
+	// If the Virtual Machine does not have any Snapshots yet, the new one is going to be Created
+
+	if MoVirtualMachine.Snapshot != nil && MoVirtualMachine.Snapshot.CurrentSnapshot != nil {
+		return MoVirtualMachine.Snapshot.CurrentSnapshot, nil
+	}
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*5)
+	defer CancelFunc()
+
+	SnapshotTask, SnapshotError := VirtualMachine.CreateSnapshot(TimeoutContext,
+		fmt.Sprintf("%s-clone-base", VirtualMachine.Name()), "Base Snapshot for the Linked Clones", false, false)
+	if SnapshotError != nil {
+		return nil, SnapshotError
+	}
+
+	SnapshotInfo, WaitError := SnapshotTask.WaitForResult(TimeoutContext, nil)
+	if WaitError != nil {
+		return nil, WaitError
+	}
+	SnapshotReference := SnapshotInfo.Result.(types.ManagedObjectReference)
+	return &SnapshotReference, nil
+}
+
+func (this *VirtualMachineManager) ReplicateVirtualMachine(VirtualMachine *object.VirtualMachine, CloneSpec VirtualMachineCloneSpec) (*object.VirtualMachine, error) {
 	// Method Replicates Virtual Machine Server and deploys a copy of that
+	// The Copy Receives Fresh IP Address and Hostname, using Guest Customization
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*10)
+	defer CancelFunc()
+
+	// Retrieving Mo Entity of the Source Virtual Machine
+	var MoVirtualMachine mo.VirtualMachine
+	Collector := property.DefaultCollector(&this.VimClient)
+	RetrieveError := Collector.RetrieveOne(TimeoutContext, VirtualMachine.Reference(),
+		[]string{"parent", "resourcePool", "datastore", "snapshot"}, &MoVirtualMachine)
+
+	if RetrieveError != nil {
+		Logger.Error("Failed to Retrieve Source Virtual Machine", zap.Error(RetrieveError))
+		return nil, exceptions.ItemDoesNotExist()
+	}
+
+	// Picking up the Location of the Copy, By Default it is the same as the Source one
+	Folder := CloneSpec.Folder
+	if Folder == nil {
+		if MoVirtualMachine.Parent == nil {
+			return nil, exceptions.ComponentDoesNotExist("Folder")
+		}
+		Folder = object.NewFolder(&this.VimClient, *MoVirtualMachine.Parent)
+	}
+
+	RelocateSpec := types.VirtualMachineRelocateSpec{
+		Pool: MoVirtualMachine.ResourcePool,
+	}
+
+	switch {
+	case CloneSpec.Datastore != nil:
+		DatastoreReference := CloneSpec.Datastore.Reference()
+		RelocateSpec.Datastore = &DatastoreReference
+	case len(MoVirtualMachine.Datastore) != 0:
+		RelocateSpec.Datastore = &MoVirtualMachine.Datastore[0]
+	}
+
+	// Setting up Customization with the new IP Address and Hostname
+	NetworkManager := network.NewVirtualMachinePublicNetworkManager()
+	CustomizationSpec, CustomizationError := NetworkManager.SetupPublicNetwork(CloneSpec.IPAddress)
+	if CustomizationError != nil {
+		Logger.Error("Failed to Setup Network Customization for the Clone", zap.Error(CustomizationError))
+		return nil, exceptions.NetworkSetupFailure()
+	}
+
+	Specification := types.VirtualMachineCloneSpec{
+		Location:      RelocateSpec,
+		Customization: CustomizationSpec,
+		PowerOn:       true,
+		Template:      false,
+	}
+
+	// Linked Clone is being Created on top of the Snapshot of the Source Virtual Machine
+	if CloneSpec.Linked {
+		Snapshot, SnapshotError := this.GetCloneBaseSnapshot(VirtualMachine, MoVirtualMachine)
+		if SnapshotError != nil {
+			Logger.Error("Failed to Obtain Base Snapshot for the Linked Clone", zap.Error(SnapshotError))
+			return nil, exceptions.VMCloneFailure()
+		}
+		Specification.Snapshot = Snapshot
+		Specification.Location.DiskMoveType = string(
+			types.VirtualMachineRelocateDiskMoveOptionsCreateNewChildDiskBacking)
+	}
+
+	CloneTask, CloneError := VirtualMachine.Clone(TimeoutContext, Folder, CloneSpec.VirtualMachineName, Specification)
+	if CloneError != nil {
+		Logger.Error("Failed to Clone Virtual Machine", zap.Error(CloneError))
+		return nil, exceptions.VMCloneFailure()
+	}
+
+	CloneInfo, WaitError := CloneTask.WaitForResult(TimeoutContext, nil)
+	if WaitError != nil {
+		Logger.Error("Failed to Clone Virtual Machine", zap.Error(WaitError))
+		return nil, exceptions.VMCloneFailure()
+	}
+
+	// Receiving Reference of the new Virtual Machine along with it's Inventory Path
+	CloneReference := CloneInfo.Result.(types.ManagedObjectReference)
+	Clone := object.NewVirtualMachine(&this.VimClient, CloneReference)
+
+	InventoryPath, PathError := find.InventoryPath(TimeoutContext, &this.VimClient, CloneReference)
+	if PathError != nil {
+		Logger.Error("Failed to Receive Inventory Path of the Clone", zap.Error(PathError))
+		return nil, exceptions.VMCloneFailure()
+	}
+	Clone.InventoryPath = InventoryPath
+
+	Logger.Info("Virtual Machine has been Cloned",
+		zap.String("Source ItemPath", VirtualMachine.InventoryPath),
+		zap.String("ItemPath", Clone.InventoryPath), zap.Bool("Linked", CloneSpec.Linked))
+	return Clone, nil
+}
+
+var (
+	ErrPowerCycleRequired = errors.New("Requested Resources can't be Changed on the Running Virtual Machine " +
+		"and it's Guest OS has not been Shut Down, Power Off the Virtual Machine and Retry")
+)
+
+// Time, the Guest OS is given to Shut Down, before the Resize is being Abandoned
+var GuestShutdownTimeout = time.Minute * 3
+
+type VirtualMachineResizeSpec struct {
+	// Requested Resources of the Running Virtual Machine, Zero Values means the Resource stays the same
+
+	CpuNum            int32
+	MemoryInMegabytes int64
+	DiskCapacityInKB  int64
+	DiskKey           int32 // Device Key of the Data Disk, Attached by the Deploy, the Capacity of which is being Changed
+
+	// Limits of the Virtual Machine, Specified in it's Configuration (Zero means Unlimited)
+	MaxCpuUsage        int64 // In MHz
+	MaxMemoryUsage     int64 // In Megabytes
+	MaxStorageCapacity int64 // In Kilobytes
+}
+
+func NewVirtualMachineResizeSpec(CpuNum int32, MemoryInMegabytes int64, DiskCapacityInKB int64) *VirtualMachineResizeSpec {
+	return &VirtualMachineResizeSpec{
+		CpuNum:            CpuNum,
+		MemoryInMegabytes: MemoryInMegabytes,
+		DiskCapacityInKB:  DiskCapacityInKB,
+	}
+}
+
+func (this *VirtualMachineManager) ValidateResizeCapacity(VirtualMachine *object.VirtualMachine, Specification types.VirtualMachineConfigSpec, ResizeSpec VirtualMachineResizeSpec) error {
+	// Checks that the new Resources fit into the Limits of the Virtual Machine and the Capacity of it's Host
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*30)
+	defer CancelFunc()
+
+	HostSystem, HostError := VirtualMachine.HostSystem(TimeoutContext)
+	if HostError != nil {
+		Logger.Error("Failed to Receive Host of the Virtual Machine", zap.Error(HostError))
+		return exceptions.ComponentDoesNotExist("HostSystem")
+	}
+
+	var MoHostSystem mo.HostSystem
+	Collector := property.DefaultCollector(&this.VimClient)
+	if RetrieveError := Collector.RetrieveOne(TimeoutContext, HostSystem.Reference(),
+		[]string{"summary.hardware"}, &MoHostSystem); RetrieveError != nil || MoHostSystem.Summary.Hardware == nil {
+		Logger.Error("Failed to Receive Hardware of the Host System", zap.Error(RetrieveError))
+		return exceptions.ComponentDoesNotExist("HostSystem")
+	}
+	Hardware := MoHostSystem.Summary.Hardware
+
+	if Specification.NumCPUs != 0 {
+		if Specification.NumCPUs > int32(Hardware.NumCpuThreads) {
+			return fmt.Errorf("Host does not have enough CPUs, Available: %d", Hardware.NumCpuThreads)
+		}
+		if ResizeSpec.MaxCpuUsage != 0 && int64(Specification.NumCPUs)*int64(Hardware.CpuMhz) > ResizeSpec.MaxCpuUsage {
+			return fmt.Errorf("Requested CPUs exceed Max CPU Usage of the Virtual Machine: %d MHz", ResizeSpec.MaxCpuUsage)
+		}
+	}
+
+	if Specification.MemoryMB != 0 {
+		if Specification.MemoryMB*1024*1024 > Hardware.MemorySize {
+			return fmt.Errorf("Host does not have enough Memory, Available: %d MB", Hardware.MemorySize/1024/1024)
+		}
+		if ResizeSpec.MaxMemoryUsage != 0 && Specification.MemoryMB > ResizeSpec.MaxMemoryUsage {
+			return fmt.Errorf("Requested Memory exceeds Max Memory Usage of the Virtual Machine: %d MB", ResizeSpec.MaxMemoryUsage)
+		}
+	}
+
+	if ResizeSpec.MaxStorageCapacity != 0 && ResizeSpec.DiskCapacityInKB > ResizeSpec.MaxStorageCapacity {
+		return fmt.Errorf("Requested Disk Capacity exceeds Max Storage Capacity of the Virtual Machine: %d KB", ResizeSpec.MaxStorageCapacity)
+	}
+	return nil
+}
+
+func (this *VirtualMachineManager) ResizeVirtualMachine(VirtualMachine *object.VirtualMachine, ResizeSpec VirtualMachineResizeSpec) (bool, error) {
+	// Changes CPU, Memory and Disk Capacity of the Virtual Machine
+	// Changes are being Applied to the Running Virtual Machine, if it Supports Hot Add
+	// Otherwise the Guest OS is being Shut Down, the Virtual Machine is being Reconfigured and Powered On back
+	// Returns `ErrPowerCycleRequired`, if the Guest OS can't be Shut Down Gracefully (e.g the VMware Tools are not Running)
+	// Returns true, if the Virtual Machine has been Power Cycled
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*5)
+	defer CancelFunc()
+
+	var MoVirtualMachine mo.VirtualMachine
+	Collector := property.DefaultCollector(&this.VimClient)
+	if RetrieveError := Collector.RetrieveOne(TimeoutContext, VirtualMachine.Reference(),
+		[]string{"config", "runtime.powerState"}, &MoVirtualMachine); RetrieveError != nil || MoVirtualMachine.Config == nil {
+		Logger.Error("Failed to Retrieve Virtual Machine", zap.Error(RetrieveError))
+		return false, exceptions.ItemDoesNotExist()
+	}
+	PoweredOn := MoVirtualMachine.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn
+
+	// Computing only the Resources, that has been Changed
+	Resources := resource_config.NewVirtualMachineResources(ResizeSpec.CpuNum, ResizeSpec.MemoryInMegabytes)
+	Delta, DeltaError := resource_config.SetupResourcesDelta(
+		MoVirtualMachine.Config.Hardware, *MoVirtualMachine.Config, PoweredOn, Resources)
+	if DeltaError != nil {
+		return false, DeltaError
+	}
+	Specification := Delta.Specification
+
+	// Growing the Data Disk of the Virtual Machine, Disks can be Extended on the Running Virtual Machine
+	// Primary Disk of the Template is not being Touched, the Disk Capacity of the Configuration Describes the Data Disk only
+	if ResizeSpec.DiskCapacityInKB != 0 {
+		Devices := object.VirtualDeviceList(MoVirtualMachine.Config.Hardware.Device)
+		Disk, IsDisk := Devices.FindByKey(ResizeSpec.DiskKey).(*types.VirtualDisk)
+		if ResizeSpec.DiskKey == 0 || !IsDisk {
+			Logger.Error("Data Disk of the Virtual Machine has not been Found", zap.String("ItemPath", VirtualMachine.InventoryPath),
+				zap.Int32("Disk Key", ResizeSpec.DiskKey))
+			return false, exceptions.ComponentDoesNotExist("Disk")
+		}
+
+		if ResizeSpec.DiskCapacityInKB != Disk.CapacityInKB {
+			DiskSpec, DiskError := storage_config.NewVirtualMachineStorageManager().GrowStorageDisk(Disk, ResizeSpec.DiskCapacityInKB)
+			if DiskError != nil {
+				return false, DiskError
+			}
+			Specification.DeviceChange = append(Specification.DeviceChange, DiskSpec)
+		}
+	}
+
+	if !Delta.Changed && len(Specification.DeviceChange) == 0 {
+		return false, nil
+	}
+
+	if ValidationError := this.ValidateResizeCapacity(VirtualMachine, Specification, ResizeSpec); ValidationError != nil {
+		return false, ValidationError
+	}
+
+	PowerCycle := PoweredOn && Delta.RequiresPowerCycle
+	if PowerCycle {
+		Logger.Info("Virtual Machine does not Support Hot Resize of the Requested Resources, Power Cycling it",
+			zap.String("ItemPath", VirtualMachine.InventoryPath))
+		if ShutdownError := this.ShutdownGuestVirtualMachine(VirtualMachine); ShutdownError != nil {
+			return false, ShutdownError
+		}
+	}
+
+	ReconfigureTask, ReconfigureError := VirtualMachine.Reconfigure(TimeoutContext, Specification)
+	if ReconfigureError == nil {
+		ReconfigureError = ReconfigureTask.Wait(TimeoutContext)
+	}
+
+	if ReconfigureError != nil {
+		Logger.Error("Failed to Resize Virtual Machine",
+			zap.String("ItemPath", VirtualMachine.InventoryPath), zap.Error(ReconfigureError))
+	}
+
+	// The Virtual Machine is being Started back even if Reconfiguration has Failed, so it does not stay Powered Off
+	if PowerCycle {
+		if StartError := this.StartVirtualMachine(VirtualMachine); StartError != nil {
+			return true, StartError
+		}
+	}
+
+	if ReconfigureError != nil {
+		return PowerCycle, exceptions.VMResizeFailure()
+	}
+	Logger.Info("Virtual Machine has been Resized",
+		zap.String("ItemPath", VirtualMachine.InventoryPath), zap.Bool("Power Cycled", PowerCycle))
+	return PowerCycle, nil
 }
@@ -0,0 +1,140 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/LovePelmeni/Infrastructure/models"
+	"go.uber.org/zap"
+)
+
+// Deploy Pipeline is a small Saga Engine, that Executes Deploy of the Virtual Machine as the Ordered Steps
+// Every Step has an Undo Action, that Compensates it, so if any Step fails, the Completed ones are being Undone in the Reversed Order
+// The Failed or Interrupted Step is being Undone as well, since it could have Changed the Virtual Machine before it has Stopped,
+// so the Undo Actions should Compensate only the Changes, the Step has Recorded in the Context
+// Progress of the Pipeline is being Persisted in the `models.Deployment` after every Step, so the Interrupted Deploy can be Resumed
+
+type DeployStep struct {
+	// Single Step of the Deploy Pipeline
+
+	Name string
+	Do   func() error
+	Undo func() error // Can be nil, if the Step does not Change anything, that needs to be Compensated
+}
+
+func NewDeployStep(Name string, Do func() error, Undo func() error) DeployStep {
+	return DeployStep{
+		Name: Name,
+		Do:   Do,
+		Undo: Undo,
+	}
+}
+
+type DeployPipeline struct {
+	// Executes Deploy Steps one by one and Rolls them Back on Failure
+
+	Deployment *models.Deployment
+	Steps      []DeployStep
+	Context    interface{}                    // Outputs of the Steps, Persisted along with the Progress, so the Resumed Steps can use them
+	Store      func(*models.Deployment) error // Stores the Progress, Saves the Deployment into the Database by Default
+}
+
+func NewDeployPipeline(Deployment *models.Deployment, Context interface{}, Steps ...DeployStep) *DeployPipeline {
+	return &DeployPipeline{
+		Deployment: Deployment,
+		Steps:      Steps,
+		Context:    Context,
+		Store:      SaveDeployment,
+	}
+}
+
+func SaveDeployment(Deployment *models.Deployment) error {
+	// Saves the Deployment into the Database
+	_, SaveError := Deployment.Save()
+	return SaveError
+}
+
+func (this *DeployPipeline) Persist() {
+	// Saves Progress of the Pipeline and Outputs of the Steps
+	SerializedContext, _ := json.Marshal(this.Context)
+	this.Deployment.Context = string(SerializedContext)
+
+	if SaveError := this.Store(this.Deployment); SaveError != nil {
+		Logger.Error("Failed to Save Deployment Progress",
+			zap.Int("Deployment ID", this.Deployment.ID), zap.Error(SaveError))
+	}
+}
+
+func (this *DeployPipeline) Execute() error {
+	// Executes Steps, that has not been Completed yet
+	// If the Deployment has been Interrupted during the Rollback, the Rollback is being Finished instead
+
+	if this.Deployment.State == models.DeploymentStateRollingBack {
+		return this.Rollback(fmt.Errorf("%s", this.Deployment.Error))
+	}
+
+	for Index := this.Deployment.CompletedSteps; Index < len(this.Steps); Index++ {
+		Step := this.Steps[Index]
+
+		this.Deployment.CurrentStep = Step.Name
+		this.Persist()
+
+		if StepError := Step.Do(); StepError != nil {
+			Logger.Error("Deploy Step has Failed", zap.Int("Deployment ID", this.Deployment.ID),
+				zap.String("Step", Step.Name), zap.Error(StepError))
+			return this.Rollback(fmt.Errorf("Step %s has Failed: %s", Step.Name, StepError))
+		}
+
+		this.Deployment.CompletedSteps = Index + 1
+		this.Persist()
+		Logger.Debug("Deploy Step has been Completed",
+			zap.Int("Deployment ID", this.Deployment.ID), zap.String("Step", Step.Name))
+	}
+
+	this.Deployment.State = models.DeploymentStateCompleted
+	this.Deployment.CurrentStep = ""
+	this.Persist()
+	return nil
+}
+
+func (this *DeployPipeline) Rollback(Cause error) error {
+	// Undoes Completed Steps in the Reversed Order and Returns the Error, that has Caused the Rollback
+
+	this.Deployment.State = models.DeploymentStateRollingBack
+	this.Deployment.Error = Cause.Error()
+	this.Persist()
+
+	// Rollback Starts from the Step, that has been Started, but not Completed
+	Start := this.Deployment.CompletedSteps - 1
+	if Start+1 < len(this.Steps) && this.Steps[Start+1].Name == this.Deployment.CurrentStep {
+		Start++
+	}
+	if Start >= len(this.Steps) {
+		Start = len(this.Steps) - 1
+	}
+
+	for Index := Start; Index >= 0; Index-- {
+		Step := this.Steps[Index]
+		this.Deployment.CurrentStep = Step.Name
+
+		if Step.Undo != nil {
+			if UndoError := Step.Undo(); UndoError != nil {
+				Logger.Error("Failed to Undo Deploy Step", zap.Int("Deployment ID", this.Deployment.ID),
+					zap.String("Step", Step.Name), zap.Error(UndoError))
+
+				this.Deployment.State = models.DeploymentStateRollbackFailed
+				this.Deployment.Error = fmt.Sprintf("%s, Failed to Undo Step %s: %s", Cause, Step.Name, UndoError)
+				this.Persist()
+				return fmt.Errorf("%s", this.Deployment.Error)
+			}
+		}
+		this.Deployment.CompletedSteps = Index
+		this.Deployment.CurrentStep = ""
+		this.Persist()
+	}
+
+	this.Deployment.State = models.DeploymentStateRolledBack
+	this.Deployment.CurrentStep = ""
+	this.Persist()
+	return Cause
+}
@@ -0,0 +1,164 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Policy of the Outbound Requests to the URLs, the Customers Provide (Webhooks, Alert Channels)
+// Requests to the Loopback, Private and Link-Local Addresses are being Rejected, so the Customer can't Reach
+// the Internal Services of the Infrastructure (e.g the Cloud Metadata Endpoint) through them
+// Hosts are being Checked on Creation of the URL, and every Address is being Checked once again, when it's being Dialed,
+// since the DNS Record of the Host can be Changed after it has been Validated
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	LookupTimeout = 5 * time.Second
+	DialTimeout   = 10 * time.Second
+
+	// Lets the Tests and the Local Development Deliver to the Servers on the Local Machine
+	AllowPrivateAddresses = false
+)
+
+var (
+	ErrForbiddenAddress = errors.New("Loopback, Private and Link-Local Addresses are not Allowed")
+	ErrUnresolvableHost = errors.New("Host can't be Resolved")
+)
+
+// Ranges, that are not Covered by the `net.IP` Checks
+var ReservedNetworks = []*net.IPNet{
+	MustParseCIDR("0.0.0.0/8"),     // "This" Network
+	MustParseCIDR("100.64.0.0/10"), // Carrier-Grade NAT
+	MustParseCIDR("192.0.0.0/24"),  // IETF Protocol Assignments
+	MustParseCIDR("198.18.0.0/15"), // Benchmarking
+	MustParseCIDR("240.0.0.0/4"),   // Reserved, including the Broadcast
+}
+
+type Resolver interface {
+	LookupIPAddr(Context context.Context, Host string) ([]net.IPAddr, error)
+}
+
+var (
+	DefaultResolver Resolver = net.DefaultResolver
+)
+
+// Resolves the Hosts to the Fixed Addresses, used by the Tests and the Local Development
+type StaticResolver map[string][]string
+
+func (this StaticResolver) LookupIPAddr(Context context.Context, Host string) ([]net.IPAddr, error) {
+	Addresses := []net.IPAddr{}
+	for _, Address := range this[Host] {
+		Addresses = append(Addresses, net.IPAddr{IP: net.ParseIP(Address)})
+	}
+	if len(Addresses) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: Host, IsNotFound: true}
+	}
+	return Addresses, nil
+}
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("EgressLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+func MustParseCIDR(Value string) *net.IPNet {
+	_, Network, ParseError := net.ParseCIDR(Value)
+	if ParseError != nil {
+		panic(ParseError)
+	}
+	return Network
+}
+
+func IsForbiddenIP(IP net.IP) bool {
+	// Checks, that the Address Belongs to the Internal Network, rather than to the Public Internet
+	if IP.IsLoopback() || IP.IsPrivate() || IP.IsUnspecified() || IP.IsLinkLocalUnicast() ||
+		IP.IsLinkLocalMulticast() || IP.IsInterfaceLocalMulticast() || IP.IsMulticast() {
+		return true
+	}
+	for _, Network := range ReservedNetworks {
+		if Network.Contains(IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateHost(Host string) error {
+	// Checks, that every Address of the Host is Allowed to be Requested
+	if AllowPrivateAddresses {
+		return nil
+	}
+	if IP := net.ParseIP(Host); IP != nil {
+		if IsForbiddenIP(IP) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+
+	Context, CancelFunc := context.WithTimeout(context.Background(), LookupTimeout)
+	defer CancelFunc()
+	Addresses, LookupError := DefaultResolver.LookupIPAddr(Context, Host)
+	if LookupError != nil || len(Addresses) == 0 {
+		Logger.Debug("Failed to Resolve Host", zap.String("Host", Host), zap.Error(LookupError))
+		return fmt.Errorf("%w: %s", ErrUnresolvableHost, Host)
+	}
+	for _, Address := range Addresses {
+		if IsForbiddenIP(Address.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+func Control(Network string, Address string, Connection syscall.RawConn) error {
+	// Checks the Address, the Connection is being Established with, after the Host has been Resolved by the Dialer
+	if AllowPrivateAddresses {
+		return nil
+	}
+	Host, _, SplitError := net.SplitHostPort(Address)
+	if SplitError != nil {
+		return SplitError
+	}
+	if IP := net.ParseIP(Host); IP == nil || IsForbiddenIP(IP) {
+		Logger.Warn("Connection to the Forbidden Address has been Rejected", zap.String("Address", Address))
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+func NewTransport() *http.Transport {
+	// Returns Transport, that only Connects to the Allowed Addresses
+	// Proxy is not being Used, since the Address of the Proxy would be Checked instead of the Target one
+	Dialer := &net.Dialer{Timeout: DialTimeout, KeepAlive: 30 * time.Second, Control: Control}
+	return &http.Transport{
+		DialContext:           Dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
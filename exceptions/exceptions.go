@@ -25,6 +25,14 @@ func VMDeployFailure() error {
 	return errors.New("Failed to Deploy Virtual Machine")
 }
 
+func VMCloneFailure() error {
+	return errors.New("Failed to Clone Virtual Machine")
+}
+
+func VMResizeFailure() error {
+	return errors.New("Failed to Resize Virtual Machine")
+}
+
 func VMShutdownFailure() error {
 	return errors.New("Failed to Shutdown Virtual Machine")
 }
@@ -48,3 +56,19 @@ func DestroyFailure() error {
 func ComponentDoesNotExist(ComponentName string) error {
 	return errors.New(fmt.Sprintf("Resource: %s does not Exist", ComponentName))
 }
+
+func JobQueueOverflow() error {
+	return errors.New("Job Queue is Full, Try a bit Later")
+}
+
+func JobPoolStopped() error {
+	return errors.New("Server is Shutting Down, Try a bit Later")
+}
+
+func JobHandlerDoesNotExist(JobType string) error {
+	return errors.New(fmt.Sprintf("No Handler has been Registered for the Job Type: %s", JobType))
+}
+
+func SnapshotFailure() error {
+	return errors.New("Failed to Perform Snapshot Operation")
+}
@@ -1,11 +1,61 @@
 package healthcheck
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/metrics"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
+
+	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Package consists of API, that provides info about the Health of the Virtual Machine Server
+// The Metrics Collector Exports the Health of every Managed Virtual Machine to the Prometheus in the Background
+// and Stores it's History, that is being Rolled up into the 5 Minutes Averages and Kept for the Limited Time
+
+var (
+	Logger *zap.Logger
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("HealthCheckLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func Configure(Config config.MetricsConfig) {
+	// Sets the Retention of the Health History
+	RawRetention = Config.RawRetention
+	RollupRetention = Config.RollupRetention
+}
+
+func init() {
+	InitializeProductionLogger()
+	for _, Gauge := range VirtualMachineGauges {
+		metrics.Register(Gauge.Gauge)
+	}
+	metrics.Register(CollectErrors, LastCollected)
+}
 
 type CPUInfo struct {
 	// Reprensents Current CPU Info of the Virtual Machine Server
@@ -18,9 +68,11 @@ type CPUInfo struct {
 
 func NewCPUInfo(OverallCpuUsage int32, OverallCpuReadness int32, MaxCpuUsage int32, CpuNums int32, CpuReservation int32) *CPUInfo {
 	return &CPUInfo{
-		CpuNums:        CpuNums,
-		MaxCpuUsage:    MaxCpuUsage,
-		CpuReservation: CpuReservation,
+		OverallCpuUsage:    OverallCpuUsage,
+		OverallCpuReadness: OverallCpuReadness,
+		CpuNums:            CpuNums,
+		MaxCpuUsage:        MaxCpuUsage,
+		CpuReservation:     CpuReservation,
 	}
 }
 
@@ -114,8 +166,12 @@ func (this *VirtualMachineHealthCheckManager) GetAliveMetrics() AliveInfo {
 	OverallStatus := this.VirtualMachine.Summary.OverallStatus
 	ConnectionState := this.VirtualMachine.Summary.Runtime.ConnectionState
 	PowerState := this.VirtualMachine.Summary.Runtime.PowerState
-	BootTime := this.VirtualMachine.Summary.Runtime.BootTime
-	NewAliveMetric := NewAliveInfo(string(OverallStatus), string(ConnectionState), string(PowerState), *BootTime)
+	// Boot Time is not being Reported for the Powered off Virtual Machines
+	var BootTime time.Time
+	if this.VirtualMachine.Summary.Runtime.BootTime != nil {
+		BootTime = *this.VirtualMachine.Summary.Runtime.BootTime
+	}
+	NewAliveMetric := NewAliveInfo(string(OverallStatus), string(ConnectionState), string(PowerState), BootTime)
 	return *NewAliveMetric
 }
 
@@ -133,10 +189,12 @@ func (this *VirtualMachineHealthCheckManager) GetMemoryUsageMetrics() MemoryUsag
 }
 
 func (this *VirtualMachineHealthCheckManager) GetStorageUsageMetrics() StorageInfo {
-	StorageShared := this.VirtualMachine.Summary.Storage.Unshared
-	StorageCommitted := this.VirtualMachine.Summary.Storage.Committed
-	StorageUnCommitted := this.VirtualMachine.Summary.Storage.Uncommitted
-	NewStorageMetric := NewStorageInfo(StorageShared, StorageCommitted, StorageUnCommitted)
+	// Storage Summary is not being Reported, until the vSphere Calculates it
+	Storage := this.VirtualMachine.Summary.Storage
+	if Storage == nil {
+		return StorageInfo{}
+	}
+	NewStorageMetric := NewStorageInfo(Storage.Unshared, Storage.Committed, Storage.Uncommitted)
 	return *NewStorageMetric
 }
 
@@ -144,7 +202,388 @@ func (this *VirtualMachineHealthCheckManager) GetHostSystemHealthMetrics() HostS
 
 	GuestHeartbeatStatus := this.VirtualMachine.Summary.QuickStats.GuestHeartbeatStatus
 	GuestOsMemoryUsage := this.VirtualMachine.Summary.QuickStats.GuestMemoryUsage
-	GuestOsFullName := this.VirtualMachine.Summary.Guest.GuestFullName
+	var GuestOsFullName string
+	if this.VirtualMachine.Summary.Guest != nil {
+		GuestOsFullName = this.VirtualMachine.Summary.Guest.GuestFullName
+	}
 	HostSystemMetric := NewHostSystemInfo(string(GuestHeartbeatStatus), GuestOsFullName, GuestOsMemoryUsage)
 	return *HostSystemMetric
 }
+
+// METRICS COLLECTOR
+
+var (
+	// Series of the Virtual Machine Gauges are being Labelled by the ID of the Record, the Owner and the Datacenter
+	VirtualMachineLabels = []string{"vm_id", "owner", "datacenter"}
+)
+
+type VirtualMachineGauge struct {
+	Gauge    *metrics.GaugeVec
+	GetValue func(Manager *VirtualMachineHealthCheckManager) float64
+}
+
+func NewVirtualMachineGauge(Name string, Help string, GetValue func(Manager *VirtualMachineHealthCheckManager) float64) VirtualMachineGauge {
+	return VirtualMachineGauge{
+		Gauge:    metrics.NewGaugeVec(Name, Help, VirtualMachineLabels...),
+		GetValue: GetValue,
+	}
+}
+
+var VirtualMachineGauges = []VirtualMachineGauge{
+	NewVirtualMachineGauge("virtual_machine_cpu_usage_mhz", "CPU Usage of the Virtual Machine",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return float64(Manager.GetCpuMetrics().OverallCpuUsage)
+		}),
+	NewVirtualMachineGauge("virtual_machine_cpu_max_usage_mhz", "CPU Usage Limit of the Virtual Machine",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return float64(Manager.GetCpuMetrics().MaxCpuUsage)
+		}),
+	NewVirtualMachineGauge("virtual_machine_cpu_count", "Number of the Virtual CPUs of the Virtual Machine",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return float64(Manager.GetCpuMetrics().CpuNums)
+		}),
+	NewVirtualMachineGauge("virtual_machine_memory_active_megabytes", "Memory, Actively Used by the Virtual Machine",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return float64(Manager.GetMemoryUsageMetrics().Active)
+		}),
+	NewVirtualMachineGauge("virtual_machine_memory_granted_megabytes", "Memory, Granted to the Virtual Machine",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return float64(Manager.GetMemoryUsageMetrics().Granted)
+		}),
+	NewVirtualMachineGauge("virtual_machine_memory_max_usage_megabytes", "Memory Usage Limit of the Virtual Machine",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return float64(Manager.GetMemoryUsageMetrics().MaxMemoryUsage)
+		}),
+	NewVirtualMachineGauge("virtual_machine_guest_memory_usage_megabytes", "Memory Usage, Reported by the Guest Operational System",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return float64(Manager.GetHostSystemHealthMetrics().GuestOsMemoryUsage)
+		}),
+	NewVirtualMachineGauge("virtual_machine_storage_committed_bytes", "Storage, Committed by the Virtual Machine",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return float64(Manager.GetStorageUsageMetrics().Committed)
+		}),
+	NewVirtualMachineGauge("virtual_machine_storage_uncommitted_bytes", "Storage, that can be Committed by the Virtual Machine",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return float64(Manager.GetStorageUsageMetrics().Uncommitted)
+		}),
+	NewVirtualMachineGauge("virtual_machine_storage_unshared_bytes", "Storage, that is not Shared with the other Virtual Machines",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return float64(Manager.GetStorageUsageMetrics().UnShared)
+		}),
+	NewVirtualMachineGauge("virtual_machine_powered_on", "Whether the Virtual Machine is Powered on (1) or not (0)",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return GetBoolValue(Manager.GetAliveMetrics().PowerState == string(types.VirtualMachinePowerStatePoweredOn))
+		}),
+	NewVirtualMachineGauge("virtual_machine_guest_heartbeat_healthy", "Whether the Guest Heartbeat of the Virtual Machine is Green (1) or not (0)",
+		func(Manager *VirtualMachineHealthCheckManager) float64 {
+			return GetBoolValue(Manager.GetHostSystemHealthMetrics().GuestOsHeartbeat == string(types.ManagedEntityStatusGreen))
+		}),
+}
+
+var (
+	CollectErrors = metrics.NewCounterVec("virtual_machine_metrics_collect_errors_total",
+		"Number of the Failed Collections of the Virtual Machine Metrics", "region")
+	LastCollected = metrics.NewGaugeVec("virtual_machine_metrics_last_collected_timestamp_seconds",
+		"Time of the Last Collection of the Virtual Machine Metrics")
+)
+
+func GetBoolValue(Value bool) float64 {
+	if Value {
+		return 1
+	}
+	return 0
+}
+
+func GetDatacenter(ItemPath string) string {
+	// Returns Name of the Datacenter, the Item Path of the Virtual Machine Starts with, e.g `/Datacenter/vm/Server`
+	return strings.Split(strings.TrimPrefix(ItemPath, "/"), "/")[0]
+}
+
+func GetSamples(Records []models.VirtualMachine, VirtualMachines map[string]mo.VirtualMachine) [][]metrics.GaugeValue {
+	// Returns Values of every Virtual Machine Gauge (in the Order of the `VirtualMachineGauges`) for the Records,
+	// that have been Found in the vSphere Inventory by the Managed Object ID
+	Samples := make([][]metrics.GaugeValue, len(VirtualMachineGauges))
+	for _, Record := range Records {
+		VirtualMachine, Exists := VirtualMachines[Record.ManagedObjectId]
+		if !Exists {
+			continue
+		}
+		Manager := NewVirtualMachineHealthCheckManager(&VirtualMachine)
+		Labels := []string{strconv.Itoa(Record.ID), strconv.Itoa(Record.OwnerId), GetDatacenter(Record.ItemPath)}
+		for Index, Gauge := range VirtualMachineGauges {
+			Samples[Index] = append(Samples[Index], metrics.GaugeValue{LabelValues: Labels, Value: Gauge.GetValue(Manager)})
+		}
+	}
+	return Samples
+}
+
+// Receives the Records and the Summaries of every Region after the Collection, such as the Alert Evaluator
+type Observer func(Records []models.VirtualMachine, VirtualMachines map[string]mo.VirtualMachine, Timestamp time.Time)
+
+type MetricsCollector struct {
+	// Background Loop, that Refreshes the Virtual Machine Gauges with the Quick Stats of the vSphere
+	// Every Replica Collects the Metrics on it's own, so the ones of any Replica can be Scraped
+
+	Registry  *vsphere.EndpointRegistry
+	Interval  time.Duration
+	Observers []Observer
+	Stopped   chan struct{}
+	Group     sync.WaitGroup
+}
+
+func NewMetricsCollector(Registry *vsphere.EndpointRegistry, Interval time.Duration) *MetricsCollector {
+	return &MetricsCollector{
+		Registry: Registry,
+		Interval: Interval,
+		Stopped:  make(chan struct{}),
+	}
+}
+
+func (this *MetricsCollector) Subscribe(Observer Observer) {
+	// Adds the Observer, should be Called before the Collector is Started
+	this.Observers = append(this.Observers, Observer)
+}
+
+func (this *MetricsCollector) Start() {
+	// Starts Collection Loop in the Background
+	this.Group.Add(1)
+	go func() {
+		defer this.Group.Done()
+		Ticker := time.NewTicker(this.Interval)
+		defer Ticker.Stop()
+
+		for {
+			if CollectError := this.Collect(); CollectError != nil {
+				Logger.Error("Failed to Collect Virtual Machine Metrics", zap.Error(CollectError))
+			}
+			select {
+			case <-this.Stopped:
+				return
+			case <-Ticker.C:
+			}
+		}
+	}()
+	Logger.Info("Metrics Collector has been Started", zap.Duration("Interval", this.Interval))
+}
+
+func (this *MetricsCollector) Stop() {
+	close(this.Stopped)
+	this.Group.Wait()
+	Logger.Info("Metrics Collector has been Stopped")
+}
+
+func (this *MetricsCollector) Collect() error {
+	// Collects Metrics of every Region and Replaces the Gauges at once, so the Virtual Machines, that have been Deleted
+	// (or Belong to the Unavailable Region) Disappear from the Output instead of Reporting Stale Values
+
+	// Replicas Store the Samples of the same Interval under the same Timestamp, so only one of them is being Kept
+	Timestamp := time.Now().Truncate(this.Interval)
+
+	Samples := make([][]metrics.GaugeValue, len(VirtualMachineGauges))
+	History := []models.VirtualMachineMetric{}
+	var Failed int
+	for _, Pool := range this.Registry.GetPools() {
+		Records, VirtualMachines, CollectError := this.CollectRegion(Pool)
+		if CollectError != nil {
+			Logger.Error("Failed to Collect Virtual Machine Metrics of the Region",
+				zap.String("Region", Pool.Endpoint.Region), zap.Error(CollectError))
+			CollectErrors.Inc(Pool.Endpoint.Region)
+			Failed++
+			continue
+		}
+		RegionSamples := GetSamples(Records, VirtualMachines)
+		for Index := range Samples {
+			Samples[Index] = append(Samples[Index], RegionSamples[Index]...)
+		}
+		History = append(History, GetHistorySamples(Records, VirtualMachines, Timestamp)...)
+
+		for _, Observer := range this.Observers {
+			Observer(Records, VirtualMachines, Timestamp)
+		}
+	}
+	for Index, Gauge := range VirtualMachineGauges {
+		Gauge.Gauge.Replace(Samples[Index])
+	}
+	LastCollected.Set(float64(time.Now().Unix()))
+
+	if HistoryError := this.StoreHistory(History, Timestamp); HistoryError != nil {
+		Logger.Error("Failed to Store Health History of the Virtual Machines", zap.Error(HistoryError))
+	}
+
+	if Failed != 0 {
+		return fmt.Errorf("Metrics of %v of %v Regions have not been Collected", Failed, len(this.Registry.Regions))
+	}
+	return nil
+}
+
+func (this *MetricsCollector) CollectRegion(Pool *vsphere.SessionPool) ([]models.VirtualMachine, map[string]mo.VirtualMachine, error) {
+	// Returns Records of the Managed Virtual Machines of the Region and the Summaries of the vSphere Inventory,
+	// Orphaned Virtual Machines are being Skipped
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), this.Interval)
+	defer CancelFunc()
+
+	VirtualMachines, RetrieveError := this.GetSummaries(TimeoutContext, Pool)
+	if RetrieveError != nil {
+		return nil, nil, RetrieveError
+	}
+
+	var Records []models.VirtualMachine
+	if Gorm := models.Database.Model(&models.VirtualMachine{}).Select(
+		"id", "owner_id", "project_id", "virtual_machine_name", "item_path", "managed_object_id").Scopes(
+		models.InRegions(this.Registry.GetRecordRegions(Pool.Endpoint.Region)...)).Where(
+		"orphaned = ? AND managed_object_id IS NOT NULL", false).Find(&Records); Gorm.Error != nil {
+		return nil, nil, Gorm.Error
+	}
+	return Records, VirtualMachines, nil
+}
+
+func (this *MetricsCollector) StoreHistory(History []models.VirtualMachineMetric, Timestamp time.Time) error {
+	// Stores the Raw Samples, Recomputes the Recent Rollups and Deletes the Samples, that are Older than the Retention
+	if StoreError := models.StoreVirtualMachineMetrics(History); StoreError != nil {
+		return StoreError
+	}
+	From := Timestamp.Add(-RollupWindow).Truncate(RollupResolution)
+	if RollupError := models.RollupVirtualMachineMetrics(RollupResolution, From, Timestamp.Add(this.Interval)); RollupError != nil {
+		return RollupError
+	}
+	if _, DeleteError := models.DeleteVirtualMachineMetrics(0, Timestamp.Add(-RawRetention)); DeleteError != nil {
+		return DeleteError
+	}
+	_, DeleteError := models.DeleteVirtualMachineMetrics(RollupResolution, Timestamp.Add(-RollupRetention))
+	return DeleteError
+}
+
+func (this *MetricsCollector) GetSummaries(Context context.Context, Pool *vsphere.SessionPool) (map[string]mo.VirtualMachine, error) {
+	// Returns Summaries of all the Virtual Machines of the vSphere Endpoint by their Managed Object ID, using a single Request
+
+	Client, ClientError := Pool.GetClient()
+	if ClientError != nil {
+		return nil, ClientError
+	}
+	Manager := view.NewManager(Client.Client)
+	ContainerView, ViewError := Manager.CreateContainerView(Context,
+		Client.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
+	if ViewError != nil {
+		return nil, ViewError
+	}
+	defer ContainerView.Destroy(context.Background())
+
+	var VirtualMachines []mo.VirtualMachine
+	if RetrieveError := ContainerView.Retrieve(Context, []string{"VirtualMachine"},
+		[]string{"summary"}, &VirtualMachines); RetrieveError != nil {
+		return nil, RetrieveError
+	}
+
+	Summaries := make(map[string]mo.VirtualMachine, len(VirtualMachines))
+	for _, VirtualMachine := range VirtualMachines {
+		Summaries[VirtualMachine.Reference().Value] = VirtualMachine
+	}
+	return Summaries, nil
+}
+
+// HEALTH HISTORY
+
+var (
+	RollupResolution = 5 * time.Minute
+	RollupWindow     = time.Hour // Rollups of the Last Hour are being Recomputed on every Collection, so the Late Samples are Included
+
+	RawRetention    = 24 * time.Hour
+	RollupRetention = 30 * 24 * time.Hour
+
+	DefaultHistoryRange  = 24 * time.Hour
+	MinHistoryResolution = time.Minute
+	MaxHistoryPoints     = 1000
+)
+
+var (
+	ErrInvalidHistoryTime       = errors.New("Invalid Time, Expected RFC 3339 Format, e.g `2006-01-02T15:04:05Z`")
+	ErrInvalidHistoryRange      = errors.New("Start of the Range should be Earlier than the End")
+	ErrInvalidHistoryResolution = errors.New("Invalid Resolution, Expected Seconds or Duration, e.g `300` or `5m`")
+)
+
+func NewVirtualMachineMetric(VirtualMachineId int, Timestamp time.Time, Manager *VirtualMachineHealthCheckManager) models.VirtualMachineMetric {
+	return models.VirtualMachineMetric{
+		VirtualMachineId: VirtualMachineId,
+		CollectedAt:      Timestamp,
+		CpuUsage:         float64(Manager.GetCpuMetrics().OverallCpuUsage),
+		MemoryActive:     float64(Manager.GetMemoryUsageMetrics().Active),
+		MemoryGranted:    float64(Manager.GetMemoryUsageMetrics().Granted),
+		GuestMemoryUsage: float64(Manager.GetHostSystemHealthMetrics().GuestOsMemoryUsage),
+		StorageCommitted: float64(Manager.GetStorageUsageMetrics().Committed),
+		PoweredOn:        GetBoolValue(Manager.GetAliveMetrics().PowerState == string(types.VirtualMachinePowerStatePoweredOn)),
+		Samples:          1,
+	}
+}
+
+func GetHistorySamples(Records []models.VirtualMachine, VirtualMachines map[string]mo.VirtualMachine, Timestamp time.Time) []models.VirtualMachineMetric {
+	// Returns Raw History Samples of the Records, that have been Found in the vSphere Inventory by the Managed Object ID
+	History := make([]models.VirtualMachineMetric, 0, len(Records))
+	for _, Record := range Records {
+		if VirtualMachine, Exists := VirtualMachines[Record.ManagedObjectId]; Exists {
+			History = append(History, NewVirtualMachineMetric(Record.ID, Timestamp, NewVirtualMachineHealthCheckManager(&VirtualMachine)))
+		}
+	}
+	return History
+}
+
+func RoundUp(Duration time.Duration, Unit time.Duration) time.Duration {
+	// Returns the Number of the Units, that Cover the Duration
+	return (Duration + Unit - 1) / Unit
+}
+
+type HistoryQuery struct {
+	// Time Range of the Health History and the Resolution of the Series
+	From       time.Time
+	To         time.Time
+	Resolution time.Duration
+	Source     time.Duration // Resolution of the Stored Samples, the Series is being Computed from, 0 stands for the Raw ones
+}
+
+func NewHistoryQuery(From string, To string, Resolution string, Now time.Time) (*HistoryQuery, error) {
+	// Parses the Query Parameters, the Last Day is being Returned by Default with the Resolution,
+	// that Keeps the Number of the Points within the `MaxHistoryPoints`
+	Query := &HistoryQuery{To: Now, From: Now.Add(-DefaultHistoryRange)}
+
+	var ParseError error
+	if len(To) != 0 {
+		if Query.To, ParseError = time.Parse(time.RFC3339, To); ParseError != nil {
+			return nil, ErrInvalidHistoryTime
+		}
+		Query.From = Query.To.Add(-DefaultHistoryRange)
+	}
+	if len(From) != 0 {
+		if Query.From, ParseError = time.Parse(time.RFC3339, From); ParseError != nil {
+			return nil, ErrInvalidHistoryTime
+		}
+	}
+	if !Query.From.Before(Query.To) {
+		return nil, ErrInvalidHistoryRange
+	}
+
+	Range := Query.To.Sub(Query.From)
+	switch {
+	case len(Resolution) != 0:
+		if Query.Resolution, ParseError = config.ParseDuration(Resolution); ParseError != nil || Query.Resolution < MinHistoryResolution {
+			return nil, ErrInvalidHistoryResolution
+		}
+	default:
+		Query.Resolution = RoundUp(Range, time.Duration(MaxHistoryPoints))
+		if Query.Resolution < MinHistoryResolution {
+			Query.Resolution = MinHistoryResolution
+		}
+	}
+	Query.Resolution = RoundUp(Query.Resolution, time.Second) * time.Second
+
+	// Raw Samples are being used, while the Resolution is Finer than the Rollups and the Raw Samples of the whole Range are Retained,
+	// otherwise the Resolution is being Rounded up to the Multiple of the Rollup one
+	if Query.Resolution >= RollupResolution || Query.From.Before(Now.Add(-RawRetention)) {
+		Query.Source = RollupResolution
+		Query.Resolution = RoundUp(Query.Resolution, RollupResolution) * RollupResolution
+	}
+
+	if Points := Range / Query.Resolution; Points > time.Duration(MaxHistoryPoints) {
+		return nil, fmt.Errorf("Range has %v Points of the Resolution, while at most %v are Allowed, Increase the Resolution", int64(Points), MaxHistoryPoints)
+	}
+	return Query, nil
+}
@@ -4,42 +4,25 @@ import (
 	"context"
 
 	"net/http"
-	"net/url"
 
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/LovePelmeni/Infrastructure/deploy"
 	"github.com/LovePelmeni/Infrastructure/healthcheck"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
 	
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/gin-gonic/gin"
-	"github.com/vmware/govmomi"
 
 	"github.com/vmware/govmomi/property"
-	"github.com/vmware/govmomi/vapi/rest"
 	"github.com/vmware/govmomi/vim25/mo"
 )
 
-var (
-	APIIp    = os.Getenv("VMWARE_SOURCE_IP")
-	Username = os.Getenv("VMWARE_SOURCE_USERNAME")
-	Password = os.Getenv("VMWARE_SOURCE_PASSWORD")
-
-	APIUrl = &url.URL{
-		Scheme: "https",
-		Path:   "/sdk/",
-		Host:   APIIp,
-		User:   url.UserPassword(Username, Password),
-	}
-)
-
-var (
-	Client *govmomi.Client
-)
-
 var (
 	Logger *zap.Logger
 )
@@ -57,24 +40,7 @@ func InitializeProductionLogger() {
 }
 
 func init() {
-	// Initializing Govmomi Client for the VM Server
-
-	var RestClient *rest.Client
-	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*10)
-	defer CancelFunc()
-
-	APIClient, ConnectionError := govmomi.NewClient(TimeoutContext, APIUrl, false)
-	switch {
-	case ConnectionError != nil:
-		Logger.Error("FAILED TO INITIALIZE CLIENT, DOES THE VMWARE HYPERVISOR ACTUALLY RUNNING?")
-
-	case ConnectionError == nil:
-		RestClient = rest.NewClient(APIClient.Client)
-		if FailedToLogin := RestClient.Login(TimeoutContext, APIUrl.User); FailedToLogin != nil {
-			Logger.Error("FAILED TO LOGIN TO THE VMWARE HYPERVISOR SERVER", zap.Error(FailedToLogin))
-		}
-	}
-	Client = APIClient
+	InitializeProductionLogger()
 }
 
 // package consists of Rest API Controllers, that Provides Info about the Virtual Machine Server Health Metrics
@@ -91,7 +57,13 @@ func GetVirtualMachineHealthMetricRestController(RequestContext *gin.Context) {
 
 	// Receiving Virtual Machine Instance
 	VirtualMachineId := RequestContext.Query("VirtualMachineId")
-	VmOwnerId := RequestContext.Query("CustomerId")
+
+	ParsedVirtualMachineId, _ := strconv.Atoi(VirtualMachineId)
+	Client, ClientError := vsphere.Registry.GetClient(models.GetVirtualMachineRegion(ParsedVirtualMachineId))
+	if ClientError != nil {
+		RequestContext.JSON(http.StatusServiceUnavailable, gin.H{"Error": ClientError.Error()})
+		return
+	}
 
 	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*1)
 	defer CancelFunc()
@@ -101,7 +73,7 @@ func GetVirtualMachineHealthMetricRestController(RequestContext *gin.Context) {
 
 	var VirtualMachine mo.VirtualMachine
 
-	VirtualMachineRef, FindError := VirtualMachineManager.GetVirtualMachine(VirtualMachineId, VmOwnerId)
+	VirtualMachineRef, FindError := VirtualMachineManager.GetVirtualMachine(VirtualMachineId)
 	RetrieveError := Collector.RetrieveOne(TimeoutContext, VirtualMachineRef.Reference(), []string{"*"}, &VirtualMachine)
 
 	if FindError != nil || RetrieveError != nil {
@@ -119,3 +91,34 @@ func GetVirtualMachineHealthMetricRestController(RequestContext *gin.Context) {
 	RequestContext.JSON(http.StatusOK, gin.H{"Metrics": HealthCheckMetrics})
 }
 
+func GetVirtualMachineHealthMetricHistoryRestController(RequestContext *gin.Context) {
+	// Returns the Health History of the Virtual Machine for the Time Range (`From` and `To` in RFC 3339, the Last Day by Default)
+	// and the Resolution (Seconds or Duration, e.g `5m`), Values of every Point are the Averages of the Samples, it Covers
+
+	VirtualMachineId, ParseError := strconv.Atoi(RequestContext.Query("VirtualMachineId"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Virtual Machine ID"})
+		return
+	}
+
+	Query, QueryError := healthcheck.NewHistoryQuery(RequestContext.Query("From"),
+		RequestContext.Query("To"), RequestContext.Query("Resolution"), time.Now())
+	if QueryError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": QueryError.Error()})
+		return
+	}
+
+	Series, SelectError := models.GetVirtualMachineMetrics(VirtualMachineId, Query.Source, Query.Resolution, Query.From, Query.To)
+	if SelectError != nil {
+		Logger.Error("Failed to Select Health History of the Virtual Machine",
+			zap.Int("Virtual Machine ID", VirtualMachineId), zap.Error(SelectError))
+		RequestContext.JSON(http.StatusInternalServerError, gin.H{"Error": "Failed to Get Health History, Try a bit Later"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{
+		"From":       Query.From,
+		"To":         Query.To,
+		"Resolution": int(Query.Resolution.Seconds()),
+		"Series":     Series,
+	})
+}
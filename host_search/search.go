@@ -1,6 +1,9 @@
 package search
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+)
 
 // Package that searches for the appropriate host machine, that is going to have at least over 10 % of the resources
 // so the Customer will be uaranteed, that his Virtual Machine Server, is not gonna be out of resources
@@ -60,10 +63,21 @@ type HostMachineSearcher struct {
 func NewHostMachineSearcher() *HostMachineSearcher {
 	return &HostMachineSearcher{}
 }
+
+var (
+	ErrNoHostMachines = errors.New("No Host Machines are Available for the Search")
+)
+
 func (this *HostMachineSearcher) GetAllHostMachines() ([]HostMachine, error) {
 	// Returns an array of the All Available Host Machines, with their credentials
+	// Inventory of the Host Machines has not been Connected yet, so there are no Machines to Search among
+	return nil, ErrNoHostMachines
 }
 
 func (this *HostMachineSearcher) SearchHostMachine(HostMachines []HostMachine, Requirements HostMachineRequirementsInterface) HostMachine {
 	// Returns an appropriate Host Machine, based on the Resources, that is available within it and Requirements
+	if len(HostMachines) == 0 {
+		return HostMachine{}
+	}
+	return HostMachines[0]
 }
@@ -0,0 +1,234 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Redis Backed Store of the Responses to the Requests with the `X-Idempotency-Key` Header
+// Once the Request has been Processed, the Retries with the same Key receive the Stored Response,
+// instead of Performing the Operation one more time
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	TimeToLive        = 24 * time.Hour  // Time, the Response is being Stored for
+	ProcessingTimeout = 5 * time.Minute // The Key is being Released, if the Replica has Crashed in the middle of the Request
+	MaxKeyLength      = 255
+)
+
+const (
+	HeaderName         = "X-Idempotency-Key"
+	ReplayedHeaderName = "Idempotent-Replayed"
+)
+
+const (
+	StateProcessing = "Processing"
+	StateCompleted  = "Completed"
+)
+
+var (
+	ErrInvalidKey = fmt.Errorf("Idempotency Key should not be longer than %v Characters", MaxKeyLength)
+)
+
+// Headers, that Describe the Current Request, rather than the Response, are not being Replayed
+var SkippedHeaders = map[string]bool{
+	"Date":                  true,
+	"Retry-After":           true,
+	"X-Ratelimit-Limit":     true,
+	"X-Ratelimit-Remaining": true,
+	"X-Ratelimit-Reset":     true,
+}
+
+// Returns the Stored Record, or Stores the Record passed, if there is no one yet
+var BeginScript = `
+local record = redis.call("GET", KEYS[1])
+if record then
+	return record
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return false`
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("IdempotencyLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+func Configure(Config config.IdempotencyConfig) {
+	TimeToLive = Config.KeyTimeToLive
+}
+
+func GetRecordKey(Identity string, Method string, Route string, Key string) string {
+	// Returns Redis Key of the Record, Keys of the different Customers and Routes never Collide
+	Hash := sha256.Sum256([]byte(strings.Join([]string{Identity, Method, Route, Key}, "\n")))
+	return "idempotency:" + hex.EncodeToString(Hash[:])
+}
+
+func GetFingerprint(Query string, Form url.Values, Body []byte) string {
+	// Returns Hash of the Request Content, so the Key, Reused with the other Request can be Detected
+	// Form is being Encoded with the Sorted Keys, so the Order of the Fields does not Change the Fingerprint
+	Hash := sha256.New()
+	Hash.Write([]byte(Query))
+	Hash.Write([]byte{0})
+	Hash.Write([]byte(Form.Encode()))
+	Hash.Write([]byte{0})
+	Hash.Write(Body)
+	return hex.EncodeToString(Hash.Sum(nil))
+}
+
+func IsStorable(StatusCode int) bool {
+	// Conflicts, Rate Limits and Server Errors are Temporary, so the Request can be Retried with the same Key
+	switch {
+	case StatusCode >= http.StatusInternalServerError:
+		return false
+	case StatusCode == http.StatusConflict, StatusCode == http.StatusTooManyRequests:
+		return false
+	default:
+		return true
+	}
+}
+
+type Record struct {
+	// Request, that has been Processed with the Idempotency Key, along with it's Response
+	State       string      `json:"State"`
+	Fingerprint string      `json:"Fingerprint"`
+	StatusCode  int         `json:"StatusCode"`
+	Header      http.Header `json:"Header"`
+	Body        []byte      `json:"Body"`
+	CreatedAt   time.Time   `json:"CreatedAt"`
+}
+
+func NewRecord(Fingerprint string) *Record {
+	// Returns Record of the Request, that is being Processed right now
+	return &Record{
+		State:       StateProcessing,
+		Fingerprint: Fingerprint,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func (this *Record) Complete(StatusCode int, Header http.Header, Body []byte) {
+	// Stores the Response in the Record
+	this.State = StateCompleted
+	this.StatusCode = StatusCode
+	this.Body = Body
+	this.Header = make(http.Header)
+	for Name, Values := range Header {
+		if !SkippedHeaders[http.CanonicalHeaderKey(Name)] {
+			this.Header[Name] = append([]string(nil), Values...)
+		}
+	}
+}
+
+func (this *Record) Replay(RequestContext *gin.Context) {
+	// Responds with the Stored Response and Aborts the Request
+	for Name, Values := range this.Header {
+		for _, Value := range Values {
+			RequestContext.Writer.Header().Add(Name, Value)
+		}
+	}
+	RequestContext.Header(ReplayedHeaderName, "true")
+	RequestContext.Status(this.StatusCode)
+	RequestContext.Writer.Write(this.Body)
+	RequestContext.Abort()
+}
+
+type Store struct {
+	Client     *redis.Client
+	TimeToLive time.Duration
+}
+
+func NewStore(Client *redis.Client, TimeToLive time.Duration) *Store {
+	return &Store{
+		Client:     Client,
+		TimeToLive: TimeToLive,
+	}
+}
+
+func (this *Store) Begin(Key string, Processing *Record) (*Record, error) {
+	// Stores the Record of the Request, that is being Processed, Returns the Existing Record instead,
+	// if the Key has already been Used
+	Serialized, EncodeError := json.Marshal(Processing)
+	if EncodeError != nil {
+		return nil, EncodeError
+	}
+	Existing, BeginError := this.Client.Eval(BeginScript, []string{Key},
+		string(Serialized), ProcessingTimeout.Milliseconds()).String()
+	if errors.Is(BeginError, redis.Nil) {
+		return nil, nil
+	}
+	if BeginError != nil {
+		return nil, BeginError
+	}
+	var ExistingRecord Record
+	if DecodeError := json.Unmarshal([]byte(Existing), &ExistingRecord); DecodeError != nil {
+		return nil, DecodeError
+	}
+	return &ExistingRecord, nil
+}
+
+func (this *Store) Complete(Key string, Completed *Record) error {
+	// Stores the Record with the Response, so it can be Replayed
+	Serialized, EncodeError := json.Marshal(Completed)
+	if EncodeError != nil {
+		return EncodeError
+	}
+	return this.Client.Set(Key, Serialized, this.TimeToLive).Err()
+}
+
+func (this *Store) Release(Key string) error {
+	// Removes the Record, so the Request can be Retried with the same Key
+	return this.Client.Del(Key).Err()
+}
+
+type ResponseRecorder struct {
+	// Response Writer, that Keeps the Copy of the Response Body
+	gin.ResponseWriter
+	Body *bytes.Buffer
+}
+
+func NewResponseRecorder(Writer gin.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{
+		ResponseWriter: Writer,
+		Body:           new(bytes.Buffer),
+	}
+}
+
+func (this *ResponseRecorder) Write(Data []byte) (int, error) {
+	this.Body.Write(Data)
+	return this.ResponseWriter.Write(Data)
+}
+
+func (this *ResponseRecorder) WriteString(Data string) (int, error) {
+	this.Body.WriteString(Data)
+	return this.ResponseWriter.WriteString(Data)
+}
@@ -0,0 +1,338 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"os"
+	"sync"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/exceptions"
+	"github.com/LovePelmeni/Infrastructure/locks"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/go-redis/redis"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Worker Pool, that Executes Long Running Virtual Machine Operations
+// (Deploy, Reconfigure, Power Operations etc...) in the Background, So the HTTP Request does not need to wait for them
+// Jobs of the Existing Virtual Machine hold it's Lock from the Enqueue until the Finish, so only one Operation runs at the time
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	WorkerPool *JobWorkerPool
+)
+
+var (
+	DefaultWorkers   = 5
+	DefaultQueueSize = 1000
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("JobsLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+	WorkerPool = NewJobWorkerPool(DefaultWorkers, DefaultQueueSize)
+}
+
+func Configure(Config config.JobsConfig, Client *redis.Client) {
+	// Applies the Size of the Pool and the Lease Time of the Locks, should be Called before the Pool is Started
+	// Pool is being Configured in place, so the Handlers, Registered by the other Packages, are being Kept
+	WorkerPool.WorkersNumber = Config.Workers
+	WorkerPool.Queue = make(chan int, Config.QueueSize)
+	WorkerPool.Locker = locks.NewLocker(Client, Config.LockTimeToLive)
+}
+
+// JobHandler Executes the Job and Returns the Result, that is going to be Serialized and Stored
+// along with the Job, so the Customer can receive it, once the Job is Finished
+type JobHandler func(Job *models.Job) (interface{}, error)
+
+type JobWorkerPool struct {
+	// Pool of the Workers, that are Picking up Queued Jobs and Executing them
+	// using the Handler, Registered for the Type of the Job
+	WorkersNumber int
+	Handlers      map[string]JobHandler
+	Resumable     map[string]bool // Job Types, that can be Safely Restarted after the Interruption
+	Locker        *locks.Locker   // Locks Virtual Machines for the Time of the Jobs, Disabled if nil
+	Queue         chan int
+	Mutex         sync.RWMutex
+	Group         sync.WaitGroup
+
+	// Queue is being Closed by the Stop, so the Jobs are being Sent only under the Read Lock, while the Pool is not Stopped
+	QueueMutex sync.RWMutex
+	Stopped    bool
+}
+
+func NewJobWorkerPool(WorkersNumber int, QueueSize int) *JobWorkerPool {
+	return &JobWorkerPool{
+		WorkersNumber: WorkersNumber,
+		Handlers:      make(map[string]JobHandler),
+		Resumable:     make(map[string]bool),
+		Queue:         make(chan int, QueueSize),
+	}
+}
+
+func (this *JobWorkerPool) RegisterHandler(JobType string, Handler JobHandler) {
+	// Registers Handler, that is going to Execute Jobs of the Specific Type
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.Handlers[JobType] = Handler
+}
+
+func (this *JobWorkerPool) RegisterResumableHandler(JobType string, Handler JobHandler) {
+	// Registers Handler, that Persists it's own Progress, so the Job, Interrupted by the Server Restart
+	// is being Queued again, instead of being marked as Failed
+	this.RegisterHandler(JobType, Handler)
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.Resumable[JobType] = true
+}
+
+func (this *JobWorkerPool) AcquireLock(Job *models.Job) (*locks.Lease, error) {
+	// Locks the Virtual Machine of the Job on behalf of the Customer, who has Requested it
+	// Returns nil, if the Job does not Operate on the Existing Virtual Machine
+
+	if this.Locker == nil || Job.VirtualMachineId == 0 {
+		return nil, nil
+	}
+	var Holder models.Customer
+	models.Database.Model(&models.Customer{}).Where("id = ?", Job.OwnerId).Find(&Holder)
+
+	Lease, LockError := this.Locker.Acquire(Job.VirtualMachineId, Job.OwnerId, Holder.Username, Job.Type)
+	if LockError != nil {
+		return nil, LockError
+	}
+	Job.LockToken = Lease.Token
+	return Lease, nil
+}
+
+func (this *JobWorkerPool) ResumeLock(Job *models.Job) (*locks.Lease, error) {
+	// Extends the Lock, taken during the Enqueue, the Lock is being Acquired again,
+	// if it has Expired, while the Job has been Waiting in the Queue or the Server has been Restarted
+
+	if this.Locker == nil || Job.LockToken == 0 {
+		return nil, nil
+	}
+	Lease := locks.NewLease(this.Locker, Job.VirtualMachineId, Job.LockToken)
+	if Renewed, RenewError := Lease.Renew(); RenewError != nil || Renewed {
+		return Lease, RenewError
+	}
+
+	Lease, LockError := this.AcquireLock(Job)
+	if LockError != nil {
+		return nil, LockError
+	}
+	if _, SaveError := Job.Save(); SaveError != nil {
+		Lease.Release()
+		return nil, SaveError
+	}
+	Lease.AttachJob(Job.ID)
+	return Lease, nil
+}
+
+func (this *JobWorkerPool) ReleaseLock(Job *models.Job) {
+	// Releases the Lock of the Virtual Machine, if it is still being held by the Job
+	if this.Locker == nil || Job.LockToken == 0 {
+		return
+	}
+	if ReleaseError := locks.NewLease(this.Locker, Job.VirtualMachineId, Job.LockToken).Release(); ReleaseError != nil {
+		Logger.Error("Failed to Release Virtual Machine Lock", zap.Int("Job ID", Job.ID), zap.Error(ReleaseError))
+	}
+}
+
+func (this *JobWorkerPool) GetLock(VirtualMachineId int) (*locks.Lock, error) {
+	// Returns Current Holder of the Virtual Machine Lock, or nil, if the Virtual Machine is not Locked or the Locks are Disabled
+	if this.Locker == nil {
+		return nil, nil
+	}
+	return this.Locker.Get(VirtualMachineId)
+}
+
+func (this *JobWorkerPool) Enqueue(Job *models.Job) error {
+	// Stores the Job in the Database and Puts it into the Queue
+	// Returns `locks.ErrLocked`, if the Virtual Machine is already Performing other Operation
+
+	if this.IsStopped() {
+		return exceptions.JobPoolStopped()
+	}
+
+	Lease, LockError := this.AcquireLock(Job)
+	if LockError != nil {
+		return LockError
+	}
+
+	Job.State = models.JobStateQueued
+	if _, CreationError := Job.Create(); CreationError != nil {
+		Logger.Error("Failed to Create new Job Record", zap.Error(CreationError))
+		if Lease != nil {
+			Lease.Release()
+		}
+		return CreationError
+	}
+	if Lease != nil {
+		Lease.AttachJob(Job.ID)
+	}
+
+	this.QueueMutex.RLock()
+	defer this.QueueMutex.RUnlock()
+
+	// The Pool has been Stopped, while the Job has been Stored, so the Job stays Queued and is being Restored by the next Start
+	if this.Stopped {
+		Logger.Info("Job has been Stored after the Workers have been Stopped, it's going to be Executed after the Restart",
+			zap.Int("Job ID", Job.ID), zap.String("Type", Job.Type))
+		return nil
+	}
+
+	select {
+	case this.Queue <- Job.ID:
+		Logger.Debug("Job has been Queued", zap.Int("Job ID", Job.ID), zap.String("Type", Job.Type))
+		return nil
+	default:
+		this.Finish(Job, nil, exceptions.JobQueueOverflow())
+		return exceptions.JobQueueOverflow()
+	}
+}
+
+func (this *JobWorkerPool) IsStopped() bool {
+	this.QueueMutex.RLock()
+	defer this.QueueMutex.RUnlock()
+	return this.Stopped
+}
+
+func (this *JobWorkerPool) Start() {
+	// Starts Workers and Puts the Jobs, that have not been Finished During Previous Run back to the Queue
+
+	var PendingJobs []models.Job
+	models.Database.Model(&models.Job{}).Where("state IN ?",
+		[]string{models.JobStateQueued, models.JobStateRunning}).Order("id").Find(&PendingJobs)
+
+	for Index := 0; Index < this.WorkersNumber; Index++ {
+		this.Group.Add(1)
+		go this.Work()
+	}
+
+	for _, PendingJob := range PendingJobs {
+		this.Mutex.RLock()
+		Resumable := this.Resumable[PendingJob.Type]
+		this.Mutex.RUnlock()
+
+		if PendingJob.State == models.JobStateRunning && !Resumable {
+			// The Process has been Stopped in the Middle of the Operation, so we can't be sure
+			// in which State the Virtual Machine is right now
+			this.Finish(&PendingJob, nil, fmt.Errorf("Job has been Interrupted by the Server Restart"))
+			continue
+		}
+		select {
+		case this.Queue <- PendingJob.ID:
+		default:
+			this.Finish(&PendingJob, nil, exceptions.JobQueueOverflow())
+		}
+	}
+	Logger.Info("Job Workers has been Started", zap.Int("Workers", this.WorkersNumber),
+		zap.Int("Restored Jobs", len(PendingJobs)))
+}
+
+func (this *JobWorkerPool) Stop() {
+	// Stops Accepting new Jobs and Waits until the Workers finish the Jobs they are currently Executing
+	this.QueueMutex.Lock()
+	if !this.Stopped {
+		this.Stopped = true
+		close(this.Queue)
+	}
+	this.QueueMutex.Unlock()
+	this.Group.Wait()
+	Logger.Info("Job Workers has been Stopped")
+}
+
+func (this *JobWorkerPool) Work() {
+	// Worker Loop, Executes Jobs from the Queue one by one
+	defer this.Group.Done()
+	for JobId := range this.Queue {
+		this.Execute(JobId)
+	}
+}
+
+func (this *JobWorkerPool) Execute(JobId int) {
+	// Executes the Job with the Handler, Registered for its Type
+
+	var Job models.Job
+	if Found := models.Database.Model(&models.Job{}).Where("id = ?", JobId).Find(&Job); Found.Error != nil || Job.ID == 0 {
+		Logger.Error("Failed to Find Queued Job", zap.Int("Job ID", JobId))
+		return
+	}
+
+	this.Mutex.RLock()
+	Handler, Exists := this.Handlers[Job.Type]
+	this.Mutex.RUnlock()
+
+	if !Exists {
+		this.Finish(&Job, nil, exceptions.JobHandlerDoesNotExist(Job.Type))
+		return
+	}
+
+	Lease, LockError := this.ResumeLock(&Job)
+	if LockError != nil {
+		this.Finish(&Job, nil, LockError)
+		return
+	}
+	StopKeepAlive := func() {}
+	if Lease != nil {
+		StopKeepAlive = Lease.KeepAlive()
+	}
+
+	Job.State = models.JobStateRunning
+	if _, SaveError := Job.Save(); SaveError != nil {
+		Logger.Error("Failed to Mark Job as Running", zap.Int("Job ID", Job.ID), zap.Error(SaveError))
+	}
+
+	Result, ExecutionError := func() (Result interface{}, ExecutionError error) {
+		// Protecting the Worker from the Panics inside the Handlers
+		defer func() {
+			if Recovered := recover(); Recovered != nil {
+				ExecutionError = fmt.Errorf("Job Handler Panicked: %v", Recovered)
+			}
+		}()
+		return Handler(&Job)
+	}()
+	StopKeepAlive()
+	this.Finish(&Job, Result, ExecutionError)
+}
+
+func (this *JobWorkerPool) Finish(Job *models.Job, Result interface{}, ExecutionError error) {
+	// Stores the Final State of the Job
+
+	switch ExecutionError {
+	case nil:
+		SerializedResult, _ := json.Marshal(Result)
+		Job.State = models.JobStateSucceeded
+		Job.Progress = 100
+		Job.Result = string(SerializedResult)
+		Logger.Debug("Job has been Finished", zap.Int("Job ID", Job.ID), zap.String("Type", Job.Type))
+	default:
+		Job.State = models.JobStateFailed
+		Job.Error = ExecutionError.Error()
+		Logger.Error("Job has been Failed", zap.Int("Job ID", Job.ID),
+			zap.String("Type", Job.Type), zap.Error(ExecutionError))
+	}
+
+	if _, SaveError := Job.Save(); SaveError != nil {
+		Logger.Error("Failed to Save Job State", zap.Int("Job ID", Job.ID), zap.Error(SaveError))
+	}
+	this.ReleaseLock(Job)
+}
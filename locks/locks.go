@@ -0,0 +1,228 @@
+package locks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Redis Backed Lease Lock of the Virtual Machine, that is being held for the whole Lifetime
+// of the Operation, which Changes it (Deploy, Resize, Power Operations, Snapshots etc...)
+// The Lease Expires on it's own, if the Replica, which holds it, Crashes, every Acquisition receives
+// new Fencing Token, so the Stale Holder can't Extend or Release the Lock of the next one
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	ErrLocked   = errors.New("Virtual Machine is already Performing other Operation, please Wait")
+	ErrLockLost = errors.New("Lock of the Virtual Machine has Expired before the Operation has been Finished")
+)
+
+// Takes the Lock, only if it is not being held by anyone, Returns new Fencing Token or 0, if the Lock is Busy
+var AcquireScript = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+local token = redis.call("INCR", KEYS[2])
+redis.call("HMSET", KEYS[1], "Token", token, "HolderId", ARGV[1], "Holder", ARGV[2], "Operation", ARGV[3], "AcquiredAt", ARGV[4])
+redis.call("PEXPIRE", KEYS[1], ARGV[5])
+return token`
+
+// Extends the Lease only if it is still being held with the Fencing Token passed
+var RenewScript = `
+if redis.call("HGET", KEYS[1], "Token") == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+// Releases the Lock only if it is still being held with the Fencing Token passed
+var ReleaseScript = `
+if redis.call("HGET", KEYS[1], "Token") == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+// Attaches the Job, that Performs the Operation, to the Lock held with the Fencing Token passed
+var AttachJobScript = `
+if redis.call("HGET", KEYS[1], "Token") == ARGV[1] then
+	return redis.call("HSET", KEYS[1], "JobId", ARGV[2])
+end
+return -1`
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("LocksLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+func GetLockKey(VirtualMachineId int) string {
+	return fmt.Sprintf("vm:lock:%d", VirtualMachineId)
+}
+
+func GetFenceKey(VirtualMachineId int) string {
+	// Counter of the Fencing Tokens, it never Expires, so the Tokens keep Increasing after the Lock has been Released
+	return fmt.Sprintf("vm:lock:fence:%d", VirtualMachineId)
+}
+
+type Lock struct {
+	// Current Holder of the Virtual Machine Lock, Exposed in the Virtual Machine Info
+	Token      int64     `json:"Token" xml:"Token"`
+	HolderId   int       `json:"HolderId" xml:"HolderId"` // Customer, who has Requested the Operation
+	Holder     string    `json:"Holder" xml:"Holder"`
+	Operation  string    `json:"Operation" xml:"Operation"`
+	JobId      int       `json:"JobId" xml:"JobId"`
+	AcquiredAt time.Time `json:"AcquiredAt" xml:"AcquiredAt"`
+	ExpiresAt  time.Time `json:"ExpiresAt" xml:"ExpiresAt"`
+}
+
+func NewLock(Values map[string]string, TimeToLive time.Duration) *Lock {
+	// Returns the Lock, Decoded from the Fields of the Redis Hash
+	Token, _ := strconv.ParseInt(Values["Token"], 10, 64)
+	HolderId, _ := strconv.Atoi(Values["HolderId"])
+	JobId, _ := strconv.Atoi(Values["JobId"])
+	AcquiredAt, _ := strconv.ParseInt(Values["AcquiredAt"], 10, 64)
+	return &Lock{
+		Token:      Token,
+		HolderId:   HolderId,
+		Holder:     Values["Holder"],
+		Operation:  Values["Operation"],
+		JobId:      JobId,
+		AcquiredAt: time.Unix(0, AcquiredAt*int64(time.Millisecond)),
+		ExpiresAt:  time.Now().Add(TimeToLive),
+	}
+}
+
+type Locker struct {
+	Client     *redis.Client
+	TimeToLive time.Duration
+}
+
+func NewLocker(Client *redis.Client, TimeToLive time.Duration) *Locker {
+	return &Locker{
+		Client:     Client,
+		TimeToLive: TimeToLive,
+	}
+}
+
+func (this *Locker) Acquire(VirtualMachineId int, HolderId int, Holder string, Operation string) (*Lease, error) {
+	// Takes the Lock of the Virtual Machine, Returns `ErrLocked`, if it is being held by other Operation
+	Token, AcquireError := this.Client.Eval(AcquireScript,
+		[]string{GetLockKey(VirtualMachineId), GetFenceKey(VirtualMachineId)},
+		HolderId, Holder, Operation, time.Now().UnixNano()/int64(time.Millisecond), this.TimeToLive.Milliseconds()).Int64()
+	if AcquireError != nil {
+		return nil, AcquireError
+	}
+	if Token == 0 {
+		return nil, ErrLocked
+	}
+	Logger.Debug("Virtual Machine Lock has been Acquired", zap.Int("Virtual Machine ID", VirtualMachineId),
+		zap.Int64("Token", Token), zap.String("Operation", Operation))
+	return NewLease(this, VirtualMachineId, Token), nil
+}
+
+func (this *Locker) Get(VirtualMachineId int) (*Lock, error) {
+	// Returns Current Holder of the Virtual Machine Lock, or nil, if the Virtual Machine is not Locked
+	Pipeline := this.Client.TxPipeline()
+	Values := Pipeline.HGetAll(GetLockKey(VirtualMachineId))
+	TimeToLive := Pipeline.PTTL(GetLockKey(VirtualMachineId))
+	if _, ExecError := Pipeline.Exec(); ExecError != nil {
+		return nil, ExecError
+	}
+	if len(Values.Val()) == 0 || TimeToLive.Val() < 0 {
+		return nil, nil
+	}
+	return NewLock(Values.Val(), TimeToLive.Val()), nil
+}
+
+type Lease struct {
+	// Lock of the Virtual Machine, held with the Specific Fencing Token
+	Locker           *Locker
+	VirtualMachineId int
+	Token            int64
+}
+
+func NewLease(Locker *Locker, VirtualMachineId int, Token int64) *Lease {
+	return &Lease{
+		Locker:           Locker,
+		VirtualMachineId: VirtualMachineId,
+		Token:            Token,
+	}
+}
+
+func (this *Lease) Renew() (bool, error) {
+	// Extends the Lease, Returns false, if it has Expired and the Lock does not belong to the Holder anymore
+	Renewed, RenewError := this.Locker.Client.Eval(RenewScript, []string{GetLockKey(this.VirtualMachineId)},
+		this.Token, this.Locker.TimeToLive.Milliseconds()).Int()
+	return Renewed == 1, RenewError
+}
+
+func (this *Lease) Release() error {
+	// Releases the Lock, if it is still being held by the Lease
+	return this.Locker.Client.Eval(ReleaseScript,
+		[]string{GetLockKey(this.VirtualMachineId)}, this.Token).Err()
+}
+
+func (this *Lease) AttachJob(JobId int) error {
+	// Records the Job, that Performs the Operation, along with the Lock
+	Attached, AttachError := this.Locker.Client.Eval(AttachJobScript,
+		[]string{GetLockKey(this.VirtualMachineId)}, this.Token, JobId).Int()
+	if AttachError != nil {
+		return AttachError
+	}
+	if Attached < 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (this *Lease) KeepAlive() (Stop func()) {
+	// Renews the Lease in the Background, until the Returned Function is being Called
+	// Renewals happen three times per Lease Time, so a single Failed one does not Expire the Lock
+
+	Stopped := make(chan struct{})
+	var Once sync.Once
+
+	go func() {
+		Ticker := time.NewTicker(this.Locker.TimeToLive / 3)
+		defer Ticker.Stop()
+		for {
+			select {
+			case <-Stopped:
+				return
+			case <-Ticker.C:
+				Renewed, RenewError := this.Renew()
+				if RenewError != nil {
+					Logger.Error("Failed to Renew Virtual Machine Lock", zap.Int("Virtual Machine ID",
+						this.VirtualMachineId), zap.Error(RenewError))
+					continue
+				}
+				if !Renewed {
+					Logger.Error("Virtual Machine Lock has been Lost", zap.Int("Virtual Machine ID",
+						this.VirtualMachineId), zap.Int64("Token", this.Token))
+					return
+				}
+			}
+		}
+	}()
+	return func() { Once.Do(func() { close(Stopped) }) }
+}
@@ -0,0 +1,211 @@
+package mailer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Mailers, that Deliver Emails to the Customers
+// SMTP Mailer is being Used in Production, File and In-Memory Mailers are being Used for the Development and Tests
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	DefaultSmtpPort = "587"
+)
+
+var (
+	ErrMailerNotConfigured = errors.New("SMTP Mailer is not Configured")
+	ErrInvalidHeader       = errors.New("Email Headers should not contain Line Breaks")
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("MailerLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+type Message struct {
+	// Plain Text Email Message
+	To      string    `json:"To"`
+	Subject string    `json:"Subject"`
+	Body    string    `json:"Body"`
+	SentAt  time.Time `json:"SentAt"`
+}
+
+func NewMessage(To string, Subject string, Body string) *Message {
+	return &Message{
+		To:      To,
+		Subject: Subject,
+		Body:    Body,
+	}
+}
+
+type Mailer interface {
+	// Interface, that Delivers Email Messages
+	Send(Message *Message) error
+}
+
+// SMTP Mailer
+
+type SmtpMailer struct {
+	// Mailer, that Sends Messages through the SMTP Server on behalf of the Support Email Address
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func NewSmtpMailer(Host string, Port string, Username string, Password string) *SmtpMailer {
+	return &SmtpMailer{
+		Host:     Host,
+		Port:     Port,
+		Username: Username,
+		Password: Password,
+		From:     Username,
+	}
+}
+
+func (this *SmtpMailer) Send(Message *Message) error {
+	if len(this.Host) == 0 || len(this.From) == 0 {
+		return ErrMailerNotConfigured
+	}
+	if strings.ContainsAny(Message.To+Message.Subject, "\r\n") {
+		return ErrInvalidHeader
+	}
+	Content := strings.Join([]string{
+		fmt.Sprintf("From: %s", this.From),
+		fmt.Sprintf("To: %s", Message.To),
+		fmt.Sprintf("Subject: %s", Message.Subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"utf-8\"",
+		"",
+		Message.Body,
+	}, "\r\n")
+
+	Auth := smtp.PlainAuth("", this.Username, this.Password, this.Host)
+	if SendError := smtp.SendMail(net.JoinHostPort(this.Host, this.Port),
+		Auth, this.From, []string{Message.To}, []byte(Content)); SendError != nil {
+		Logger.Error("Failed to Send Email", zap.String("Subject", Message.Subject), zap.Error(SendError))
+		return SendError
+	}
+	return nil
+}
+
+// File Mailer
+
+type FileMailer struct {
+	// Mailer, that Appends Messages to the JSON Lines File, instead of Sending them
+	Path  string
+	Mutex sync.Mutex
+}
+
+func NewFileMailer(Path string) *FileMailer {
+	return &FileMailer{Path: Path}
+}
+
+func (this *FileMailer) Send(Message *Message) error {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+
+	File, OpenError := os.OpenFile(this.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if OpenError != nil {
+		return OpenError
+	}
+	defer File.Close()
+
+	Message.SentAt = time.Now()
+	return json.NewEncoder(File).Encode(Message)
+}
+
+// In-Memory Mailer
+
+type MemoryMailer struct {
+	// Mailer, that Keeps Messages in Memory, so the Tests can Inspect them
+	Messages []Message
+	Mutex    sync.Mutex
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{Messages: []Message{}}
+}
+
+func (this *MemoryMailer) Send(Message *Message) error {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	Message.SentAt = time.Now()
+	this.Messages = append(this.Messages, *Message)
+	return nil
+}
+
+func (this *MemoryMailer) GetMessages(To string) []Message {
+	// Returns Messages, that have been Sent to the Address
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	Messages := []Message{}
+	for _, Message := range this.Messages {
+		if Message.To == To {
+			Messages = append(Messages, Message)
+		}
+	}
+	return Messages
+}
+
+// Mailer of the Application
+
+var (
+	// Messages are not being Sent, until the Mailer is Configured
+	DefaultMailer Mailer = NewSmtpMailer("", DefaultSmtpPort, "", "")
+)
+
+func NewMailerFromConfig(Config config.MailerConfig) Mailer {
+	// Returns Mailer of the `MAILER_BACKEND`, SMTP Mailer is being Configured with the `SUPPORT_EMAIL_*` Keys
+	switch Config.Backend {
+	case "file":
+		return NewFileMailer(Config.FilePath)
+	case "memory":
+		return NewMemoryMailer()
+	default:
+		return NewSmtpMailer(Config.SmtpHost, strconv.Itoa(Config.SmtpPort), Config.Address, Config.Password)
+	}
+}
+
+func Configure(Config config.MailerConfig) {
+	DefaultMailer = NewMailerFromConfig(Config)
+}
+
+func SetMailer(Mailer Mailer) {
+	// Replaces the Mailer of the Application, used by the Tests
+	DefaultMailer = Mailer
+}
+
+func Send(To string, Subject string, Body string) error {
+	// Sends the Message with the Mailer of the Application
+	return DefaultMailer.Send(NewMessage(To, Subject, Body))
+}
@@ -1,38 +1,21 @@
 package main
 
 import (
-	"context"
 	"errors"
+	"flag"
 
 	"fmt"
-	"net/http"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"os"
-	"os/signal"
-	"syscall"
+	"strconv"
 
-	"github.com/LovePelmeni/Infrastructure/healthcheck_rest"
-	"github.com/LovePelmeni/Infrastructure/middlewares"
-	"github.com/LovePelmeni/Infrastructure/ssh_rest"
-
-	customer_rest "github.com/LovePelmeni/Infrastructure/customer_rest"
-	host_search_rest "github.com/LovePelmeni/Infrastructure/host_search_rest"
-	suggestion_rest "github.com/LovePelmeni/Infrastructure/suggestion_rest"
-
-	vm_rest "github.com/LovePelmeni/Infrastructure/vm_rest"
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-)
-
-var (
-	APPLICATION_HOST = os.Getenv("APPLICATION_HOST")
-	APPLICATION_PORT = os.Getenv("APPLICATION_PORT")
-
-	FRONT_APPLICATION_HOST = os.Getenv("FRONT_APPLICATION_HOST")
-	FRONT_APPLICATION_PORT = os.Getenv("FRONT_APPLICATION_PORT")
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/migrations"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/server"
 )
 
 var (
@@ -56,163 +39,94 @@ func init() {
 	InitializeProductionLogger()
 }
 
-type Server struct {
-	ServerHost string `json:"ServerHost"`
-	ServerPort string `json:"ServerPort"`
-}
-
-func NewServer(ServerHost string, ServerPort string) *Server {
-	return &Server{
-		ServerHost: ServerHost,
-		ServerPort: ServerPort,
-	}
-}
-
-func (this *Server) Run() {
+const MigrateUsage = "Usage: main migrate [up [Version] | down [Steps] | status]"
 
-	Router := gin.Default()
-	// Setting Up Cross Origin Resource Sharing Policy
+func Migrate(Arguments []string) error {
+	// Applies, Reverts or Shows Status of the Database Schema Migrations
+	Migrator := migrations.NewMigrator(models.Database, migrations.Migrations)
 
-	Router.Use(cors.New(cors.Config{
-		AllowOrigins: []string{
-			fmt.Sprintf("http://%s:%s", APPLICATION_HOST, APPLICATION_PORT),
-			fmt.Sprintf("http://%s:%s", FRONT_APPLICATION_HOST, FRONT_APPLICATION_PORT),
-		},
-		AllowMethods:     []string{"POST", "PUT", "DELETE", "GET", "OPTIONS"},
-		AllowCredentials: true,
-		AllowHeaders:     []string{"*"},
-		AllowWebSockets:  false,
-	}))
-
-	// Setting up Healthcheck Rest Endpoint
-
-	Router.GET("/ping/", func(context *gin.Context) {
-		context.JSON(http.StatusOK, nil)
-	})
-
-	// Customers Rest API Endpoints
-
-	CustomerGroup := Router.Group("/customer/")
-	{
-		CustomerGroup.POST("/login/", customer_rest.LoginRestController, middlewares.NonAuthorizationRequiredMiddleware())
-		CustomerGroup.POST("/logout/", customer_rest.LogoutRestController, middlewares.AuthorizationRequiredMiddleware())
-
-		CustomerGroup.POST("/create/", customer_rest.CreateCustomerRestController, middlewares.NonAuthorizationRequiredMiddleware())
-		CustomerGroup.PUT("/reset/password/", customer_rest.ResetPasswordRestController, middlewares.AuthorizationRequiredMiddleware())
-		CustomerGroup.DELETE("/delete/", customer_rest.DeleteCustomerRestController, middlewares.AuthorizationRequiredMiddleware())
-		CustomerGroup.GET("/get/profile/", customer_rest.GetCustomerProfileRestController, middlewares.AuthorizationRequiredMiddleware())
+	Command := "up"
+	if len(Arguments) > 0 {
+		Command = Arguments[0]
 	}
 
-	// Virtual Machines Rest API Endpoints
-
-	VirtualMachineGroup := Router.Group("/vm/").Use(
-		middlewares.AuthorizationRequiredMiddleware(),
-		middlewares.IsVirtualMachineOwnerMiddleware(),
-		middlewares.InfrastructureHealthCircuitBreakerMiddleware(),
-		middlewares.IsReadyToPerformOperationMiddleware())
-	{
-		{
-			VirtualMachineGroup.POST("/initialize/", vm_rest.InitializeVirtualMachineRestController) // initialized new Virtual Machine (Emtpy)
-			VirtualMachineGroup.PUT("/deploy/", vm_rest.DeployVirtualMachineRestController)          // Applies Configuration to the Initialized Machine
-			VirtualMachineGroup.DELETE("/remove/", vm_rest.RemoveVirtualMachineRestController)       // Removes Existing Virtual Machine
-			VirtualMachineGroup.POST("/start/", vm_rest.StartVirtualMachineRestController)           // Starts Virtual Machine
-			VirtualMachineGroup.POST("/reboot/", vm_rest.RebootVirtualMachineRestController)         // Reboots Virtual Machine
-			VirtualMachineGroup.DELETE("/shutdown/", vm_rest.ShutdownVirtualMachineRestController)   // Shutting Down Virtual Machine
-		}
+	switch Command {
 
-		{
-			VirtualMachineGroup.GET("/get/list/", vm_rest.GetCustomerVirtualMachine) // Customer's Virtual Machines
-			VirtualMachineGroup.GET("/get/", vm_rest.GetCustomerVirtualMachines)     // Customer's Specific Virtual Machine
+	case "up":
+		var TargetVersion int64
+		if len(Arguments) > 1 {
+			Version, ParseError := strconv.ParseInt(Arguments[1], 10, 64)
+			if ParseError != nil || Version <= 0 {
+				return errors.New(MigrateUsage)
+			}
+			TargetVersion = Version
 		}
-		VirtualMachineGroup.GET("/health/metrics/", healthcheck_rest.GetVirtualMachineHealthMetricRestController) // HealthCheck Metrics of the Virtual Machine
-	}
-
-	// Host System Rest Endpoints
-
-	HostSystemGroup := Router.Group("/host/").Use(
-
-		middlewares.AuthorizationRequiredMiddleware(),
-		middlewares.IsVirtualMachineOwnerMiddleware(),
-		middlewares.InfrastructureHealthCircuitBreakerMiddleware(),
-		middlewares.IsReadyToPerformOperationMiddleware())
-	{
-		HostSystemGroup.POST("system/start/", vm_rest.StartGuestOSRestController)
-		HostSystemGroup.PUT("system/restart/", vm_rest.RebootGuestOSRestController)
-		HostSystemGroup.DELETE("system/shutdown/", vm_rest.ShutdownGuestOsRestController)
-	}
-
-	// SSH Rest Endpoints
-
-	SshSystemGroup := Router.Group("/ssh/").Use(
-
-		middlewares.AuthorizationRequiredMiddleware(),
-		middlewares.IsVirtualMachineOwnerMiddleware(),
-		middlewares.InfrastructureHealthCircuitBreakerMiddleware(),
-		middlewares.IsReadyToPerformOperationMiddleware(),
-	)
-	{
-		SshSystemGroup.GET("/get/ssh/certificate/", ssh_rest.GetDownloadPublicSshCertificateRestController)
-	}
-
-	// Suggestions Rest Endpoints
-
-	SuggestionsGroup := Router.Group("/suggestions/").Use(
-		middlewares.AuthorizationRequiredMiddleware(),
-		middlewares.InfrastructureHealthCircuitBreakerMiddleware())
-	{
-		SuggestionsGroup.POST("/datacenter/", suggestion_rest.GetDatacentersSuggestions) // do not change to Safe Methods such as GET, OPTIONS, etc...
-		SuggestionsGroup.GET("/os/", suggestion_rest.GetAvailableOsSystemsRestController)
-		SuggestionsGroup.GET("/load/balancer/", suggestion_rest.GetAvailableLoadBalancersRestController)
-		SuggestionsGroup.GET("/pre/installed/tool/", suggestion_rest.GetAvailableInstallationToolsRestController)
-	}
-
-	// Host Machine Search Engine Rest Endpoints
+		Applied, MigrateError := Migrator.Up(TargetVersion)
+		fmt.Printf("Applied %d Migration(s)\n", Applied)
+		return MigrateError
+
+	case "down":
+		Steps := 1
+		if len(Arguments) > 1 {
+			Parsed, ParseError := strconv.Atoi(Arguments[1])
+			if ParseError != nil || Parsed <= 0 {
+				return errors.New(MigrateUsage)
+			}
+			Steps = Parsed
+		}
+		Reverted, MigrateError := Migrator.Down(Steps)
+		fmt.Printf("Reverted %d Migration(s)\n", Reverted)
+		return MigrateError
+
+	case "status":
+		Statuses, StatusError := Migrator.Status()
+		if StatusError != nil {
+			return StatusError
+		}
+		for _, Status := range Statuses {
+			AppliedAt := "pending"
+			if Status.Applied {
+				AppliedAt = Status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d %-40s %s\n", Status.Version, Status.Name, AppliedAt)
+		}
+		return nil
 
-	SearchEngineGroup := Router.Group("/host/machine/")
-	{
-		SearchEngineGroup.POST("/search/", host_search_rest.FindHostMachineRestController)
+	default:
+		return errors.New(MigrateUsage)
 	}
+}
 
-	// Support Rest API Endpoints
-
-	SupportGroup := Router.Group("/support/").Use(middlewares.AuthorizationRequiredMiddleware())
-	{
-		SupportGroup.POST("/feedback/", customer_rest.SupportRestController)
+func main() {
+	// Loading the Configuration, the Application does not Start, until every Problem of it is Fixed
+	Config, ConfigError := config.Load(os.Args[1:])
+	if errors.Is(ConfigError, flag.ErrHelp) {
+		return
 	}
-
-	Server := &http.Server{
-		Addr:    fmt.Sprintf("%s:%s", this.ServerHost, this.ServerPort),
-		Handler: Router,
+	if ConfigError != nil {
+		Logger.Error("Failed to Load Configuration", zap.Error(ConfigError))
+		fmt.Fprintln(os.Stderr, ConfigError.Error())
+		os.Exit(1)
 	}
+	server.Configure(Config)
 
-	ServerShutDownContext, ErrorCancelMethod := signal.NotifyContext(context.Background(),
-		os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT) // Creating Notify Context that triggers server to shut down
-	// after receiving system SIGTERM or SIGQUIT Signal from Kubernetes / Localhost.
-
-	go this.Shutdown(ServerShutDownContext, ErrorCancelMethod, Server)
-
-	Exception := Server.ListenAndServe()
-	if errors.Is(Exception, http.ErrServerClosed) {
-		ServerShutDownContext.Done()
-	} else {
-		fmt.Print("Server has been Shutdown For Some Reason, Check `MainLog.json` for more info")
-		Logger.Error(
-			"Error while Running the Server", zap.NamedError("RuntimeError", Exception))
-		ServerShutDownContext.Done()
+	// Connecting to the Database, Schema is being Managed by the `migrate` Subcommand
+	if OpenError := models.Open(*models.NewDatabaseConfigFromConfig(Config.Database)); OpenError != nil {
+		Logger.Error("Failed to Open Database", zap.Error(OpenError))
+		fmt.Println(OpenError.Error())
+		os.Exit(1)
 	}
-}
 
-func (this *Server) Shutdown(Context context.Context, CancelFunc context.CancelFunc, ServerInstance *http.Server) {
-	select {
-	case <-Context.Done():
-		defer CancelFunc()
-		ShutdownError := ServerInstance.Shutdown(context.Background())
-		Logger.Info("Server has been Shutdown", zap.NamedError("ShutdownError", ShutdownError))
+	if len(Config.Arguments) > 0 && Config.Arguments[0] == "migrate" {
+		if MigrateError := Migrate(Config.Arguments[1:]); MigrateError != nil {
+			Logger.Error("Failed to Migrate Database", zap.Error(MigrateError))
+			fmt.Println(MigrateError.Error())
+			os.Exit(1)
+		}
+		return
 	}
-}
 
-func main() {
 	Logger.Debug("Running Http Application Server...")
-	httpServer := NewServer(APPLICATION_HOST, APPLICATION_PORT)
+	httpServer := server.NewServer(Config)
 	httpServer.Run()
 }
@@ -0,0 +1,343 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Package consists of the Counters, Gauges and Histograms of the Application and the Exporter,
+// that Exposes them in the Prometheus Text Exposition Format
+// Metrics are being Declared by the Packages, that Update them, and Registered in the `DefaultRegistry`
+
+const (
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	// Buckets of the Latency Histograms in Seconds
+	DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+)
+
+type Metric interface {
+	GetName() string
+	Write(Buffer *bytes.Buffer)
+}
+
+// Exposition Format
+
+func FormatValue(Value float64) string {
+	switch {
+	case math.IsInf(Value, 1):
+		return "+Inf"
+	case math.IsInf(Value, -1):
+		return "-Inf"
+	case math.IsNaN(Value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(Value, 'g', -1, 64)
+	}
+}
+
+var (
+	LabelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	HelpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func FormatLabels(Names []string, Values []string, Extra ...string) string {
+	// Returns Labels of the Series, e.g `{method="GET",route="/vm/get/"}`, Extra Labels are being Passed as Name and Value Pairs
+	Pairs := make([]string, 0, len(Names)+len(Extra)/2)
+	for Index, Name := range Names {
+		Pairs = append(Pairs, fmt.Sprintf(`%s="%s"`, Name, LabelEscaper.Replace(Values[Index])))
+	}
+	for Index := 0; Index+1 < len(Extra); Index += 2 {
+		Pairs = append(Pairs, fmt.Sprintf(`%s="%s"`, Extra[Index], LabelEscaper.Replace(Extra[Index+1])))
+	}
+	if len(Pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(Pairs, ",") + "}"
+}
+
+type Descriptor struct {
+	// Name, Description and Label Names, that are Shared by all the Series of the Metric
+	Name       string
+	Help       string
+	Type       string
+	LabelNames []string
+}
+
+func (this Descriptor) GetName() string {
+	return this.Name
+}
+
+func (this Descriptor) WriteHeader(Buffer *bytes.Buffer) {
+	fmt.Fprintf(Buffer, "# HELP %s %s\n# TYPE %s %s\n", this.Name, HelpEscaper.Replace(this.Help), this.Name, this.Type)
+}
+
+func (this Descriptor) GetKey(LabelValues []string) string {
+	if len(LabelValues) != len(this.LabelNames) {
+		panic(fmt.Sprintf("Metric `%s` has %v Labels, %v Values have been Passed", this.Name, len(this.LabelNames), len(LabelValues)))
+	}
+	return strings.Join(LabelValues, "\xff")
+}
+
+func GetSortedKeys[Value any](Series map[string]Value) []string {
+	// Series are being Written in the same Order every time, so the Output is Stable
+	Keys := make([]string, 0, len(Series))
+	for Key := range Series {
+		Keys = append(Keys, Key)
+	}
+	sort.Strings(Keys)
+	return Keys
+}
+
+// Counter
+
+type CounterSeries struct {
+	LabelValues []string
+	Value       float64
+}
+
+type CounterVec struct {
+	// Value, that only Grows, such as the Number of the Requests
+	Descriptor
+	Mutex  sync.Mutex
+	Series map[string]*CounterSeries
+}
+
+func NewCounterVec(Name string, Help string, LabelNames ...string) *CounterVec {
+	return &CounterVec{
+		Descriptor: Descriptor{Name: Name, Help: Help, Type: "counter", LabelNames: LabelNames},
+		Series:     make(map[string]*CounterSeries),
+	}
+}
+
+func (this *CounterVec) Add(Value float64, LabelValues ...string) {
+	Key := this.GetKey(LabelValues)
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	Series, Exists := this.Series[Key]
+	if !Exists {
+		Series = &CounterSeries{LabelValues: append([]string(nil), LabelValues...)}
+		this.Series[Key] = Series
+	}
+	Series.Value += Value
+}
+
+func (this *CounterVec) Inc(LabelValues ...string) {
+	this.Add(1, LabelValues...)
+}
+
+func (this *CounterVec) Get(LabelValues ...string) float64 {
+	Key := this.GetKey(LabelValues)
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	if Series, Exists := this.Series[Key]; Exists {
+		return Series.Value
+	}
+	return 0
+}
+
+func (this *CounterVec) Write(Buffer *bytes.Buffer) {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.WriteHeader(Buffer)
+	for _, Key := range GetSortedKeys(this.Series) {
+		Series := this.Series[Key]
+		fmt.Fprintf(Buffer, "%s%s %s\n", this.Name, FormatLabels(this.LabelNames, Series.LabelValues), FormatValue(Series.Value))
+	}
+}
+
+// Gauge
+
+type GaugeValue struct {
+	LabelValues []string
+	Value       float64
+}
+
+type GaugeVec struct {
+	// Value, that can Go up and down, such as the Memory Usage of the Virtual Machine
+	Descriptor
+	Mutex  sync.Mutex
+	Series map[string]*GaugeValue
+}
+
+func NewGaugeVec(Name string, Help string, LabelNames ...string) *GaugeVec {
+	return &GaugeVec{
+		Descriptor: Descriptor{Name: Name, Help: Help, Type: "gauge", LabelNames: LabelNames},
+		Series:     make(map[string]*GaugeValue),
+	}
+}
+
+func (this *GaugeVec) Set(Value float64, LabelValues ...string) {
+	Key := this.GetKey(LabelValues)
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.Series[Key] = &GaugeValue{LabelValues: append([]string(nil), LabelValues...), Value: Value}
+}
+
+func (this *GaugeVec) Replace(Values []GaugeValue) {
+	// Replaces all the Series at once, so the ones, that are not being Reported anymore, Disappear
+	Series := make(map[string]*GaugeValue, len(Values))
+	for Index := range Values {
+		Series[this.GetKey(Values[Index].LabelValues)] = &Values[Index]
+	}
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.Series = Series
+}
+
+func (this *GaugeVec) Get(LabelValues ...string) (float64, bool) {
+	Key := this.GetKey(LabelValues)
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	if Series, Exists := this.Series[Key]; Exists {
+		return Series.Value, true
+	}
+	return 0, false
+}
+
+func (this *GaugeVec) Write(Buffer *bytes.Buffer) {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.WriteHeader(Buffer)
+	for _, Key := range GetSortedKeys(this.Series) {
+		Series := this.Series[Key]
+		fmt.Fprintf(Buffer, "%s%s %s\n", this.Name, FormatLabels(this.LabelNames, Series.LabelValues), FormatValue(Series.Value))
+	}
+}
+
+// Histogram
+
+type HistogramSeries struct {
+	LabelValues []string
+	Counts      []uint64 // Number of the Observations of every Bucket, not Cumulative
+	Sum         float64
+	Count       uint64
+}
+
+type HistogramVec struct {
+	// Distribution of the Observed Values, such as the Latency of the Requests
+	Descriptor
+	Buckets []float64 // Upper Bounds of the Buckets in the Ascending Order
+	Mutex   sync.Mutex
+	Series  map[string]*HistogramSeries
+}
+
+func NewHistogramVec(Name string, Help string, Buckets []float64, LabelNames ...string) *HistogramVec {
+	return &HistogramVec{
+		Descriptor: Descriptor{Name: Name, Help: Help, Type: "histogram", LabelNames: LabelNames},
+		Buckets:    Buckets,
+		Series:     make(map[string]*HistogramSeries),
+	}
+}
+
+func (this *HistogramVec) Observe(Value float64, LabelValues ...string) {
+	Key := this.GetKey(LabelValues)
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	Series, Exists := this.Series[Key]
+	if !Exists {
+		Series = &HistogramSeries{
+			LabelValues: append([]string(nil), LabelValues...),
+			Counts:      make([]uint64, len(this.Buckets)),
+		}
+		this.Series[Key] = Series
+	}
+	if Index := sort.SearchFloat64s(this.Buckets, Value); Index < len(this.Buckets) {
+		Series.Counts[Index]++
+	}
+	Series.Sum += Value
+	Series.Count++
+}
+
+func (this *HistogramVec) GetCount(LabelValues ...string) uint64 {
+	Key := this.GetKey(LabelValues)
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	if Series, Exists := this.Series[Key]; Exists {
+		return Series.Count
+	}
+	return 0
+}
+
+func (this *HistogramVec) Write(Buffer *bytes.Buffer) {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.WriteHeader(Buffer)
+	for _, Key := range GetSortedKeys(this.Series) {
+		Series := this.Series[Key]
+		var Cumulative uint64
+		for Index, Bound := range this.Buckets {
+			Cumulative += Series.Counts[Index]
+			fmt.Fprintf(Buffer, "%s_bucket%s %d\n", this.Name,
+				FormatLabels(this.LabelNames, Series.LabelValues, "le", FormatValue(Bound)), Cumulative)
+		}
+		fmt.Fprintf(Buffer, "%s_bucket%s %d\n", this.Name,
+			FormatLabels(this.LabelNames, Series.LabelValues, "le", "+Inf"), Series.Count)
+		Labels := FormatLabels(this.LabelNames, Series.LabelValues)
+		fmt.Fprintf(Buffer, "%s_sum%s %s\n", this.Name, Labels, FormatValue(Series.Sum))
+		fmt.Fprintf(Buffer, "%s_count%s %d\n", this.Name, Labels, Series.Count)
+	}
+}
+
+// Registry
+
+type Registry struct {
+	// Metrics, Exposed by the Exporter
+	Mutex   sync.RWMutex
+	Metrics map[string]Metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{Metrics: make(map[string]Metric)}
+}
+
+var (
+	DefaultRegistry = NewRegistry()
+)
+
+func (this *Registry) Register(Metrics ...Metric) {
+	// Registers the Metrics, Panics if the Name is already Taken, since it's the Mistake of the Declaration
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	for _, Metric := range Metrics {
+		if _, Exists := this.Metrics[Metric.GetName()]; Exists {
+			panic(fmt.Sprintf("Metric `%s` has already been Registered", Metric.GetName()))
+		}
+		this.Metrics[Metric.GetName()] = Metric
+	}
+}
+
+func Register(Metrics ...Metric) {
+	DefaultRegistry.Register(Metrics...)
+}
+
+func (this *Registry) Write(Buffer *bytes.Buffer) {
+	this.Mutex.RLock()
+	defer this.Mutex.RUnlock()
+	for _, Name := range GetSortedKeys(this.Metrics) {
+		this.Metrics[Name].Write(Buffer)
+	}
+}
+
+func (this *Registry) Handler(Token string) gin.HandlerFunc {
+	// Exposes the Metrics to the Prometheus, the Bearer Token is being Required, if it's Specified
+	return func(RequestContext *gin.Context) {
+		if len(Token) != 0 && RequestContext.GetHeader("Authorization") != "Bearer "+Token {
+			RequestContext.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"Error": "Invalid Metrics Token"})
+			return
+		}
+		var Buffer bytes.Buffer
+		this.Write(&Buffer)
+		RequestContext.Data(http.StatusOK, ContentType, Buffer.Bytes())
+	}
+}
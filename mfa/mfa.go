@@ -0,0 +1,195 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/models"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Time Based One Time Passwords (RFC 6238), used as the Second Factor of the Customer Login
+// Codes are being Generated by the Authenticator App from the Shared Secret, Recovery Codes can be Used once instead of them
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	Issuer = "KubeLagoon" // Name of the Service, that is being Shown in the Authenticator App
+)
+
+const (
+	Period             = 30 // Seconds, every Code is Valid for
+	Digits             = 6
+	Skew               = 1 // Number of the Neighbouring Periods, which Codes are still being Accepted, to Tolerate Clock Drift
+	SecretSize         = 20
+	RecoveryCodesCount = 10
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("MfaLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+func Configure(Config config.MfaConfig) {
+	Issuer = Config.Issuer
+}
+
+func GetIssuer() string {
+	return Issuer
+}
+
+func GenerateSecret() (string, error) {
+	// Returns new Random Base32 Encoded Secret, Shared with the Authenticator App
+	Random := make([]byte, SecretSize)
+	if _, RandomError := rand.Read(Random); RandomError != nil {
+		return "", RandomError
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(Random), nil
+}
+
+func GetProvisioningURI(AccountName string, Secret string) string {
+	// Returns `otpauth://` URI, that is being Encoded into the QR Code and Scanned by the Authenticator App
+	Issuer := GetIssuer()
+	Query := url.Values{}
+	Query.Set("secret", Secret)
+	Query.Set("issuer", Issuer)
+	Query.Set("algorithm", "SHA1")
+	Query.Set("digits", fmt.Sprint(Digits))
+	Query.Set("period", fmt.Sprint(Period))
+	return fmt.Sprintf("otpauth://totp/%s:%s?%s",
+		url.PathEscape(Issuer), url.PathEscape(AccountName), Query.Encode())
+}
+
+func GetTimeStep(Time time.Time) int64 {
+	return Time.Unix() / Period
+}
+
+func GenerateCode(Secret string, Step int64) (string, error) {
+	// Returns Code of the Time Step, (HOTP of the Step Counter, RFC 4226)
+	Key, DecodeError := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(Secret))
+	if DecodeError != nil {
+		return "", DecodeError
+	}
+
+	Counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(Counter, uint64(Step))
+	Mac := hmac.New(sha1.New, Key)
+	Mac.Write(Counter)
+	Sum := Mac.Sum(nil)
+
+	Offset := Sum[len(Sum)-1] & 0x0f
+	Truncated := binary.BigEndian.Uint32(Sum[Offset:Offset+4]) & 0x7fffffff
+
+	Modulo := uint32(1)
+	for Index := 0; Index < Digits; Index++ {
+		Modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, Truncated%Modulo), nil
+}
+
+func ValidateCode(Secret string, Code string, LastUsedStep int64, Time time.Time) (int64, bool) {
+	// Validates the Code against the Current Time Step and it's Neighbours
+	// Returns the Matched Step, Codes of the Steps, that have been Used already, are being Rejected to Prevent Replay
+	Current := GetTimeStep(Time)
+	for Step := Current - Skew; Step <= Current+Skew; Step++ {
+		if Step <= LastUsedStep {
+			continue
+		}
+		Expected, GenerateError := GenerateCode(Secret, Step)
+		if GenerateError != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(Expected), []byte(Code)) == 1 {
+			return Step, true
+		}
+	}
+	return 0, false
+}
+
+func HashRecoveryCode(Code string) string {
+	Hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(Code))))
+	return hex.EncodeToString(Hash[:])
+}
+
+func GenerateRecoveryCodes() ([]string, []string, error) {
+	// Returns new Recovery Codes along with their Hashes, only Hashes are being Stored
+	Codes, Hashes := []string{}, []string{}
+	for Index := 0; Index < RecoveryCodesCount; Index++ {
+		Random := make([]byte, 5)
+		if _, RandomError := rand.Read(Random); RandomError != nil {
+			return nil, nil, RandomError
+		}
+		Encoded := strings.ToLower(hex.EncodeToString(Random))
+		Code := Encoded[:5] + "-" + Encoded[5:]
+		Codes = append(Codes, Code)
+		Hashes = append(Hashes, HashRecoveryCode(Code))
+	}
+	return Codes, Hashes, nil
+}
+
+func VerifyCustomer(Customer *models.Customer, Code string) bool {
+	// Verifies the Second Factor of the Customer, either the Code of the Authenticator App or one of the Recovery Codes
+	// Used Codes are being Persisted, so they can't be Used again
+
+	if len(Customer.MfaSecret) == 0 {
+		return false
+	}
+
+	if Step, Valid := ValidateCode(Customer.MfaSecret, strings.TrimSpace(Code), Customer.MfaLastUsedStep, time.Now()); Valid {
+		Customer.MfaLastUsedStep = Step
+		if SaveError := Customer.SaveMfa(); SaveError != nil {
+			Logger.Error("Failed to Save Used MFA Code", zap.Error(SaveError))
+			return false
+		}
+		return true
+	}
+
+	Hash := HashRecoveryCode(Code)
+	Remaining := []string{}
+	Used := false
+	for _, Stored := range Customer.GetMfaRecoveryCodes() {
+		if !Used && subtle.ConstantTimeCompare([]byte(Stored), []byte(Hash)) == 1 {
+			Used = true
+			continue
+		}
+		Remaining = append(Remaining, Stored)
+	}
+	if !Used {
+		return false
+	}
+
+	Customer.MfaRecoveryCodes = strings.Join(Remaining, ",")
+	if SaveError := Customer.SaveMfa(); SaveError != nil {
+		Logger.Error("Failed to Save Used MFA Recovery Code", zap.Error(SaveError))
+		return false
+	}
+	Logger.Info("MFA Recovery Code has been Used", zap.Int("CustomerId", Customer.ID), zap.Int("Remaining", len(Remaining)))
+	return true
+}
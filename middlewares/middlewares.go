@@ -1,22 +1,26 @@
 package middlewares
 
 import (
-	"context"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 
 	"net/http"
-	"net/url"
 
 	"os"
 	"strconv"
-
-	"strings"
 	"time"
 
 	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/idempotency"
+	"github.com/LovePelmeni/Infrastructure/metrics"
 	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/policy"
+	"github.com/LovePelmeni/Infrastructure/ratelimit"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
 
-	"github.com/vmware/govmomi"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
@@ -32,6 +36,17 @@ var (
 	RedisClient *redis.Client
 )
 
+var (
+	MaxMultipartMemory int64 = 32 << 20 // Size of the Multipart Form, that is being Kept in Memory, same as the Default one of the Gin
+)
+
+var (
+	// Requests are being Labelled by the Route Pattern, not the Path, so the IDs do not Produce new Series
+	HttpRequests        = metrics.NewCounterVec("http_requests_total", "Number of the Handled HTTP Requests", "method", "route", "status")
+	HttpRequestDuration = metrics.NewHistogramVec("http_request_duration_seconds", "Latency of the HTTP Requests",
+		metrics.DefaultBuckets, "method", "route")
+)
+
 func InitializeProductionLogger() {
 	// Initializing Zap Logger
 	config := zap.NewProductionEncoderConfig()
@@ -46,145 +61,208 @@ func InitializeProductionLogger() {
 
 func init() {
 	InitializeProductionLogger()
-	DatabaseNumber, _ := strconv.Atoi(os.Getenv("CACHE_STORAGE_DATABASE_NUMBER"))
-	Client := redis.NewClient(&redis.Options{
-		Password: os.Getenv("CACHE_STORAGE_DATABASE_PASSWORD"),
-		Addr: fmt.Sprintf("%s:%s", os.Getenv("CACHE_STORAGE_DATABASE_HOST"),
-			os.Getenv("CACHE_STORAGE_DATABASE_PORT")),
-		DB: DatabaseNumber,
+	metrics.Register(HttpRequests, HttpRequestDuration)
+}
+
+func Configure(Config config.CacheConfig) {
+	// Connects the Cache Storage, that is being Shared by the Middlewares and the Rest Controllers
+	RedisClient = redis.NewClient(&redis.Options{
+		Password: Config.Password,
+		Addr:     Config.GetAddress(),
+		DB:       Config.DatabaseNumber,
 	})
-	RedisClient = Client
 }
 
 // VIRTUAL MACHINE MIDDLEWARES
 
-func IsVirtualMachineOwnerMiddleware() gin.HandlerFunc {
+func PolicyCheckMiddleware() gin.HandlerFunc {
+	// Checks, that the Customer's Role in the Project of the Virtual Machine (or the `ProjectId` passed)
+	// Allows to Perform the Requested Action, Requests without any of them are being Scoped by the Rest Controllers
 	return func(context *gin.Context) {
-		VirtualMachineId := context.Query("VirtualMachineId")
-		OwnerId := context.Query("OwnerId")
-		if Exists := models.Database.Model(
-			&models.VirtualMachine{}).Where("id = ? AND owner_id = ?", VirtualMachineId, OwnerId); Exists.Error != nil {
+
+		Credentials, JwtError := authentication.GetRequestCredentials(context)
+		if JwtError != nil {
 			context.AbortWithStatusJSON(
-				http.StatusForbidden, gin.H{"Status": "You're not the Owner of this VM"})
+				http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
 			return
 		}
-		context.Next()
-	}
-}
+		Action := policy.GetRequestAction(context.Request.Method, context.FullPath())
 
-func SetReadyOperationMiddleware() gin.HandlerFunc {
-	// Sets status `Ready` to the Virtual Machine
-	// being called only on HTTP Response
-	return func(Context *gin.Context) {
+		var ProjectId int
+		switch {
 
-		VirtualMachineId := Context.Query("VirtualMachineId")
-		var VirtualMachine models.VirtualMachine
+		case len(context.Query("VirtualMachineId")) != 0:
+			var VirtualMachine models.VirtualMachine
+			models.Database.Model(&models.VirtualMachine{}).Select("id", "project_id").Where(
+				"id = ?", context.Query("VirtualMachineId")).Find(&VirtualMachine)
+			if VirtualMachine.ID == 0 {
+				context.AbortWithStatusJSON(
+					http.StatusNotFound, gin.H{"Error": "Virtual Machine Does Not Exist"})
+				return
+			}
+			ProjectId = VirtualMachine.ProjectId
+
+		case len(context.Request.FormValue("ProjectId")) != 0:
+			Parsed, ParseError := strconv.Atoi(context.Request.FormValue("ProjectId"))
+			if ParseError != nil {
+				context.AbortWithStatusJSON(
+					http.StatusBadRequest, gin.H{"Error": "Invalid Project ID"})
+				return
+			}
+			ProjectId = Parsed
 
-		models.Database.Model(&models.VirtualMachine{}).Where(
-			"id = ?", VirtualMachineId).Find(&VirtualMachine)
+		default:
+			context.Next()
+			return
+		}
 
-		VirtualMachine.State = models.StatusNotReady
-		VirtualMachine.Save()
-		Context.Next()
+		if AuthorizationError := policy.AuthorizeInProject(Credentials.UserId, ProjectId, Action); AuthorizationError != nil {
+			context.AbortWithStatusJSON(
+				http.StatusForbidden, gin.H{"Error": AuthorizationError.Error()})
+			return
+		}
+		context.Next()
 	}
 }
 
-func SetNotReadyOperationMiddleware() gin.HandlerFunc {
-	// Sets status `NotReady` to the Virtual Machine
-	// being called only on HTTP Request
-	return func(Context *gin.Context) {
-
-		VirtualMachineId := Context.Query("VirtualMachineId")
-		var VirtualMachine models.VirtualMachine
-
-		models.Database.Model(&models.VirtualMachine{}).Where(
-			"id = ?", VirtualMachineId).Find(&VirtualMachine)
-
-		if Context.Request.Response.StatusCode != 0 || len(Context.Request.Response.Status) != 0 {
-			VirtualMachine.State = models.StatusReady
-			VirtualMachine.Save()
-		}
-		Context.Next()
+func GetIdempotencyIdentity(RequestContext *gin.Context) string {
+	// Returns Identity, the Idempotency Keys are being Scoped to: the Customer or the IP Address, if the Request is Anonymous
+	if Credentials, CredentialsError := authentication.GetRequestCredentials(RequestContext); CredentialsError == nil {
+		return fmt.Sprintf("customer:%v", Credentials.UserId)
 	}
+	return "ip:" + RequestContext.ClientIP()
 }
 
-func IsReadyToPerformOperationMiddleware() gin.HandlerFunc {
-	// Middlewares is used to prevent following case scenario
-
-	// Suppose: Virtual Machine is Currently busy, and is applying new Configuration
-	// So we need to add some blocker in order to prevent any critical operations on that specific
-	// machine, to prevent corruption,
-	// Virtual Machine Model has 2 states: `Ready` and `NotReady`
-
-	// When `NotReady` State occurs, it means, that this Virtual Machine is already being used and performs another operation
-	// So this Middleware is being used for detecting that State, before performing new Request to that Specific VM
-	return func(Context *gin.Context) {
+func RequestIdempotencyMiddleware() gin.HandlerFunc {
+	// Middleware, that Makes the Request with the `X-Idempotency-Key` Header Safe to Retry
+	// The Response is being Stored and Replayed to the Retries, instead of Performing the Operation one more time
+	// Requests without the Header are being Passed as is
 
-		var VirtualMachineId = Context.Query("VirtualMachineId")
-		var VirtualMachine models.VirtualMachine
+	Store := idempotency.NewStore(RedisClient, idempotency.TimeToLive)
+	return func(RequestContext *gin.Context) {
 
-		models.Database.Model(&models.VirtualMachine{}).Where(
-			"id = ?", VirtualMachineId).Find(&VirtualMachine)
+		Key := RequestContext.GetHeader(idempotency.HeaderName)
+		if len(Key) == 0 {
+			RequestContext.Next()
+			return
+		}
+		if len(Key) > idempotency.MaxKeyLength {
+			RequestContext.AbortWithStatusJSON(http.StatusBadRequest,
+				gin.H{"Error": idempotency.ErrInvalidKey.Error()})
+			return
+		}
 
-		switch {
+		// Form might have been Parsed by the Previous Middlewares already, so the Body has been Consumed by them
+		// The Parsed Form is being Fingerprinted along with the rest of the Body, e.g the JSON one, that is not being Parsed
+		if ParseError := RequestContext.Request.ParseMultipartForm(MaxMultipartMemory); ParseError != nil && !errors.Is(ParseError, http.ErrNotMultipart) {
+			RequestContext.AbortWithStatusJSON(http.StatusBadRequest,
+				gin.H{"Error": "Failed to Read the Request Body"})
+			return
+		}
+		Body, ReadError := io.ReadAll(RequestContext.Request.Body)
+		if ReadError != nil {
+			RequestContext.AbortWithStatusJSON(http.StatusBadRequest,
+				gin.H{"Error": "Failed to Read the Request Body"})
+			return
+		}
+		RequestContext.Request.Body = io.NopCloser(bytes.NewReader(Body))
 
-		case VirtualMachine.State == "NotReady":
-			Context.AbortWithStatusJSON(http.StatusServiceUnavailable,
-				gin.H{"Error": "this Server is already Performing other Operation, please Wait"})
+		RecordKey := idempotency.GetRecordKey(GetIdempotencyIdentity(RequestContext),
+			RequestContext.Request.Method, RequestContext.FullPath(), Key)
+		Record := idempotency.NewRecord(idempotency.GetFingerprint(
+			RequestContext.Request.URL.RawQuery, RequestContext.Request.PostForm, Body))
 
-		case VirtualMachine.State == "Ready":
-			Context.Next()
+		Existing, BeginError := Store.Begin(RecordKey, Record)
+		if BeginError != nil {
+			Logger.Error("Failed to Check Idempotency Key", zap.Error(BeginError))
+			RequestContext.AbortWithStatusJSON(http.StatusServiceUnavailable,
+				gin.H{"Error": "Service Is Currently Not Available, Try a bit Later"})
+			return
+		}
 
+		switch {
+		case Existing == nil:
+		case Existing.Fingerprint != Record.Fingerprint:
+			RequestContext.AbortWithStatusJSON(http.StatusUnprocessableEntity,
+				gin.H{"Error": "Idempotency Key has already been Used with the other Request"})
+			return
+		case Existing.State == idempotency.StateProcessing:
+			RequestContext.AbortWithStatusJSON(http.StatusConflict,
+				gin.H{"Error": "Request with the same Idempotency Key is still being Processed"})
+			return
 		default:
-			Context.Next()
+			Existing.Replay(RequestContext)
+			return
 		}
-	}
-}
-func RequestIdempotencyMiddleware() gin.HandlerFunc {
-	// Middleware checks for the Idempotency Of the HTTP Requests
-	// To Avoid Unpleasent Situations
 
-	return func(RequestContext *gin.Context) {
+		Recorder := idempotency.NewResponseRecorder(RequestContext.Writer)
+		RequestContext.Writer = Recorder
+		RequestContext.Next()
 
-		RequestNumber := RequestContext.GetHeader("X-Idempotency-Key")
-		var UrlCacheKey string
-		for _, Property := range RequestContext.Params {
-			UrlCacheKey += fmt.Sprintf("&%s", Property)
-		}
-		if Result, Error := RedisClient.Get(UrlCacheKey + "=" + RequestNumber).Result(); Error != nil {
-			RedisClient.Set(UrlCacheKey+"="+RequestNumber, "Request is Being Processed", 10*time.Minute)
-			RequestContext.Next()
-		} else {
-			MapValuesResponse := func() map[string]string {
-				var Map map[string]string
-				for Index, Key := range strings.Split(Result, "=")[0] {
-					Map[string(Key)] = string(strings.Split(Result, "=")[1][Index])
-				}
-				return Map
-			}()
-			RequestContext.AbortWithStatusJSON(http.StatusNotModified, MapValuesResponse)
+		if !idempotency.IsStorable(Recorder.Status()) {
+			if ReleaseError := Store.Release(RecordKey); ReleaseError != nil {
+				Logger.Error("Failed to Release Idempotency Key", zap.Error(ReleaseError))
+			}
 			return
 		}
+		Record.Complete(Recorder.Status(), Recorder.Header(), Recorder.Body.Bytes())
+		if CompleteError := Store.Complete(RecordKey, Record); CompleteError != nil {
+			Logger.Error("Failed to Store Idempotent Response", zap.Error(CompleteError))
+		}
 	}
 }
 
 // AUTHORIZATION MIDDLEWARES
 
 func AuthorizationRequiredMiddleware() gin.HandlerFunc {
-	// Middleware checks for customer is being Authorized
+	// Middleware checks for customer is being Authorized, either by the Jwt Token or by the API Key
 	return func(context *gin.Context) {
+
+		// Automation Clients are being Authorized by the API Keys, that are Limited by the Scopes
+		if ApiKey := authentication.GetRequestApiKey(context); len(ApiKey) != 0 {
+			Credentials, KeyError := authentication.AuthenticateApiKey(ApiKey)
+			if KeyError != nil {
+				context.AbortWithStatusJSON(
+					http.StatusForbidden, gin.H{"Error": KeyError.Error()})
+				return
+			}
+			Scope := policy.GetRequiredScope(context.Request.Method, context.FullPath())
+			if len(Scope) == 0 || !Credentials.HasScope(Scope) {
+				context.AbortWithStatusJSON(
+					http.StatusForbidden, gin.H{"Error": fmt.Sprintf("API Key is not Allowed to Perform this Operation, Required Scope: `%s`", Scope)})
+				return
+			}
+			context.Set(authentication.CredentialsContextKey, Credentials)
+			context.Next()
+			return
+		}
+
 		if len(context.GetHeader("Authorization")) == 0 {
 			context.AbortWithStatusJSON(
 				http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
 			return
 		}
 
-		if _, Error := authentication.GetCustomerJwtCredentials(
-			context.GetHeader("Authorization")); Error != nil {
+		Credentials, Error := authentication.GetCustomerJwtCredentials(context.GetHeader("Authorization"))
+		if Error != nil {
 			context.AbortWithStatusJSON(
 				http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
 			return
 		}
+
+		// Checking, that the Token has not been Revoked by the Logout
+		Revoked, DenylistError := authentication.IsAccessTokenRevoked(RedisClient, Credentials)
+		if DenylistError != nil {
+			Logger.Error("Failed to Check Access Token Denylist", zap.Error(DenylistError))
+			context.AbortWithStatusJSON(
+				http.StatusServiceUnavailable, gin.H{"Error": "Failed to Verify Authorization, Try a bit Later"})
+			return
+		}
+		if Revoked {
+			context.AbortWithStatusJSON(
+				http.StatusForbidden, gin.H{"Error": "Token has been Revoked, Please Login again"})
+			return
+		}
 		context.Next()
 	}
 }
@@ -209,37 +287,104 @@ func NonAuthorizationRequiredMiddleware() gin.HandlerFunc {
 	}
 }
 
+// RATE LIMITING MIDDLEWARES
+
+func GetRateLimitIdentity(context *gin.Context) string {
+	// Returns Identity, the Requests are being Counted for: the API Key, the Customer of the Jwt Token or the IP Address
+	if ApiKey := authentication.GetRequestApiKey(context); len(ApiKey) != 0 {
+		return "key:" + authentication.HashToken(ApiKey)
+	}
+	if Credentials, JwtError := authentication.GetCustomerJwtCredentials(context.GetHeader("Authorization")); JwtError == nil {
+		return fmt.Sprintf("customer:%v", Credentials.UserId)
+	}
+	return "ip:" + context.ClientIP()
+}
+
+func RateLimitMiddleware(Name string, Limit ratelimit.Limit) gin.HandlerFunc {
+	// Middleware, that Limits Requests of every Identity to the Route Group with the Token Bucket
+	// Requests are not being Limited, if Redis is not Available
+	Limiter := ratelimit.NewLimiter(RedisClient)
+	return func(context *gin.Context) {
+
+		Result, LimitError := Limiter.Allow(Name, GetRateLimitIdentity(context), Limit)
+		if LimitError != nil {
+			Logger.Error("Failed to Check Rate Limit", zap.String("Group", Name), zap.Error(LimitError))
+			context.Next()
+			return
+		}
+
+		context.Header("X-RateLimit-Limit", strconv.Itoa(Result.Limit))
+		context.Header("X-RateLimit-Remaining", strconv.Itoa(Result.Remaining))
+		context.Header("X-RateLimit-Reset", strconv.Itoa(int(Result.ResetAfter.Seconds()+0.999)))
+
+		if !Result.Allowed {
+			context.Header("Retry-After", strconv.Itoa(int(Result.RetryAfter.Seconds()+0.999)))
+			context.AbortWithStatusJSON(
+				http.StatusTooManyRequests, gin.H{"Error": "Too many Requests, Try a bit Later"})
+			return
+		}
+		context.Next()
+	}
+}
+
 // ---------------------------------------------
 
 func InfrastructureHealthCircuitBreakerMiddleware() gin.HandlerFunc {
-	// Middleware, that checks for VM Server Accessibility, when the Request Associated within It
-	// is being Requested, If the Datacenter is currently not available, it will respond with
-	// not available Exception
+	// Middleware, that Rejects the Request with the Not Available Exception, if the vSphere of the Region is not Available
+	// The Region is being Taken from the Virtual Machine (or the `Region` passed), Requests without any of them are being Passed,
+	// while at least one of the Regions is Available
+	// The Availability is being Tracked by the Circuit Breakers of the vSphere Session Pools, so the Middleware does not Dial them
 	return func(RequestContext *gin.Context) {
 
-		var (
-			APIIp    = os.Getenv("VMWARE_SOURCE_IP")
-			Username = os.Getenv("VMWARE_SOURCE_USERNAME")
-			Password = os.Getenv("VMWARE_SOURCE_PASSWORD")
+		var Region string
+		switch {
+		case len(RequestContext.Query("VirtualMachineId")) != 0:
+			VirtualMachineId, _ := strconv.Atoi(RequestContext.Query("VirtualMachineId"))
+			Region = models.GetVirtualMachineRegion(VirtualMachineId)
 
-			APIUrl = &url.URL{
-				Scheme: "https",
-				Path:   "/sdk/",
-				Host:   APIIp,
-				User:   url.UserPassword(Username, Password),
+		case len(RequestContext.Request.PostFormValue("Region")) != 0:
+			Region = RequestContext.Request.PostFormValue("Region")
+
+		default:
+			if !vsphere.Registry.IsAnyAvailable() {
+				RequestContext.Header("Retry-After", strconv.Itoa(int(vsphere.Registry.Cooldown.Seconds())))
+				RequestContext.AbortWithStatusJSON(http.StatusServiceUnavailable,
+					gin.H{"Error": "Service Is Currently Not Available, Try a bit Later"})
+				return
 			}
-		)
-		// Checking if the Infrastructure is able to perform the Http Request, related to the VM
-		TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*10)
-		defer CancelFunc()
+			RequestContext.Next()
+			return
+		}
 
-		_, ConnectionError := govmomi.NewClient(TimeoutContext, APIUrl, false)
-		if ConnectionError != nil {
+		Pool, RegionError := vsphere.Registry.Get(Region)
+		if RegionError != nil {
+			RequestContext.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"Error": RegionError.Error()})
+			return
+		}
+		if !Pool.IsAvailable() {
+			RequestContext.Header("Retry-After", strconv.Itoa(int(Pool.Breaker.Cooldown.Seconds())))
 			RequestContext.AbortWithStatusJSON(http.StatusServiceUnavailable,
-				gin.H{"Error": "Service Is Currently Not Available, Try a bit Later"})
+				gin.H{"Error": fmt.Sprintf("Region `%s` Is Currently Not Available, Try a bit Later", Pool.Endpoint.Region)})
 			return
 		}
 		RequestContext.Next()
 	}
 }
 
+// ---------------------------------------------
+
+func MetricsMiddleware() gin.HandlerFunc {
+	// Counts the Requests and Observes their Latency by the Route, Requests to the Unknown Routes are being Labelled as `unmatched`
+	return func(RequestContext *gin.Context) {
+		StartedAt := time.Now()
+		RequestContext.Next()
+
+		Route := RequestContext.FullPath()
+		if len(Route) == 0 {
+			Route = "unmatched"
+		}
+		Method := RequestContext.Request.Method
+		HttpRequests.Inc(Method, Route, strconv.Itoa(RequestContext.Writer.Status()))
+		HttpRequestDuration.Observe(time.Since(StartedAt).Seconds(), Method, Route)
+	}
+}
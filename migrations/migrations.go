@@ -0,0 +1,268 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+)
+
+// Package consists of the Versioned Migrations of the Database Schema
+// Every Migration has the Up and Down Step, Applied Versions are being Tracked in the `schema_migrations` Table
+// Migrations are being Applied Explicitly using the `migrate` Subcommand of the Main Binary
+
+var (
+	Logger *zap.Logger
+)
+
+// Key of the Postgres Advisory Lock, so only one Replica Applies Migrations at the time
+const MigrationsLockKey = 7213400921
+
+var (
+	ErrUnknownVersion = errors.New("Migration Version is not Registered")
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("MigrationsLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+// MigrationFunc Applies or Reverts the Schema Change within the Transaction passed
+type MigrationFunc func(Transaction *gorm.DB) error
+
+type Migration struct {
+	// Single Versioned Change of the Database Schema
+	Version int64
+	Name    string
+	Up      MigrationFunc
+	Down    MigrationFunc
+}
+
+func NewSQLMigration(Version int64, Name string, Up []string, Down []string) *Migration {
+	// Returns Migration, that Executes Raw SQL Statements in the Order they are passed
+	return &Migration{
+		Version: Version,
+		Name:    Name,
+		Up:      ExecStatements(Up),
+		Down:    ExecStatements(Down),
+	}
+}
+
+func ExecStatements(Statements []string) MigrationFunc {
+	return func(Transaction *gorm.DB) error {
+		for _, Statement := range Statements {
+			if Executed := Transaction.Exec(Statement); Executed.Error != nil {
+				return Executed.Error
+			}
+		}
+		return nil
+	}
+}
+
+type SchemaMigration struct {
+	// Applied Migration Record
+	Version   int64     `json:"Version" xml:"Version" gorm:"primaryKey;autoIncrement:false;"`
+	Name      string    `json:"Name" xml:"Name" gorm:"type:varchar(255);not null;"`
+	AppliedAt time.Time `json:"AppliedAt" xml:"AppliedAt" gorm:"not null;"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type MigrationStatus struct {
+	// Status of the Registered Migration
+	Version   int64      `json:"Version" xml:"Version"`
+	Name      string     `json:"Name" xml:"Name"`
+	Applied   bool       `json:"Applied" xml:"Applied"`
+	AppliedAt *time.Time `json:"AppliedAt" xml:"AppliedAt"`
+}
+
+type Migrator struct {
+	// Applies and Reverts Registered Migrations against the Database
+	Database   *gorm.DB
+	Migrations []*Migration
+}
+
+func NewMigrator(Database *gorm.DB, Migrations []*Migration) *Migrator {
+	Sorted := append([]*Migration{}, Migrations...)
+	sort.Slice(Sorted, func(i, j int) bool { return Sorted[i].Version < Sorted[j].Version })
+	return &Migrator{
+		Database:   Database,
+		Migrations: Sorted,
+	}
+}
+
+func (this *Migrator) EnsureSchemaTable() error {
+	// Creates the Table, that Tracks Applied Migrations, if it does not Exist yet
+	Created := this.Database.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name varchar(255) NOT NULL,
+		applied_at timestamptz NOT NULL
+	)`)
+	return Created.Error
+}
+
+func (this *Migrator) AppliedVersions() (map[int64]SchemaMigration, error) {
+	// Returns Migrations, that have been Applied already
+	var Applied []SchemaMigration
+	if Found := this.Database.Model(&SchemaMigration{}).Order("version").Find(&Applied); Found.Error != nil {
+		return nil, Found.Error
+	}
+	Versions := make(map[int64]SchemaMigration)
+	for _, Migration := range Applied {
+		Versions[Migration.Version] = Migration
+	}
+	return Versions, nil
+}
+
+func (this *Migrator) Up(TargetVersion int64) (int, error) {
+	// Applies Pending Migrations up to the Target Version, 0 means all of them
+	// Returns Number of the Migrations Applied
+	if SchemaError := this.EnsureSchemaTable(); SchemaError != nil {
+		return 0, SchemaError
+	}
+	if TargetVersion != 0 && this.Find(TargetVersion) == nil {
+		return 0, ErrUnknownVersion
+	}
+
+	AppliedNumber := 0
+	for _, Migration := range this.Migrations {
+		if TargetVersion != 0 && Migration.Version > TargetVersion {
+			break
+		}
+		Applied, ApplyError := this.apply(Migration)
+		if ApplyError != nil {
+			Logger.Error("Failed to Apply Migration", zap.Int64("Version", Migration.Version),
+				zap.String("Name", Migration.Name), zap.Error(ApplyError))
+			return AppliedNumber, fmt.Errorf("migration %d (%s): %w", Migration.Version, Migration.Name, ApplyError)
+		}
+		if Applied {
+			Logger.Info("Migration has been Applied", zap.Int64("Version", Migration.Version), zap.String("Name", Migration.Name))
+			AppliedNumber++
+		}
+	}
+	return AppliedNumber, nil
+}
+
+func (this *Migrator) Down(Steps int) (int, error) {
+	// Reverts the Latest Applied Migrations, Returns Number of the Migrations Reverted
+	if SchemaError := this.EnsureSchemaTable(); SchemaError != nil {
+		return 0, SchemaError
+	}
+	RevertedNumber := 0
+	for Index := len(this.Migrations) - 1; Index >= 0 && RevertedNumber < Steps; Index-- {
+		Migration := this.Migrations[Index]
+		Reverted, RevertError := this.revert(Migration)
+		if RevertError != nil {
+			Logger.Error("Failed to Revert Migration", zap.Int64("Version", Migration.Version),
+				zap.String("Name", Migration.Name), zap.Error(RevertError))
+			return RevertedNumber, fmt.Errorf("migration %d (%s): %w", Migration.Version, Migration.Name, RevertError)
+		}
+		if Reverted {
+			Logger.Info("Migration has been Reverted", zap.Int64("Version", Migration.Version), zap.String("Name", Migration.Name))
+			RevertedNumber++
+		}
+	}
+	return RevertedNumber, nil
+}
+
+func (this *Migrator) Status() ([]MigrationStatus, error) {
+	// Returns Status of every Registered Migration
+	if SchemaError := this.EnsureSchemaTable(); SchemaError != nil {
+		return nil, SchemaError
+	}
+	Applied, AppliedError := this.AppliedVersions()
+	if AppliedError != nil {
+		return nil, AppliedError
+	}
+	Statuses := []MigrationStatus{}
+	for _, Migration := range this.Migrations {
+		Status := MigrationStatus{Version: Migration.Version, Name: Migration.Name}
+		if Record, Exists := Applied[Migration.Version]; Exists {
+			AppliedAt := Record.AppliedAt
+			Status.Applied = true
+			Status.AppliedAt = &AppliedAt
+		}
+		Statuses = append(Statuses, Status)
+	}
+	return Statuses, nil
+}
+
+func (this *Migrator) Find(Version int64) *Migration {
+	for _, Migration := range this.Migrations {
+		if Migration.Version == Version {
+			return Migration
+		}
+	}
+	return nil
+}
+
+func (this *Migrator) apply(Migration *Migration) (bool, error) {
+	// Applies the Migration within the Transaction, Skips it, if it has been Applied by another Replica already
+	Applied := false
+	TransactionError := this.Database.Transaction(func(Transaction *gorm.DB) error {
+		if Locked := Transaction.Exec("SELECT pg_advisory_xact_lock(?)", MigrationsLockKey); Locked.Error != nil {
+			return Locked.Error
+		}
+		var Exists int64
+		if Counted := Transaction.Model(&SchemaMigration{}).Where("version = ?", Migration.Version).Count(&Exists); Counted.Error != nil {
+			return Counted.Error
+		}
+		if Exists != 0 {
+			return nil
+		}
+		if UpError := Migration.Up(Transaction); UpError != nil {
+			return UpError
+		}
+		Applied = true
+		return Transaction.Create(&SchemaMigration{
+			Version:   Migration.Version,
+			Name:      Migration.Name,
+			AppliedAt: time.Now(),
+		}).Error
+	})
+	return Applied, TransactionError
+}
+
+func (this *Migrator) revert(Migration *Migration) (bool, error) {
+	// Reverts the Migration within the Transaction, Skips it, if it has not been Applied
+	Reverted := false
+	TransactionError := this.Database.Transaction(func(Transaction *gorm.DB) error {
+		if Locked := Transaction.Exec("SELECT pg_advisory_xact_lock(?)", MigrationsLockKey); Locked.Error != nil {
+			return Locked.Error
+		}
+		var Exists int64
+		if Counted := Transaction.Model(&SchemaMigration{}).Where("version = ?", Migration.Version).Count(&Exists); Counted.Error != nil {
+			return Counted.Error
+		}
+		if Exists == 0 {
+			return nil
+		}
+		if Migration.Down == nil {
+			return fmt.Errorf("migration %d (%s) can not be Reverted", Migration.Version, Migration.Name)
+		}
+		if DownError := Migration.Down(Transaction); DownError != nil {
+			return DownError
+		}
+		Reverted = true
+		return Transaction.Where("version = ?", Migration.Version).Delete(&SchemaMigration{}).Error
+	})
+	return Reverted, TransactionError
+}
@@ -0,0 +1,433 @@
+package migrations
+
+// Registered Migrations of the Database Schema
+// New Migration should be Appended to the End of the List with the next Version, Applied Migrations must not be Edited
+
+var Migrations = []*Migration{
+
+	// Initial Schema, Tables are being Created only if they does not Exist,
+	// so the Databases, Created by the `AutoMigrate` before, can be Migrated as well
+	NewSQLMigration(1, "initial_schema",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS customers (
+				id bigserial PRIMARY KEY,
+				username varchar(100) NOT NULL UNIQUE,
+				email varchar(100) NOT NULL UNIQUE,
+				password varchar(100) NOT NULL,
+				city text NOT NULL,
+				country varchar(100) NOT NULL,
+				zip_code varchar(100) NOT NULL,
+				street varchar(100) NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS virtual_machines (
+				id bigserial PRIMARY KEY,
+				state varchar(10) NOT NULL,
+				ssh_key text DEFAULT NULL,
+				configuration text DEFAULT NULL,
+				owner_id bigint NOT NULL,
+				virtual_machine_name varchar(15) NOT NULL,
+				item_path varchar(100) NOT NULL,
+				ip_address varchar(100) NOT NULL UNIQUE,
+				created_at timestamptz
+			)`,
+			`CREATE TABLE IF NOT EXISTS jobs (
+				id bigserial PRIMARY KEY,
+				type varchar(50) NOT NULL,
+				virtual_machine_id bigint DEFAULT NULL,
+				owner_id bigint NOT NULL,
+				state varchar(20) NOT NULL,
+				progress bigint NOT NULL DEFAULT 0,
+				error text DEFAULT NULL,
+				payload text DEFAULT NULL,
+				result text DEFAULT NULL,
+				created_at timestamptz,
+				updated_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_jobs_owner_id ON jobs (owner_id)`,
+			`CREATE TABLE IF NOT EXISTS snapshots (
+				id bigserial PRIMARY KEY,
+				virtual_machine_id bigint NOT NULL,
+				owner_id bigint NOT NULL,
+				name varchar(80) NOT NULL,
+				description text DEFAULT NULL,
+				snapshot_ref varchar(100) NOT NULL,
+				memory boolean NOT NULL DEFAULT false,
+				quiesce boolean NOT NULL DEFAULT false,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_snapshots_virtual_machine_id ON snapshots (virtual_machine_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_snapshots_owner_id ON snapshots (owner_id)`,
+			`CREATE TABLE IF NOT EXISTS deployments (
+				id bigserial PRIMARY KEY,
+				virtual_machine_id bigint NOT NULL,
+				job_id bigint DEFAULT NULL,
+				state varchar(20) NOT NULL,
+				current_step varchar(50) DEFAULT NULL,
+				completed_steps bigint NOT NULL DEFAULT 0,
+				configuration text NOT NULL,
+				context text DEFAULT NULL,
+				error text DEFAULT NULL,
+				created_at timestamptz,
+				updated_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_deployments_virtual_machine_id ON deployments (virtual_machine_id)`,
+			`CREATE TABLE IF NOT EXISTS unmanaged_virtual_machines (
+				id bigserial PRIMARY KEY,
+				managed_object_id varchar(50) NOT NULL UNIQUE,
+				item_path varchar(255) NOT NULL,
+				power_state varchar(20) DEFAULT NULL,
+				detected_at timestamptz
+			)`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS unmanaged_virtual_machines`,
+			`DROP TABLE IF EXISTS deployments`,
+			`DROP TABLE IF EXISTS snapshots`,
+			`DROP TABLE IF EXISTS jobs`,
+			`DROP TABLE IF EXISTS virtual_machines`,
+			`DROP TABLE IF EXISTS customers`,
+		},
+	),
+
+	// Virtual Machine Inventory State, Synchronized by the Reconciler
+	NewSQLMigration(2, "virtual_machines_inventory_state",
+		[]string{
+			`ALTER TABLE virtual_machines ADD COLUMN IF NOT EXISTS managed_object_id varchar(50) DEFAULT NULL`,
+			`ALTER TABLE virtual_machines ADD COLUMN IF NOT EXISTS power_state varchar(20) DEFAULT NULL`,
+			`ALTER TABLE virtual_machines ADD COLUMN IF NOT EXISTS orphaned boolean NOT NULL DEFAULT false`,
+			`ALTER TABLE virtual_machines ADD COLUMN IF NOT EXISTS reconciled_at timestamptz DEFAULT NULL`,
+			`CREATE INDEX IF NOT EXISTS idx_virtual_machines_managed_object_id ON virtual_machines (managed_object_id)`,
+		},
+		[]string{
+			`DROP INDEX IF EXISTS idx_virtual_machines_managed_object_id`,
+			`ALTER TABLE virtual_machines DROP COLUMN IF EXISTS reconciled_at`,
+			`ALTER TABLE virtual_machines DROP COLUMN IF EXISTS orphaned`,
+			`ALTER TABLE virtual_machines DROP COLUMN IF EXISTS power_state`,
+			`ALTER TABLE virtual_machines DROP COLUMN IF EXISTS managed_object_id`,
+		},
+	),
+
+	// Customer can Own many Virtual Machines, up to the Limit
+	NewSQLMigration(3, "virtual_machines_ownership",
+		[]string{
+			`ALTER TABLE virtual_machines DROP CONSTRAINT IF EXISTS virtual_machines_owner_id_key`,
+			`ALTER TABLE virtual_machines ALTER COLUMN owner_id TYPE bigint USING owner_id::bigint`,
+			`CREATE INDEX IF NOT EXISTS idx_virtual_machines_owner_id ON virtual_machines (owner_id)`,
+			`ALTER TABLE customers ADD COLUMN IF NOT EXISTS virtual_machines_limit bigint NOT NULL DEFAULT 0`,
+		},
+		[]string{
+			`ALTER TABLE customers DROP COLUMN IF EXISTS virtual_machines_limit`,
+			`DROP INDEX IF EXISTS idx_virtual_machines_owner_id`,
+		},
+	),
+
+	// Organizations with the Projects and Role Based Memberships, Virtual Machines Belong to the Projects
+	// Every Existing Customer receives Personal Organization with the Default Project, their Virtual Machines are being Moved to
+	NewSQLMigration(4, "organizations_projects_memberships",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS organizations (
+				id bigserial PRIMARY KEY,
+				name varchar(100) NOT NULL,
+				created_by bigint NOT NULL,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_organizations_created_by ON organizations (created_by)`,
+			`CREATE TABLE IF NOT EXISTS projects (
+				id bigserial PRIMARY KEY,
+				organization_id bigint NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+				name varchar(100) NOT NULL,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_projects_organization_id ON projects (organization_id)`,
+			`CREATE TABLE IF NOT EXISTS memberships (
+				id bigserial PRIMARY KEY,
+				organization_id bigint NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+				customer_id bigint NOT NULL,
+				role varchar(20) NOT NULL,
+				created_at timestamptz
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_memberships_organization_customer ON memberships (organization_id, customer_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_memberships_customer_id ON memberships (customer_id)`,
+			`ALTER TABLE virtual_machines ADD COLUMN IF NOT EXISTS project_id bigint DEFAULT NULL`,
+			`CREATE INDEX IF NOT EXISTS idx_virtual_machines_project_id ON virtual_machines (project_id)`,
+
+			`INSERT INTO organizations (name, created_by, created_at)
+				SELECT customers.username, customers.id, now() FROM customers`,
+			`INSERT INTO memberships (organization_id, customer_id, role, created_at)
+				SELECT organizations.id, organizations.created_by, 'Owner', now() FROM organizations`,
+			`INSERT INTO projects (organization_id, name, created_at)
+				SELECT organizations.id, 'Default', now() FROM organizations`,
+			`UPDATE virtual_machines SET project_id = projects.id
+				FROM projects JOIN organizations ON organizations.id = projects.organization_id
+				WHERE organizations.created_by = virtual_machines.owner_id AND virtual_machines.project_id IS NULL`,
+		},
+		[]string{
+			`DROP INDEX IF EXISTS idx_virtual_machines_project_id`,
+			`ALTER TABLE virtual_machines DROP COLUMN IF EXISTS project_id`,
+			`DROP TABLE IF EXISTS memberships`,
+			`DROP TABLE IF EXISTS projects`,
+			`DROP TABLE IF EXISTS organizations`,
+		},
+	),
+
+	// Hashed Refresh Tokens, Rotated on every Refresh
+	NewSQLMigration(5, "refresh_tokens",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS refresh_tokens (
+				id bigserial PRIMARY KEY,
+				customer_id bigint NOT NULL,
+				family_id varchar(36) NOT NULL,
+				token_hash varchar(64) NOT NULL UNIQUE,
+				expires_at timestamptz NOT NULL,
+				revoked_at timestamptz DEFAULT NULL,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_customer_id ON refresh_tokens (customer_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id)`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS refresh_tokens`,
+		},
+	),
+
+	// Hashed Personal API Keys of the Automation Clients
+	NewSQLMigration(6, "api_keys",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS api_keys (
+				id bigserial PRIMARY KEY,
+				customer_id bigint NOT NULL,
+				name varchar(100) NOT NULL,
+				prefix varchar(12) NOT NULL,
+				key_hash varchar(64) NOT NULL UNIQUE,
+				scopes varchar(255) NOT NULL,
+				expires_at timestamptz NOT NULL,
+				revoked_at timestamptz DEFAULT NULL,
+				last_used_at timestamptz DEFAULT NULL,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_api_keys_customer_id ON api_keys (customer_id)`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS api_keys`,
+		},
+	),
+
+	// Time Based One Time Password Second Factor of the Customers
+	NewSQLMigration(7, "customers_mfa",
+		[]string{
+			`ALTER TABLE customers ADD COLUMN IF NOT EXISTS mfa_enabled boolean NOT NULL DEFAULT false`,
+			`ALTER TABLE customers ADD COLUMN IF NOT EXISTS mfa_secret varchar(64) DEFAULT NULL`,
+			`ALTER TABLE customers ADD COLUMN IF NOT EXISTS mfa_recovery_codes text DEFAULT NULL`,
+			`ALTER TABLE customers ADD COLUMN IF NOT EXISTS mfa_last_used_step bigint NOT NULL DEFAULT 0`,
+			`ALTER TABLE organizations ADD COLUMN IF NOT EXISTS require_mfa boolean NOT NULL DEFAULT false`,
+		},
+		[]string{
+			`ALTER TABLE organizations DROP COLUMN IF EXISTS require_mfa`,
+			`ALTER TABLE customers DROP COLUMN IF EXISTS mfa_last_used_step`,
+			`ALTER TABLE customers DROP COLUMN IF EXISTS mfa_recovery_codes`,
+			`ALTER TABLE customers DROP COLUMN IF EXISTS mfa_secret`,
+			`ALTER TABLE customers DROP COLUMN IF EXISTS mfa_enabled`,
+		},
+	),
+
+	// Single Use Tokens of the Password Reset and Email Verification, Existing Customers are Considered Verified
+	NewSQLMigration(8, "customer_tokens_email_verification",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS customer_tokens (
+				id bigserial PRIMARY KEY,
+				customer_id bigint NOT NULL,
+				purpose varchar(30) NOT NULL,
+				token_hash varchar(64) NOT NULL UNIQUE,
+				expires_at timestamptz NOT NULL,
+				used_at timestamptz DEFAULT NULL,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_customer_tokens_customer_id ON customer_tokens (customer_id)`,
+			`ALTER TABLE customers ADD COLUMN IF NOT EXISTS email_verified boolean NOT NULL DEFAULT false`,
+			`UPDATE customers SET email_verified = true`,
+		},
+		[]string{
+			`ALTER TABLE customers DROP COLUMN IF EXISTS email_verified`,
+			`DROP TABLE IF EXISTS customer_tokens`,
+		},
+	),
+
+	// Identities of the Customers at the OpenID Connect Provider
+	NewSQLMigration(9, "customers_oidc_identity",
+		[]string{
+			`ALTER TABLE customers ADD COLUMN IF NOT EXISTS oidc_issuer varchar(255) DEFAULT NULL`,
+			`ALTER TABLE customers ADD COLUMN IF NOT EXISTS oidc_subject varchar(255) DEFAULT NULL`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_oidc_identity ON customers (oidc_issuer, oidc_subject)`,
+		},
+		[]string{
+			`DROP INDEX IF EXISTS idx_customers_oidc_identity`,
+			`ALTER TABLE customers DROP COLUMN IF EXISTS oidc_subject`,
+			`ALTER TABLE customers DROP COLUMN IF EXISTS oidc_issuer`,
+		},
+	),
+
+	// Fencing Tokens of the Virtual Machine Locks, held by the Jobs
+	NewSQLMigration(10, "jobs_lock_token",
+		[]string{
+			`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS lock_token bigint NOT NULL DEFAULT 0`,
+		},
+		[]string{
+			`ALTER TABLE jobs DROP COLUMN IF EXISTS lock_token`,
+		},
+	),
+
+	// Regions of the vSphere Endpoints, Existing Virtual Machines stay in the Default Region
+	NewSQLMigration(11, "virtual_machines_region",
+		[]string{
+			`ALTER TABLE virtual_machines ADD COLUMN IF NOT EXISTS region varchar(50) NOT NULL DEFAULT ''`,
+			`CREATE INDEX IF NOT EXISTS idx_virtual_machines_region ON virtual_machines (region)`,
+			`ALTER TABLE unmanaged_virtual_machines ADD COLUMN IF NOT EXISTS region varchar(50) NOT NULL DEFAULT ''`,
+			`ALTER TABLE unmanaged_virtual_machines DROP CONSTRAINT IF EXISTS unmanaged_virtual_machines_managed_object_id_key`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_unmanaged_virtual_machines_region_managed_object_id ON unmanaged_virtual_machines (region, managed_object_id)`,
+		},
+		[]string{
+			`DROP INDEX IF EXISTS idx_unmanaged_virtual_machines_region_managed_object_id`,
+			`ALTER TABLE unmanaged_virtual_machines DROP COLUMN IF EXISTS region`,
+			`DELETE FROM unmanaged_virtual_machines`,
+			`ALTER TABLE unmanaged_virtual_machines ADD CONSTRAINT unmanaged_virtual_machines_managed_object_id_key UNIQUE (managed_object_id)`,
+			`DROP INDEX IF EXISTS idx_virtual_machines_region`,
+			`ALTER TABLE virtual_machines DROP COLUMN IF EXISTS region`,
+		},
+	),
+
+	// Health History of the Virtual Machines, Raw Samples and their Rollups
+	NewSQLMigration(12, "virtual_machine_metrics",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS virtual_machine_metrics (
+				virtual_machine_id bigint NOT NULL,
+				resolution bigint NOT NULL,
+				collected_at timestamptz NOT NULL,
+				cpu_usage double precision NOT NULL DEFAULT 0,
+				memory_active double precision NOT NULL DEFAULT 0,
+				memory_granted double precision NOT NULL DEFAULT 0,
+				guest_memory_usage double precision NOT NULL DEFAULT 0,
+				storage_committed double precision NOT NULL DEFAULT 0,
+				powered_on double precision NOT NULL DEFAULT 0,
+				samples bigint NOT NULL DEFAULT 1,
+				PRIMARY KEY (virtual_machine_id, resolution, collected_at)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_virtual_machine_metrics_resolution_collected_at ON virtual_machine_metrics (resolution, collected_at)`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS virtual_machine_metrics`,
+		},
+	),
+
+	// Alert Rules, Notification Channels and Alerts of the Projects
+	NewSQLMigration(13, "alerting",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS alert_rules (
+				id bigserial PRIMARY KEY,
+				project_id bigint NOT NULL,
+				virtual_machine_id bigint DEFAULT NULL,
+				name varchar(100) NOT NULL,
+				metric varchar(50) NOT NULL,
+				comparator varchar(2) NOT NULL,
+				threshold double precision NOT NULL,
+				duration bigint NOT NULL DEFAULT 0,
+				enabled boolean NOT NULL DEFAULT true,
+				created_by bigint NOT NULL,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_alert_rules_project_id ON alert_rules (project_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_alert_rules_virtual_machine_id ON alert_rules (virtual_machine_id)`,
+			`CREATE TABLE IF NOT EXISTS alert_channels (
+				id bigserial PRIMARY KEY,
+				project_id bigint NOT NULL,
+				name varchar(100) NOT NULL,
+				type varchar(20) NOT NULL,
+				target text NOT NULL,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_alert_channels_project_id ON alert_channels (project_id)`,
+			`CREATE TABLE IF NOT EXISTS alerts (
+				id bigserial PRIMARY KEY,
+				rule_id bigint NOT NULL,
+				virtual_machine_id bigint NOT NULL,
+				state varchar(20) NOT NULL,
+				value double precision NOT NULL DEFAULT 0,
+				started_at timestamptz NOT NULL,
+				fired_at timestamptz DEFAULT NULL,
+				resolved_at timestamptz DEFAULT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_alerts_rule_id ON alerts (rule_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_alerts_virtual_machine_id ON alerts (virtual_machine_id)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_active ON alerts (rule_id, virtual_machine_id) WHERE state <> 'Resolved'`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS alerts`,
+			`DROP TABLE IF EXISTS alert_channels`,
+			`DROP TABLE IF EXISTS alert_rules`,
+		},
+	),
+
+	// Webhooks of the Customers and the Deliveries of the Lifecycle Events of the Virtual Machines
+	NewSQLMigration(14, "webhooks",
+		[]string{
+			`CREATE TABLE IF NOT EXISTS webhooks (
+				id bigserial PRIMARY KEY,
+				owner_id bigint NOT NULL,
+				url text NOT NULL,
+				secret varchar(100) NOT NULL,
+				event_types text NOT NULL DEFAULT '',
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_webhooks_owner_id ON webhooks (owner_id)`,
+			`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+				id bigserial PRIMARY KEY,
+				webhook_id bigint NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+				event_id varchar(36) NOT NULL,
+				event_type varchar(50) NOT NULL,
+				payload text NOT NULL,
+				state varchar(20) NOT NULL,
+				attempts bigint NOT NULL DEFAULT 0,
+				next_attempt_at timestamptz NOT NULL,
+				status_code bigint NOT NULL DEFAULT 0,
+				error text DEFAULT NULL,
+				delivered_at timestamptz DEFAULT NULL,
+				created_at timestamptz,
+				updated_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries (event_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE state = 'Pending'`,
+		},
+		[]string{
+			`DROP TABLE IF EXISTS webhook_deliveries`,
+			`DROP TABLE IF EXISTS webhooks`,
+		},
+	),
+
+	// SSH Credentials have been Stored in the Results of the Deploy Jobs, that are Readable by every Member of the Project
+	// They can't be Restored back, so the Down Step does Nothing
+	NewSQLMigration(15, "remove_job_ssh_credentials",
+		[]string{
+			`UPDATE jobs SET result = (result::jsonb - 'SshInfo')::text WHERE type = 'Deploy' AND result LIKE '%"SshInfo"%'`,
+		},
+		[]string{},
+	),
+
+	// Device Key of the Data Disk, so the Resize Grows the Disk, the Deploy has Attached, rather than the Primary Disk of the Template
+	NewSQLMigration(16, "virtual_machines_data_disk_key",
+		[]string{
+			`ALTER TABLE virtual_machines ADD COLUMN IF NOT EXISTS data_disk_key integer DEFAULT NULL`,
+		},
+		[]string{
+			`ALTER TABLE virtual_machines DROP COLUMN IF EXISTS data_disk_key`,
+		},
+	),
+
+	// SSH Credentials have been Persisted in the Context of the Deployments along with the Progress of the Deploy
+	// They can't be Restored back, so the Down Step does Nothing
+	NewSQLMigration(17, "remove_deployment_ssh_credentials",
+		[]string{
+			`UPDATE deployments SET context = (context::jsonb - 'SshInfo')::text WHERE context LIKE '%"SshInfo"%'`,
+		},
+		[]string{},
+	),
+}
@@ -3,10 +3,15 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/LovePelmeni/Infrastructure/config"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -30,11 +35,7 @@ const StatusNotReady = "NotReady" // Defines the Status of the Virtual Machine A
 const StatusReady = "Ready"       // Defines the Status of Virtual Machine Availability
 
 var (
-	DATABASE_NAME     = os.Getenv("DATABASE_NAME")
-	DATABASE_HOST     = os.Getenv("DATABASE_HOST")
-	DATABASE_PORT     = os.Getenv("DATABASE_PORT")
-	DATABASE_USER     = os.Getenv("DATABASE_USER")
-	DATABASE_PASSWORD = os.Getenv("DATABASE_PASSWORD")
+	DefaultVirtualMachinesLimit = 10 // Can be Overridden with the `CUSTOMER_VIRTUAL_MACHINES_LIMIT` Configuration Key
 )
 
 func InitializeProductionLogger() {
@@ -50,28 +51,65 @@ func InitializeProductionLogger() {
 }
 
 func init() {
-	DatabaseInstance, ConnectionError := gorm.Open(postgres.New(postgres.Config{
-		DSN: fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s",
-			DATABASE_HOST, DATABASE_PORT, DATABASE_USER, DATABASE_PASSWORD, DATABASE_NAME),
-	}))
+	InitializeProductionLogger()
+}
 
-	switch ConnectionError {
+func Configure(Config config.CustomersConfig) {
+	DefaultVirtualMachinesLimit = Config.VirtualMachinesLimit
+}
+
+type DatabaseConfig struct {
+	// Credentials of the PostgreSQL Database
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+}
 
-	case gorm.ErrInvalidDB:
-		panic("Please Setup Correct Credentials for your PostgreSQL Database: Host, Port, User, Password, DbName")
+func NewDatabaseConfig(Host string, Port string, User string, Password string, Name string) *DatabaseConfig {
+	return &DatabaseConfig{
+		Host:     Host,
+		Port:     Port,
+		User:     User,
+		Password: Password,
+		Name:     Name,
+	}
+}
 
-	case gorm.ErrUnsupportedDriver:
-		panic("Invalid Database Driver")
+func NewDatabaseConfigFromConfig(Config config.DatabaseConfig) *DatabaseConfig {
+	// Returns Database Credentials of the Application Configuration
+	return NewDatabaseConfig(Config.Host, strconv.Itoa(Config.Port), Config.User, Config.Password, Config.Name)
+}
 
-	case gorm.ErrNotImplemented:
-		panic("Please Setup Credentials for the Database, " +
+func (this *DatabaseConfig) Validate() error {
+	if len(this.Host) == 0 || len(this.Port) == 0 || len(this.User) == 0 || len(this.Name) == 0 {
+		return errors.New("Please Setup Credentials for the Database, " +
 			"so it knows where to connect, go to `env` " +
 			"directory and fill up `project.env` file with new Database Credentials")
 	}
+	return nil
+}
+
+func Open(Config DatabaseConfig) error {
+	// Opens Connection to the PostgreSQL Database
+	// Schema of the Database is being Managed by the `migrations` package, so it needs to be Migrated Separately
+
+	if ValidationError := Config.Validate(); ValidationError != nil {
+		return ValidationError
+	}
+
+	DatabaseInstance, ConnectionError := gorm.Open(postgres.New(postgres.Config{
+		DSN: fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s",
+			Config.Host, Config.Port, Config.User, Config.Password, Config.Name),
+	}))
+	if ConnectionError != nil {
+		Logger.Error("Failed to Connect to the Database", zap.String("Host", Config.Host), zap.Error(ConnectionError))
+		return ConnectionError
+	}
 
 	Database = DatabaseInstance
-	Database.AutoMigrate(&Customer{}, &VirtualMachine{})
-	InitializeProductionLogger()
+	return nil
 }
 
 type Customer struct {
@@ -85,6 +123,69 @@ type Customer struct {
 	Country string `json:"Country" xml:"Country" gorm:"type:varchar(100); not null;"`
 	ZipCode string `json:"ZipCode" xml:"ZipCode" gorm:"type:varchar(100); not null;"`
 	Street  string `json:"Street" xml:"Street" gorm:"type:varchar(100); not null;"`
+
+	// Max Number of the Virtual Machines, the Customer can Own, `DefaultVirtualMachinesLimit` is being used, if it's not Specified
+	VirtualMachinesLimit int `json:"VirtualMachinesLimit" xml:"VirtualMachinesLimit" gorm:"not null;default:0;"`
+
+	// Customer has Confirmed the Email Address, by Following the Link from the Verification Email
+	EmailVerified bool `json:"EmailVerified" xml:"EmailVerified" gorm:"not null;default:false;"`
+
+	// Identity of the Customer at the OpenID Connect Provider, if the Customer has Logged in with the Single Sign-On
+	OidcIssuer  string `json:"-" xml:"-" gorm:"type:varchar(255);default:null;uniqueIndex:idx_customers_oidc_identity;"`
+	OidcSubject string `json:"-" xml:"-" gorm:"type:varchar(255);default:null;uniqueIndex:idx_customers_oidc_identity;"`
+
+	// Time Based One Time Password Second Factor, Recovery Codes are being Stored as the SHA-256 Hashes Separated by Comma
+	MfaEnabled       bool   `json:"MfaEnabled" xml:"MfaEnabled" gorm:"not null;default:false;"`
+	MfaSecret        string `json:"-" xml:"-" gorm:"type:varchar(64);default:null;"`
+	MfaRecoveryCodes string `json:"-" xml:"-" gorm:"type:text;default:null;"`
+	MfaLastUsedStep  int64  `json:"-" xml:"-" gorm:"not null;default:0;"` // Time Step of the Last Accepted Code, Prevents Replay
+}
+
+func (this *Customer) GetVirtualMachinesLimit() int {
+	// Returns Max Number of the Virtual Machines, the Customer can Own
+	if this.VirtualMachinesLimit > 0 {
+		return this.VirtualMachinesLimit
+	}
+	return DefaultVirtualMachinesLimit
+}
+
+func (this *Customer) GetMfaRecoveryCodes() []string {
+	// Returns Hashes of the Recovery Codes, that have not been Used yet
+	if len(this.MfaRecoveryCodes) == 0 {
+		return []string{}
+	}
+	return strings.Split(this.MfaRecoveryCodes, ",")
+}
+
+func (this *Customer) SaveMfa() error {
+	// Saves the Second Factor Settings of the Customer
+	Saved := Database.Model(&Customer{}).Where("id = ?", this.ID).Updates(map[string]interface{}{
+		"mfa_enabled":        this.MfaEnabled,
+		"mfa_secret":         this.MfaSecret,
+		"mfa_recovery_codes": this.MfaRecoveryCodes,
+		"mfa_last_used_step": this.MfaLastUsedStep,
+	})
+	return Saved.Error
+}
+
+func GetCustomerByOidcIdentity(Issuer string, Subject string) (*Customer, bool) {
+	// Returns the Customer, Linked to the Identity of the OpenID Connect Provider
+	var FoundCustomer Customer
+	Database.Model(&Customer{}).Where("oidc_issuer = ? AND oidc_subject = ?", Issuer, Subject).Find(&FoundCustomer)
+	return &FoundCustomer, FoundCustomer.ID != 0
+}
+
+func (this *Customer) LinkOidcIdentity(Issuer string, Subject string) error {
+	// Links the Identity of the OpenID Connect Provider to the Customer, the Email is Considered Verified by the Provider
+	Linked := Database.Model(&Customer{}).Where("id = ?", this.ID).Updates(map[string]interface{}{
+		"oidc_issuer":    Issuer,
+		"oidc_subject":   Subject,
+		"email_verified": true,
+	})
+	if Linked.Error == nil {
+		this.OidcIssuer, this.OidcSubject, this.EmailVerified = Issuer, Subject, true
+	}
+	return Linked.Error
 }
 
 func NewCustomer(Username string, Password string, Email string, City string, Country string, ZipCode string, Street string) *Customer {
@@ -104,19 +205,27 @@ func NewCustomer(Username string, Password string, Email string, City string, Co
 }
 
 func (this *Customer) Create() (*gorm.DB, error) {
-	// Creates New Customer Profile
-
-	PasswordHash, _ := bcrypt.GenerateFromPassword([]byte(this.Password), 14)
-	this.Password = string(PasswordHash)
-
-	CreatedCustomer := Database.Model(&Customer{}).Create(this)
-	return CreatedCustomer, CreatedCustomer.Error
+	// Creates New Customer Profile along with the Personal Organization, the Customer Owns
+	// Password has already been Hashed by the `NewCustomer`
+
+	TransactionError := Database.Transaction(func(Transaction *gorm.DB) error {
+		if CreatedCustomer := Transaction.Model(&Customer{}).Create(this); CreatedCustomer.Error != nil {
+			return CreatedCustomer.Error
+		}
+		_, OrganizationError := CreateOrganization(Transaction, this.Username, this.ID)
+		return OrganizationError
+	})
+	return Database, TransactionError
 }
 
 func (this *Customer) Delete(UserId int) (*gorm.DB, error) {
 	// Deletes Customer Profile
 	DeletedCustomer := Database.Where("id = ?", UserId).Delete(&Customer{})
 	Database.Unscoped().Where("id = ?", UserId).Delete(&Customer{})
+	Database.Where("customer_id = ?", UserId).Delete(&Membership{})
+	RevokeCustomerRefreshTokens(UserId)
+	Database.Where("customer_id = ?", UserId).Delete(&CustomerToken{})
+	Database.Model(&ApiKey{}).Where("customer_id = ? AND revoked_at IS NULL", UserId).Update("revoked_at", time.Now())
 	return DeletedCustomer, DeletedCustomer.Error
 }
 
@@ -127,11 +236,20 @@ type VirtualMachine struct {
 	State              string                      `json:"State" xml:"State" gorm:"type:varchar(10); not null;"`
 	SshInfo            SSHConfiguration            `json:"sshKey" xml:"sshKey" gorm:"column:ssh_key;type:text;default:null;"`
 	Configuration      VirtualMachineConfiguration `json:"Configuration" xml:"Configuration" gorm:"column:configuration;type:text;default:null;"`
-	OwnerId            int                         `json:"OwnerId" xml:"OwnerId" gorm:"<-:create;type:varchar(100);not null;unique;"`
+	OwnerId            int                         `json:"OwnerId" xml:"OwnerId" gorm:"<-:create;not null;index;"` // Customer, who Created the Virtual Machine
+	ProjectId          int                         `json:"ProjectId" xml:"ProjectId" gorm:"default:null;index;"`   // Project, the Virtual Machine Belongs to
 	VirtualMachineName string                      `json:"VirtualMachineName" xml:"VirtualMachineName" gorm:"type:varchar(15);not null;"`
-	ItemPath           string                      `json:"ItemPath" xml:"ItemPath" gorm:"<-:create;type:varchar(100);not null;"`
-	IPAddress          string                      `json:"IPAddress" xml:"IPAddress" gorm:"<-:create;type:varchar(100);not null;unique;"`
+	ItemPath           string                      `json:"ItemPath" xml:"ItemPath" gorm:"type:varchar(100);not null;"`
+	IPAddress          string                      `json:"IPAddress" xml:"IPAddress" gorm:"type:varchar(100);not null;unique;"`
+	Region             string                      `json:"Region" xml:"Region" gorm:"type:varchar(50);not null;default:'';index;"` // Region of the vSphere Endpoint, Empty one stands for the Default Region
 	CreatedAt          time.Time                   `json:"CreatedAt" xml:"CreatedAt" gorm:"<-:create; default:"`
+	DataDiskKey        int32                       `json:"-" xml:"-" gorm:"default:null;"` // vSphere Device Key of the Data Disk, Attached by the Deploy
+
+	// Actual State of the Virtual Machine in the vSphere, Synchronized by the Reconciler
+	ManagedObjectId string     `json:"-" xml:"-" gorm:"type:varchar(50);default:null;index;"`
+	PowerState      string     `json:"PowerState" xml:"PowerState" gorm:"type:varchar(20);default:null;"`
+	Orphaned        bool       `json:"Orphaned" xml:"Orphaned" gorm:"not null;default:false;"` // Virtual Machine does not Exist in the vSphere anymore
+	ReconciledAt    *time.Time `json:"ReconciledAt" xml:"ReconciledAt" gorm:"default:null;"`
 }
 
 func NewVirtualMachine(
@@ -164,14 +282,68 @@ func (this *VirtualMachine) Save() (*gorm.DB, error) {
 func (this *VirtualMachine) Create() (*gorm.DB, error) {
 	// Creates New Virtual Machine Object
 
-	Created := Database.Clauses(clause.OnConflict{Columns: []clause.Column{
-		{Table: "VirtualMachine", Name: "VirtualMachineName"}},
-		DoUpdates: clause.Assignments(map[string]interface{}{
-			"VirtualMachineName": gorm.Expr("virtual_machine_name + _ + uuid_generate_v3()"),
-		})}).Create(&this)
+	Created := Database.Model(&VirtualMachine{}).Create(this)
 	return Created, Created.Error
 }
 
+var (
+	ErrVirtualMachinesLimitExceeded = errors.New("Virtual Machines Limit has been Exceeded")
+)
+
+func CountCustomerVirtualMachines(Query *gorm.DB, OwnerId int) int64 {
+	// Returns Number of the Virtual Machines, the Customer Owns, including the ones, that are being Created right now
+	var VirtualMachinesNumber, PendingNumber int64
+	Query.Model(&VirtualMachine{}).Where("owner_id = ?", OwnerId).Count(&VirtualMachinesNumber)
+	Query.Model(&Job{}).Where("owner_id = ? AND type IN ? AND state IN ?", OwnerId,
+		[]string{JobTypeInitialize, JobTypeClone}, []string{JobStateQueued, JobStateRunning}).Count(&PendingNumber)
+	return VirtualMachinesNumber + PendingNumber
+}
+
+func CheckVirtualMachinesLimit(Transaction *gorm.DB, OwnerId int) error {
+	// Checks, that the Customer is allowed to Create one more Virtual Machine
+	// Row of the Customer is being Locked until the end of the Transaction, so the Concurrent Checks are being Made one after another
+	var Owner Customer
+	if Selected := Transaction.Model(&Customer{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where(
+		"id = ?", OwnerId).Find(&Owner); Selected.Error != nil {
+		return Selected.Error
+	}
+	if Limit := Owner.GetVirtualMachinesLimit(); CountCustomerVirtualMachines(Transaction, OwnerId) >= int64(Limit) {
+		return fmt.Errorf("%w, You can Own up to %v Virtual Machines", ErrVirtualMachinesLimitExceeded, Limit)
+	}
+	return nil
+}
+
+func AccessibleProjects(CustomerId int) *gorm.DB {
+	// Returns Query of the Project IDs, the Customer has any Membership in
+	// Projects of the Organizations, that Require MFA are not Accessible, until the Customer Enables it
+	return Database.Model(&Project{}).Select("projects.id").Joins(
+		"JOIN memberships ON memberships.organization_id = projects.organization_id").Joins(
+		"JOIN organizations ON organizations.id = projects.organization_id").Joins(
+		"JOIN customers ON customers.id = memberships.customer_id").Where(
+		"memberships.customer_id = ? AND (organizations.require_mfa = false OR customers.mfa_enabled = true)", CustomerId)
+}
+
+func AccessibleBy(CustomerId int) func(Query *gorm.DB) *gorm.DB {
+	// Scope, that Limits Virtual Machines to the ones, that Belong to the Projects, the Customer is Member of
+	return func(Query *gorm.DB) *gorm.DB {
+		return Query.Where("project_id IN (?)", AccessibleProjects(CustomerId))
+	}
+}
+
+func InRegions(Regions ...string) func(Query *gorm.DB) *gorm.DB {
+	// Scope, that Limits Virtual Machines to the ones, Served by the vSphere Endpoints of the Regions
+	return func(Query *gorm.DB) *gorm.DB {
+		return Query.Where("region IN (?)", Regions)
+	}
+}
+
+func GetVirtualMachineRegion(VirtualMachineId int) string {
+	// Returns Region of the Virtual Machine, Empty one stands for the Default Region
+	var Region string
+	Database.Model(&VirtualMachine{}).Select("region").Where("id = ?", VirtualMachineId).Scan(&Region)
+	return Region
+}
+
 func (this *VirtualMachine) Delete() (*gorm.DB, error) {
 	// Deletes the Virtual Machine ORM Object....
 
@@ -326,3 +498,976 @@ func (this *SSHConfiguration) Value() ([]byte, error) {
 	Serialized, Error := json.Marshal(this)
 	return Serialized, Error
 }
+
+// Virtual Machine Operation Jobs
+
+const (
+	JobStateQueued    = "Queued"    // Job has been Stored and is Waiting for the Free Worker
+	JobStateRunning   = "Running"   // Job is being Executed by one of the Workers right now
+	JobStateSucceeded = "Succeeded" // Job has been Executed Successfully
+	JobStateFailed    = "Failed"    // Job has been Failed, the Reason is stored in the `Error` Field
+)
+
+const (
+	JobTypeInitialize      = "Initialize"
+	JobTypeDeploy          = "Deploy"
+	JobTypeStart           = "Start"
+	JobTypeReboot          = "Reboot"
+	JobTypeShutdown        = "Shutdown"
+	JobTypeRemove          = "Remove"
+	JobTypeClone           = "Clone"
+	JobTypeResize          = "Resize"
+	JobTypeStartGuestOS    = "StartGuestOS"
+	JobTypeRebootGuestOS   = "RebootGuestOS"
+	JobTypeShutdownGuestOS = "ShutdownGuestOS"
+	JobTypeCreateSnapshot  = "CreateSnapshot"
+	JobTypeRevertSnapshot  = "RevertSnapshot"
+	JobTypeRemoveSnapshot  = "RemoveSnapshot"
+)
+
+type Job struct {
+	// Job Database ORM Model, represents Long Running Operation on the Virtual Machine Server
+	// That is being Executed in the Background, instead of blocking the HTTP Request
+	ID               int
+	Type             string    `json:"Type" xml:"Type" gorm:"type:varchar(50);not null;"`
+	VirtualMachineId int       `json:"VirtualMachineId" xml:"VirtualMachineId" gorm:"default:null;"`
+	OwnerId          int       `json:"OwnerId" xml:"OwnerId" gorm:"not null;index;"`
+	State            string    `json:"State" xml:"State" gorm:"type:varchar(20);not null;"`
+	Progress         int       `json:"Progress" xml:"Progress" gorm:"not null;default:0;"`
+	Error            string    `json:"Error" xml:"Error" gorm:"type:text;default:null;"`
+	Payload          string    `json:"-" xml:"-" gorm:"type:text;default:null;"`
+	Result           string    `json:"Result" xml:"Result" gorm:"type:text;default:null;"`
+	LockToken        int64     `json:"-" xml:"-" gorm:"not null;default:0;"` // Fencing Token of the Virtual Machine Lock, held by the Job
+	CreatedAt        time.Time `json:"CreatedAt" xml:"CreatedAt"`
+	UpdatedAt        time.Time `json:"UpdatedAt" xml:"UpdatedAt"`
+}
+
+func NewJob(Type string, VirtualMachineId int, OwnerId int, Payload interface{}) (*Job, error) {
+	// Returns New Queued Job, Payload is being Serialized into JSON, so it can be restored by the Worker
+	SerializedPayload, EncodeError := json.Marshal(Payload)
+	if EncodeError != nil {
+		return nil, EncodeError
+	}
+	return &Job{
+		Type:             Type,
+		VirtualMachineId: VirtualMachineId,
+		OwnerId:          OwnerId,
+		State:            JobStateQueued,
+		Payload:          string(SerializedPayload),
+	}, nil
+}
+
+// Receives the Job, once it's State or Progress has been Stored, such as the Stream of the Customer's Events
+type JobObserver func(Job Job)
+
+var (
+	JobObservers []JobObserver
+)
+
+func SubscribeJobs(Observer JobObserver) {
+	// Adds the Observer of the Jobs, should be Called on the Startup
+	JobObservers = append(JobObservers, Observer)
+}
+
+func (this *Job) Notify() {
+	for _, Observer := range JobObservers {
+		Observer(*this)
+	}
+}
+
+func (this *Job) CreatesVirtualMachine() bool {
+	// Checks, that the Job Creates new Virtual Machine of the Owner
+	return this.Type == JobTypeInitialize || this.Type == JobTypeClone
+}
+
+func (this *Job) Create() (*gorm.DB, error) {
+	// Creates New Job Object
+	// Job, that Creates new Virtual Machine, is being Stored within the same Transaction, the Limit of the Owner is Checked in,
+	// so the Concurrent Requests can't Exceed it
+	Created := Database
+	TransactionError := Database.Transaction(func(Transaction *gorm.DB) error {
+		if this.CreatesVirtualMachine() {
+			if LimitError := CheckVirtualMachinesLimit(Transaction, this.OwnerId); LimitError != nil {
+				return LimitError
+			}
+		}
+		Created = Transaction.Model(&Job{}).Create(this)
+		return Created.Error
+	})
+	if TransactionError == nil {
+		this.Notify()
+	}
+	return Created, TransactionError
+}
+
+func (this *Job) Save() (*gorm.DB, error) {
+	// Saves the Current Job Object
+	Saved := Database.Save(this)
+	if Saved.Error == nil {
+		this.Notify()
+	}
+	return Saved, Saved.Error
+}
+
+func (this *Job) UpdateProgress(Progress int) error {
+	// Updates Progress of the Job in Percents, so the Customer can see how far the Operation went
+	this.Progress = Progress
+	Updated := Database.Model(&Job{}).Where("id = ?", this.ID).Update("progress", Progress)
+	if Updated.Error == nil {
+		this.Notify()
+	}
+	return Updated.Error
+}
+
+func (this *Job) DecodePayload(Payload interface{}) error {
+	// Decodes Serialized Job Payload into the Structure passed
+	return json.Unmarshal([]byte(this.Payload), Payload)
+}
+
+type Snapshot struct {
+	// Snapshot Database ORM Model, represents Checkpoint of the Virtual Machine Server State
+	// The Customer can Roll Back to
+	ID               int
+	VirtualMachineId int       `json:"VirtualMachineId" xml:"VirtualMachineId" gorm:"not null;index;"`
+	OwnerId          int       `json:"OwnerId" xml:"OwnerId" gorm:"not null;index;"`
+	Name             string    `json:"Name" xml:"Name" gorm:"type:varchar(80);not null;"`
+	Description      string    `json:"Description" xml:"Description" gorm:"type:text;default:null;"`
+	SnapshotRef      string    `json:"SnapshotRef" xml:"SnapshotRef" gorm:"<-:create;type:varchar(100);not null;"` // vSphere Managed Object ID of the Snapshot
+	Memory           bool      `json:"Memory" xml:"Memory" gorm:"not null;default:false;"`
+	Quiesce          bool      `json:"Quiesce" xml:"Quiesce" gorm:"not null;default:false;"`
+	CreatedAt        time.Time `json:"CreatedAt" xml:"CreatedAt"`
+}
+
+func NewSnapshot(VirtualMachineId int, OwnerId int, Name string, Description string, SnapshotRef string, Memory bool, Quiesce bool) *Snapshot {
+	return &Snapshot{
+		VirtualMachineId: VirtualMachineId,
+		OwnerId:          OwnerId,
+		Name:             Name,
+		Description:      Description,
+		SnapshotRef:      SnapshotRef,
+		Memory:           Memory,
+		Quiesce:          Quiesce,
+	}
+}
+
+func (this *Snapshot) Create() (*gorm.DB, error) {
+	// Creates New Snapshot Object
+	Created := Database.Model(&Snapshot{}).Create(this)
+	return Created, Created.Error
+}
+
+func (this *Snapshot) Delete() (*gorm.DB, error) {
+	// Deletes the Snapshot Object
+	Deleted := Database.Model(&Snapshot{}).Where("id = ?", this.ID).Delete(this)
+	return Deleted, Deleted.Error
+}
+
+// Deployment States
+
+const (
+	DeploymentStateRunning        = "Running"
+	DeploymentStateCompleted      = "Completed"
+	DeploymentStateRollingBack    = "RollingBack"
+	DeploymentStateRolledBack     = "RolledBack"
+	DeploymentStateRollbackFailed = "RollbackFailed"
+)
+
+type Deployment struct {
+	// Deployment Database ORM Model, Tracks Progress of the Deploy Pipeline of the Virtual Machine Server
+	// So the Failed Deploy can be either Rolled Back or Resumed from the Step it has been Interrupted on
+	ID               int
+	VirtualMachineId int       `json:"VirtualMachineId" xml:"VirtualMachineId" gorm:"not null;index;"`
+	JobId            int       `json:"JobId" xml:"JobId" gorm:"default:null;"`
+	State            string    `json:"State" xml:"State" gorm:"type:varchar(20);not null;"`
+	CurrentStep      string    `json:"CurrentStep" xml:"CurrentStep" gorm:"type:varchar(50);default:null;"`
+	CompletedSteps   int       `json:"CompletedSteps" xml:"CompletedSteps" gorm:"not null;default:0;"`
+	Configuration    string    `json:"-" xml:"-" gorm:"type:text;not null;"`     // Serialized Custom Configuration, that is being Applied
+	Context          string    `json:"-" xml:"-" gorm:"type:text;default:null;"` // Serialized Outputs of the Completed Steps
+	Error            string    `json:"Error" xml:"Error" gorm:"type:text;default:null;"`
+	CreatedAt        time.Time `json:"CreatedAt" xml:"CreatedAt"`
+	UpdatedAt        time.Time `json:"UpdatedAt" xml:"UpdatedAt"`
+}
+
+func NewDeployment(VirtualMachineId int, JobId int, Configuration string) *Deployment {
+	return &Deployment{
+		VirtualMachineId: VirtualMachineId,
+		JobId:            JobId,
+		State:            DeploymentStateRunning,
+		Configuration:    Configuration,
+	}
+}
+
+func GetUnfinishedDeployment(VirtualMachineId int) (*Deployment, bool) {
+	// Returns Deployment of the Virtual Machine, that has been Interrupted and should be Resumed
+	var UnfinishedDeployment Deployment
+	Database.Model(&Deployment{}).Where("virtual_machine_id = ? AND state IN ?", VirtualMachineId,
+		[]string{DeploymentStateRunning, DeploymentStateRollingBack}).Order("id DESC").Find(&UnfinishedDeployment)
+	return &UnfinishedDeployment, UnfinishedDeployment.ID != 0
+}
+
+func (this *Deployment) IsActive() bool {
+	// Checks, that the Job of the Deployment has not been Finished yet, so the Deployment is still going to be Continued by it
+	var Count int64
+	Database.Model(&Job{}).Where("id = ? AND state IN ?", this.JobId,
+		[]string{JobStateQueued, JobStateRunning}).Count(&Count)
+	return Count != 0
+}
+
+func (this *Deployment) Create() (*gorm.DB, error) {
+	// Creates New Deployment Object
+	Created := Database.Model(&Deployment{}).Create(this)
+	return Created, Created.Error
+}
+
+func (this *Deployment) Save() (*gorm.DB, error) {
+	// Saves the Current Deployment Object
+	Saved := Database.Save(this)
+	return Saved, Saved.Error
+}
+
+type UnmanagedVirtualMachine struct {
+	// Virtual Machine, that Exists in the vSphere, but does not have any Database Record
+	// Table is being Refreshed by the Reconciler on every Run
+	ID              int
+	Region          string    `json:"Region" xml:"Region" gorm:"type:varchar(50);not null;default:'';uniqueIndex:idx_unmanaged_virtual_machines_region_managed_object_id;"`
+	ManagedObjectId string    `json:"ManagedObjectId" xml:"ManagedObjectId" gorm:"type:varchar(50);not null;uniqueIndex:idx_unmanaged_virtual_machines_region_managed_object_id;"` // Unique within the vSphere Endpoint only
+	ItemPath        string    `json:"ItemPath" xml:"ItemPath" gorm:"type:varchar(255);not null;"`
+	PowerState      string    `json:"PowerState" xml:"PowerState" gorm:"type:varchar(20);default:null;"`
+	DetectedAt      time.Time `json:"DetectedAt" xml:"DetectedAt"`
+}
+
+// Health History of the Virtual Machines
+
+type VirtualMachineMetric struct {
+	// Health Sample of the Virtual Machine, Raw Samples (Resolution 0) are being Rolled up into the Averages
+	// of the Longer Periods, the Resolution of which is being Stored in Seconds
+	VirtualMachineId int       `json:"-" xml:"-" gorm:"primaryKey;autoIncrement:false;"`
+	Resolution       int       `json:"-" xml:"-" gorm:"primaryKey;autoIncrement:false;"`
+	CollectedAt      time.Time `json:"CollectedAt" xml:"CollectedAt" gorm:"primaryKey;"`
+	CpuUsage         float64   `json:"CpuUsage" xml:"CpuUsage" gorm:"not null;default:0;"`                 // MHz
+	MemoryActive     float64   `json:"MemoryActive" xml:"MemoryActive" gorm:"not null;default:0;"`         // MB
+	MemoryGranted    float64   `json:"MemoryGranted" xml:"MemoryGranted" gorm:"not null;default:0;"`       // MB
+	GuestMemoryUsage float64   `json:"GuestMemoryUsage" xml:"GuestMemoryUsage" gorm:"not null;default:0;"` // MB
+	StorageCommitted float64   `json:"StorageCommitted" xml:"StorageCommitted" gorm:"not null;default:0;"` // Bytes
+	PoweredOn        float64   `json:"PoweredOn" xml:"PoweredOn" gorm:"not null;default:0;"`               // Share of the Samples, the Virtual Machine has been Powered on
+	Samples          int       `json:"Samples" xml:"Samples" gorm:"not null;default:1;"`                   // Number of the Raw Samples, the Value is the Average of
+}
+
+// Columns of the Health Sample, that are being Averaged on the Rollup
+var VirtualMachineMetricColumns = []string{
+	"cpu_usage", "memory_active", "memory_granted", "guest_memory_usage", "storage_committed", "powered_on",
+}
+
+func StoreVirtualMachineMetrics(Metrics []VirtualMachineMetric) error {
+	// Stores the Raw Samples, Samples, that have already been Stored by the other Replica for the same Timestamp, are being Skipped
+	if len(Metrics) == 0 {
+		return nil
+	}
+	return Database.Clauses(clause.OnConflict{DoNothing: true}).Create(&Metrics).Error
+}
+
+func RollupVirtualMachineMetrics(Resolution time.Duration, From time.Time, To time.Time) error {
+	// Averages the Raw Samples of the Time Range into the Buckets of the Resolution,
+	// Buckets, that already Exist, are being Recomputed, so the Late Samples are being Included
+	Averages := make([]string, 0, len(VirtualMachineMetricColumns))
+	Updates := make([]string, 0, len(VirtualMachineMetricColumns)+1)
+	for _, Column := range VirtualMachineMetricColumns {
+		Averages = append(Averages, fmt.Sprintf("avg(%s)", Column))
+		Updates = append(Updates, fmt.Sprintf("%s = excluded.%s", Column, Column))
+	}
+	Updates = append(Updates, "samples = excluded.samples")
+
+	Seconds := int(Resolution.Seconds())
+	return Database.Exec(fmt.Sprintf(`INSERT INTO virtual_machine_metrics
+		(virtual_machine_id, resolution, collected_at, %s, samples)
+		SELECT virtual_machine_id, CAST(? AS bigint), to_timestamp(floor(extract(epoch FROM collected_at) / ?) * ?) AS bucket, %s, count(*)
+		FROM virtual_machine_metrics WHERE resolution = 0 AND collected_at >= ? AND collected_at < ?
+		GROUP BY virtual_machine_id, bucket
+		ON CONFLICT (virtual_machine_id, resolution, collected_at) DO UPDATE SET %s`,
+		strings.Join(VirtualMachineMetricColumns, ", "), strings.Join(Averages, ", "), strings.Join(Updates, ", ")),
+		Seconds, Seconds, Seconds, From, To).Error
+}
+
+func DeleteVirtualMachineMetrics(Resolution time.Duration, Before time.Time) (int64, error) {
+	// Deletes the Samples of the Resolution, that are Older than the Retention Allows
+	Deleted := Database.Where("resolution = ? AND collected_at < ?", int(Resolution.Seconds()), Before).Delete(&VirtualMachineMetric{})
+	return Deleted.RowsAffected, Deleted.Error
+}
+
+func GetVirtualMachineMetrics(VirtualMachineId int, Source time.Duration, Resolution time.Duration, From time.Time, To time.Time) ([]VirtualMachineMetric, error) {
+	// Returns the Series of the Virtual Machine, Samples of the Source Resolution are being Averaged into the Buckets
+	// of the Requested one (weighted by the Number of the Raw Samples), Empty Buckets are being Omitted
+	Averages := make([]string, 0, len(VirtualMachineMetricColumns))
+	for _, Column := range VirtualMachineMetricColumns {
+		Averages = append(Averages, fmt.Sprintf("sum(%s * samples) / sum(samples) AS %s", Column, Column))
+	}
+	Seconds := int(Resolution.Seconds())
+
+	var Metrics []VirtualMachineMetric
+	Selected := Database.Raw(fmt.Sprintf(`SELECT virtual_machine_id, CAST(? AS bigint) AS resolution,
+		to_timestamp(floor(extract(epoch FROM collected_at) / ?) * ?) AS collected_at, %s, CAST(sum(samples) AS bigint) AS samples
+		FROM virtual_machine_metrics WHERE virtual_machine_id = ? AND resolution = ? AND collected_at >= ? AND collected_at < ?
+		GROUP BY virtual_machine_id, 3 ORDER BY 3`, strings.Join(Averages, ", ")),
+		Seconds, Seconds, Seconds, VirtualMachineId, int(Source.Seconds()), From, To).Scan(&Metrics)
+	return Metrics, Selected.Error
+}
+
+// Alerting
+
+const (
+	AlertStatePending  = "Pending"  // Condition is being Met, but not for the Duration of the Rule yet
+	AlertStateFiring   = "Firing"   // Condition has been Met for the Duration of the Rule, Channels have been Notified
+	AlertStateResolved = "Resolved" // Condition is not being Met anymore
+)
+
+const (
+	ChannelTypeEmail   = "Email"
+	ChannelTypeWebhook = "Webhook" // Generic Webhook, that Receives the Notification as JSON
+	ChannelTypeSlack   = "Slack"   // Slack Compatible Incoming Webhook
+)
+
+type AlertRule struct {
+	// Alert Rule Database ORM Model, Fires, once the Metric of the Virtual Machine has been Compared to the Threshold Successfully
+	// for the whole Duration, Rules without the Virtual Machine Apply to every Virtual Machine of the Project
+	ID               int
+	ProjectId        int       `json:"ProjectId" xml:"ProjectId" gorm:"<-:create;not null;index;"`
+	VirtualMachineId int       `json:"VirtualMachineId" xml:"VirtualMachineId" gorm:"<-:create;default:null;index;"`
+	Name             string    `json:"Name" xml:"Name" gorm:"type:varchar(100);not null;"`
+	Metric           string    `json:"Metric" xml:"Metric" gorm:"type:varchar(50);not null;"`
+	Comparator       string    `json:"Comparator" xml:"Comparator" gorm:"type:varchar(2);not null;"`
+	Threshold        float64   `json:"Threshold" xml:"Threshold" gorm:"not null;"`
+	Duration         int       `json:"Duration" xml:"Duration" gorm:"not null;default:0;"` // Seconds
+	Enabled          bool      `json:"Enabled" xml:"Enabled" gorm:"not null;default:true;"`
+	CreatedBy        int       `json:"CreatedBy" xml:"CreatedBy" gorm:"<-:create;not null;"`
+	CreatedAt        time.Time `json:"CreatedAt" xml:"CreatedAt"`
+}
+
+func NewAlertRule(ProjectId int, VirtualMachineId int, Name string, Metric string, Comparator string, Threshold float64, Duration time.Duration, CreatedBy int) *AlertRule {
+	return &AlertRule{
+		ProjectId:        ProjectId,
+		VirtualMachineId: VirtualMachineId,
+		Name:             Name,
+		Metric:           Metric,
+		Comparator:       Comparator,
+		Threshold:        Threshold,
+		Duration:         int(Duration.Seconds()),
+		Enabled:          true,
+		CreatedBy:        CreatedBy,
+	}
+}
+
+func (this *AlertRule) Create() (*gorm.DB, error) {
+	// Creates New Alert Rule Object
+	Created := Database.Model(&AlertRule{}).Create(this)
+	return Created, Created.Error
+}
+
+func (this *AlertRule) Delete() (*gorm.DB, error) {
+	// Deletes the Alert Rule, Active Alerts of it are being Resolved Silently
+	Deleted := Database.Model(&AlertRule{}).Where("id = ?", this.ID).Delete(this)
+	if Deleted.Error != nil {
+		return Deleted, Deleted.Error
+	}
+	Resolved := Database.Model(&Alert{}).Where("rule_id = ? AND state <> ?", this.ID, AlertStateResolved).Updates(
+		map[string]interface{}{"state": AlertStateResolved, "resolved_at": time.Now()})
+	return Resolved, Resolved.Error
+}
+
+func (this *AlertRule) GetDuration() time.Duration {
+	return time.Duration(this.Duration) * time.Second
+}
+
+func GetProjectAlertRule(ProjectId int, RuleId int) (*AlertRule, bool) {
+	var Rule AlertRule
+	Selected := Database.Model(&AlertRule{}).Where("id = ? AND project_id = ?", RuleId, ProjectId).First(&Rule)
+	return &Rule, Selected.Error == nil
+}
+
+func GetProjectAlertRules(ProjectId int) ([]AlertRule, error) {
+	var Rules []AlertRule
+	Selected := Database.Model(&AlertRule{}).Where("project_id = ?", ProjectId).Order("id").Find(&Rules)
+	return Rules, Selected.Error
+}
+
+func GetEnabledAlertRules() ([]AlertRule, error) {
+	var Rules []AlertRule
+	Selected := Database.Model(&AlertRule{}).Where("enabled = ?", true).Find(&Rules)
+	return Rules, Selected.Error
+}
+
+type AlertChannel struct {
+	// Notification Channel of the Project, every Channel of the Project is being Notified about it's Alerts
+	ID        int
+	ProjectId int       `json:"ProjectId" xml:"ProjectId" gorm:"<-:create;not null;index;"`
+	Name      string    `json:"Name" xml:"Name" gorm:"type:varchar(100);not null;"`
+	Type      string    `json:"Type" xml:"Type" gorm:"type:varchar(20);not null;"`
+	Target    string    `json:"Target" xml:"Target" gorm:"type:text;not null;"` // Email Address or the URL of the Webhook
+	CreatedAt time.Time `json:"CreatedAt" xml:"CreatedAt"`
+}
+
+func NewAlertChannel(ProjectId int, Name string, Type string, Target string) *AlertChannel {
+	return &AlertChannel{
+		ProjectId: ProjectId,
+		Name:      Name,
+		Type:      Type,
+		Target:    Target,
+	}
+}
+
+func (this *AlertChannel) Create() (*gorm.DB, error) {
+	// Creates New Alert Channel Object
+	Created := Database.Model(&AlertChannel{}).Create(this)
+	return Created, Created.Error
+}
+
+func (this *AlertChannel) Delete() (*gorm.DB, error) {
+	// Deletes the Alert Channel Object
+	Deleted := Database.Model(&AlertChannel{}).Where("id = ?", this.ID).Delete(this)
+	return Deleted, Deleted.Error
+}
+
+func GetProjectAlertChannel(ProjectId int, ChannelId int) (*AlertChannel, bool) {
+	var Channel AlertChannel
+	Selected := Database.Model(&AlertChannel{}).Where("id = ? AND project_id = ?", ChannelId, ProjectId).First(&Channel)
+	return &Channel, Selected.Error == nil
+}
+
+func GetProjectAlertChannels(ProjectId int) ([]AlertChannel, error) {
+	var Channels []AlertChannel
+	Selected := Database.Model(&AlertChannel{}).Where("project_id = ?", ProjectId).Order("id").Find(&Channels)
+	return Channels, Selected.Error
+}
+
+type Alert struct {
+	// Alert Database ORM Model, the State of the Rule for the Virtual Machine
+	// Only one Alert of the Rule and the Virtual Machine can be Active (not Resolved) at the time, so the Replicas,
+	// that Evaluate the Rules Concurrently, do not Notify the Channels twice
+	ID               int
+	RuleId           int        `json:"RuleId" xml:"RuleId" gorm:"<-:create;not null;index;"`
+	VirtualMachineId int        `json:"VirtualMachineId" xml:"VirtualMachineId" gorm:"<-:create;not null;index;"`
+	State            string     `json:"State" xml:"State" gorm:"type:varchar(20);not null;"`
+	Value            float64    `json:"Value" xml:"Value" gorm:"not null;default:0;"` // Value of the Metric on the Latest Transition
+	StartedAt        time.Time  `json:"StartedAt" xml:"StartedAt" gorm:"not null;"`   // Time, the Condition has been Met first
+	FiredAt          *time.Time `json:"FiredAt" xml:"FiredAt" gorm:"default:null;"`
+	ResolvedAt       *time.Time `json:"ResolvedAt" xml:"ResolvedAt" gorm:"default:null;"`
+}
+
+func NewAlert(RuleId int, VirtualMachineId int, State string, Value float64, StartedAt time.Time) *Alert {
+	return &Alert{
+		RuleId:           RuleId,
+		VirtualMachineId: VirtualMachineId,
+		State:            State,
+		Value:            Value,
+		StartedAt:        StartedAt,
+	}
+}
+
+func (this *Alert) Create() bool {
+	// Creates the Alert, Returns false, if the Active Alert of the Rule and the Virtual Machine Exists already
+	Created := Database.Clauses(clause.OnConflict{DoNothing: true}).Create(this)
+	return Created.Error == nil && Created.RowsAffected == 1
+}
+
+func (this *Alert) Transit(From string, To string, Value float64, At time.Time) bool {
+	// Moves the Alert from one State to the other, Returns false, if it has been Moved already (e.g by the other Replica)
+	Updates := map[string]interface{}{"state": To, "value": Value}
+	switch To {
+	case AlertStateFiring:
+		Updates["fired_at"] = At
+	case AlertStateResolved:
+		Updates["resolved_at"] = At
+	}
+	Transited := Database.Model(&Alert{}).Where("id = ? AND state = ?", this.ID, From).Updates(Updates)
+	if Transited.Error != nil || Transited.RowsAffected != 1 {
+		return false
+	}
+	this.State, this.Value = To, Value
+	return true
+}
+
+func (this *Alert) Discard() bool {
+	// Deletes the Pending Alert, the Condition of which is not being Met anymore before it has Fired
+	Deleted := Database.Where("id = ? AND state = ?", this.ID, AlertStatePending).Delete(&Alert{})
+	return Deleted.Error == nil && Deleted.RowsAffected == 1
+}
+
+func GetActiveAlerts() ([]Alert, error) {
+	var Alerts []Alert
+	Selected := Database.Model(&Alert{}).Where("state <> ?", AlertStateResolved).Find(&Alerts)
+	return Alerts, Selected.Error
+}
+
+func GetProjectAlerts(ProjectId int, State string, Limit int) ([]Alert, error) {
+	// Returns the Latest Alerts of the Rules of the Project, Alerts of every State are being Returned, if the State is Empty
+	var Alerts []Alert
+	Query := Database.Model(&Alert{}).Joins("JOIN alert_rules ON alert_rules.id = alerts.rule_id").Where(
+		"alert_rules.project_id = ?", ProjectId)
+	if len(State) != 0 {
+		Query = Query.Where("alerts.state = ?", State)
+	}
+	Selected := Query.Order("alerts.started_at DESC").Limit(Limit).Find(&Alerts)
+	return Alerts, Selected.Error
+}
+
+// Webhooks
+
+const (
+	DeliveryStatePending   = "Pending"   // Waiting for the next Attempt
+	DeliveryStateSucceeded = "Succeeded" // Endpoint has Responded with the 2xx Status
+	DeliveryStateFailed    = "Failed"    // Every Attempt has been Failed
+)
+
+type Webhook struct {
+	// Webhook Database ORM Model, Endpoint of the Customer, that Receives the Lifecycle Events of the Virtual Machines
+	// of the Projects, the Customer is Member of
+	ID         int
+	OwnerId    int       `json:"OwnerId" xml:"OwnerId" gorm:"<-:create;not null;index;"`
+	URL        string    `json:"URL" xml:"URL" gorm:"type:text;not null;"`
+	Secret     string    `json:"-" xml:"-" gorm:"type:varchar(100);not null;"`           // Key of the HMAC Signatures, is being Shown to the Customer only once
+	EventTypes string    `json:"EventTypes" xml:"EventTypes" gorm:"type:text;not null;"` // Comma Separated, Empty one stands for every Event
+	CreatedAt  time.Time `json:"CreatedAt" xml:"CreatedAt"`
+}
+
+func NewWebhook(OwnerId int, URL string, Secret string, EventTypes []string) *Webhook {
+	return &Webhook{
+		OwnerId:    OwnerId,
+		URL:        URL,
+		Secret:     Secret,
+		EventTypes: strings.Join(EventTypes, ","),
+	}
+}
+
+func (this *Webhook) Create() (*gorm.DB, error) {
+	// Creates New Webhook Object
+	Created := Database.Model(&Webhook{}).Create(this)
+	return Created, Created.Error
+}
+
+func (this *Webhook) Delete() (*gorm.DB, error) {
+	// Deletes the Webhook along with it's Deliveries
+	Deleted := Database.Model(&Webhook{}).Where("id = ?", this.ID).Delete(this)
+	return Deleted, Deleted.Error
+}
+
+func (this *Webhook) Accepts(EventType string) bool {
+	if len(this.EventTypes) == 0 {
+		return true
+	}
+	for _, Accepted := range strings.Split(this.EventTypes, ",") {
+		if Accepted == EventType {
+			return true
+		}
+	}
+	return false
+}
+
+func GetCustomerWebhook(OwnerId int, WebhookId int) (*Webhook, bool) {
+	var CustomerWebhook Webhook
+	Selected := Database.Model(&Webhook{}).Where("id = ? AND owner_id = ?", WebhookId, OwnerId).First(&CustomerWebhook)
+	return &CustomerWebhook, Selected.Error == nil
+}
+
+func GetCustomerWebhooks(OwnerId int) ([]Webhook, error) {
+	var Webhooks []Webhook
+	Selected := Database.Model(&Webhook{}).Where("owner_id = ?", OwnerId).Order("id").Find(&Webhooks)
+	return Webhooks, Selected.Error
+}
+
+func GetWebhooks(WebhookIds []int) ([]Webhook, error) {
+	var Webhooks []Webhook
+	Selected := Database.Model(&Webhook{}).Where("id IN (?)", WebhookIds).Find(&Webhooks)
+	return Webhooks, Selected.Error
+}
+
+func GetVirtualMachineWebhooks(VirtualMachine VirtualMachine) ([]Webhook, error) {
+	// Returns Webhooks of the Customers, the Virtual Machine is Accessible by, and of it's Owner
+	var Webhooks []Webhook
+	Selected := Database.Model(&Webhook{}).Where("owner_id = ? OR owner_id IN (?)", VirtualMachine.OwnerId,
+		Database.Model(&Membership{}).Select("memberships.customer_id").Joins(
+			"JOIN projects ON projects.organization_id = memberships.organization_id").Joins(
+			"JOIN organizations ON organizations.id = memberships.organization_id").Joins(
+			"JOIN customers ON customers.id = memberships.customer_id").Where(
+			"projects.id = ? AND (organizations.require_mfa = false OR customers.mfa_enabled = true)", VirtualMachine.ProjectId),
+	).Order("id").Find(&Webhooks)
+	return Webhooks, Selected.Error
+}
+
+type WebhookDelivery struct {
+	// Webhook Delivery Database ORM Model, the Event, that is being Sent to the Webhook until it Succeeds or Runs out of the Attempts
+	// Replicas Claim the Due Deliveries by Moving the Next Attempt forward, so every Attempt is being Made only once
+	ID            int
+	WebhookId     int        `json:"WebhookId" xml:"WebhookId" gorm:"<-:create;not null;index;"`
+	EventId       string     `json:"EventId" xml:"EventId" gorm:"<-:create;type:varchar(36);not null;index;"` // Same for the Redeliveries of the Event
+	EventType     string     `json:"EventType" xml:"EventType" gorm:"<-:create;type:varchar(50);not null;"`
+	Payload       string     `json:"Payload" xml:"Payload" gorm:"<-:create;type:text;not null;"`
+	State         string     `json:"State" xml:"State" gorm:"type:varchar(20);not null;"`
+	Attempts      int        `json:"Attempts" xml:"Attempts" gorm:"not null;default:0;"`
+	NextAttemptAt time.Time  `json:"NextAttemptAt" xml:"NextAttemptAt" gorm:"not null;"`
+	StatusCode    int        `json:"StatusCode" xml:"StatusCode" gorm:"not null;default:0;"` // Status of the Latest Attempt, 0 if the Endpoint has not Responded
+	Error         string     `json:"Error" xml:"Error" gorm:"type:text;default:null;"`
+	DeliveredAt   *time.Time `json:"DeliveredAt" xml:"DeliveredAt" gorm:"default:null;"`
+	CreatedAt     time.Time  `json:"CreatedAt" xml:"CreatedAt"`
+	UpdatedAt     time.Time  `json:"UpdatedAt" xml:"UpdatedAt"`
+}
+
+func NewWebhookDelivery(WebhookId int, EventId string, EventType string, Payload string) *WebhookDelivery {
+	return &WebhookDelivery{
+		WebhookId:     WebhookId,
+		EventId:       EventId,
+		EventType:     EventType,
+		Payload:       Payload,
+		State:         DeliveryStatePending,
+		NextAttemptAt: time.Now(),
+	}
+}
+
+func (this *WebhookDelivery) Create() (*gorm.DB, error) {
+	// Creates New Webhook Delivery Object
+	Created := Database.Model(&WebhookDelivery{}).Create(this)
+	return Created, Created.Error
+}
+
+func (this *WebhookDelivery) Save() (*gorm.DB, error) {
+	// Saves the Result of the Attempt
+	Saved := Database.Save(this)
+	return Saved, Saved.Error
+}
+
+func ClaimWebhookDeliveries(Limit int, Lease time.Duration) ([]WebhookDelivery, error) {
+	// Returns Due Pending Deliveries, their Next Attempt is being Moved forward by the Lease,
+	// so the other Replicas do not Pick them up, while the Current one Sends them
+	var Deliveries []WebhookDelivery
+	Now := time.Now()
+	Claimed := Database.Raw(`UPDATE webhook_deliveries SET next_attempt_at = ?, updated_at = ? WHERE id IN (
+		SELECT id FROM webhook_deliveries WHERE state = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED
+		) RETURNING *`, Now.Add(Lease), Now, DeliveryStatePending, Now, Limit).Scan(&Deliveries)
+	return Deliveries, Claimed.Error
+}
+
+func GetWebhookDeliveries(WebhookId int, Limit int) ([]WebhookDelivery, error) {
+	// Returns the Latest Deliveries of the Webhook
+	var Deliveries []WebhookDelivery
+	Selected := Database.Model(&WebhookDelivery{}).Where("webhook_id = ?", WebhookId).Order("id DESC").Limit(Limit).Find(&Deliveries)
+	return Deliveries, Selected.Error
+}
+
+func GetCustomerWebhookDelivery(OwnerId int, DeliveryId int) (*WebhookDelivery, bool) {
+	var Delivery WebhookDelivery
+	Selected := Database.Model(&WebhookDelivery{}).Joins("JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id").Where(
+		"webhook_deliveries.id = ? AND webhooks.owner_id = ?", DeliveryId, OwnerId).First(&Delivery)
+	return &Delivery, Selected.Error == nil
+}
+
+// Organizations, Projects and Memberships
+
+const (
+	RoleOwner    = "Owner"    // Manages the Organization, it's Projects and Members, including other Owners
+	RoleAdmin    = "Admin"    // Manages Projects, Members and Virtual Machines of the Organization
+	RoleOperator = "Operator" // Operates Existing Virtual Machines (Power, Deploy, Resize, Snapshots etc...)
+	RoleViewer   = "Viewer"   // Has Read Only Access to the Virtual Machines
+)
+
+const DefaultProjectName = "Default"
+
+type Organization struct {
+	// Organization Database ORM Model, Group of the Customers, that Share Infrastructure
+	ID        int
+	Name      string    `json:"Name" xml:"Name" gorm:"type:varchar(100);not null;"`
+	CreatedBy int       `json:"CreatedBy" xml:"CreatedBy" gorm:"<-:create;not null;index;"`
+	CreatedAt time.Time `json:"CreatedAt" xml:"CreatedAt"`
+
+	// Members are not being Allowed to Access the Organization, until they Enable the Second Factor
+	RequireMfa bool `json:"RequireMfa" xml:"RequireMfa" gorm:"not null;default:false;"`
+}
+
+type Project struct {
+	// Project Database ORM Model, Virtual Machines of the Organization are being Grouped by the Projects
+	ID             int
+	OrganizationId int       `json:"OrganizationId" xml:"OrganizationId" gorm:"<-:create;not null;index;"`
+	Name           string    `json:"Name" xml:"Name" gorm:"type:varchar(100);not null;"`
+	CreatedAt      time.Time `json:"CreatedAt" xml:"CreatedAt"`
+}
+
+type Membership struct {
+	// Membership Database ORM Model, Role of the Customer in the Organization
+	// Role is being Applied to every Project of the Organization
+	ID             int
+	OrganizationId int       `json:"OrganizationId" xml:"OrganizationId" gorm:"<-:create;not null;uniqueIndex:idx_memberships_organization_customer;"`
+	CustomerId     int       `json:"CustomerId" xml:"CustomerId" gorm:"<-:create;not null;uniqueIndex:idx_memberships_organization_customer;index;"`
+	Role           string    `json:"Role" xml:"Role" gorm:"type:varchar(20);not null;"`
+	CreatedAt      time.Time `json:"CreatedAt" xml:"CreatedAt"`
+}
+
+func IsValidRole(Role string) bool {
+	switch Role {
+	case RoleOwner, RoleAdmin, RoleOperator, RoleViewer:
+		return true
+	default:
+		return false
+	}
+}
+
+func NewOrganization(Name string, CreatedBy int) *Organization {
+	return &Organization{
+		Name:      Name,
+		CreatedBy: CreatedBy,
+	}
+}
+
+func NewProject(OrganizationId int, Name string) *Project {
+	return &Project{
+		OrganizationId: OrganizationId,
+		Name:           Name,
+	}
+}
+
+func NewMembership(OrganizationId int, CustomerId int, Role string) *Membership {
+	return &Membership{
+		OrganizationId: OrganizationId,
+		CustomerId:     CustomerId,
+		Role:           Role,
+	}
+}
+
+func CreateOrganization(Transaction *gorm.DB, Name string, OwnerId int) (*Organization, error) {
+	// Creates New Organization with the Default Project, the Customer becomes the Owner of
+	CreatedOrganization := NewOrganization(Name, OwnerId)
+	if Created := Transaction.Model(&Organization{}).Create(CreatedOrganization); Created.Error != nil {
+		return nil, Created.Error
+	}
+	if Created := Transaction.Model(&Membership{}).Create(
+		NewMembership(CreatedOrganization.ID, OwnerId, RoleOwner)); Created.Error != nil {
+		return nil, Created.Error
+	}
+	if Created := Transaction.Model(&Project{}).Create(
+		NewProject(CreatedOrganization.ID, DefaultProjectName)); Created.Error != nil {
+		return nil, Created.Error
+	}
+	return CreatedOrganization, nil
+}
+
+func (this *Project) Create() (*gorm.DB, error) {
+	// Creates New Project Object
+	Created := Database.Model(&Project{}).Create(this)
+	return Created, Created.Error
+}
+
+func (this *Membership) Create() (*gorm.DB, error) {
+	// Creates New Membership Object
+	Created := Database.Model(&Membership{}).Create(this)
+	return Created, Created.Error
+}
+
+func (this *Membership) Save() (*gorm.DB, error) {
+	// Saves the Current Membership Object
+	Saved := Database.Save(this)
+	return Saved, Saved.Error
+}
+
+func (this *Membership) Delete() (*gorm.DB, error) {
+	// Deletes the Membership Object
+	Deleted := Database.Model(&Membership{}).Where("id = ?", this.ID).Delete(this)
+	return Deleted, Deleted.Error
+}
+
+func GetMembership(OrganizationId int, CustomerId int) (*Membership, bool) {
+	// Returns Membership of the Customer in the Organization
+	var FoundMembership Membership
+	Database.Model(&Membership{}).Where(
+		"organization_id = ? AND customer_id = ?", OrganizationId, CustomerId).Find(&FoundMembership)
+	return &FoundMembership, FoundMembership.ID != 0
+}
+
+func GetProjectMembership(ProjectId int, CustomerId int) (*Membership, bool) {
+	// Returns Membership of the Customer in the Organization, the Project Belongs to
+	var FoundMembership Membership
+	Database.Model(&Membership{}).Joins(
+		"JOIN projects ON projects.organization_id = memberships.organization_id").Where(
+		"projects.id = ? AND memberships.customer_id = ?", ProjectId, CustomerId).Find(&FoundMembership)
+	return &FoundMembership, FoundMembership.ID != 0
+}
+
+func GetOrganization(OrganizationId int) (*Organization, bool) {
+	// Returns the Organization by it's ID
+	var FoundOrganization Organization
+	Database.Model(&Organization{}).Where("id = ?", OrganizationId).Find(&FoundOrganization)
+	return &FoundOrganization, FoundOrganization.ID != 0
+}
+
+func (this *Organization) SetRequireMfa(RequireMfa bool) error {
+	// Enables or Disables the Second Factor Requirement for the Members of the Organization
+	Updated := Database.Model(&Organization{}).Where("id = ?", this.ID).Update("require_mfa", RequireMfa)
+	if Updated.Error == nil {
+		this.RequireMfa = RequireMfa
+	}
+	return Updated.Error
+}
+
+func CountOrganizationOwners(OrganizationId int) int64 {
+	// Returns Number of the Owners of the Organization
+	var OwnersNumber int64
+	Database.Model(&Membership{}).Where("organization_id = ? AND role = ?", OrganizationId, RoleOwner).Count(&OwnersNumber)
+	return OwnersNumber
+}
+
+type RefreshToken struct {
+	// Refresh Token Database ORM Model, Token itself is not being Stored, only it's SHA-256 Hash
+	// Tokens are being Rotated on every Refresh, Rotated Tokens of the same Session share the Family ID,
+	// so the whole Session can be Revoked, once the Rotated Token is being Reused
+	ID         int
+	CustomerId int        `json:"CustomerId" xml:"CustomerId" gorm:"<-:create;not null;index;"`
+	FamilyId   string     `json:"FamilyId" xml:"FamilyId" gorm:"<-:create;type:varchar(36);not null;index;"`
+	TokenHash  string     `json:"-" xml:"-" gorm:"<-:create;type:varchar(64);not null;unique;"`
+	ExpiresAt  time.Time  `json:"ExpiresAt" xml:"ExpiresAt" gorm:"not null;"`
+	RevokedAt  *time.Time `json:"RevokedAt" xml:"RevokedAt" gorm:"default:null;"`
+	CreatedAt  time.Time  `json:"CreatedAt" xml:"CreatedAt"`
+}
+
+func NewRefreshToken(CustomerId int, FamilyId string, TokenHash string, ExpiresAt time.Time) *RefreshToken {
+	return &RefreshToken{
+		CustomerId: CustomerId,
+		FamilyId:   FamilyId,
+		TokenHash:  TokenHash,
+		ExpiresAt:  ExpiresAt,
+	}
+}
+
+func (this *RefreshToken) Create() (*gorm.DB, error) {
+	// Creates New Refresh Token Object
+	Created := Database.Model(&RefreshToken{}).Create(this)
+	return Created, Created.Error
+}
+
+func (this *RefreshToken) IsActive() bool {
+	// Checks, that the Refresh Token has been neither Revoked nor Expired
+	return this.RevokedAt == nil && time.Now().Before(this.ExpiresAt)
+}
+
+func (this *RefreshToken) Revoke() bool {
+	// Revokes the Refresh Token, Returns false, if it has been Revoked already, (e.g by the Concurrent Refresh)
+	Revoked := Database.Model(&RefreshToken{}).Where(
+		"id = ? AND revoked_at IS NULL", this.ID).Update("revoked_at", time.Now())
+	return Revoked.Error == nil && Revoked.RowsAffected == 1
+}
+
+func RevokeRefreshTokenFamily(FamilyId string) error {
+	// Revokes every Refresh Token of the Session
+	Revoked := Database.Model(&RefreshToken{}).Where(
+		"family_id = ? AND revoked_at IS NULL", FamilyId).Update("revoked_at", time.Now())
+	return Revoked.Error
+}
+
+func RevokeCustomerRefreshTokens(CustomerId int) error {
+	// Revokes every Refresh Token of the Customer, so all the Sessions have to Login again
+	Revoked := Database.Model(&RefreshToken{}).Where(
+		"customer_id = ? AND revoked_at IS NULL", CustomerId).Update("revoked_at", time.Now())
+	return Revoked.Error
+}
+
+type ApiKey struct {
+	// Personal API Key Database ORM Model, Authorizes Automation Clients on behalf of the Customer
+	// Key itself is being Shown only once, on Creation, only it's SHA-256 Hash and Prefix are being Stored
+	ID         int
+	CustomerId int        `json:"CustomerId" xml:"CustomerId" gorm:"<-:create;not null;index;"`
+	Name       string     `json:"Name" xml:"Name" gorm:"type:varchar(100);not null;"`
+	Prefix     string     `json:"Prefix" xml:"Prefix" gorm:"<-:create;type:varchar(12);not null;"` // First Characters of the Key, so the Customer can tell the Keys apart
+	KeyHash    string     `json:"-" xml:"-" gorm:"<-:create;type:varchar(64);not null;unique;"`
+	Scopes     string     `json:"Scopes" xml:"Scopes" gorm:"type:varchar(255);not null;"` // Comma Separated Scopes, e.g `vm:read,vm:write`
+	ExpiresAt  time.Time  `json:"ExpiresAt" xml:"ExpiresAt" gorm:"not null;"`
+	RevokedAt  *time.Time `json:"RevokedAt" xml:"RevokedAt" gorm:"default:null;"`
+	LastUsedAt *time.Time `json:"LastUsedAt" xml:"LastUsedAt" gorm:"default:null;"`
+	CreatedAt  time.Time  `json:"CreatedAt" xml:"CreatedAt"`
+}
+
+func NewApiKey(CustomerId int, Name string, Prefix string, KeyHash string, Scopes []string, ExpiresAt time.Time) *ApiKey {
+	return &ApiKey{
+		CustomerId: CustomerId,
+		Name:       Name,
+		Prefix:     Prefix,
+		KeyHash:    KeyHash,
+		Scopes:     strings.Join(Scopes, ","),
+		ExpiresAt:  ExpiresAt,
+	}
+}
+
+func (this *ApiKey) Create() (*gorm.DB, error) {
+	// Creates New API Key Object
+	Created := Database.Model(&ApiKey{}).Create(this)
+	return Created, Created.Error
+}
+
+func (this *ApiKey) IsActive() bool {
+	// Checks, that the API Key has been neither Revoked nor Expired
+	return this.RevokedAt == nil && time.Now().Before(this.ExpiresAt)
+}
+
+func (this *ApiKey) GetScopes() []string {
+	if len(this.Scopes) == 0 {
+		return []string{}
+	}
+	return strings.Split(this.Scopes, ",")
+}
+
+func (this *ApiKey) Revoke() error {
+	// Revokes the API Key, so it can't be Used anymore
+	Revoked := Database.Model(&ApiKey{}).Where(
+		"id = ? AND revoked_at IS NULL", this.ID).Update("revoked_at", time.Now())
+	return Revoked.Error
+}
+
+func (this *ApiKey) Touch() error {
+	// Records the Time, the API Key has been Used Last
+	Touched := Database.Model(&ApiKey{}).Where("id = ?", this.ID).Update("last_used_at", time.Now())
+	return Touched.Error
+}
+
+const (
+	TokenPurposePasswordReset     = "PasswordReset"
+	TokenPurposeEmailVerification = "EmailVerification"
+)
+
+type CustomerToken struct {
+	// Single Use Token Database ORM Model, that is being Sent to the Customer's Email
+	// to Confirm the Email Address or Reset the Forgotten Password, only it's SHA-256 Hash is being Stored
+	ID         int
+	CustomerId int        `json:"CustomerId" xml:"CustomerId" gorm:"<-:create;not null;index;"`
+	Purpose    string     `json:"Purpose" xml:"Purpose" gorm:"<-:create;type:varchar(30);not null;"`
+	TokenHash  string     `json:"-" xml:"-" gorm:"<-:create;type:varchar(64);not null;unique;"`
+	ExpiresAt  time.Time  `json:"ExpiresAt" xml:"ExpiresAt" gorm:"not null;"`
+	UsedAt     *time.Time `json:"UsedAt" xml:"UsedAt" gorm:"default:null;"`
+	CreatedAt  time.Time  `json:"CreatedAt" xml:"CreatedAt"`
+}
+
+func NewCustomerToken(CustomerId int, Purpose string, TokenHash string, ExpiresAt time.Time) *CustomerToken {
+	return &CustomerToken{
+		CustomerId: CustomerId,
+		Purpose:    Purpose,
+		TokenHash:  TokenHash,
+		ExpiresAt:  ExpiresAt,
+	}
+}
+
+func (this *CustomerToken) Create() (*gorm.DB, error) {
+	// Creates New Token Object, Tokens of the same Purpose, Issued before, can't be Used anymore
+	TransactionError := Database.Transaction(func(Transaction *gorm.DB) error {
+		if Expired := Transaction.Model(&CustomerToken{}).Where(
+			"customer_id = ? AND purpose = ? AND used_at IS NULL", this.CustomerId, this.Purpose).Update(
+			"used_at", time.Now()); Expired.Error != nil {
+			return Expired.Error
+		}
+		return Transaction.Model(&CustomerToken{}).Create(this).Error
+	})
+	return Database, TransactionError
+}
+
+func (this *CustomerToken) IsActive() bool {
+	// Checks, that the Token has been neither Used nor Expired
+	return this.UsedAt == nil && time.Now().Before(this.ExpiresAt)
+}
+
+func (this *CustomerToken) Use() bool {
+	// Marks the Token as Used, Returns false, if it has been Used already by the Concurrent Request
+	Used := Database.Model(&CustomerToken{}).Where(
+		"id = ? AND used_at IS NULL", this.ID).Update("used_at", time.Now())
+	return Used.Error == nil && Used.RowsAffected == 1
+}
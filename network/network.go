@@ -3,14 +3,14 @@ package network
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"os"
+	"strings"
 	"time"
 
-	"reflect"
 	"regexp"
 
-	"strings"
-
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25"
@@ -18,9 +18,6 @@ import (
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-
-	"golang.org/x/exp/maps"
-	"golang.org/x/exp/slices"
 )
 
 var (
@@ -43,7 +40,6 @@ func init() {
 	InitializeProductionLogger()
 }
 
-
 type VirtualMachineIPAddress struct {
 	// Struct, Representing Virtual Machine IP Address
 	Options  types.BaseCustomizationOptions
@@ -53,45 +49,53 @@ type VirtualMachineIPAddress struct {
 	Hostname string `json:"Hostname,omitempty"`
 }
 
-func (this *VirtualMachineIPAddress) GetValidationRegexPatterns() map[string]string {
-	// returns Slice of the Regexes
-	return map[string]string{}
-}
+var (
+	ErrInvalidIPAddress = errors.New("Invalid IP Address")
+	ErrInvalidNetmask   = errors.New("Invalid Netmask")
+	ErrInvalidGateway   = errors.New("Invalid Gateway")
+	ErrInvalidHostname  = errors.New("Invalid Hostname")
+)
 
-func (this *VirtualMachineIPAddress) ValidateCredentials() VirtualMachineIPAddress {
+var (
+	HostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`) // Single Label of the RFC 1123
+)
 
-	// Checks if the Input has appropriate format and has valid values
-	var InvalidValues []string // array of the Invalid Value Field names
-	FieldValueGenerators := map[string]func() types.BaseCustomizationIpGenerator{
+func (this *VirtualMachineIPAddress) Validate() error {
+	// Checks, that the IP Address, Netmask and Gateway are valid IPv4 Addresses and the Gateway is within the Subnet
+	// Netmask and Gateway are Optional, Hostname is Required, since the Guest Customization can't go without it
 
-		"Gateway": func() types.BaseCustomizationIpGenerator {
-			return &types.CustomizationCustomIpGenerator{}
-		},
-		"Netmask": func() types.BaseCustomizationIpGenerator {
-			return &types.CustomizationDhcpIpGenerator{}
-		},
-		"Hostname": func() types.BaseCustomizationIpGenerator {
-			return &types.CustomizationCustomIpGenerator{}
-		},
+	IPAddress := net.ParseIP(this.IPv4).To4()
+	if IPAddress == nil {
+		return fmt.Errorf("%w `%s`", ErrInvalidIPAddress, this.IPv4)
 	}
 
-	//  Validating Inputs
-	Patterns := this.GetValidationRegexPatterns()
-	for Index := 0; Index < reflect.TypeOf(this).NumField(); Index++ {
-		if Matches, MatchError := regexp.MatchString(Patterns[strings.ToLower(reflect.ValueOf(this).Type().Field(Index).Name)],
-			reflect.ValueOf(this).Field(Index).String()); MatchError != nil || Matches != true {
-			InvalidValues = append(InvalidValues, reflect.ValueOf(this).Type().Field(Index).Name)
+	var Netmask net.IPMask
+	if len(this.Netmask) != 0 {
+		ParsedNetmask := net.ParseIP(this.Netmask).To4()
+		if ParsedNetmask == nil {
+			return fmt.Errorf("%w `%s`", ErrInvalidNetmask, this.Netmask)
+		}
+		// Non Contiguous Masks (e.g 255.0.255.0) have Zero Size
+		if Ones, Bits := net.IPMask(ParsedNetmask).Size(); Ones == 0 || Bits == 0 {
+			return fmt.Errorf("%w `%s`", ErrInvalidNetmask, this.Netmask)
 		}
+		Netmask = net.IPMask(ParsedNetmask)
 	}
 
-	// Generating new Values if Some of the Are Empty
-	for _, Field := range InvalidValues {
-		if slices.Contains(maps.Keys(FieldValueGenerators), strings.ToTitle(Field)) {
-			GeneratedValue := FieldValueGenerators[Field]()
-			reflect.ValueOf(this).FieldByName(Field).Set(reflect.ValueOf(GeneratedValue))
+	if len(this.Gateway) != 0 {
+		Gateway := net.ParseIP(this.Gateway).To4()
+		if Gateway == nil || Gateway.Equal(IPAddress) {
+			return fmt.Errorf("%w `%s`", ErrInvalidGateway, this.Gateway)
+		}
+		if Netmask != nil && !IPAddress.Mask(Netmask).Equal(Gateway.Mask(Netmask)) {
+			return fmt.Errorf("%w `%s`, it is not within the Subnet of the IP Address", ErrInvalidGateway, this.Gateway)
 		}
 	}
-	return *this
+
+	if !HostnamePattern.MatchString(this.Hostname) {
+		return fmt.Errorf("%w `%s`", ErrInvalidHostname, this.Hostname)
+	}
+	return nil
 }
 
 func NewVirtualMachineIPAddress(IPv4 string, Netmask string, Gateway string, Hostname string) *VirtualMachineIPAddress {
@@ -113,14 +117,20 @@ func NewVirtualMachinePublicNetworkManager() *VirtualMachinePublicNetworkManager
 
 func (this *VirtualMachinePublicNetworkManager) SetupPublicNetwork(IPCredentials VirtualMachineIPAddress) (*types.CustomizationSpec, error) {
 
-	IPCredentials = IPCredentials.ValidateCredentials()
+	if ValidationError := IPCredentials.Validate(); ValidationError != nil {
+		return nil, ValidationError
+	}
+	var Gateways []string
+	if len(IPCredentials.Gateway) != 0 {
+		Gateways = []string{IPCredentials.Gateway}
+	}
 	// Setting up Customized IP Credentials for the Virtual Machine
 	CustomizedIP := types.CustomizationAdapterMapping{
 		Adapter: types.CustomizationIPSettings{
 
 			Ip:         &types.CustomizationFixedIp{IpAddress: IPCredentials.IPv4}, // Setting UP IP Address
 			SubnetMask: IPCredentials.Netmask,                                      // Setting UP Subnet Mask
-			Gateway:    []string{IPCredentials.Gateway},                            // Setting up Gateway
+			Gateway:    Gateways,                                                   // Setting up Gateway
 			IpV6Spec: &types.CustomizationIPSettingsIpV6AddressSpec{
 				Ip: []types.BaseCustomizationIpV6Generator{
 					&types.CustomizationAutoIpV6Generator{}},
@@ -137,6 +147,30 @@ func (this *VirtualMachinePublicNetworkManager) SetupPublicNetwork(IPCredentials
 	return CustomizedIPSettings, nil
 }
 
+func (this *VirtualMachinePublicNetworkManager) SetupDynamicNetwork(Hostname string) (*types.CustomizationSpec, error) {
+	// Returns Customization, that Releases the Fixed IP Address of the Virtual Machine, so it Receives the Address from the DHCP
+	// Domain of the Hostname is being Dropped, since the Customization Accepts the Short Name only
+
+	Hostname = strings.SplitN(Hostname, ".", 2)[0]
+	if len(Hostname) == 0 {
+		return nil, fmt.Errorf("%w `%s`", ErrInvalidHostname, Hostname)
+	}
+	DynamicIP := types.CustomizationAdapterMapping{
+		Adapter: types.CustomizationIPSettings{
+			Ip: &types.CustomizationDhcpIpGenerator{},
+			IpV6Spec: &types.CustomizationIPSettingsIpV6AddressSpec{
+				Ip: []types.BaseCustomizationIpV6Generator{
+					&types.CustomizationAutoIpV6Generator{}},
+			},
+		},
+	}
+	return &types.CustomizationSpec{
+		NicSettingMap: []types.CustomizationAdapterMapping{DynamicIP},
+		Identity: &types.CustomizationLinuxPrep{
+			HostName: &types.CustomizationFixedName{Name: Hostname},
+		}}, nil
+}
+
 func (this *VirtualMachinePublicNetworkManager) ConnectVirtualMachineToNetwork(Network *object.Network, VirtualMachine *object.VirtualMachine) {
 	// Connects Virtual Machine Server to the Network specified
 }
@@ -0,0 +1,508 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-redis/redis"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the OpenID Connect Client, that Authenticates Customers with the External Identity Provider
+// Authorization Code Flow with PKCE is being used, ID Tokens are being Verified with the Keys of the Provider (JWKS)
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	DefaultScopes   = []string{"openid", "email", "profile"}
+	RequestTimeout  = 10 * time.Second
+	ClockSkew       = time.Minute // Tolerated Clock Difference between the Application and the Provider
+	KeysRefreshRate = time.Minute // Keys are not being Refetched more often, than that, on Unknown Key ID
+)
+
+var (
+	ErrNotConfigured    = errors.New("Single Sign-On is not Configured")
+	ErrInvalidIdToken   = errors.New("Invalid ID Token")
+	ErrUnknownKey       = errors.New("ID Token is Signed with Unknown Key")
+	ErrInvalidDiscovery = errors.New("Invalid Discovery Document of the Provider")
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("OidcLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+type Config struct {
+	// Client Registration of the Application at the Identity Provider
+	IssuerURL    string
+	ClientId     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+func NewConfig(IssuerURL string, ClientId string, ClientSecret string, RedirectURL string, Scopes []string) *Config {
+	if len(Scopes) == 0 {
+		Scopes = DefaultScopes
+	}
+	return &Config{
+		IssuerURL:    strings.TrimSuffix(IssuerURL, "/"),
+		ClientId:     ClientId,
+		ClientSecret: ClientSecret,
+		RedirectURL:  RedirectURL,
+		Scopes:       Scopes,
+	}
+}
+
+func (this *Config) IsConfigured() bool {
+	return len(this.IssuerURL) != 0 && len(this.ClientId) != 0 && len(this.RedirectURL) != 0
+}
+
+type Discovery struct {
+	// OpenID Provider Metadata, Published at the `/.well-known/openid-configuration`
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type TokenResponse struct {
+	// Response of the Token Endpoint
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IdToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type Audience []string
+
+func (this *Audience) UnmarshalJSON(Data []byte) error {
+	// Audience of the ID Token can be either the Single String or the Array of them
+	var Single string
+	if json.Unmarshal(Data, &Single) == nil {
+		*this = Audience{Single}
+		return nil
+	}
+	var Multiple []string
+	if UnmarshalError := json.Unmarshal(Data, &Multiple); UnmarshalError != nil {
+		return UnmarshalError
+	}
+	*this = Audience(Multiple)
+	return nil
+}
+
+func (this Audience) Contains(ClientId string) bool {
+	for _, Value := range this {
+		if Value == ClientId {
+			return true
+		}
+	}
+	return false
+}
+
+type IdTokenClaims struct {
+	// Claims of the ID Token, the Customer is being Identified by
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          Audience `json:"aud"`
+	ExpiresAt         int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	NotBefore         int64    `json:"nbf,omitempty"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+func (this *IdTokenClaims) Valid() error {
+	// Checks the Lifetime of the ID Token, Issuer, Audience and Nonce are being Checked by the Provider
+	Now := time.Now()
+	if this.ExpiresAt == 0 || Now.After(time.Unix(this.ExpiresAt, 0).Add(ClockSkew)) {
+		return errors.New("ID Token has Expired")
+	}
+	if this.IssuedAt != 0 && Now.Add(ClockSkew).Before(time.Unix(this.IssuedAt, 0)) {
+		return errors.New("ID Token has been Issued in the Future")
+	}
+	if this.NotBefore != 0 && Now.Add(ClockSkew).Before(time.Unix(this.NotBefore, 0)) {
+		return errors.New("ID Token is not Valid yet")
+	}
+	return nil
+}
+
+type JsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyId     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+func (this *JsonWebKey) GetPublicKey() (*rsa.PublicKey, error) {
+	// Returns RSA Public Key, Encoded in the JWK
+	Modulus, ModulusError := base64.RawURLEncoding.DecodeString(this.Modulus)
+	if ModulusError != nil {
+		return nil, ModulusError
+	}
+	Exponent, ExponentError := base64.RawURLEncoding.DecodeString(this.Exponent)
+	if ExponentError != nil {
+		return nil, ExponentError
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(Modulus),
+		E: int(new(big.Int).SetBytes(Exponent).Int64()),
+	}, nil
+}
+
+type Provider struct {
+	// OpenID Connect Client of the Identity Provider, Caches Signing Keys of it
+
+	Config    *Config
+	Discovery Discovery
+	Client    *http.Client
+
+	Mutex         sync.Mutex
+	Keys          map[string]*rsa.PublicKey
+	KeysFetchedAt time.Time
+}
+
+func NewProvider(Context context.Context, Config *Config) (*Provider, error) {
+	// Returns new Provider, Configured with the Discovery Document of the Issuer
+	if !Config.IsConfigured() {
+		return nil, ErrNotConfigured
+	}
+	Provider := &Provider{
+		Config: Config,
+		Client: &http.Client{Timeout: RequestTimeout},
+		Keys:   make(map[string]*rsa.PublicKey),
+	}
+	if DiscoveryError := Provider.GetJSON(Context,
+		Config.IssuerURL+"/.well-known/openid-configuration", &Provider.Discovery); DiscoveryError != nil {
+		Logger.Error("Failed to Receive OIDC Discovery Document", zap.Error(DiscoveryError))
+		return nil, DiscoveryError
+	}
+
+	// The Issuer of the Document should be the same as the Configured one, so the Tokens of the other Issuer can't be Accepted
+	if strings.TrimSuffix(Provider.Discovery.Issuer, "/") != Config.IssuerURL ||
+		len(Provider.Discovery.AuthorizationEndpoint) == 0 ||
+		len(Provider.Discovery.TokenEndpoint) == 0 || len(Provider.Discovery.JwksURI) == 0 {
+		return nil, ErrInvalidDiscovery
+	}
+	return Provider, nil
+}
+
+func (this *Provider) GetJSON(Context context.Context, URL string, Result interface{}) error {
+	Request, RequestError := http.NewRequestWithContext(Context, http.MethodGet, URL, nil)
+	if RequestError != nil {
+		return RequestError
+	}
+	Request.Header.Set("Accept", "application/json")
+	Response, ResponseError := this.Client.Do(Request)
+	if ResponseError != nil {
+		return ResponseError
+	}
+	defer Response.Body.Close()
+	if Response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Request to %s has Failed with Status %v", URL, Response.StatusCode)
+	}
+	return json.NewDecoder(Response.Body).Decode(Result)
+}
+
+// Authorization Code Flow
+
+func GenerateRandomString() (string, error) {
+	// Returns Random URL Safe String, used for the State, Nonce and PKCE Code Verifier
+	Random := make([]byte, 32)
+	if _, RandomError := rand.Read(Random); RandomError != nil {
+		return "", RandomError
+	}
+	return base64.RawURLEncoding.EncodeToString(Random), nil
+}
+
+func GetCodeChallenge(CodeVerifier string) string {
+	// Returns PKCE Code Challenge of the Verifier, using the `S256` Method
+	Hash := sha256.Sum256([]byte(CodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(Hash[:])
+}
+
+func (this *Provider) GetAuthorizationURL(State string, Nonce string, CodeVerifier string) string {
+	// Returns URL of the Provider's Login Page, the Customer is being Redirected to
+	Query := url.Values{}
+	Query.Set("response_type", "code")
+	Query.Set("client_id", this.Config.ClientId)
+	Query.Set("redirect_uri", this.Config.RedirectURL)
+	Query.Set("scope", strings.Join(this.Config.Scopes, " "))
+	Query.Set("state", State)
+	Query.Set("nonce", Nonce)
+	Query.Set("code_challenge", GetCodeChallenge(CodeVerifier))
+	Query.Set("code_challenge_method", "S256")
+
+	Separator := "?"
+	if strings.Contains(this.Discovery.AuthorizationEndpoint, "?") {
+		Separator = "&"
+	}
+	return this.Discovery.AuthorizationEndpoint + Separator + Query.Encode()
+}
+
+func (this *Provider) ExchangeCode(Context context.Context, Code string, CodeVerifier string) (*TokenResponse, error) {
+	// Exchanges the Authorization Code, Received on the Redirect URL, for the Tokens of the Customer
+
+	Form := url.Values{}
+	Form.Set("grant_type", "authorization_code")
+	Form.Set("code", Code)
+	Form.Set("redirect_uri", this.Config.RedirectURL)
+	Form.Set("client_id", this.Config.ClientId)
+	Form.Set("code_verifier", CodeVerifier)
+	if len(this.Config.ClientSecret) != 0 {
+		Form.Set("client_secret", this.Config.ClientSecret)
+	}
+
+	Request, RequestError := http.NewRequestWithContext(Context, http.MethodPost,
+		this.Discovery.TokenEndpoint, strings.NewReader(Form.Encode()))
+	if RequestError != nil {
+		return nil, RequestError
+	}
+	Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Request.Header.Set("Accept", "application/json")
+
+	Response, ResponseError := this.Client.Do(Request)
+	if ResponseError != nil {
+		return nil, ResponseError
+	}
+	defer Response.Body.Close()
+	if Response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Token Endpoint has Responded with Status %v", Response.StatusCode)
+	}
+
+	var Tokens TokenResponse
+	if DecodeError := json.NewDecoder(Response.Body).Decode(&Tokens); DecodeError != nil {
+		return nil, DecodeError
+	}
+	if len(Tokens.IdToken) == 0 {
+		return nil, errors.New("Token Endpoint has not Returned the ID Token")
+	}
+	return &Tokens, nil
+}
+
+// Pending Authorization Requests are being Stored in Redis, until the Customer Returns from the Provider
+
+var (
+	AuthorizationRequestLifetime = 10 * time.Minute
+)
+
+const (
+	StateCookieName = "oidc-state"
+	StateCookiePath = "/customer/oidc/callback/" // The Cookie is only being Sent to the Callback
+)
+
+var (
+	ErrStateMismatch = errors.New("Login has been Started from the other Browser, Please Login again")
+)
+
+// Returns the Value and Deletes the Key, so the State can only be Used once
+var PopScript = `
+local Value = redis.call("GET", KEYS[1])
+if Value then
+	redis.call("DEL", KEYS[1])
+end
+return Value`
+
+type AuthorizationRequest struct {
+	// Secrets of the Login Attempt, the State is being Passed through the Provider and Identifies the Request
+	State        string `json:"-"`
+	Nonce        string `json:"Nonce"`
+	CodeVerifier string `json:"CodeVerifier"`
+}
+
+func NewAuthorizationRequest() (*AuthorizationRequest, error) {
+	Request := &AuthorizationRequest{}
+	for _, Value := range []*string{&Request.State, &Request.Nonce, &Request.CodeVerifier} {
+		Random, RandomError := GenerateRandomString()
+		if RandomError != nil {
+			return nil, RandomError
+		}
+		*Value = Random
+	}
+	return Request, nil
+}
+
+func (this *AuthorizationRequest) GetStateBinding() string {
+	// Returns Hash of the State, that is being Stored in the Cookie of the Browser, the Login has been Started from
+	// The Callback is only being Accepted from the same Browser, so the Attacker can't Log the Customer in with his own Account
+	Hash := sha256.Sum256([]byte(this.State))
+	return hex.EncodeToString(Hash[:])
+}
+
+func IsStateBound(State string, Binding string) bool {
+	// Checks, that the State has been Returned to the Browser, the Login has been Started from
+	Expected := (&AuthorizationRequest{State: State}).GetStateBinding()
+	return len(State) != 0 && subtle.ConstantTimeCompare([]byte(Expected), []byte(Binding)) == 1
+}
+
+func GetAuthorizationRequestKey(State string) string {
+	return fmt.Sprintf("oidc:state:%s", State)
+}
+
+func (this *AuthorizationRequest) Save(Client *redis.Client) error {
+	Serialized, SerializeError := json.Marshal(this)
+	if SerializeError != nil {
+		return SerializeError
+	}
+	return Client.Set(GetAuthorizationRequestKey(this.State), Serialized, AuthorizationRequestLifetime).Err()
+}
+
+func PopAuthorizationRequest(Client *redis.Client, State string) (*AuthorizationRequest, error) {
+	// Returns the Authorization Request by it's State, the Request can't be Received twice
+	Serialized, PopError := Client.Eval(PopScript, []string{GetAuthorizationRequestKey(State)}).String()
+	if PopError == redis.Nil {
+		return nil, errors.New("Login Attempt has Expired or has been Completed already")
+	}
+	if PopError != nil {
+		return nil, PopError
+	}
+	Request := &AuthorizationRequest{State: State}
+	if DecodeError := json.Unmarshal([]byte(Serialized), Request); DecodeError != nil {
+		return nil, DecodeError
+	}
+	return Request, nil
+}
+
+// ID Token Verification
+
+func (this *Provider) RefreshKeys(Context context.Context) error {
+	// Fetches Signing Keys of the Provider, Keys of other Types than RSA are being Skipped
+	var KeySet struct {
+		Keys []JsonWebKey `json:"keys"`
+	}
+	if FetchError := this.GetJSON(Context, this.Discovery.JwksURI, &KeySet); FetchError != nil {
+		return FetchError
+	}
+	Keys := make(map[string]*rsa.PublicKey)
+	for _, Key := range KeySet.Keys {
+		if Key.KeyType != "RSA" || (len(Key.Use) != 0 && Key.Use != "sig") {
+			continue
+		}
+		PublicKey, KeyError := Key.GetPublicKey()
+		if KeyError != nil {
+			Logger.Warn("Failed to Parse OIDC Signing Key", zap.String("KeyId", Key.KeyId), zap.Error(KeyError))
+			continue
+		}
+		Keys[Key.KeyId] = PublicKey
+	}
+	this.Keys = Keys
+	this.KeysFetchedAt = time.Now()
+	return nil
+}
+
+func (this *Provider) GetKey(Context context.Context, KeyId string) (*rsa.PublicKey, error) {
+	// Returns Signing Key by it's ID, Keys are being Refetched, once the Provider Rotates them
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+
+	if Key, Exists := this.Keys[KeyId]; Exists {
+		return Key, nil
+	}
+	if time.Since(this.KeysFetchedAt) < KeysRefreshRate {
+		return nil, ErrUnknownKey
+	}
+	if RefreshError := this.RefreshKeys(Context); RefreshError != nil {
+		return nil, RefreshError
+	}
+	if Key, Exists := this.Keys[KeyId]; Exists {
+		return Key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (this *Provider) VerifyIdToken(Context context.Context, IdToken string, Nonce string) (*IdTokenClaims, error) {
+	// Verifies Signature, Issuer, Audience, Lifetime and Nonce of the ID Token and Returns it's Claims
+
+	Claims := &IdTokenClaims{}
+	Parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg()}}
+	if _, ParseError := Parser.ParseWithClaims(IdToken, Claims, func(Token *jwt.Token) (interface{}, error) {
+		KeyId, _ := Token.Header["kid"].(string)
+		return this.GetKey(Context, KeyId)
+	}); ParseError != nil {
+		Logger.Debug("Failed to Verify ID Token", zap.Error(ParseError))
+		return nil, ErrInvalidIdToken
+	}
+
+	switch {
+	case strings.TrimSuffix(Claims.Issuer, "/") != this.Config.IssuerURL:
+		return nil, fmt.Errorf("%w: Unexpected Issuer", ErrInvalidIdToken)
+	case !Claims.Audience.Contains(this.Config.ClientId):
+		return nil, fmt.Errorf("%w: Unexpected Audience", ErrInvalidIdToken)
+	case len(Claims.Nonce) == 0 || Claims.Nonce != Nonce:
+		return nil, fmt.Errorf("%w: Unexpected Nonce", ErrInvalidIdToken)
+	case len(Claims.Subject) == 0:
+		return nil, fmt.Errorf("%w: Missing Subject", ErrInvalidIdToken)
+	}
+	return Claims, nil
+}
+
+// Provider of the Application
+
+var (
+	ProviderConfig  = NewConfig("", "", "", "", nil)
+	DefaultProvider *Provider
+	ProviderMutex   sync.Mutex
+)
+
+func Configure(Config config.OidcConfig) {
+	// Applies the Client Registration, the Provider is being Discovered again on the next Use
+	ProviderMutex.Lock()
+	defer ProviderMutex.Unlock()
+	ProviderConfig = NewConfig(Config.IssuerURL, Config.ClientId,
+		Config.ClientSecret, Config.RedirectURL, Config.Scopes)
+	DefaultProvider = nil
+}
+
+func GetProvider(Context context.Context) (*Provider, error) {
+	// Returns Provider of the Configured Client Registration
+	// Discovery Document is being Fetched on the First Use, so the Application Starts even if the Provider is Down
+	ProviderMutex.Lock()
+	defer ProviderMutex.Unlock()
+
+	if DefaultProvider != nil {
+		return DefaultProvider, nil
+	}
+	Provider, ProviderError := NewProvider(Context, ProviderConfig)
+	if ProviderError != nil {
+		return nil, ProviderError
+	}
+	DefaultProvider = Provider
+	return DefaultProvider, nil
+}
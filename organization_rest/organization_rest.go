@@ -0,0 +1,344 @@
+package organization_rest
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/policy"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+)
+
+// Package consists of Rest API Controllers, for Managing Organizations, their Projects and Members
+// Every Controller, that Operates on the Existing Organization, Checks the Role of the Customer using the `policy` package
+
+var (
+	Logger *zap.Logger
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("OrganizationRestLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+func GetAuthorizedMembership(RequestContext *gin.Context, Action string) (*models.Membership, bool) {
+	// Returns Membership of the Customer in the Organization, specified in the `OrganizationId` Param
+	// Responds with the Error and Returns false, if the Customer's Role does not Allow to Perform the Action
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return nil, false
+	}
+
+	OrganizationId, ParseError := strconv.Atoi(RequestContext.Request.FormValue("OrganizationId"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Organization ID"})
+		return nil, false
+	}
+
+	Membership, Exists := models.GetMembership(OrganizationId, jwtCredentials.UserId)
+	if !Exists {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Organization Does Not Exist"})
+		return nil, false
+	}
+	if AuthorizationError := policy.Authorize(jwtCredentials.UserId, Membership, Action); AuthorizationError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": AuthorizationError.Error()})
+		return nil, false
+	}
+	return Membership, true
+}
+
+func GetOrganizationMember(RequestContext *gin.Context, OrganizationId int) (*models.Membership, bool) {
+	// Returns Membership of the Customer, specified in the `CustomerId` Param
+
+	CustomerId, ParseError := strconv.Atoi(RequestContext.Request.FormValue("CustomerId"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Customer ID"})
+		return nil, false
+	}
+	Membership, Exists := models.GetMembership(OrganizationId, CustomerId)
+	if !Exists {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Member Does Not Exist"})
+		return nil, false
+	}
+	return Membership, true
+}
+
+// Organizations Rest API Endpoints
+
+func CreateOrganizationRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Creates new Organization with the Default Project, the Customer becomes the Owner of
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	Name := RequestContext.PostForm("Name")
+	if len(Name) == 0 || len(Name) > 100 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Organization Name should be between 1 and 100 Characters"})
+		return
+	}
+
+	var Organization *models.Organization
+	TransactionError := models.Database.Transaction(func(Transaction *gorm.DB) error {
+		Created, CreationError := models.CreateOrganization(Transaction, Name, jwtCredentials.UserId)
+		Organization = Created
+		return CreationError
+	})
+	if TransactionError != nil {
+		Logger.Error("Failed to Create Organization", zap.Error(TransactionError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Create Organization"})
+		return
+	}
+	RequestContext.JSON(http.StatusCreated, gin.H{"Organization": Organization})
+}
+
+func ListOrganizationsRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns Organizations, the Customer is Member of, along with the Customer's Role in them
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	var Organizations []struct {
+		models.Organization
+		Role string `json:"Role" xml:"Role"`
+	}
+	if Gorm := models.Database.Model(&models.Organization{}).Select("organizations.*, memberships.role").Joins(
+		"JOIN memberships ON memberships.organization_id = organizations.id").Where(
+		"memberships.customer_id = ?", jwtCredentials.UserId).Order("organizations.id").Find(&Organizations); Gorm.Error != nil {
+		Logger.Error("Failed to Receive Customer Organizations", zap.Error(Gorm.Error))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Receive Organizations"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": Organizations})
+}
+
+func SetMfaRequirementRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Enables or Disables the Second Factor Requirement for the Members of the Organization
+	// Members without the Second Factor lose Access to the Organization's Projects, until they Enable it
+
+	Membership, Authorized := GetAuthorizedMembership(RequestContext, policy.ActionManage)
+	if !Authorized {
+		return
+	}
+	RequireMfa, ParseError := strconv.ParseBool(RequestContext.PostForm("RequireMfa"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid RequireMfa Value"})
+		return
+	}
+
+	// Customers can't Lock themselves out of the Organization
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("id = ?", Membership.CustomerId).Find(&Customer)
+	if RequireMfa && !Customer.MfaEnabled {
+		RequestContext.JSON(http.StatusConflict, gin.H{"Error": "Enable Two Factor Authentication for your Account First"})
+		return
+	}
+
+	Organization, Exists := models.GetOrganization(Membership.OrganizationId)
+	if !Exists {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Organization Does Not Exist"})
+		return
+	}
+	if UpdateError := Organization.SetRequireMfa(RequireMfa); UpdateError != nil {
+		Logger.Error("Failed to Update Organization MFA Requirement", zap.Error(UpdateError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Update Organization"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Organization": Organization})
+}
+
+// Projects Rest API Endpoints
+
+func CreateProjectRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Creates new Project in the Organization
+
+	Membership, Authorized := GetAuthorizedMembership(RequestContext, policy.ActionManage)
+	if !Authorized {
+		return
+	}
+
+	Name := RequestContext.PostForm("Name")
+	if len(Name) == 0 || len(Name) > 100 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Project Name should be between 1 and 100 Characters"})
+		return
+	}
+
+	NewProject := models.NewProject(Membership.OrganizationId, Name)
+	if _, CreationError := NewProject.Create(); CreationError != nil {
+		Logger.Error("Failed to Create Project", zap.Error(CreationError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Create Project"})
+		return
+	}
+	RequestContext.JSON(http.StatusCreated, gin.H{"Project": NewProject})
+}
+
+func ListProjectsRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns Projects of the Organization
+
+	Membership, Authorized := GetAuthorizedMembership(RequestContext, policy.ActionView)
+	if !Authorized {
+		return
+	}
+
+	var Projects []models.Project
+	if Gorm := models.Database.Model(&models.Project{}).Where(
+		"organization_id = ?", Membership.OrganizationId).Order("id").Find(&Projects); Gorm.Error != nil {
+		Logger.Error("Failed to Receive Organization Projects", zap.Error(Gorm.Error))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Receive Projects"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": Projects})
+}
+
+// Members Rest API Endpoints
+
+func ListMembersRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns Members of the Organization with their Roles
+
+	Membership, Authorized := GetAuthorizedMembership(RequestContext, policy.ActionView)
+	if !Authorized {
+		return
+	}
+
+	var Members []struct {
+		CustomerId int    `json:"CustomerId" xml:"CustomerId"`
+		Username   string `json:"Username" xml:"Username"`
+		Email      string `json:"Email" xml:"Email"`
+		Role       string `json:"Role" xml:"Role"`
+	}
+	if Gorm := models.Database.Model(&models.Membership{}).Select(
+		"memberships.customer_id, customers.username, customers.email, memberships.role").Joins(
+		"JOIN customers ON customers.id = memberships.customer_id").Where(
+		"memberships.organization_id = ?", Membership.OrganizationId).Order("memberships.id").Find(&Members); Gorm.Error != nil {
+		Logger.Error("Failed to Receive Organization Members", zap.Error(Gorm.Error))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Receive Members"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": Members})
+}
+
+func AddMemberRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Adds the Customer with the `Email` to the Organization with the Role
+
+	Membership, Authorized := GetAuthorizedMembership(RequestContext, policy.ActionManage)
+	if !Authorized {
+		return
+	}
+
+	Role := RequestContext.PostForm("Role")
+	if !models.IsValidRole(Role) {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Role has been Passed"})
+		return
+	}
+	if !policy.CanAssignRole(Membership.Role, Role) {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You're not Allowed to Assign this Role"})
+		return
+	}
+
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Where("email = ?", RequestContext.PostForm("Email")).Find(&Customer)
+	if Customer.ID == 0 {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Customer with this Email Does Not Exist"})
+		return
+	}
+	if _, Exists := models.GetMembership(Membership.OrganizationId, Customer.ID); Exists {
+		RequestContext.JSON(http.StatusConflict, gin.H{"Error": "Customer is already a Member of the Organization"})
+		return
+	}
+
+	NewMembership := models.NewMembership(Membership.OrganizationId, Customer.ID, Role)
+	if _, CreationError := NewMembership.Create(); CreationError != nil {
+		Logger.Error("Failed to Add Organization Member", zap.Error(CreationError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Add Member"})
+		return
+	}
+	RequestContext.JSON(http.StatusCreated, gin.H{"Membership": NewMembership})
+}
+
+func UpdateMemberRoleRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Changes Role of the Organization Member
+
+	Membership, Authorized := GetAuthorizedMembership(RequestContext, policy.ActionManage)
+	if !Authorized {
+		return
+	}
+	Member, Found := GetOrganizationMember(RequestContext, Membership.OrganizationId)
+	if !Found {
+		return
+	}
+
+	Role := RequestContext.PostForm("Role")
+	if !models.IsValidRole(Role) {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Role has been Passed"})
+		return
+	}
+	if !policy.CanAssignRole(Membership.Role, Member.Role) || !policy.CanAssignRole(Membership.Role, Role) {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You're not Allowed to Assign this Role"})
+		return
+	}
+	if Member.Role == models.RoleOwner && Role != models.RoleOwner && models.CountOrganizationOwners(Membership.OrganizationId) <= 1 {
+		RequestContext.JSON(http.StatusConflict, gin.H{"Error": "Organization should have at least one Owner"})
+		return
+	}
+
+	Member.Role = Role
+	if _, SaveError := Member.Save(); SaveError != nil {
+		Logger.Error("Failed to Update Member Role", zap.Error(SaveError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Update Role"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Membership": Member})
+}
+
+func RemoveMemberRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Removes the Member from the Organization
+
+	Membership, Authorized := GetAuthorizedMembership(RequestContext, policy.ActionManage)
+	if !Authorized {
+		return
+	}
+	Member, Found := GetOrganizationMember(RequestContext, Membership.OrganizationId)
+	if !Found {
+		return
+	}
+
+	if !policy.CanAssignRole(Membership.Role, Member.Role) {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You're not Allowed to Remove this Member"})
+		return
+	}
+	if Member.Role == models.RoleOwner && models.CountOrganizationOwners(Membership.OrganizationId) <= 1 {
+		RequestContext.JSON(http.StatusConflict, gin.H{"Error": "Organization should have at least one Owner"})
+		return
+	}
+
+	if _, DeleteError := Member.Delete(); DeleteError != nil {
+		Logger.Error("Failed to Remove Organization Member", zap.Error(DeleteError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Remove Member"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Removed"})
+}
@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/models"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Role Based Access Policy of the Organizations
+// Customer's Role in the Organization defines, what Actions the Customer can Perform on the Projects and Virtual Machines of it
+
+var (
+	Logger *zap.Logger
+)
+
+const (
+	ActionView       = "View"       // Reading Virtual Machines, their Metrics, Snapshots and Jobs
+	ActionOperate    = "Operate"    // Operations on the Existing Virtual Machines
+	ActionManage     = "Manage"     // Creating, Cloning and Removing Virtual Machines, Managing Projects and Members
+	ActionAdminister = "Administer" // Managing Owners of the Organization
+)
+
+var (
+	// Actions, every Role is Allowed to Perform
+	RolePermissions = map[string][]string{
+		models.RoleOwner:    {ActionView, ActionOperate, ActionManage, ActionAdminister},
+		models.RoleAdmin:    {ActionView, ActionOperate, ActionManage},
+		models.RoleOperator: {ActionView, ActionOperate},
+		models.RoleViewer:   {ActionView},
+	}
+
+	// Routes, that Require other Action, than the one, Derived from the HTTP Method
+	RouteActions = map[string]string{
+		"/vm/initialize/":           ActionManage,
+		"/vm/remove/":               ActionManage,
+		"/vm/clone/":                ActionManage,
+		"/ssh/get/ssh/certificate/": ActionOperate,
+	}
+)
+
+var (
+	// Scopes of the API Keys, Required by the Routes, Routes without any Scope can't be Requested with the API Key
+	RouteScopes = map[string]string{
+		"/organization/list/":         authentication.ScopeVirtualMachinesRead,
+		"/organization/project/list/": authentication.ScopeVirtualMachinesRead,
+	}
+	RoutePrefixScopes = map[string]string{
+		"/suggestions/": authentication.ScopeVirtualMachinesRead,
+		"/ssh/":         authentication.ScopeSshRead,
+	}
+	// Route Prefixes, that Require the Read Scope for the Safe Methods and the Write Scope for the other ones
+	VirtualMachineRoutePrefixes = []string{"/vm/", "/host/"}
+)
+
+var (
+	ErrNotAllowed  = errors.New("You're not Allowed to Perform this Operation")
+	ErrMfaRequired = errors.New("Organization Requires Two Factor Authentication, Please Enable it")
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("PolicyLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+func IsAllowed(Role string, Action string) bool {
+	// Checks, that the Role Allows to Perform the Action
+	for _, Allowed := range RolePermissions[Role] {
+		if Allowed == Action {
+			return true
+		}
+	}
+	return false
+}
+
+func GetRequestAction(Method string, Route string) string {
+	// Returns Action, the HTTP Request is going to Perform, Safe Methods only Require the `View` Action
+	if Action, Exists := RouteActions[Route]; Exists {
+		return Action
+	}
+	switch Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ActionView
+	default:
+		return ActionOperate
+	}
+}
+
+func IsMfaSatisfied(CustomerId int, OrganizationId int) bool {
+	// Checks, that the Customer has Enabled the Second Factor, if the Organization Requires it
+	Organization, Exists := models.GetOrganization(OrganizationId)
+	if !Exists || !Organization.RequireMfa {
+		return true
+	}
+	var Customer models.Customer
+	models.Database.Model(&models.Customer{}).Select("id", "mfa_enabled").Where("id = ?", CustomerId).Find(&Customer)
+	return Customer.MfaEnabled
+}
+
+func Authorize(CustomerId int, Membership *models.Membership, Action string) error {
+	// Returns the Reason, the Member is not Allowed to Perform the Action, or nil
+	if !IsAllowed(Membership.Role, Action) {
+		return ErrNotAllowed
+	}
+	if !IsMfaSatisfied(CustomerId, Membership.OrganizationId) {
+		return ErrMfaRequired
+	}
+	return nil
+}
+
+func AuthorizeInProject(CustomerId int, ProjectId int, Action string) error {
+	// Returns the Reason, the Customer is not Allowed to Perform the Action in the Project, or nil
+	Membership, Exists := models.GetProjectMembership(ProjectId, CustomerId)
+	if !Exists {
+		Logger.Debug("Customer is not a Member of the Project",
+			zap.Int("CustomerId", CustomerId), zap.Int("ProjectId", ProjectId))
+		return ErrNotAllowed
+	}
+	return Authorize(CustomerId, Membership, Action)
+}
+
+func IsAuthorizedInOrganization(CustomerId int, OrganizationId int, Action string) bool {
+	// Checks, that the Customer is Allowed to Perform the Action in the Organization
+	Membership, Exists := models.GetMembership(OrganizationId, CustomerId)
+	return Exists && Authorize(CustomerId, Membership, Action) == nil
+}
+
+func IsAuthorizedInProject(CustomerId int, ProjectId int, Action string) bool {
+	// Checks, that the Customer is Allowed to Perform the Action in the Project
+	return AuthorizeInProject(CustomerId, ProjectId, Action) == nil
+}
+
+func CanAssignRole(AssignerRole string, Role string) bool {
+	// Checks, that the Member with the Assigner Role can Grant or Revoke the Role, only Owners can Manage other Owners
+	if Role == models.RoleOwner {
+		return IsAllowed(AssignerRole, ActionAdminister)
+	}
+	return IsAllowed(AssignerRole, ActionManage)
+}
+
+func GetRequiredScope(Method string, Route string) string {
+	// Returns Scope, the API Key should have to Request the Route, Empty Scope means, that API Keys are not Allowed
+	if Scope, Exists := RouteScopes[Route]; Exists {
+		return Scope
+	}
+	for Prefix, Scope := range RoutePrefixScopes {
+		if strings.HasPrefix(Route, Prefix) {
+			return Scope
+		}
+	}
+	for _, Prefix := range VirtualMachineRoutePrefixes {
+		if !strings.HasPrefix(Route, Prefix) {
+			continue
+		}
+		if GetRequestAction(Method, Route) == ActionView {
+			return authentication.ScopeVirtualMachinesRead
+		}
+		return authentication.ScopeVirtualMachinesWrite
+	}
+	return ""
+}
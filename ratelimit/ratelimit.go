@@ -0,0 +1,220 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/go-redis/redis"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Redis Backed Token Bucket Rate Limiter and the Lockout of the Accounts after Failed Logins
+// Buckets are being Shared between the Replicas of the Application, so the Limits are Global
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	MaxFailedLogins    = int64(5)
+	BaseLockout        = 30 * time.Second // Lockout after the First Exceeding Attempt
+	MaxLockout         = time.Hour
+	FailedLoginsWindow = 24 * time.Hour // Failed Attempts are being Forgotten, if there is no new ones
+
+	Overrides = map[string]Limit{} // Limits of the Route Groups, Overridden in the Configuration
+)
+
+// Refills the Bucket according to the Time Passed, then Takes one Token out of it, if there is any
+// Returns Whether the Request is Allowed, Remaining Tokens, Milliseconds until the next Token and until the Bucket is Full
+var TokenBucketScript = `
+local Rate = tonumber(ARGV[1])
+local Burst = tonumber(ARGV[2])
+local Now = tonumber(ARGV[3])
+
+local State = redis.call("HMGET", KEYS[1], "tokens", "timestamp")
+local Tokens = tonumber(State[1])
+local Timestamp = tonumber(State[2])
+if Tokens == nil or Timestamp == nil then
+	Tokens = Burst
+	Timestamp = Now
+end
+
+Tokens = math.min(Burst, Tokens + math.max(0, Now - Timestamp) * Rate)
+local Allowed = 0
+local RetryAfter = 0
+if Tokens >= 1 then
+	Tokens = Tokens - 1
+	Allowed = 1
+else
+	RetryAfter = math.ceil((1 - Tokens) / Rate)
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(Tokens), "timestamp", Now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(Burst / Rate))
+return {Allowed, math.floor(Tokens), RetryAfter, math.ceil((Burst - Tokens) / Rate)}`
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("RateLimitLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+func Configure(Config config.RateLimitConfig) {
+	// Applies the Lockout Policy and the `RATE_LIMIT_<NAME>` Overrides of the Limits
+	MaxFailedLogins = int64(Config.MaxFailedLogins)
+	BaseLockout = Config.BaseLockout
+	MaxLockout = Config.MaxLockout
+
+	Overrides = make(map[string]Limit, len(Config.Limits))
+	for Name, Override := range Config.Limits {
+		Overrides[Name] = NewLimit(Override.Requests, Override.Period)
+	}
+}
+
+type Limit struct {
+	// Bucket holds up to `Burst` Tokens and is being Refilled with `Requests` Tokens every `Period`
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func NewLimit(Requests int, Period time.Duration) Limit {
+	return Limit{
+		Requests: Requests,
+		Period:   Period,
+		Burst:    Requests,
+	}
+}
+
+func GetLimit(Name string, Default Limit) Limit {
+	// Returns the Limit, Overridden in the Configuration, or the Default one
+	if Override, Ok := Overrides[strings.ToLower(Name)]; Ok {
+		return Override
+	}
+	return Default
+}
+
+func (this Limit) GetRate() float64 {
+	// Returns Number of the Tokens, being Added to the Bucket every Millisecond
+	return float64(this.Requests) / float64(this.Period.Milliseconds())
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // Time until the Request can be Retried, if it has not been Allowed
+	ResetAfter time.Duration // Time until the Bucket is Full again
+}
+
+type Limiter struct {
+	Client *redis.Client
+}
+
+func NewLimiter(Client *redis.Client) *Limiter {
+	return &Limiter{Client: Client}
+}
+
+func GetBucketKey(Name string, Identity string) string {
+	return fmt.Sprintf("ratelimit:%s:%s", Name, Identity)
+}
+
+func (this *Limiter) Allow(Name string, Identity string, Limit Limit) (*Result, error) {
+	// Takes the Token out of the Bucket of the Identity (IP Address, Customer or API Key) in the Named Route Group
+	Values, EvalError := this.Client.Eval(TokenBucketScript, []string{GetBucketKey(Name, Identity)},
+		strconv.FormatFloat(Limit.GetRate(), 'f', -1, 64), Limit.Burst, time.Now().UnixNano()/int64(time.Millisecond)).Result()
+	if EvalError != nil {
+		return nil, EvalError
+	}
+	Response, Valid := Values.([]interface{})
+	if !Valid || len(Response) != 4 {
+		return nil, errors.New("Unexpected Response of the Rate Limiter")
+	}
+	Numbers := make([]int64, 4)
+	for Index, Value := range Response {
+		Numbers[Index], _ = Value.(int64)
+	}
+	return &Result{
+		Allowed:    Numbers[0] == 1,
+		Limit:      Limit.Burst,
+		Remaining:  int(Numbers[1]),
+		RetryAfter: time.Duration(Numbers[2]) * time.Millisecond,
+		ResetAfter: time.Duration(Numbers[3]) * time.Millisecond,
+	}, nil
+}
+
+// Accounts Lockout
+// Every Failed Login of the Account over the `MaxFailedLogins` Locks it out, the Lockout is being Doubled with every next one
+
+func GetFailedLoginsKey(Account string) string {
+	return fmt.Sprintf("login:failed:%s", strings.ToLower(Account))
+}
+
+func GetLockoutKey(Account string) string {
+	return fmt.Sprintf("login:lockout:%s", strings.ToLower(Account))
+}
+
+func GetLockoutDuration(FailedLogins int64) time.Duration {
+	// Returns Lockout after the Number of the Failed Logins, Zero if the Account is not being Locked yet
+	if FailedLogins < MaxFailedLogins {
+		return 0
+	}
+	Exponent := float64(FailedLogins - MaxFailedLogins)
+	Lockout := time.Duration(float64(BaseLockout) * math.Pow(2, math.Min(Exponent, 32)))
+	if Lockout > MaxLockout || Lockout <= 0 {
+		return MaxLockout
+	}
+	return Lockout
+}
+
+func GetLockout(Client *redis.Client, Account string) (time.Duration, error) {
+	// Returns Time, the Account is still Locked for
+	TimeToLive, TimeError := Client.PTTL(GetLockoutKey(Account)).Result()
+	if TimeError != nil {
+		return 0, TimeError
+	}
+	if TimeToLive < 0 {
+		return 0, nil
+	}
+	return TimeToLive, nil
+}
+
+func RegisterFailedLogin(Client *redis.Client, Account string) (time.Duration, error) {
+	// Counts the Failed Login of the Account and Locks it, once there are too many of them
+	// Returns the Lockout, that has been Applied
+	FailedLogins, IncrementError := Client.Incr(GetFailedLoginsKey(Account)).Result()
+	if IncrementError != nil {
+		return 0, IncrementError
+	}
+	Client.Expire(GetFailedLoginsKey(Account), FailedLoginsWindow)
+
+	Lockout := GetLockoutDuration(FailedLogins)
+	if Lockout == 0 {
+		return 0, nil
+	}
+	Logger.Warn("Account has been Locked after Failed Logins", zap.String("Account", Account),
+		zap.Int64("FailedLogins", FailedLogins), zap.Duration("Lockout", Lockout))
+	return Lockout, Client.Set(GetLockoutKey(Account), FailedLogins, Lockout).Err()
+}
+
+func ResetFailedLogins(Client *redis.Client, Account string) error {
+	return Client.Del(GetFailedLoginsKey(Account), GetLockoutKey(Account)).Err()
+}
@@ -0,0 +1,370 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/middlewares"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
+	"github.com/google/uuid"
+
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+)
+
+// Package consists of the Background Loop, that Synchronizes Database Records of the Virtual Machines
+// with the Actual vSphere Inventory (Power State, IP Address, Item Path) and Flags Orphans on both Sides
+// Only one Replica of the Application runs the Loop at the time, the Leader is being Elected using Redis
+// Every Region is being Reconciled with the Inventory of it's own vSphere Endpoint
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	LeaderKey = "reconciler:leader"
+)
+
+// Extends the Leadership only if it still belongs to the Current Replica
+var RenewLeadershipScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+// Releases the Leadership only if it still belongs to the Current Replica
+var ReleaseLeadershipScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("ReconcilerLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+type InventoryItem struct {
+	// Actual State of the Virtual Machine in the vSphere
+	ManagedObjectId string
+	ItemPath        string
+	PowerState      string
+	IPAddress       string
+}
+
+type InventoryReconciler struct {
+	// Background Loop, that Reconciles Virtual Machines Database Records with the vSphere Inventory
+
+	Registry   *vsphere.EndpointRegistry
+	Interval   time.Duration
+	InstanceId string // Identifies the Replica, that holds the Leadership
+	Stopped    chan struct{}
+	Group      sync.WaitGroup
+}
+
+func NewInventoryReconciler(Registry *vsphere.EndpointRegistry, Interval time.Duration) *InventoryReconciler {
+	return &InventoryReconciler{
+		Registry:   Registry,
+		Interval:   Interval,
+		InstanceId: uuid.NewString(),
+		Stopped:    make(chan struct{}),
+	}
+}
+
+func (this *InventoryReconciler) Start() {
+	// Starts Reconciliation Loop in the Background
+	this.Group.Add(1)
+	go func() {
+		defer this.Group.Done()
+		Ticker := time.NewTicker(this.Interval)
+		defer Ticker.Stop()
+
+		for {
+			if this.AcquireLeadership() {
+				if ReconcileError := this.Reconcile(); ReconcileError != nil {
+					Logger.Error("Failed to Reconcile vSphere Inventory", zap.Error(ReconcileError))
+				}
+			}
+			select {
+			case <-this.Stopped:
+				return
+			case <-Ticker.C:
+			}
+		}
+	}()
+	Logger.Info("Reconciler has been Started", zap.Duration("Interval", this.Interval),
+		zap.String("Instance", this.InstanceId))
+}
+
+func (this *InventoryReconciler) Stop() {
+	// Stops Reconciliation Loop and Gives up the Leadership, so the other Replica can take it over
+	close(this.Stopped)
+	this.Group.Wait()
+	this.ReleaseLeadership()
+	Logger.Info("Reconciler has been Stopped")
+}
+
+func (this *InventoryReconciler) AcquireLeadership() bool {
+	// Returns true, if the Current Replica is the Leader
+	// The Leadership is being held for two Intervals, so it expires if the Leader Crashes
+
+	LeaseTime := this.Interval * 2
+	Acquired, AcquireError := middlewares.RedisClient.SetNX(LeaderKey, this.InstanceId, LeaseTime).Result()
+	if AcquireError != nil {
+		Logger.Error("Failed to Acquire Reconciler Leadership", zap.Error(AcquireError))
+		return false
+	}
+	if Acquired {
+		Logger.Info("Reconciler Leadership has been Acquired", zap.String("Instance", this.InstanceId))
+		return true
+	}
+
+	// The Leadership has been Acquired Earlier, Extending it
+	Renewed, RenewError := middlewares.RedisClient.Eval(RenewLeadershipScript,
+		[]string{LeaderKey}, this.InstanceId, LeaseTime.Milliseconds()).Int()
+	return RenewError == nil && Renewed == 1
+}
+
+func (this *InventoryReconciler) ReleaseLeadership() {
+	if ReleaseError := middlewares.RedisClient.Eval(ReleaseLeadershipScript,
+		[]string{LeaderKey}, this.InstanceId).Err(); ReleaseError != nil {
+		Logger.Error("Failed to Release Reconciler Leadership", zap.Error(ReleaseError))
+	}
+}
+
+func (this *InventoryReconciler) GetInventory(Context context.Context, Pool *vsphere.SessionPool) (map[string]InventoryItem, error) {
+	// Returns all the Virtual Machines of the vSphere Endpoint (except Templates), by their Managed Object ID
+
+	Client, ClientError := Pool.GetClient()
+	if ClientError != nil {
+		return nil, ClientError
+	}
+	Manager := view.NewManager(Client.Client)
+	RootFolder := Client.ServiceContent.RootFolder
+
+	ContainerView, ViewError := Manager.CreateContainerView(Context, RootFolder,
+		[]string{"VirtualMachine", "Folder", "Datacenter"}, true)
+	if ViewError != nil {
+		return nil, ViewError
+	}
+	defer ContainerView.Destroy(context.Background())
+
+	var VirtualMachines []mo.VirtualMachine
+	if RetrieveError := ContainerView.Retrieve(Context, []string{"VirtualMachine"},
+		[]string{"name", "parent", "runtime.powerState", "guest.ipAddress", "config.template"}, &VirtualMachines); RetrieveError != nil {
+		return nil, RetrieveError
+	}
+
+	var Folders []mo.Folder
+	if RetrieveError := ContainerView.Retrieve(Context, []string{"Folder"}, []string{"name", "parent"}, &Folders); RetrieveError != nil {
+		return nil, RetrieveError
+	}
+
+	var Datacenters []mo.Datacenter
+	if RetrieveError := ContainerView.Retrieve(Context, []string{"Datacenter"}, []string{"name", "parent"}, &Datacenters); RetrieveError != nil {
+		return nil, RetrieveError
+	}
+
+	// Collecting Parents of the Virtual Machines, so the Inventory Paths can be Built without Extra Requests
+	Entities := make(map[types.ManagedObjectReference]mo.ManagedEntity)
+	for _, Folder := range Folders {
+		Entities[Folder.Reference()] = Folder.ManagedEntity
+	}
+	for _, Datacenter := range Datacenters {
+		Entities[Datacenter.Reference()] = Datacenter.ManagedEntity
+	}
+
+	Inventory := make(map[string]InventoryItem)
+	for _, VirtualMachine := range VirtualMachines {
+		if VirtualMachine.Config != nil && VirtualMachine.Config.Template {
+			continue
+		}
+
+		Path := []string{VirtualMachine.Name}
+		for Parent := VirtualMachine.Parent; Parent != nil && *Parent != RootFolder; {
+			Entity, Exists := Entities[*Parent]
+			if !Exists {
+				break
+			}
+			Path = append([]string{Entity.Name}, Path...)
+			Parent = Entity.Parent
+		}
+
+		Item := InventoryItem{
+			ManagedObjectId: VirtualMachine.Reference().Value,
+			ItemPath:        "/" + strings.Join(Path, "/"),
+			PowerState:      string(VirtualMachine.Runtime.PowerState),
+		}
+		if VirtualMachine.Guest != nil {
+			Item.IPAddress = VirtualMachine.Guest.IpAddress
+		}
+		Inventory[Item.ManagedObjectId] = Item
+	}
+	return Inventory, nil
+}
+
+func (this *InventoryReconciler) Reconcile() error {
+	// Reconciles every Region, the Unavailable Region does not Prevent the other ones from being Reconciled
+
+	var Failed int
+	for _, Pool := range this.Registry.GetPools() {
+		if ReconcileError := this.ReconcileRegion(Pool); ReconcileError != nil {
+			Logger.Error("Failed to Reconcile vSphere Inventory of the Region",
+				zap.String("Region", Pool.Endpoint.Region), zap.Error(ReconcileError))
+			Failed++
+		}
+	}
+	if Failed != 0 {
+		return fmt.Errorf("%v of %v Regions have not been Reconciled", Failed, len(this.Registry.Regions))
+	}
+	return nil
+}
+
+func (this *InventoryReconciler) ReconcileRegion(Pool *vsphere.SessionPool) error {
+	// Compares Database Records of the Region with the Inventory of it's vSphere Endpoint and Fixes the Records
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), this.Interval)
+	defer CancelFunc()
+
+	Inventory, InventoryError := this.GetInventory(TimeoutContext, Pool)
+	if InventoryError != nil {
+		return InventoryError
+	}
+
+	Region := Pool.Endpoint.Region
+	Regions := this.Registry.GetRecordRegions(Region)
+
+	var VirtualMachines []models.VirtualMachine
+	if Gorm := models.Database.Model(&models.VirtualMachine{}).Scopes(
+		models.InRegions(Regions...)).Find(&VirtualMachines); Gorm.Error != nil {
+		return Gorm.Error
+	}
+
+	Reconciliation := NewReconciliation(Region, VirtualMachines, Inventory, time.Now())
+	for VirtualMachineId, Updates := range Reconciliation.Updates {
+		if Gorm := models.Database.Model(&models.VirtualMachine{}).Where(
+			"id = ?", VirtualMachineId).Updates(Updates); Gorm.Error != nil {
+			Logger.Error("Failed to Update Reconciled Virtual Machine",
+				zap.Int("Virtual Machine ID", VirtualMachineId), zap.Error(Gorm.Error))
+		}
+	}
+
+	// Refreshing the List of the Virtual Machines, that are not Managed by the Application
+	// The List is being Replaced within the Transaction, so it's never Observed Empty or Partially Stored
+	Unmanaged := Reconciliation.Unmanaged
+	if TransactionError := models.Database.Transaction(func(Transaction *gorm.DB) error {
+		if Gorm := Transaction.Where("region IN (?)", Regions).Delete(&models.UnmanagedVirtualMachine{}); Gorm.Error != nil {
+			return Gorm.Error
+		}
+		if len(Unmanaged) != 0 {
+			return Transaction.Create(&Unmanaged).Error
+		}
+		return nil
+	}); TransactionError != nil {
+		Logger.Error("Failed to Store Unmanaged Virtual Machines", zap.Error(TransactionError))
+	}
+
+	Logger.Debug("vSphere Inventory has been Reconciled", zap.String("Region", Region), zap.Int("Virtual Machines", len(VirtualMachines)),
+		zap.Int("Unmanaged", len(Unmanaged)))
+	return nil
+}
+
+type Reconciliation struct {
+	// Changes of the Database Records of the Region, that make them Match the vSphere Inventory
+
+	Updates   map[int]map[string]interface{} // Updated Columns of the Virtual Machine Records by their ID
+	Unmanaged []models.UnmanagedVirtualMachine
+}
+
+func NewReconciliation(Region string, VirtualMachines []models.VirtualMachine, Inventory map[string]InventoryItem, ReconciledAt time.Time) *Reconciliation {
+	// Compares Database Records of the Region with the Inventory of it's vSphere Endpoint
+	// Records of the Virtual Machines, that does not Exist anymore are being marked as Orphaned
+	// Virtual Machines, that does not have the Records are being Returned as the Unmanaged ones
+
+	InventoryByPath := make(map[string]InventoryItem)
+	for _, Item := range Inventory {
+		InventoryByPath[Item.ItemPath] = Item
+	}
+
+	Reconciliation := &Reconciliation{Updates: make(map[int]map[string]interface{})}
+	Managed := make(map[string]bool)
+
+	for _, VirtualMachine := range VirtualMachines {
+		// Virtual Machine is being Matched by the Managed Object ID, that does not change on Rename or Move
+		// The Item Path is only used for the Records, that has not been Reconciled yet
+		Item, Exists := Inventory[VirtualMachine.ManagedObjectId]
+		if !Exists {
+			Item, Exists = InventoryByPath[VirtualMachine.ItemPath]
+		}
+
+		Updates := map[string]interface{}{"reconciled_at": ReconciledAt}
+		switch Exists {
+		case false:
+			if !VirtualMachine.Orphaned {
+				Logger.Warn("Virtual Machine does not Exist in the vSphere anymore",
+					zap.Int("Virtual Machine ID", VirtualMachine.ID), zap.String("ItemPath", VirtualMachine.ItemPath))
+			}
+			Updates["orphaned"] = true
+
+		case true:
+			Managed[Item.ManagedObjectId] = true
+			Updates["orphaned"] = false
+			Updates["managed_object_id"] = Item.ManagedObjectId
+			Updates["power_state"] = Item.PowerState
+
+			// Virtual Machine without the Region has been Found in the Default one, Storing it Explicitly
+			if len(VirtualMachine.Region) == 0 {
+				Updates["region"] = Region
+			}
+
+			if Item.ItemPath != VirtualMachine.ItemPath {
+				Logger.Info("Virtual Machine has been Moved", zap.Int("Virtual Machine ID", VirtualMachine.ID),
+					zap.String("Old ItemPath", VirtualMachine.ItemPath), zap.String("ItemPath", Item.ItemPath))
+				Updates["item_path"] = Item.ItemPath
+			}
+			// IP Address is only Known, while the Guest Tools are Running
+			if len(Item.IPAddress) != 0 && Item.IPAddress != VirtualMachine.IPAddress {
+				Updates["ip_address"] = Item.IPAddress
+			}
+		}
+		Reconciliation.Updates[VirtualMachine.ID] = Updates
+	}
+
+	for ManagedObjectId, Item := range Inventory {
+		if !Managed[ManagedObjectId] {
+			Reconciliation.Unmanaged = append(Reconciliation.Unmanaged, models.UnmanagedVirtualMachine{
+				Region:          Region,
+				ManagedObjectId: Item.ManagedObjectId,
+				ItemPath:        Item.ItemPath,
+				PowerState:      Item.PowerState,
+				DetectedAt:      ReconciledAt,
+			})
+		}
+	}
+	sort.Slice(Reconciliation.Unmanaged, func(First, Second int) bool {
+		return Reconciliation.Unmanaged[First].ManagedObjectId < Reconciliation.Unmanaged[Second].ManagedObjectId
+	})
+	return Reconciliation
+}
@@ -1,6 +1,7 @@
 package resource_config
 
 import (
+	"errors"
 	"os"
 
 	"github.com/vmware/govmomi/vim25/types"
@@ -64,9 +65,56 @@ func SetupResources(Resources VirtualMachineResources) (*types.VirtualMachineCon
 	ResourceSpecification := types.VirtualMachineConfigSpec{
 		NumCPUs:             Resources.CpuNum,
 		NumCoresPerSocket:   Resources.CpuNum / 2,
-		MemoryMB:            1024 * Resources.MemoryInMegabytes,
+		MemoryMB:            Resources.MemoryInMegabytes,
 		CpuHotAddEnabled:    types.NewBool(true),
 		MemoryHotAddEnabled: types.NewBool(true),
 	}
 	return &ResourceSpecification, nil
 }
+
+type VirtualMachineResourcesDelta struct {
+	// Minimal Reconfiguration, that is required to bring Virtual Machine Resources to the Requested ones
+
+	Specification      types.VirtualMachineConfigSpec
+	Changed            bool // Resources differ from the Current ones
+	RequiresPowerCycle bool // Change can't be Applied to the Running Virtual Machine
+}
+
+func SetupResourcesDelta(Hardware types.VirtualHardware, Config types.VirtualMachineConfigInfo, PoweredOn bool, Resources VirtualMachineResources) (*VirtualMachineResourcesDelta, error) {
+	// Computes Reconfiguration, that only Contains Resources, that has been Changed
+	// Zero Values of the Requested Resources means they should stay the same
+
+	if Resources.CpuNum < 0 || Resources.MemoryInMegabytes < 0 {
+		return nil, errors.New("Resources can't be Negative")
+	}
+
+	Target, _ := SetupResources(Resources)
+	Delta := &VirtualMachineResourcesDelta{}
+
+	if Resources.CpuNum != 0 && Target.NumCPUs != Hardware.NumCPU {
+		Delta.Changed = true
+		Delta.Specification.NumCPUs = Target.NumCPUs
+
+		// CPUs can only be Added to the Running Virtual Machine, if CPU Hot Add is Enabled
+		// Removing CPUs always requires the Virtual Machine to be Powered Off
+		HotAddEnabled := Config.CpuHotAddEnabled != nil && *Config.CpuHotAddEnabled
+		if PoweredOn && (Target.NumCPUs < Hardware.NumCPU || !HotAddEnabled) {
+			Delta.RequiresPowerCycle = true
+		}
+		if Delta.RequiresPowerCycle && Target.NumCoresPerSocket != 0 {
+			Delta.Specification.NumCoresPerSocket = Target.NumCoresPerSocket
+		}
+	}
+
+	if Resources.MemoryInMegabytes != 0 && Target.MemoryMB != int64(Hardware.MemoryMB) {
+		Delta.Changed = true
+		Delta.Specification.MemoryMB = Target.MemoryMB
+
+		// Same as for the CPUs, Memory can be only Hot Added, but not Removed
+		HotAddEnabled := Config.MemoryHotAddEnabled != nil && *Config.MemoryHotAddEnabled
+		if PoweredOn && (Target.MemoryMB < int64(Hardware.MemoryMB) || !HotAddEnabled) {
+			Delta.RequiresPowerCycle = true
+		}
+	}
+	return Delta, nil
+}
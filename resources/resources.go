@@ -443,8 +443,8 @@ func (this *ClusterComputeResourceManager) HasEnoughResources(ClusterComputeReso
 	if ClusterComputeResource.Summary.GetComputeResourceSummary().TotalCpu < Requirements.CpuNum {
 		return false
 	}
-	// Checking if Total Memory in Megabytes is enough, to deploy customer Application
-	if ClusterComputeResource.Summary.GetComputeResourceSummary().TotalMemory*1024 < Requirements.MemoryInMegabytes {
+	// Checking if Total Memory in Megabytes is enough, to deploy customer Application, the vSphere Reports it in Bytes
+	if ClusterComputeResource.Summary.GetComputeResourceSummary().TotalMemory/1024/1024 < Requirements.MemoryInMegabytes {
 		return false
 	}
 	return true
@@ -0,0 +1,416 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/alert_rest"
+	"github.com/LovePelmeni/Infrastructure/alerting"
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/healthcheck"
+	"github.com/LovePelmeni/Infrastructure/healthcheck_rest"
+	"github.com/LovePelmeni/Infrastructure/idempotency"
+	"github.com/LovePelmeni/Infrastructure/jobs"
+	"github.com/LovePelmeni/Infrastructure/mailer"
+	"github.com/LovePelmeni/Infrastructure/metrics"
+	"github.com/LovePelmeni/Infrastructure/mfa"
+	"github.com/LovePelmeni/Infrastructure/middlewares"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/oidc"
+	"github.com/LovePelmeni/Infrastructure/organization_rest"
+	"github.com/LovePelmeni/Infrastructure/ratelimit"
+	"github.com/LovePelmeni/Infrastructure/reconciler"
+	"github.com/LovePelmeni/Infrastructure/snapshot_rest"
+	"github.com/LovePelmeni/Infrastructure/ssh_rest"
+	"github.com/LovePelmeni/Infrastructure/stream"
+	"github.com/LovePelmeni/Infrastructure/stream_rest"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
+	"github.com/LovePelmeni/Infrastructure/webhook_rest"
+	"github.com/LovePelmeni/Infrastructure/webhooks"
+
+	customer_rest "github.com/LovePelmeni/Infrastructure/customer_rest"
+	host_search_rest "github.com/LovePelmeni/Infrastructure/host_search_rest"
+	suggestion_rest "github.com/LovePelmeni/Infrastructure/suggestion_rest"
+
+	vm_rest "github.com/LovePelmeni/Infrastructure/vm_rest"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// Package consists of the HTTP Server of the Application: Routes of the API and the Background Subsystems, that are being Started along with it
+
+var (
+	Logger *zap.Logger
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("ServerLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	// Initializing Production Logger using Uber SDK
+	InitializeProductionLogger()
+}
+
+func Configure(Config *config.Config) {
+	// Passes the Sections of the Configuration to the Subsystems, should be Called before the Server is Started
+	authentication.Configure(Config.Authentication)
+	mfa.Configure(Config.Mfa)
+	oidc.Configure(Config.Oidc)
+	mailer.Configure(Config.Mailer)
+	ratelimit.Configure(Config.RateLimit)
+	idempotency.Configure(Config.Idempotency)
+	models.Configure(Config.Customers)
+	middlewares.Configure(Config.Cache)
+	jobs.Configure(Config.Jobs, middlewares.RedisClient)
+	stream.Configure(Config.Stream, middlewares.RedisClient)
+	vsphere.Configure(Config.Vsphere)
+	healthcheck.Configure(Config.Metrics)
+	alerting.Configure(Config.Alerting)
+	webhooks.Configure(Config.Webhooks)
+	customer_rest.Configure(Config.Application)
+}
+
+type Server struct {
+	ServerHost string `json:"ServerHost"`
+	ServerPort string `json:"ServerPort"`
+
+	Config     *config.Config                  `json:"-"`
+	Reconciler *reconciler.InventoryReconciler `json:"-"`
+	Collector  *healthcheck.MetricsCollector   `json:"-"`
+	Watcher    *stream.PowerStateWatcher       `json:"-"`
+	Dispatcher *webhooks.Dispatcher            `json:"-"`
+}
+
+func NewServer(Config *config.Config) *Server {
+	return &Server{
+		ServerHost: Config.Application.Host,
+		ServerPort: strconv.Itoa(Config.Application.Port),
+		Config:     Config,
+	}
+}
+
+func RateLimit(Name string, Requests int, Period time.Duration) gin.HandlerFunc {
+	// Returns Rate Limiting Middleware of the Route Group, the Limit can be Overridden with the `RATE_LIMIT_<NAME>` Configuration Key
+	return middlewares.RateLimitMiddleware(Name, ratelimit.GetLimit(Name, ratelimit.NewLimit(Requests, Period)))
+}
+
+func (this *Server) NewRouter() *gin.Engine {
+	// Returns Router with all the Rest API Endpoints of the Server, Background Services are being Started by the `Run`
+
+	Router := gin.Default()
+	Router.Use(middlewares.MetricsMiddleware())
+	// Setting Up Cross Origin Resource Sharing Policy
+
+	Router.Use(cors.New(cors.Config{
+		AllowOrigins: []string{
+			fmt.Sprintf("http://%s:%s", this.ServerHost, this.ServerPort),
+			this.Config.Application.GetFrontApplicationURL(),
+		},
+		AllowMethods:     []string{"POST", "PUT", "DELETE", "GET", "OPTIONS"},
+		AllowCredentials: true,
+		AllowHeaders:     []string{"*"},
+		AllowWebSockets:  false,
+	}))
+
+	// Setting up Healthcheck Rest Endpoint
+
+	Router.GET("/ping/", func(context *gin.Context) {
+		context.JSON(http.StatusOK, nil)
+	})
+
+	// Prometheus Metrics of the Requests, vSphere Calls and Virtual Machines
+
+	Router.GET("/metrics", metrics.DefaultRegistry.Handler(this.Config.Metrics.Token))
+
+	// Customers Rest API Endpoints
+	// Endpoints, that Check Credentials or Send Emails, have the Stricter Limit, since every Attempt is Expensive
+
+	AuthenticationLimit := RateLimit("authentication", 10, time.Minute)
+
+	CustomerGroup := Router.Group("/customer/", RateLimit("customer", 60, time.Minute))
+	{
+		CustomerGroup.POST("/login/", AuthenticationLimit, customer_rest.LoginRestController, middlewares.NonAuthorizationRequiredMiddleware())
+		CustomerGroup.POST("/logout/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.LogoutRestController)
+		CustomerGroup.POST("/logout/all/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.LogoutAllSessionsRestController)
+		CustomerGroup.POST("/token/refresh/", AuthenticationLimit, customer_rest.RefreshTokenRestController)
+		CustomerGroup.POST("/login/mfa/", AuthenticationLimit, customer_rest.LoginMfaRestController, middlewares.NonAuthorizationRequiredMiddleware())
+		CustomerGroup.GET("/oidc/login/", AuthenticationLimit, customer_rest.OidcLoginRestController)
+		CustomerGroup.GET("/oidc/callback/", AuthenticationLimit, customer_rest.OidcCallbackRestController)
+
+		CustomerGroup.POST("/mfa/enroll/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.EnrollMfaRestController)
+		CustomerGroup.POST("/mfa/verify/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.VerifyMfaRestController)
+		CustomerGroup.POST("/mfa/disable/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.DisableMfaRestController)
+
+		CustomerGroup.POST("/api-keys/create/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.CreateApiKeyRestController)
+		CustomerGroup.GET("/api-keys/list/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.ListApiKeysRestController)
+		CustomerGroup.DELETE("/api-keys/revoke/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.RevokeApiKeyRestController)
+
+		CustomerGroup.POST("/create/", AuthenticationLimit, customer_rest.CreateCustomerRestController, middlewares.NonAuthorizationRequiredMiddleware())
+		CustomerGroup.PUT("/reset/password/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.ResetPasswordRestController)
+		CustomerGroup.POST("/password/forgot/", AuthenticationLimit, customer_rest.ForgotPasswordRestController)
+		CustomerGroup.POST("/password/reset/", AuthenticationLimit, customer_rest.ResetForgottenPasswordRestController)
+		CustomerGroup.GET("/email/verify/", customer_rest.VerifyEmailRestController)
+		CustomerGroup.POST("/email/verify/resend/", AuthenticationLimit, customer_rest.ResendVerificationEmailRestController)
+		CustomerGroup.DELETE("/delete/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.DeleteCustomerRestController)
+		CustomerGroup.GET("/get/profile/", middlewares.AuthorizationRequiredMiddleware(), customer_rest.GetCustomerProfileRestController)
+	}
+
+	// Organizations Rest API Endpoints, Access to the Virtual Machines is being Shared between the Members of the Organization
+
+	OrganizationGroup := Router.Group("/organization/").Use(RateLimit("organization", 120, time.Minute), middlewares.AuthorizationRequiredMiddleware())
+	{
+		OrganizationGroup.POST("/create/", organization_rest.CreateOrganizationRestController)
+		OrganizationGroup.GET("/list/", organization_rest.ListOrganizationsRestController)
+		OrganizationGroup.PUT("/mfa/require/", organization_rest.SetMfaRequirementRestController)
+
+		OrganizationGroup.POST("/project/create/", organization_rest.CreateProjectRestController)
+		OrganizationGroup.GET("/project/list/", organization_rest.ListProjectsRestController)
+
+		OrganizationGroup.GET("/member/list/", organization_rest.ListMembersRestController)
+		OrganizationGroup.POST("/member/add/", organization_rest.AddMemberRestController)
+		OrganizationGroup.PUT("/member/role/", organization_rest.UpdateMemberRoleRestController)
+		OrganizationGroup.DELETE("/member/remove/", organization_rest.RemoveMemberRestController)
+	}
+
+	// Virtual Machines Rest API Endpoints
+
+	// Routes, that Create new Virtual Machines, can be Safely Retried with the `X-Idempotency-Key` Header
+	Idempotent := middlewares.RequestIdempotencyMiddleware()
+
+	VirtualMachineGroup := Router.Group("/vm/").Use(
+		RateLimit("vm", 120, time.Minute),
+		middlewares.AuthorizationRequiredMiddleware(),
+		middlewares.PolicyCheckMiddleware(),
+		middlewares.InfrastructureHealthCircuitBreakerMiddleware())
+	{
+		{
+			VirtualMachineGroup.POST("/initialize/", Idempotent, vm_rest.InitializeVirtualMachineRestController) // initialized new Virtual Machine (Emtpy)
+			VirtualMachineGroup.PUT("/deploy/", vm_rest.DeployVirtualMachineRestController)                      // Applies Configuration to the Initialized Machine
+			VirtualMachineGroup.DELETE("/remove/", vm_rest.RemoveVirtualMachineRestController)                   // Removes Existing Virtual Machine
+			VirtualMachineGroup.POST("/clone/", Idempotent, vm_rest.CloneVirtualMachineRestController)           // Clones Existing Virtual Machine
+			VirtualMachineGroup.PATCH("/resources/", vm_rest.ResizeVirtualMachineRestController)                 // Changes CPU, Memory and Disk of the Virtual Machine
+			VirtualMachineGroup.POST("/start/", vm_rest.StartVirtualMachineRestController)                       // Starts Virtual Machine
+			VirtualMachineGroup.POST("/reboot/", vm_rest.RebootVirtualMachineRestController)                     // Reboots Virtual Machine
+			VirtualMachineGroup.DELETE("/shutdown/", vm_rest.ShutdownVirtualMachineRestController)               // Shutting Down Virtual Machine
+		}
+
+		{
+			VirtualMachineGroup.GET("/get/list/", vm_rest.GetCustomerVirtualMachines) // Customer's Virtual Machines
+			VirtualMachineGroup.GET("/get/", vm_rest.GetCustomerVirtualMachine)       // Customer's Specific Virtual Machine
+		}
+		VirtualMachineGroup.GET("/health/metrics/", healthcheck_rest.GetVirtualMachineHealthMetricRestController)                // HealthCheck Metrics of the Virtual Machine
+		VirtualMachineGroup.GET("/health/metrics/history/", healthcheck_rest.GetVirtualMachineHealthMetricHistoryRestController) // Health History of the Virtual Machine
+		VirtualMachineGroup.GET("/jobs/:JobId/", vm_rest.GetVirtualMachineJobRestController)                                     // Status of the Virtual Machine Operation Job
+	}
+
+	// Virtual Machine Snapshot Rest Endpoints
+
+	SnapshotGroup := Router.Group("/vm/snapshot/").Use(
+		RateLimit("snapshot", 60, time.Minute),
+		middlewares.AuthorizationRequiredMiddleware(),
+		middlewares.PolicyCheckMiddleware(),
+		middlewares.InfrastructureHealthCircuitBreakerMiddleware())
+	{
+		SnapshotGroup.GET("/list/", snapshot_rest.ListSnapshotsRestController)       // Snapshots of the Virtual Machine
+		SnapshotGroup.POST("/create/", snapshot_rest.CreateSnapshotRestController)   // Takes new Snapshot
+		SnapshotGroup.POST("/revert/", snapshot_rest.RevertSnapshotRestController)   // Reverts Virtual Machine to the Snapshot
+		SnapshotGroup.DELETE("/remove/", snapshot_rest.RemoveSnapshotRestController) // Removes Snapshot
+	}
+
+	// Host System Rest Endpoints
+
+	HostSystemGroup := Router.Group("/host/").Use(
+		RateLimit("host", 60, time.Minute),
+		middlewares.AuthorizationRequiredMiddleware(),
+		middlewares.PolicyCheckMiddleware(),
+		middlewares.InfrastructureHealthCircuitBreakerMiddleware())
+	{
+		HostSystemGroup.POST("system/start/", vm_rest.StartGuestOSRestController)
+		HostSystemGroup.PUT("system/restart/", vm_rest.RebootGuestOSRestController)
+		HostSystemGroup.DELETE("system/shutdown/", vm_rest.ShutdownGuestOsRestController)
+	}
+
+	// SSH Rest Endpoints
+
+	SshSystemGroup := Router.Group("/ssh/").Use(
+		RateLimit("ssh", 30, time.Minute),
+		middlewares.AuthorizationRequiredMiddleware(),
+		middlewares.PolicyCheckMiddleware(),
+		middlewares.InfrastructureHealthCircuitBreakerMiddleware(),
+	)
+	{
+		SshSystemGroup.GET("/get/ssh/certificate/", ssh_rest.GetDownloadPublicSshCertificateRestController)
+	}
+
+	// Suggestions Rest Endpoints
+
+	SuggestionsGroup := Router.Group("/suggestions/").Use(
+		RateLimit("suggestions", 60, time.Minute),
+		middlewares.AuthorizationRequiredMiddleware(),
+		middlewares.InfrastructureHealthCircuitBreakerMiddleware())
+	{
+		SuggestionsGroup.POST("/datacenter/", suggestion_rest.GetDatacentersSuggestions) // do not change to Safe Methods such as GET, OPTIONS, etc...
+		SuggestionsGroup.GET("/os/", suggestion_rest.GetAvailableOsSystemsRestController)
+		SuggestionsGroup.GET("/load/balancer/", suggestion_rest.GetAvailableLoadBalancersRestController)
+		SuggestionsGroup.GET("/pre/installed/tool/", suggestion_rest.GetAvailableInstallationToolsRestController)
+	}
+
+	// Host Machine Search Engine Rest Endpoints
+
+	SearchEngineGroup := Router.Group("/host/machine/", RateLimit("search", 30, time.Minute))
+	{
+		SearchEngineGroup.POST("/search/", host_search_rest.FindHostMachineRestController)
+	}
+
+	// Alerting Rest API Endpoints
+
+	AlertGroup := Router.Group("/alerts/").Use(RateLimit("alerts", 60, time.Minute), middlewares.AuthorizationRequiredMiddleware())
+	{
+		AlertGroup.POST("/rules/create/", alert_rest.CreateAlertRuleRestController)
+		AlertGroup.GET("/rules/list/", alert_rest.ListAlertRulesRestController)
+		AlertGroup.DELETE("/rules/remove/", alert_rest.RemoveAlertRuleRestController)
+
+		AlertGroup.POST("/channels/create/", alert_rest.CreateAlertChannelRestController)
+		AlertGroup.GET("/channels/list/", alert_rest.ListAlertChannelsRestController)
+		AlertGroup.DELETE("/channels/remove/", alert_rest.RemoveAlertChannelRestController)
+
+		AlertGroup.GET("/list/", alert_rest.ListAlertsRestController) // Latest Alerts of the Project
+	}
+
+	// Event Stream Rest API Endpoints
+
+	StreamGroup := Router.Group("/stream/").Use(RateLimit("stream", 10, time.Minute), middlewares.AuthorizationRequiredMiddleware())
+	{
+		StreamGroup.GET("/events/", stream_rest.StreamEventsRestController) // Server-Sent Events of the Customer's Virtual Machines
+	}
+
+	// Webhooks Rest API Endpoints
+
+	WebhookGroup := Router.Group("/webhooks/").Use(RateLimit("webhooks", 60, time.Minute), middlewares.AuthorizationRequiredMiddleware())
+	{
+		WebhookGroup.POST("/create/", webhook_rest.CreateWebhookRestController)
+		WebhookGroup.GET("/list/", webhook_rest.ListWebhooksRestController)
+		WebhookGroup.DELETE("/remove/", webhook_rest.RemoveWebhookRestController)
+
+		WebhookGroup.GET("/deliveries/", webhook_rest.ListWebhookDeliveriesRestController)       // Delivery Log of the Webhook
+		WebhookGroup.POST("/deliveries/redeliver/", webhook_rest.RedeliverWebhookRestController) // Sends the Event once again
+	}
+
+	// Support Rest API Endpoints
+
+	SupportGroup := Router.Group("/support/").Use(RateLimit("support", 10, time.Minute), middlewares.AuthorizationRequiredMiddleware())
+	{
+		SupportGroup.POST("/feedback/", customer_rest.SupportRestController)
+	}
+
+	return Router
+}
+
+func (this *Server) Run() {
+
+	Router := this.NewRouter()
+
+	// Starting Broker, that Streams the Events of the Virtual Machines to the Customers, the Progress of the Jobs is being
+	// Published through the Redis, since the Customer's Stream may be Served by the other Replica
+	stream.DefaultBroker.Start()
+	models.SubscribeJobs(stream.DefaultBroker.ObserveJob)
+
+	// Starting Workers, that Execute Virtual Machine Operation Jobs in the Background
+	jobs.WorkerPool.Start()
+
+	// Connecting to the vSphere Endpoints of all the Regions, the Sessions are being Kept Alive and Restored in the Background
+	vsphere.Registry.Start()
+
+	// Starting Reconciler, that Keeps Virtual Machine Records in Sync with the vSphere Inventory
+	this.Reconciler = reconciler.NewInventoryReconciler(vsphere.Registry, this.Config.Reconciler.Interval)
+	this.Reconciler.Start()
+
+	// Starting Collector, that Refreshes Health Metrics of the Virtual Machines
+	// Alert Rules are being Evaluated after every Collection
+	this.Collector = healthcheck.NewMetricsCollector(vsphere.Registry, this.Config.Metrics.CollectInterval)
+	this.Collector.Subscribe(alerting.NewEvaluator(alerting.NewNotifiers()).Observe)
+	this.Collector.Subscribe(stream.DefaultBroker.ObserveHealth)
+	this.Collector.Start()
+
+	// Starting Watcher, that Streams the Power State Changes of the Virtual Machines as soon as the vSphere Reports them
+	this.Watcher = stream.NewPowerStateWatcher(vsphere.Registry, stream.DefaultBroker)
+	this.Watcher.Start()
+
+	// Starting Dispatcher, that Sends the Lifecycle Events of the Virtual Machines to the Webhooks of the Customers
+	this.Dispatcher = webhooks.NewDispatcher()
+	this.Dispatcher.Start()
+
+	Server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%s", this.ServerHost, this.ServerPort),
+		Handler: Router,
+	}
+
+	ServerShutDownContext, ErrorCancelMethod := signal.NotifyContext(context.Background(),
+		os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT) // Creating Notify Context that triggers server to shut down
+	// after receiving system SIGTERM or SIGQUIT Signal from Kubernetes / Localhost.
+
+	// `ListenAndServe` Returns as soon as the Shutdown Begins, so the Run Waits until the Jobs and the Subsystems are Stopped
+	ShutdownFinished := make(chan struct{})
+	go this.Shutdown(ServerShutDownContext, ErrorCancelMethod, Server, ShutdownFinished)
+
+	Exception := Server.ListenAndServe()
+	if !errors.Is(Exception, http.ErrServerClosed) {
+		fmt.Print("Server has been Shutdown For Some Reason, Check `ServerLog.json` for more info")
+		Logger.Error(
+			"Error while Running the Server", zap.NamedError("RuntimeError", Exception))
+		// Stopping the Subsystems, that have been Started, since there is no Signal to Wait for
+		ErrorCancelMethod()
+	}
+	<-ShutdownFinished
+}
+
+func (this *Server) Shutdown(Context context.Context, CancelFunc context.CancelFunc, ServerInstance *http.Server, Finished chan<- struct{}) {
+	select {
+	case <-Context.Done():
+		defer close(Finished)
+		defer CancelFunc()
+		// Finishing the Open Event Streams, otherwise the Server Waits for them
+		stream.DefaultBroker.Stop()
+		ShutdownError := ServerInstance.Shutdown(context.Background())
+		Logger.Info("Server has been Shutdown", zap.NamedError("ShutdownError", ShutdownError))
+
+		// Waiting for the Jobs, that are being Executed right now
+		jobs.WorkerPool.Stop()
+
+		if this.Reconciler != nil {
+			this.Reconciler.Stop()
+		}
+		if this.Collector != nil {
+			this.Collector.Stop()
+		}
+		if this.Watcher != nil {
+			this.Watcher.Stop()
+		}
+		if this.Dispatcher != nil {
+			this.Dispatcher.Stop()
+		}
+		vsphere.Registry.Stop()
+	}
+}
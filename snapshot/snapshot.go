@@ -0,0 +1,157 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+
+	"os"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/exceptions"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Package consists of the Manager, that Wraps vSphere Snapshot Tasks of the Virtual Machine Server
+// (Create, Revert, Remove), so the Customer can take a Checkpoint before Risky Changes and Roll Back
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	SnapshotTimeout = time.Minute * 10
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("SnapshotLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+type VirtualMachineSnapshotOptions struct {
+	// Options of the new Virtual Machine Snapshot
+
+	Name        string
+	Description string
+	Memory      bool // Includes Memory Dump of the Running Virtual Machine, so it can be Reverted to the Powered On State
+	Quiesce     bool // Flushes Guest File System Buffers Before taking the Snapshot (Requires VMware Tools)
+}
+
+func NewVirtualMachineSnapshotOptions(Name string, Description string, Memory bool, Quiesce bool) *VirtualMachineSnapshotOptions {
+	return &VirtualMachineSnapshotOptions{
+		Name:        Name,
+		Description: Description,
+		Memory:      Memory,
+		Quiesce:     Quiesce,
+	}
+}
+
+func (this *VirtualMachineSnapshotOptions) Validate() error {
+	// Validates Snapshot Options
+	if len(this.Name) == 0 || len(this.Name) > 80 {
+		return errors.New("Snapshot Name should be between 1 and 80 Characters")
+	}
+	return nil
+}
+
+type VirtualMachineSnapshotManagerInterface interface {
+	// Interface, represents Manager Class, for handling Snapshots of the Virtual Machine
+	CreateSnapshot(VirtualMachine *object.VirtualMachine, Options VirtualMachineSnapshotOptions) (string, error)
+	RevertToSnapshot(VirtualMachine *object.VirtualMachine, SnapshotRef string, SuppressPowerOn bool) error
+	RemoveSnapshot(VirtualMachine *object.VirtualMachine, SnapshotRef string, RemoveChildren bool) error
+}
+
+type VirtualMachineSnapshotManager struct {
+	// Manager Class, for handling Snapshots of the Virtual Machine
+	VirtualMachineSnapshotManagerInterface
+	Client vim25.Client
+}
+
+func NewVirtualMachineSnapshotManager(Client vim25.Client) *VirtualMachineSnapshotManager {
+	return &VirtualMachineSnapshotManager{
+		Client: Client,
+	}
+}
+
+func (this *VirtualMachineSnapshotManager) CreateSnapshot(VirtualMachine *object.VirtualMachine, Options VirtualMachineSnapshotOptions) (string, error) {
+	// Takes new Snapshot of the Virtual Machine and Returns Managed Object ID of it
+
+	if ValidationError := Options.Validate(); ValidationError != nil {
+		return "", ValidationError
+	}
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), SnapshotTimeout)
+	defer CancelFunc()
+
+	SnapshotTask, SnapshotError := VirtualMachine.CreateSnapshot(TimeoutContext,
+		Options.Name, Options.Description, Options.Memory, Options.Quiesce)
+	if SnapshotError != nil {
+		Logger.Error("Failed to Create Snapshot", zap.String("Virtual Machine", VirtualMachine.InventoryPath), zap.Error(SnapshotError))
+		return "", exceptions.SnapshotFailure()
+	}
+
+	SnapshotInfo, WaitError := SnapshotTask.WaitForResult(TimeoutContext, nil)
+	if WaitError != nil {
+		Logger.Error("Failed to Create Snapshot", zap.String("Virtual Machine", VirtualMachine.InventoryPath), zap.Error(WaitError))
+		return "", exceptions.SnapshotFailure()
+	}
+
+	SnapshotReference := SnapshotInfo.Result.(types.ManagedObjectReference)
+	Logger.Info("Snapshot has been Created", zap.String("Virtual Machine", VirtualMachine.InventoryPath),
+		zap.String("Snapshot", SnapshotReference.Value))
+	return SnapshotReference.Value, nil
+}
+
+func (this *VirtualMachineSnapshotManager) RevertToSnapshot(VirtualMachine *object.VirtualMachine, SnapshotRef string, SuppressPowerOn bool) error {
+	// Reverts Virtual Machine to the Snapshot
+	// `SnapshotRef` can be either Managed Object ID or the Name of the Snapshot
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), SnapshotTimeout)
+	defer CancelFunc()
+
+	RevertTask, RevertError := VirtualMachine.RevertToSnapshot(TimeoutContext, SnapshotRef, SuppressPowerOn)
+	if RevertError == nil {
+		RevertError = RevertTask.Wait(TimeoutContext)
+	}
+
+	if RevertError != nil {
+		Logger.Error("Failed to Revert to the Snapshot", zap.String("Virtual Machine", VirtualMachine.InventoryPath),
+			zap.String("Snapshot", SnapshotRef), zap.Error(RevertError))
+		return exceptions.SnapshotFailure()
+	}
+	return nil
+}
+
+func (this *VirtualMachineSnapshotManager) RemoveSnapshot(VirtualMachine *object.VirtualMachine, SnapshotRef string, RemoveChildren bool) error {
+	// Removes Snapshot of the Virtual Machine and Consolidates it's Disks
+
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), SnapshotTimeout)
+	defer CancelFunc()
+
+	RemoveTask, RemoveError := VirtualMachine.RemoveSnapshot(TimeoutContext, SnapshotRef, RemoveChildren, types.NewBool(true))
+	if RemoveError == nil {
+		RemoveError = RemoveTask.Wait(TimeoutContext)
+	}
+
+	if RemoveError != nil {
+		Logger.Error("Failed to Remove Snapshot", zap.String("Virtual Machine", VirtualMachine.InventoryPath),
+			zap.String("Snapshot", SnapshotRef), zap.Error(RemoveError))
+		return exceptions.SnapshotFailure()
+	}
+	return nil
+}
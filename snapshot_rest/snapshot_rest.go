@@ -0,0 +1,288 @@
+package snapshot_rest
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/exceptions"
+	"github.com/LovePelmeni/Infrastructure/jobs"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/snapshot"
+	"github.com/LovePelmeni/Infrastructure/vm_rest"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of Rest API Controllers, for Managing Snapshots of the Virtual Machine Server
+// Creating, Reverting and Removing Snapshots are Long Running Operations, so they are being Executed as Jobs
+
+var (
+	Logger *zap.Logger
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("SnapshotRestLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+
+	// Registering Handlers for the Snapshot Jobs
+	jobs.WorkerPool.RegisterHandler(models.JobTypeCreateSnapshot, CreateSnapshotJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeRevertSnapshot, RevertSnapshotJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeRemoveSnapshot, RemoveSnapshotJobHandler)
+}
+
+type CreateSnapshotPayload struct {
+	// Payload of the Job, that Takes new Snapshot of the Virtual Machine
+	Name        string `json:"Name"`
+	Description string `json:"Description"`
+	Memory      bool   `json:"Memory"`
+	Quiesce     bool   `json:"Quiesce"`
+}
+
+type SnapshotOperationPayload struct {
+	// Payload of the Job, that Reverts the Virtual Machine to the Snapshot or Removes it
+	SnapshotId      int  `json:"SnapshotId"`
+	SuppressPowerOn bool `json:"SuppressPowerOn"`
+}
+
+func GetCustomerVirtualMachine(RequestContext *gin.Context) (*models.VirtualMachine, int, bool) {
+	// Returns Virtual Machine, specified in the `VirtualMachineId` Query Param, from the Projects, the Customer is Member of
+	// Responds with the Error and Returns false, if the Customer is not Authorized or the Virtual Machine Does not Exist
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return nil, 0, false
+	}
+
+	var VirtualMachine models.VirtualMachine
+	models.Database.Model(&models.VirtualMachine{}).Where(
+		"id = ?", RequestContext.Query("VirtualMachineId")).Scopes(models.AccessibleBy(jwtCredentials.UserId)).Find(&VirtualMachine)
+
+	if VirtualMachine.ID == 0 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Virtual Machine Does Not Exist"})
+		return nil, 0, false
+	}
+	return &VirtualMachine, jwtCredentials.UserId, true
+}
+
+func GetVirtualMachineSnapshot(RequestContext *gin.Context, VirtualMachineId int) (*models.Snapshot, bool) {
+	// Returns Snapshot of the Virtual Machine, specified in the `SnapshotId` Query Param
+
+	var Snapshot models.Snapshot
+	models.Database.Model(&models.Snapshot{}).Where(
+		"id = ? AND virtual_machine_id = ?", RequestContext.Query("SnapshotId"), VirtualMachineId).Find(&Snapshot)
+
+	if Snapshot.ID == 0 {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Snapshot Does Not Exist"})
+		return nil, false
+	}
+	return &Snapshot, true
+}
+
+func ListSnapshotsRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns Snapshots of the Virtual Machine, from the Newest to the Oldest one
+
+	VirtualMachine, _, Found := GetCustomerVirtualMachine(RequestContext)
+	if !Found {
+		return
+	}
+
+	var Snapshots []models.Snapshot
+	if Gorm := models.Database.Model(&models.Snapshot{}).Where(
+		"virtual_machine_id = ?", VirtualMachine.ID).Order("created_at DESC").Find(&Snapshots); Gorm.Error != nil {
+		Logger.Error("Failed to Receive Virtual Machine Snapshots", zap.Error(Gorm.Error))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Receive Snapshots"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": Snapshots})
+}
+
+func CreateSnapshotRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Takes new Snapshot of the Virtual Machine
+
+	VirtualMachine, OwnerId, Found := GetCustomerVirtualMachine(RequestContext)
+	if !Found {
+		return
+	}
+
+	Memory, MemoryError := strconv.ParseBool(RequestContext.DefaultPostForm("Memory", "false"))
+	Quiesce, QuiesceError := strconv.ParseBool(RequestContext.DefaultPostForm("Quiesce", "false"))
+	if MemoryError != nil || QuiesceError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Snapshot Options has been Passed"})
+		return
+	}
+
+	Payload := CreateSnapshotPayload{
+		Name:        RequestContext.PostForm("Name"),
+		Description: RequestContext.PostForm("Description"),
+		Memory:      Memory,
+		Quiesce:     Quiesce,
+	}
+
+	Options := snapshot.NewVirtualMachineSnapshotOptions(Payload.Name, Payload.Description, Payload.Memory, Payload.Quiesce)
+	if ValidationError := Options.Validate(); ValidationError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": ValidationError.Error()})
+		return
+	}
+	vm_rest.EnqueueVirtualMachineJob(RequestContext, models.JobTypeCreateSnapshot, VirtualMachine.ID, OwnerId, Payload)
+}
+
+func RevertSnapshotRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Reverts the Virtual Machine to the Snapshot
+
+	VirtualMachine, OwnerId, Found := GetCustomerVirtualMachine(RequestContext)
+	if !Found {
+		return
+	}
+
+	Snapshot, Found := GetVirtualMachineSnapshot(RequestContext, VirtualMachine.ID)
+	if !Found {
+		return
+	}
+
+	SuppressPowerOn, ParseError := strconv.ParseBool(RequestContext.DefaultPostForm("SuppressPowerOn", "false"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid SuppressPowerOn Flag has been Passed"})
+		return
+	}
+
+	Payload := SnapshotOperationPayload{SnapshotId: Snapshot.ID, SuppressPowerOn: SuppressPowerOn}
+	vm_rest.EnqueueVirtualMachineJob(RequestContext, models.JobTypeRevertSnapshot, VirtualMachine.ID, OwnerId, Payload)
+}
+
+func RemoveSnapshotRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Removes Snapshot of the Virtual Machine
+
+	VirtualMachine, OwnerId, Found := GetCustomerVirtualMachine(RequestContext)
+	if !Found {
+		return
+	}
+
+	Snapshot, Found := GetVirtualMachineSnapshot(RequestContext, VirtualMachine.ID)
+	if !Found {
+		return
+	}
+
+	Payload := SnapshotOperationPayload{SnapshotId: Snapshot.ID}
+	vm_rest.EnqueueVirtualMachineJob(RequestContext, models.JobTypeRemoveSnapshot, VirtualMachine.ID, OwnerId, Payload)
+}
+
+func GetJobSnapshot(Job *models.Job) (*models.Snapshot, error) {
+	// Returns Snapshot, the Job is Associated with
+
+	var Payload SnapshotOperationPayload
+	if DecodeError := Job.DecodePayload(&Payload); DecodeError != nil {
+		return nil, DecodeError
+	}
+
+	var Snapshot models.Snapshot
+	models.Database.Model(&models.Snapshot{}).Where(
+		"id = ? AND virtual_machine_id = ?", Payload.SnapshotId, Job.VirtualMachineId).Find(&Snapshot)
+
+	if Snapshot.ID == 0 {
+		return nil, exceptions.ItemDoesNotExist()
+	}
+	return &Snapshot, nil
+}
+
+func CreateSnapshotJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Takes new Snapshot of the Virtual Machine and Records it in the Database
+
+	var Payload CreateSnapshotPayload
+	if DecodeError := Job.DecodePayload(&Payload); DecodeError != nil {
+		return nil, DecodeError
+	}
+
+	VmManager, VirtualMachine, FindError := vm_rest.GetJobVirtualMachine(Job)
+	if FindError != nil {
+		return nil, FindError
+	}
+	Job.UpdateProgress(10)
+
+	SnapshotManager := snapshot.NewVirtualMachineSnapshotManager(VmManager.VimClient)
+	Options := snapshot.NewVirtualMachineSnapshotOptions(Payload.Name, Payload.Description, Payload.Memory, Payload.Quiesce)
+
+	SnapshotRef, SnapshotError := SnapshotManager.CreateSnapshot(VirtualMachine, *Options)
+	if SnapshotError != nil {
+		return nil, SnapshotError
+	}
+	Job.UpdateProgress(90)
+
+	NewSnapshot := models.NewSnapshot(Job.VirtualMachineId, Job.OwnerId,
+		Payload.Name, Payload.Description, SnapshotRef, Payload.Memory, Payload.Quiesce)
+
+	if _, CreationError := NewSnapshot.Create(); CreationError != nil {
+		Logger.Error("Failed to Create Snapshot Database Record", zap.Error(CreationError))
+		return nil, CreationError
+	}
+	return gin.H{"Status": "Created", "SnapshotId": NewSnapshot.ID}, nil
+}
+
+func RevertSnapshotJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Reverts the Virtual Machine to the Snapshot
+
+	var Payload SnapshotOperationPayload
+	if DecodeError := Job.DecodePayload(&Payload); DecodeError != nil {
+		return nil, DecodeError
+	}
+
+	Snapshot, SnapshotError := GetJobSnapshot(Job)
+	if SnapshotError != nil {
+		return nil, SnapshotError
+	}
+
+	VmManager, VirtualMachine, FindError := vm_rest.GetJobVirtualMachine(Job)
+	if FindError != nil {
+		return nil, FindError
+	}
+	Job.UpdateProgress(10)
+
+	SnapshotManager := snapshot.NewVirtualMachineSnapshotManager(VmManager.VimClient)
+	if RevertError := SnapshotManager.RevertToSnapshot(VirtualMachine, Snapshot.SnapshotRef, Payload.SuppressPowerOn); RevertError != nil {
+		return nil, RevertError
+	}
+	return gin.H{"Status": "Reverted", "SnapshotId": Snapshot.ID}, nil
+}
+
+func RemoveSnapshotJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Removes Snapshot of the Virtual Machine along with it's Database Record
+
+	Snapshot, SnapshotError := GetJobSnapshot(Job)
+	if SnapshotError != nil {
+		return nil, SnapshotError
+	}
+
+	VmManager, VirtualMachine, FindError := vm_rest.GetJobVirtualMachine(Job)
+	if FindError != nil {
+		return nil, FindError
+	}
+	Job.UpdateProgress(10)
+
+	// Child Snapshots are being Kept, so the Database Records of them stay Valid
+	SnapshotManager := snapshot.NewVirtualMachineSnapshotManager(VmManager.VimClient)
+	if RemoveError := SnapshotManager.RemoveSnapshot(VirtualMachine, Snapshot.SnapshotRef, false); RemoveError != nil {
+		return nil, RemoveError
+	}
+	Job.UpdateProgress(90)
+
+	if _, DeleteError := Snapshot.Delete(); DeleteError != nil {
+		Logger.Error("Failed to Delete Snapshot Database Record", zap.Int("Snapshot ID", Snapshot.ID), zap.Error(DeleteError))
+		return nil, DeleteError
+	}
+	return gin.H{"Status": "Removed"}, nil
+}
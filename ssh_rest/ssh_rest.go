@@ -33,15 +33,15 @@ func init() {
 func GetDownloadPublicSshCertificateRestController(Context *gin.Context) {
 	// Rest Controller, that returns Download File for the Virtual Machine Server Ssh Option 
 
-	JwtCustomerCredentials, _ := authentication.GetCustomerJwtCredentials(Context.GetHeader("Authorization"))
+	JwtCustomerCredentials, _ := authentication.GetRequestCredentials(Context)
 	VirtualMachineId := Context.Query("VirtualMachineId")
-	VirtualMachineOwnerId := JwtCustomerCredentials.Id
+	CustomerId := JwtCustomerCredentials.UserId
 
 	// Retrieving Virtual Machine Model Record 
 
 	var VirtualMachine models.VirtualMachine 
 	models.Database.Model(&models.VirtualMachine{}).Where(
-	"id = ? AND owner_id = ?", VirtualMachineId, VirtualMachineOwnerId).Find(&VirtualMachine)
+	"id = ?", VirtualMachineId).Scopes(models.AccessibleBy(CustomerId)).Find(&VirtualMachine)
 
 
 	// Obtaining Info about the Initializing the SSH Certificates 
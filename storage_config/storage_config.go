@@ -1,6 +1,7 @@
 package storage_config
 
 import (
+	"errors"
 	"os"
 
 	"github.com/vmware/govmomi/object"
@@ -87,3 +88,21 @@ func (this *VirtualMachineStorageManager) SetupStorageDisk(
 	}
 	return DeviceSpec, nil
 }
+
+func (this *VirtualMachineStorageManager) GrowStorageDisk(Disk *types.VirtualDisk, CapacityInKB int64) (*types.VirtualDeviceConfigSpec, error) {
+	// Extends Existing Virtual Disk up to the new Capacity
+	// Disks can't be Shrunk, so the new Capacity should be bigger than the Current one
+
+	if CapacityInKB <= Disk.CapacityInKB {
+		return nil, errors.New("New Disk Capacity should be Bigger than the Current one")
+	}
+
+	Disk.CapacityInKB = CapacityInKB
+	Disk.CapacityInBytes = CapacityInKB * 1024
+
+	DeviceSpec := &types.VirtualDeviceConfigSpec{
+		Operation: types.VirtualDeviceConfigSpecOperationEdit,
+		Device:    Disk,
+	}
+	return DeviceSpec, nil
+}
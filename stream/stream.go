@@ -0,0 +1,379 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/healthcheck"
+	"github.com/LovePelmeni/Infrastructure/metrics"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
+	"github.com/go-redis/redis"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Broker, that Fans out the Events of the Virtual Machines (Power State Changes,
+// Job Progress and Health Metrics) to the Streams of the Customers
+// Power State and Health are being Observed by every Replica on it's own, so these Events are being Delivered locally,
+// Jobs are being Executed by the Worker of the single Replica, so their Events are being Delivered through the Redis Channel
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	Channel           = "stream:events" // Redis Channel, the Events are being Published to
+	KeepAliveInterval = 15 * time.Second
+	BufferSize        = 100
+	RetryInterval     = 10 * time.Second // Interval between the Attempts to Watch the Unavailable Region
+)
+
+const (
+	EventPowerState = "PowerState"
+	EventJob        = "Job"
+	EventHealth     = "Health"
+)
+
+var (
+	DefaultBroker *Broker
+)
+
+var (
+	Subscribers   = metrics.NewGaugeVec("stream_subscribers", "Number of the Open Event Streams of the Current Replica")
+	DroppedEvents = metrics.NewCounterVec("stream_events_dropped_total", "Number of the Events, Dropped for the Slow Streams", "type")
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("StreamLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+	metrics.Register(Subscribers, DroppedEvents)
+	DefaultBroker = NewBroker(nil)
+}
+
+func Configure(Config config.StreamConfig, Client *redis.Client) {
+	// Applies the Buffer Size of the Streams and the Redis Client of the Broker, should be Called before the Broker is Started
+	KeepAliveInterval = Config.KeepAliveInterval
+	BufferSize = Config.BufferSize
+	DefaultBroker.Client = Client
+}
+
+type Event struct {
+	// Event of the Virtual Machine, Events of the Jobs are being Delivered to their Owners,
+	// the other ones to the Members of the Project of the Virtual Machine
+	Type             string          `json:"Type"`
+	VirtualMachineId int             `json:"VirtualMachineId"`
+	ProjectId        int             `json:"ProjectId"`
+	OwnerId          int             `json:"OwnerId"`
+	Data             json.RawMessage `json:"Data"`
+	Timestamp        time.Time       `json:"Timestamp"`
+}
+
+func NewEvent(Type string, VirtualMachineId int, ProjectId int, OwnerId int, Data interface{}) (Event, error) {
+	Serialized, EncodeError := json.Marshal(Data)
+	if EncodeError != nil {
+		return Event{}, EncodeError
+	}
+	return Event{
+		Type:             Type,
+		VirtualMachineId: VirtualMachineId,
+		ProjectId:        ProjectId,
+		OwnerId:          OwnerId,
+		Data:             Serialized,
+		Timestamp:        time.Now(),
+	}, nil
+}
+
+// SUBSCRIPTIONS
+
+type Subscription struct {
+	// Stream of the Customer, Receives the Events of the Virtual Machines of the Projects, the Customer is Member of
+	CustomerId int
+	Events     chan Event
+	Mutex      sync.RWMutex
+	Projects   map[int]bool
+}
+
+func NewSubscription(CustomerId int, Projects []int) *Subscription {
+	Subscription := &Subscription{CustomerId: CustomerId, Events: make(chan Event, BufferSize)}
+	Subscription.SetProjects(Projects)
+	return Subscription
+}
+
+func (this *Subscription) SetProjects(Projects []int) {
+	// Replaces the Projects of the Customer, e.g once the Customer has Joined the new one
+	Accessible := make(map[int]bool, len(Projects))
+	for _, ProjectId := range Projects {
+		Accessible[ProjectId] = true
+	}
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.Projects = Accessible
+}
+
+func (this *Subscription) Accepts(Event Event) bool {
+	if Event.Type == EventJob {
+		return Event.OwnerId == this.CustomerId
+	}
+	this.Mutex.RLock()
+	defer this.Mutex.RUnlock()
+	return this.Projects[Event.ProjectId]
+}
+
+// BROKER
+
+type Broker struct {
+	// Delivers the Events to the Subscriptions of the Current Replica
+	// Events, Published through the Redis, are being Delivered to the Subscriptions of every Replica
+
+	Client        *redis.Client // Events are being Delivered only locally, if nil
+	PubSub        *redis.PubSub
+	Mutex         sync.RWMutex
+	Subscriptions map[*Subscription]bool
+	Group         sync.WaitGroup
+}
+
+func NewBroker(Client *redis.Client) *Broker {
+	return &Broker{Client: Client, Subscriptions: make(map[*Subscription]bool)}
+}
+
+func (this *Broker) Start() {
+	// Starts Receiving the Events of the other Replicas from the Redis Channel
+	if this.Client == nil {
+		return
+	}
+	this.PubSub = this.Client.Subscribe(Channel)
+	Messages := this.PubSub.Channel()
+
+	this.Group.Add(1)
+	go func() {
+		defer this.Group.Done()
+		for Message := range Messages {
+			var Received Event
+			if DecodeError := json.Unmarshal([]byte(Message.Payload), &Received); DecodeError != nil {
+				Logger.Error("Failed to Decode Stream Event", zap.Error(DecodeError))
+				continue
+			}
+			this.Deliver(Received)
+		}
+	}()
+	Logger.Info("Stream Broker has been Started", zap.String("Channel", Channel))
+}
+
+func (this *Broker) Stop() {
+	// Closes every Subscription, so the Open Streams are being Finished and the Server can Shut Down
+	if this.PubSub != nil {
+		this.PubSub.Close()
+	}
+	this.Group.Wait()
+
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	for Subscription := range this.Subscriptions {
+		close(Subscription.Events)
+		delete(this.Subscriptions, Subscription)
+	}
+	Subscribers.Set(0)
+	Logger.Info("Stream Broker has been Stopped")
+}
+
+func (this *Broker) Subscribe(Subscription *Subscription) {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.Subscriptions[Subscription] = true
+	Subscribers.Set(float64(len(this.Subscriptions)))
+}
+
+func (this *Broker) Unsubscribe(Subscription *Subscription) {
+	// Removes the Subscription, it's Channel is being Closed, unless the Broker has Closed it already
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	if this.Subscriptions[Subscription] {
+		close(Subscription.Events)
+		delete(this.Subscriptions, Subscription)
+	}
+	Subscribers.Set(float64(len(this.Subscriptions)))
+}
+
+func (this *Broker) Publish(Event Event) {
+	// Publishes the Event to the Subscriptions of every Replica
+	// Event is being Delivered locally, if the Redis is not Available, so the Current Replica does not Miss it at least
+	if this.Client != nil {
+		Serialized, _ := json.Marshal(Event)
+		PublishError := this.Client.Publish(Channel, Serialized).Err()
+		if PublishError == nil {
+			return
+		}
+		Logger.Error("Failed to Publish Stream Event", zap.String("Type", Event.Type), zap.Error(PublishError))
+	}
+	this.Deliver(Event)
+}
+
+func (this *Broker) Deliver(Event Event) {
+	// Delivers the Event to the Subscriptions of the Current Replica, the Event is being Dropped for the Subscription,
+	// that has not Received the Buffered ones yet, so the Slow Client does not Block the others
+	this.Mutex.RLock()
+	defer this.Mutex.RUnlock()
+	for Subscription := range this.Subscriptions {
+		if !Subscription.Accepts(Event) {
+			continue
+		}
+		select {
+		case Subscription.Events <- Event:
+		default:
+			DroppedEvents.Inc(Event.Type)
+		}
+	}
+}
+
+// SOURCES
+
+func (this *Broker) ObserveJob(Job models.Job) {
+	// Publishes the State and the Progress of the Job to it's Owner, Subscribed to the Jobs of the `models` package
+	Event, EncodeError := NewEvent(EventJob, Job.VirtualMachineId, 0, Job.OwnerId, Job)
+	if EncodeError != nil {
+		Logger.Error("Failed to Encode Job Event", zap.Int("JobId", Job.ID), zap.Error(EncodeError))
+		return
+	}
+	this.Publish(Event)
+}
+
+func (this *Broker) ObserveHealth(Records []models.VirtualMachine, VirtualMachines map[string]mo.VirtualMachine, Timestamp time.Time) {
+	// Delivers the Health Metrics of the Virtual Machines after the Collection, Subscribed to the Metrics Collector
+	Projects := make(map[int]int, len(Records))
+	for _, Record := range Records {
+		Projects[Record.ID] = Record.ProjectId
+	}
+	for _, Metric := range healthcheck.GetHistorySamples(Records, VirtualMachines, Timestamp) {
+		Event, EncodeError := NewEvent(EventHealth, Metric.VirtualMachineId, Projects[Metric.VirtualMachineId], 0, Metric)
+		if EncodeError != nil {
+			Logger.Error("Failed to Encode Health Event", zap.Int("VirtualMachineId", Metric.VirtualMachineId), zap.Error(EncodeError))
+			continue
+		}
+		this.Deliver(Event)
+	}
+}
+
+type PowerState struct {
+	PowerState string `json:"PowerState"`
+}
+
+type PowerStateWatcher struct {
+	// Watches the Power State of the Virtual Machines of every Region with the Property Collector of the vSphere,
+	// so the Changes are being Delivered at once instead of the next Collection
+
+	Registry *vsphere.EndpointRegistry
+	Broker   *Broker
+	Context  context.Context
+	Cancel   context.CancelFunc
+	Group    sync.WaitGroup
+}
+
+func NewPowerStateWatcher(Registry *vsphere.EndpointRegistry, Broker *Broker) *PowerStateWatcher {
+	Context, Cancel := context.WithCancel(context.Background())
+	return &PowerStateWatcher{Registry: Registry, Broker: Broker, Context: Context, Cancel: Cancel}
+}
+
+func (this *PowerStateWatcher) Start() {
+	// Starts Watching every Region in the Background, the Watch is being Restarted, once the Session of the Region Fails
+	for _, Pool := range this.Registry.GetPools() {
+		this.Group.Add(1)
+		go func(Pool *vsphere.SessionPool) {
+			defer this.Group.Done()
+			for {
+				if WatchError := this.WatchRegion(Pool); WatchError != nil && this.Context.Err() == nil {
+					Logger.Error("Failed to Watch Power State of the Region",
+						zap.String("Region", Pool.Endpoint.Region), zap.Error(WatchError))
+				}
+				select {
+				case <-this.Context.Done():
+					return
+				case <-time.After(RetryInterval):
+				}
+			}
+		}(Pool)
+	}
+	Logger.Info("Power State Watcher has been Started")
+}
+
+func (this *PowerStateWatcher) Stop() {
+	this.Cancel()
+	this.Group.Wait()
+	Logger.Info("Power State Watcher has been Stopped")
+}
+
+func (this *PowerStateWatcher) WatchRegion(Pool *vsphere.SessionPool) error {
+	// Waits for the Changes of the Power State of the Virtual Machines of the Region, until the Watcher is Stopped
+	Client, ClientError := Pool.GetClient()
+	if ClientError != nil {
+		return ClientError
+	}
+	return WatchPowerState(this.Context, Client.Client, func(ManagedObjectId string, State string) {
+		this.Publish(Pool.Endpoint.Region, ManagedObjectId, State)
+	})
+}
+
+func WatchPowerState(Context context.Context, Client *vim25.Client, OnChange func(ManagedObjectId string, State string)) error {
+	// Calls the Function on every Change of the Power State of any Virtual Machine of the vSphere, until the Context is Done
+
+	Manager := view.NewManager(Client)
+	ContainerView, ViewError := Manager.CreateContainerView(Context, Client.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
+	if ViewError != nil {
+		return ViewError
+	}
+	defer ContainerView.Destroy(context.Background())
+
+	Filter := new(property.WaitFilter).Add(ContainerView.Reference(), "VirtualMachine", []string{"runtime.powerState"},
+		&types.TraversalSpec{Type: "ContainerView", Path: "view"})
+	Filter.Spec.ObjectSet[0].Skip = types.NewBool(true)
+
+	return property.WaitForUpdates(Context, property.DefaultCollector(Client), Filter, func(Updates []types.ObjectUpdate) bool {
+		for _, Update := range Updates {
+			// Current State of every Virtual Machine is being Reported as `enter` on the Start of the Watch
+			if Update.Kind != types.ObjectUpdateKindModify {
+				continue
+			}
+			for _, Change := range Update.ChangeSet {
+				if State, Ok := Change.Val.(types.VirtualMachinePowerState); Ok && Change.Name == "runtime.powerState" {
+					OnChange(Update.Obj.Value, string(State))
+				}
+			}
+		}
+		return false
+	})
+}
+
+func (this *PowerStateWatcher) Publish(Region string, ManagedObjectId string, State string) {
+	// Delivers the Power State Change of the Managed Virtual Machine, Changes of the Orphans are being Skipped
+	var Record models.VirtualMachine
+	models.Database.Model(&models.VirtualMachine{}).Select("id", "project_id").Scopes(
+		models.InRegions(this.Registry.GetRecordRegions(Region)...)).Where(
+		"managed_object_id = ?", ManagedObjectId).Find(&Record)
+	if Record.ID == 0 {
+		return
+	}
+	Event, _ := NewEvent(EventPowerState, Record.ID, Record.ProjectId, 0, PowerState{PowerState: State})
+	this.Broker.Deliver(Event)
+}
@@ -0,0 +1,142 @@
+package stream_rest
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/middlewares"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/stream"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Rest API Controller, that Streams the Events of the Customer's Virtual Machines
+// as the Server-Sent Events, so the Frontend does not need to Poll the Virtual Machines, Jobs and Health Metrics
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	RefreshInterval = time.Minute // Interval of Refreshing the Projects, the Customer is Member of, and Checking the Token is not Revoked
+)
+
+const (
+	EventUnauthorized = "Unauthorized" // Last Event of the Stream, that is being Closed, since the Token has Expired or has been Revoked
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("StreamRestLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+func GetCustomerProjects(CustomerId int) ([]int, error) {
+	var Projects []int
+	Selected := models.AccessibleProjects(CustomerId).Find(&Projects)
+	return Projects, Selected.Error
+}
+
+func CloseUnauthorized(RequestContext *gin.Context, Reason string) {
+	// Sends the Reason, the Stream is being Closed for, the Client should Authorize again before Reopening it
+	RequestContext.SSEvent(EventUnauthorized, gin.H{"Error": Reason})
+	RequestContext.Writer.Flush()
+}
+
+func StreamEventsRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Streams the Power State Changes, the Job Progress and the Health Metrics of the Virtual Machines
+	// of the Customer's Projects, every Event is being Sent as `event: <Type>` with the JSON Data
+	// Stream is being Kept Open by the Comments, until the Client Disconnects or the Server Shuts Down
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+	Projects, ProjectsError := GetCustomerProjects(jwtCredentials.UserId)
+	if ProjectsError != nil {
+		Logger.Error("Failed to Receive Customer Projects", zap.Error(ProjectsError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Open Stream"})
+		return
+	}
+
+	Subscription := stream.NewSubscription(jwtCredentials.UserId, Projects)
+	stream.DefaultBroker.Subscribe(Subscription)
+	defer stream.DefaultBroker.Unsubscribe(Subscription)
+
+	RequestContext.Header("Content-Type", "text/event-stream")
+	RequestContext.Header("Cache-Control", "no-cache")
+	RequestContext.Header("Connection", "keep-alive")
+	RequestContext.Header("X-Accel-Buffering", "no") // Disables the Buffering of the Nginx
+	RequestContext.Status(http.StatusOK)
+	RequestContext.Writer.Flush()
+
+	KeepAlive := time.NewTicker(stream.KeepAliveInterval)
+	defer KeepAlive.Stop()
+	Refresh := time.NewTicker(RefreshInterval)
+	defer Refresh.Stop()
+
+	// Stream is being Closed, once the Access Token Expires, the Client Reopens it with the Refreshed one
+	// API Keys does not Expire within the Token, they are being Checked on every Refresh instead
+	var Expired <-chan time.Time
+	if jwtCredentials.ExpiresAt != 0 {
+		ExpiryTimer := time.NewTimer(time.Until(time.Unix(jwtCredentials.ExpiresAt, 0)))
+		defer ExpiryTimer.Stop()
+		Expired = ExpiryTimer.C
+	}
+
+	for {
+		select {
+		case <-RequestContext.Request.Context().Done():
+			return
+
+		case Event, Open := <-Subscription.Events:
+			if !Open {
+				return
+			}
+			RequestContext.SSEvent(Event.Type, Event)
+
+		case <-KeepAlive.C:
+			io.WriteString(RequestContext.Writer, ": keep-alive\n\n")
+
+		case <-Expired:
+			CloseUnauthorized(RequestContext, "Access Token has Expired")
+			return
+
+		case <-Refresh.C:
+			// Token, that has been Revoked by the Logout, should not Keep Receiving the Events
+			// Stream is being Closed, if the Denylist can't be Checked, the same way the Authorization Middleware Rejects the Request
+			Revoked, RevokedError := authentication.IsCredentialsRevoked(middlewares.RedisClient, jwtCredentials)
+			if RevokedError != nil {
+				Logger.Error("Failed to Check Access Token Denylist", zap.Error(RevokedError))
+				CloseUnauthorized(RequestContext, "Failed to Verify Authorization, Try a bit Later")
+				return
+			}
+			if Revoked {
+				CloseUnauthorized(RequestContext, "Token has been Revoked, Please Login again")
+				return
+			}
+			if Projects, ProjectsError := GetCustomerProjects(jwtCredentials.UserId); ProjectsError == nil {
+				Subscription.SetProjects(Projects)
+			}
+			continue
+		}
+		RequestContext.Writer.Flush()
+	}
+}
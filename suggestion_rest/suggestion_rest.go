@@ -1,47 +1,24 @@
 package suggestion_rest
 
 import (
-	"context"
-	_ "context"
 	"encoding/json"
 
 	"net/http"
-	"net/url"
 
 	"os"
-	"time"
-	_ "time"
+	"sync"
 
 	"github.com/LovePelmeni/Infrastructure/host_system"
 	"github.com/LovePelmeni/Infrastructure/resources"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/gin-gonic/gin"
-	"github.com/vmware/govmomi"
 
 	"github.com/vmware/govmomi/object"
-	"github.com/vmware/govmomi/vapi/rest"
-	_ "github.com/vmware/govmomi/vapi/rest"
-)
-
-// Insfrastructure API Environment Variables
-var (
-	APIIp    = os.Getenv("VMWARE_SOURCE_IP")
-	Username = os.Getenv("VMWARE_SOURCE_USERNAME")
-	Password = os.Getenv("VMWARE_SOURCE_PASSWORD")
-
-	APIUrl = &url.URL{
-		Scheme: "https",
-		Path:   "/sdk/",
-		Host:   APIIp,
-		User:   url.UserPassword(Username, Password),
-	}
-)
-
-var (
-	Client *govmomi.Client
+	"github.com/vmware/govmomi/vim25"
 )
 
 var (
@@ -63,28 +40,20 @@ func InitializeProductionLogger() {
 func init() {
 
 	InitializeProductionLogger()
-	var RestClient *rest.Client
-	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*10)
-	defer CancelFunc()
-
-	APIClient, ConnectionError := govmomi.NewClient(TimeoutContext, APIUrl, false)
-	switch {
-	case ConnectionError != nil:
-		Logger.Error("FAILED TO INITIALIZE CLIENT, DOES THE VMWARE HYPERVISOR ACTUALLY RUNNING?")
-
-	case ConnectionError == nil:
-		RestClient = rest.NewClient(APIClient.Client)
-		if FailedToLogin := RestClient.Login(TimeoutContext, APIUrl.User); FailedToLogin != nil {
-			Logger.Error("FAILED TO LOGIN TO THE VMWARE HYPERVISOR SERVER, ERROR: %s", zap.Error(FailedToLogin))
-		}
-	}
-	Client = APIClient
 }
 
 // Suggestions Resources API Controllers
 
+type DatacenterSuggestion struct {
+	// Datacenter, that Matches the Resource Requirements, along with the Region, it is Located at
+	Region     string             `json:"Region" xml:"Region"`
+	Endpoint   string             `json:"Endpoint" xml:"Endpoint"`
+	Datacenter *object.Datacenter `json:"Datacenter" xml:"Datacenter"`
+}
+
 func GetDatacentersSuggestions(RequestContext *gin.Context) {
-	// Returns Suggestions for the Datacenter Resource, based on the Customer Needs
+	// Returns Suggestions for the Datacenter Resource, based on the Customer Needs, across all the Regions
+	// Regions are being Queried Concurrently, the Unavailable ones are being Skipped and Listed in the Response
 
 	// Initializing Resource Requirements
 	Requirements := RequestContext.PostForm("ResourceRequirements")
@@ -94,20 +63,52 @@ func GetDatacentersSuggestions(RequestContext *gin.Context) {
 	if Error != nil {
 		Logger.Error("Failed to Parse Query Of Available Datacenters", zap.Error(Error))
 		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": Error})
+		return
+	}
+
+	// Receiving the QuerySet of the Available Datacenters of every Region, according to the Resource Requirements
+	Pools := vsphere.Registry.GetPools()
+	RegionSuggestions := make([][]DatacenterSuggestion, len(Pools))
+	Unavailable := make([]bool, len(Pools))
+
+	var Group sync.WaitGroup
+	for Index, Pool := range Pools {
+		Client, ClientError := Pool.GetClient()
+		if ClientError != nil {
+			Unavailable[Index] = true
+			continue
+		}
+		Group.Add(1)
+		go func(Index int, Endpoint vsphere.Endpoint, Client *vim25.Client) {
+			defer Group.Done()
+			SuggestionDatacenterManager := resources.NewDatacenterResourceManager(Client)
+			for _, Datacenter := range SuggestionDatacenterManager.GetAvailableDatacenters(*ResourceRequirements) {
+				RegionSuggestions[Index] = append(RegionSuggestions[Index], DatacenterSuggestion{
+					Region:     Endpoint.Region,
+					Endpoint:   Endpoint.Name,
+					Datacenter: Datacenter,
+				})
+			}
+		}(Index, Pool.Endpoint, Client.Client)
+	}
+	Group.Wait()
+
+	Suggestions := make([]DatacenterSuggestion, 0)
+	UnavailableRegions := make([]string, 0)
+	for Index, Pool := range Pools {
+		if Unavailable[Index] {
+			UnavailableRegions = append(UnavailableRegions, Pool.Endpoint.Region)
+			continue
+		}
+		Suggestions = append(Suggestions, RegionSuggestions[Index]...)
 	}
 
-	// Receiving the QuerySet of the Available Datacenters, according to the Resource Requirements
-	SuggestionDatacenterManager := resources.NewDatacenterResourceManager(Client.Client)
-	SuggestedResources := SuggestionDatacenterManager.GetAvailableDatacenters(*ResourceRequirements)
-
-	switch len(SuggestedResources) {
-	default:
-		RequestContext.JSON(http.StatusOK,
-			gin.H{"Datacenters": SuggestedResources})
-	case 0:
-		RequestContext.JSON(http.StatusOK,
-			gin.H{"Datacenters": make([]*object.Datacenter, 0)})
+	if len(UnavailableRegions) == len(Pools) {
+		RequestContext.JSON(http.StatusServiceUnavailable, gin.H{"Error": vsphere.ErrUnavailable.Error()})
+		return
 	}
+	RequestContext.JSON(http.StatusOK,
+		gin.H{"Datacenters": Suggestions, "UnavailableRegions": UnavailableRegions})
 }
 
 func GetAvailableOsSystemsRestController(RequestContext *gin.Context) {
@@ -0,0 +1,180 @@
+package alerting_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/alerting"
+	"github.com/LovePelmeni/Infrastructure/egress"
+	"github.com/LovePelmeni/Infrastructure/healthcheck"
+	"github.com/LovePelmeni/Infrastructure/mailer"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+type AlertingTestSuite struct {
+	suite.Suite
+	Notification alerting.Notification
+}
+
+func TestAlertingSuite(t *testing.T) {
+	suite.Run(t, new(AlertingTestSuite))
+}
+
+func (this *AlertingTestSuite) SetupTest() {
+	this.Notification = alerting.Notification{
+		State: models.AlertStateFiring, RuleId: 1, RuleName: "High CPU", ProjectId: 2, VirtualMachineId: 3,
+		VirtualMachineName: "web", Metric: "cpu_usage_percent", Comparator: ">", Threshold: 90, Value: 97.5,
+	}
+
+	// Sink of the Notifications Listens on the Loopback Address
+	egress.AllowPrivateAddresses = true
+	egress.DefaultResolver = egress.StaticResolver{
+		"hooks.slack.com":      {"52.1.2.3"},
+		"internal.example.com": {"172.16.0.8"},
+	}
+}
+
+func (this *AlertingTestSuite) TearDownTest() {
+	egress.AllowPrivateAddresses = false
+	egress.DefaultResolver = net.DefaultResolver
+}
+
+func NewVirtualMachine() mo.VirtualMachine {
+	var VirtualMachine mo.VirtualMachine
+	VirtualMachine.Summary.QuickStats.OverallCpuUsage = 1800
+	VirtualMachine.Summary.Runtime.MaxCpuUsage = 2000
+	VirtualMachine.Summary.QuickStats.GuestMemoryUsage = 512
+	VirtualMachine.Summary.QuickStats.GuestHeartbeatStatus = types.ManagedEntityStatusGray
+	return VirtualMachine
+}
+
+func (this *AlertingTestSuite) TestMetrics() {
+	VirtualMachine := NewVirtualMachine()
+	Sample := alerting.NewSample(models.VirtualMachine{ID: 1}, healthcheck.NewVirtualMachineHealthCheckManager(&VirtualMachine))
+
+	assert.Equal(this.T(), float64(1800), Sample.Values["cpu_usage"])
+	assert.Equal(this.T(), float64(90), Sample.Values["cpu_usage_percent"])
+	assert.Equal(this.T(), float64(512), Sample.Values["guest_memory_usage"])
+
+	// Values, that are not Reported by the vSphere, are not being Evaluated
+	assert.NotContains(this.T(), Sample.Values, "memory_active_percent", "Memory Limit is Unknown")
+	assert.NotContains(this.T(), Sample.Values, "guest_heartbeat", "Gray Heartbeat is Unknown")
+}
+
+func (this *AlertingTestSuite) TestSamples() {
+	Samples := alerting.GetSamples([]models.VirtualMachine{
+		{ID: 1, ManagedObjectId: "vm-1"}, {ID: 2, ManagedObjectId: "vm-2"},
+	}, map[string]mo.VirtualMachine{"vm-1": NewVirtualMachine()})
+	assert.Len(this.T(), Samples, 1)
+	assert.Equal(this.T(), 1, Samples[0].VirtualMachine.ID)
+}
+
+func (this *AlertingTestSuite) TestValidation() {
+	assert.NoError(this.T(), alerting.ValidateRule("cpu_usage_percent", ">="))
+	assert.ErrorIs(this.T(), alerting.ValidateRule("disk_usage", ">"), alerting.ErrUnknownMetric)
+	assert.ErrorIs(this.T(), alerting.ValidateRule("cpu_usage", "=>"), alerting.ErrUnknownComparator)
+
+	assert.NoError(this.T(), alerting.ValidateChannel(models.ChannelTypeEmail, "ops@example.com"))
+	assert.NoError(this.T(), alerting.ValidateChannel(models.ChannelTypeSlack, "https://hooks.slack.com/services/T/B/X"))
+	assert.Error(this.T(), alerting.ValidateChannel(models.ChannelTypeEmail, "Ops <ops@example.com>"))
+	assert.Error(this.T(), alerting.ValidateChannel(models.ChannelTypeWebhook, "/relative/path"))
+	assert.Error(this.T(), alerting.ValidateChannel(models.ChannelTypeWebhook, "ftp://example.com"))
+	assert.ErrorIs(this.T(), alerting.ValidateChannel("Pager", "https://example.com"), alerting.ErrUnknownChannelType)
+}
+
+func (this *AlertingTestSuite) TestInternalChannel() {
+	egress.AllowPrivateAddresses = false
+	assert.NoError(this.T(), alerting.ValidateChannel(models.ChannelTypeSlack, "https://hooks.slack.com/services/T/B/X"))
+	assert.ErrorIs(this.T(), alerting.ValidateChannel(models.ChannelTypeWebhook, "http://169.254.169.254/"), egress.ErrForbiddenAddress)
+	assert.ErrorIs(this.T(), alerting.ValidateChannel(models.ChannelTypeSlack, "https://internal.example.com/"), egress.ErrForbiddenAddress)
+
+	Sink := alerting.NewHttpSink()
+	defer Sink.Close()
+	for _, Notifier := range []alerting.Notifier{alerting.NewWebhookNotifier(), alerting.NewSlackNotifier()} {
+		assert.ErrorIs(this.T(), Notifier.Notify(context.Background(), Sink.GetURL(), this.Notification), egress.ErrForbiddenAddress)
+	}
+	assert.Empty(this.T(), Sink.GetRequests())
+}
+
+func (this *AlertingTestSuite) TestMatching() {
+	VirtualMachine := models.VirtualMachine{ID: 1, ProjectId: 10}
+	assert.True(this.T(), alerting.IsMatching(models.AlertRule{ProjectId: 10}, VirtualMachine), "Project Rule")
+	assert.True(this.T(), alerting.IsMatching(models.AlertRule{ProjectId: 10, VirtualMachineId: 1}, VirtualMachine))
+	assert.False(this.T(), alerting.IsMatching(models.AlertRule{ProjectId: 10, VirtualMachineId: 2}, VirtualMachine))
+	assert.False(this.T(), alerting.IsMatching(models.AlertRule{ProjectId: 20}, VirtualMachine))
+}
+
+func (this *AlertingTestSuite) TestTransitions() {
+	Now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	Rule := models.AlertRule{Duration: 300}
+	Pending := &models.Alert{State: models.AlertStatePending, StartedAt: Now.Add(-time.Minute)}
+	Firing := &models.Alert{State: models.AlertStateFiring, StartedAt: Now.Add(-time.Hour)}
+
+	assert.Equal(this.T(), alerting.TransitionOpen, alerting.GetTransition(Rule, nil, true, Now))
+	assert.Equal(this.T(), alerting.TransitionNone, alerting.GetTransition(Rule, nil, false, Now))
+
+	// Pending Alert Fires only after the Duration of the Rule
+	assert.Equal(this.T(), alerting.TransitionNone, alerting.GetTransition(Rule, Pending, true, Now))
+	assert.Equal(this.T(), alerting.TransitionFire, alerting.GetTransition(Rule, Pending, true, Now.Add(4*time.Minute)))
+	assert.Equal(this.T(), alerting.TransitionDiscard, alerting.GetTransition(Rule, Pending, false, Now))
+
+	// Firing Alert Notifies only once
+	assert.Equal(this.T(), alerting.TransitionNone, alerting.GetTransition(Rule, Firing, true, Now))
+	assert.Equal(this.T(), alerting.TransitionResolve, alerting.GetTransition(Rule, Firing, false, Now))
+}
+
+func (this *AlertingTestSuite) TestComparators() {
+	assert.True(this.T(), alerting.Comparators[">"](91, 90))
+	assert.False(this.T(), alerting.Comparators[">"](90, 90))
+	assert.True(this.T(), alerting.Comparators[">="](90, 90))
+	assert.True(this.T(), alerting.Comparators["<"](1, 2))
+	assert.True(this.T(), alerting.Comparators["!="](1, 2))
+}
+
+func (this *AlertingTestSuite) TestWebhookNotifier() {
+	Sink := alerting.NewHttpSink()
+	defer Sink.Close()
+
+	assert.NoError(this.T(), alerting.NewWebhookNotifier().Notify(context.Background(), Sink.GetURL()+"/hook", this.Notification))
+	Requests := Sink.GetRequests()
+	assert.Len(this.T(), Requests, 1)
+	assert.Equal(this.T(), "/hook", Requests[0].Path)
+	assert.Equal(this.T(), "application/json", Requests[0].Header.Get("Content-Type"))
+
+	var Received alerting.Notification
+	assert.NoError(this.T(), json.Unmarshal(Requests[0].Body, &Received))
+	assert.Equal(this.T(), this.Notification, Received)
+
+	// Failed Deliveries are being Reported
+	Sink.SetStatusCode(http.StatusInternalServerError)
+	assert.Error(this.T(), alerting.NewWebhookNotifier().Notify(context.Background(), Sink.GetURL(), this.Notification))
+}
+
+func (this *AlertingTestSuite) TestSlackNotifier() {
+	Sink := alerting.NewHttpSink()
+	defer Sink.Close()
+
+	assert.NoError(this.T(), alerting.NewSlackNotifier().Notify(context.Background(), Sink.GetURL(), this.Notification))
+	var Message map[string]string
+	assert.NoError(this.T(), json.Unmarshal(Sink.GetRequests()[0].Body, &Message))
+	assert.Equal(this.T(), map[string]string{"text": this.Notification.GetSummary()}, Message)
+	assert.Equal(this.T(), "[Firing] High CPU: cpu_usage_percent of the Virtual Machine `web` (ID 3) is 97.5, Rule is > 90", Message["text"])
+}
+
+func (this *AlertingTestSuite) TestEmailNotifier() {
+	Mailer := mailer.NewMemoryMailer()
+	mailer.SetMailer(Mailer)
+
+	assert.NoError(this.T(), alerting.EmailNotifier{}.Notify(context.Background(), "ops@example.com", this.Notification))
+	Messages := Mailer.GetMessages("ops@example.com")
+	assert.Len(this.T(), Messages, 1)
+	assert.Equal(this.T(), this.Notification.GetSummary(), Messages[0].Subject)
+}
@@ -0,0 +1,82 @@
+package authentication_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/tests/fakeredis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthenticationTestSuite struct {
+	suite.Suite
+}
+
+func TestAuthenticationSuite(t *testing.T) {
+	suite.Run(t, new(AuthenticationTestSuite))
+}
+
+func (this *AuthenticationTestSuite) TestAccessTokenClaims() {
+	FirstToken, FirstError := authentication.CreateJwtToken(1, "Username", "Email@gmail.com")
+	SecondToken, SecondError := authentication.CreateJwtToken(1, "Username", "Email@gmail.com")
+	assert.NoError(this.T(), FirstError)
+	assert.NoError(this.T(), SecondError)
+
+	First, _ := authentication.GetCustomerJwtCredentials(FirstToken)
+	Second, _ := authentication.GetCustomerJwtCredentials(SecondToken)
+	assert.Equal(this.T(), 1, First.UserId)
+	assert.NotEmpty(this.T(), First.Id)
+	assert.NotEqual(this.T(), First.Id, Second.Id, "Every Token should have Unique ID, so it can be Revoked")
+
+	Lifetime := time.Unix(First.ExpiresAt, 0).Sub(time.Unix(First.IssuedAt, 0))
+	assert.Equal(this.T(), authentication.AccessTokenLifetime, Lifetime)
+}
+
+func (this *AuthenticationTestSuite) TestRefreshTokenHash() {
+	Token, GenerateError := authentication.GenerateRandomToken()
+	assert.NoError(this.T(), GenerateError)
+
+	Other, _ := authentication.GenerateRandomToken()
+	assert.NotEqual(this.T(), Token, Other)
+
+	Hash := authentication.HashToken(Token)
+	assert.Len(this.T(), Hash, 64)
+	assert.NotContains(this.T(), Hash, Token, "Refresh Token should not be Stored as it is")
+	assert.Equal(this.T(), Hash, authentication.HashToken(Token))
+}
+
+func (this *AuthenticationTestSuite) TestApiKeyScopes() {
+	ApiKeyCredentials := &authentication.JwtToken{ApiKeyId: 1, Scopes: []string{authentication.ScopeVirtualMachinesRead}}
+	assert.True(this.T(), ApiKeyCredentials.HasScope(authentication.ScopeVirtualMachinesRead))
+	assert.False(this.T(), ApiKeyCredentials.HasScope(authentication.ScopeVirtualMachinesWrite))
+
+	JwtCredentials := &authentication.JwtToken{UserId: 1}
+	assert.True(this.T(), JwtCredentials.HasScope(authentication.ScopeVirtualMachinesWrite), "Jwt Tokens are not Limited by the Scopes")
+
+	assert.True(this.T(), authentication.IsValidScope("vm:write"))
+	assert.False(this.T(), authentication.IsValidScope("vm:admin"))
+}
+
+func (this *AuthenticationTestSuite) TestRevokedSessions() {
+	Redis := fakeredis.NewServer()
+	defer Redis.Close()
+	Client := Redis.GetClient()
+
+	RevokedAt := time.Now().Unix()
+	assert.NoError(this.T(), Client.Set(authentication.GetRevokedBeforeKey(1), RevokedAt, time.Hour).Err())
+
+	// Token, Issued right after the Sessions have been Revoked, is still Valid, even within the same Second
+	for IssuedAt, Revoked := range map[int64]bool{RevokedAt - 1: true, RevokedAt: false, RevokedAt + 1: false} {
+		Credentials := &authentication.JwtToken{UserId: 1}
+		Credentials.Id, Credentials.IssuedAt = "token", IssuedAt
+		IsRevoked, RevokedError := authentication.IsAccessTokenRevoked(Client, Credentials)
+		assert.NoError(this.T(), RevokedError)
+		assert.Equal(this.T(), Revoked, IsRevoked, IssuedAt-RevokedAt)
+	}
+
+	// Other Customers' Sessions are not Affected
+	IsRevoked, _ := authentication.IsAccessTokenRevoked(Client, &authentication.JwtToken{UserId: 2})
+	assert.False(this.T(), IsRevoked)
+}
@@ -0,0 +1,228 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigTestSuite struct {
+	suite.Suite
+	Environment []string
+}
+
+func TestConfigSuite(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
+
+func (this *ConfigTestSuite) SetupTest() {
+	// Minimal Valid Environment of the Application
+	this.Environment = []string{
+		"JWT_SECRET_KEY=" + strings.Repeat("k", config.MinSecretKeyLength),
+		"DATABASE_HOST=postgres",
+		"DATABASE_USER=postgres_user",
+		"DATABASE_NAME=infrastructure_db",
+		"CACHE_STORAGE_HOST=redis",
+		"VMWARE_SOURCE_IP=10.0.0.1",
+	}
+}
+
+func (this *ConfigTestSuite) WriteFile(Name string, Content string) string {
+	Path := filepath.Join(this.T().TempDir(), Name)
+	assert.NoError(this.T(), os.WriteFile(Path, []byte(Content), 0600))
+	return Path
+}
+
+func (this *ConfigTestSuite) GetProblems(LoadError error) []string {
+	var Problems *config.ValidationError
+	if !errors.As(LoadError, &Problems) {
+		this.T().Fatalf("Expected Validation Error, got %v", LoadError)
+	}
+	return Problems.Problems
+}
+
+func (this *ConfigTestSuite) TestDefaults() {
+	Config, LoadError := config.LoadFrom(nil, this.Environment)
+	assert.NoError(this.T(), LoadError)
+
+	assert.Equal(this.T(), 8001, Config.Application.Port)
+	assert.Equal(this.T(), 5432, Config.Database.Port)
+	assert.Equal(this.T(), "redis:6379", Config.Cache.GetAddress())
+	assert.Equal(this.T(), 15*time.Minute, Config.Authentication.AccessTokenLifetime)
+	assert.Equal(this.T(), []string{"openid", "email", "profile"}, Config.Oidc.Scopes)
+	assert.Equal(this.T(), "smtp", Config.Mailer.Backend)
+	assert.Equal(this.T(), time.Minute, Config.Jobs.LockTimeToLive)
+	assert.Equal(this.T(), 24*time.Hour, Config.Idempotency.KeyTimeToLive)
+	assert.Equal(this.T(), time.Minute, Config.Metrics.CollectInterval)
+	assert.Equal(this.T(), 10*time.Second, Config.Alerting.NotificationTimeout)
+	assert.Equal(this.T(), 15*time.Second, Config.Stream.KeepAliveInterval)
+	assert.Equal(this.T(), 8, Config.Webhooks.MaxAttempts)
+	assert.Equal(this.T(), 10, Config.Customers.VirtualMachinesLimit)
+	assert.Empty(this.T(), Config.RateLimit.Limits)
+
+	// Single vCenter of the `VMWARE_SOURCE_*` Keys Serves the Default Region
+	assert.Len(this.T(), Config.Vsphere.Endpoints, 1)
+	assert.Equal(this.T(), config.DefaultRegion, Config.Vsphere.Endpoints[0].Region)
+	assert.Equal(this.T(), "10.0.0.1", Config.Vsphere.Endpoints[0].Host)
+}
+
+func (this *ConfigTestSuite) TestRequired() {
+	_, LoadError := config.LoadFrom(nil, []string{"MAILER_BACKEND=pigeon"})
+	Problems := this.GetProblems(LoadError)
+
+	// Every Problem is being Reported at once
+	for _, Expected := range []string{
+		"JWT_SECRET_KEY is Required", "DATABASE_HOST is Required", "DATABASE_USER is Required",
+		"DATABASE_NAME is Required", "CACHE_STORAGE_HOST is Required", "Either VMWARE_SOURCE_IP or VSPHERE_ENDPOINTS is Required",
+	} {
+		assert.Contains(this.T(), Problems, Expected)
+	}
+	assert.Contains(this.T(), LoadError.Error(), "MAILER_BACKEND should be one of")
+}
+
+func (this *ConfigTestSuite) TestInvalidValues() {
+	_, LoadError := config.LoadFrom(nil, append(this.Environment,
+		"APPLICATION_PORT=http", "RECONCILE_INTERVAL=-5", "JOB_WORKERS_NUMBER=0", "LOGIN_LOCKOUT_BASE=2h",
+		"RATE_LIMIT_SEARCH=10", "JWT_SECRET_KEY=short", "METRICS_HISTORY_RAW_RETENTION=1440h"))
+	Problems := this.GetProblems(LoadError)
+	assert.Len(this.T(), Problems, 7)
+	assert.Contains(this.T(), LoadError.Error(), "APPLICATION_PORT should be an Integer")
+	assert.Contains(this.T(), LoadError.Error(), "RECONCILE_INTERVAL should not be less than 1s")
+	assert.Contains(this.T(), LoadError.Error(), "JOB_WORKERS_NUMBER should not be less than 1")
+	assert.Contains(this.T(), LoadError.Error(), "LOGIN_LOCKOUT_BASE should not be greater than the LOGIN_LOCKOUT_MAX")
+	assert.Contains(this.T(), LoadError.Error(), "RATE_LIMIT_SEARCH")
+	assert.Contains(this.T(), LoadError.Error(), "JWT_SECRET_KEY should be at least")
+	assert.Contains(this.T(), LoadError.Error(), "METRICS_HISTORY_RAW_RETENTION should not be greater than the METRICS_HISTORY_ROLLUP_RETENTION")
+}
+
+func (this *ConfigTestSuite) TestDurations() {
+	Config, LoadError := config.LoadFrom(nil, append(this.Environment,
+		"ACCESS_TOKEN_LIFETIME=600", "RECONCILE_INTERVAL=1m30s", "CACHE_STORAGE_DATABASE_NUMBER=0"))
+	assert.NoError(this.T(), LoadError)
+	assert.Equal(this.T(), 10*time.Minute, Config.Authentication.AccessTokenLifetime)
+	assert.Equal(this.T(), 90*time.Second, Config.Reconciler.Interval)
+	assert.Equal(this.T(), 0, Config.Cache.DatabaseNumber)
+}
+
+func (this *ConfigTestSuite) TestRateLimits() {
+	Config, LoadError := config.LoadFrom(nil, append(this.Environment, "RATE_LIMIT_AUTHENTICATION=10/60", "RATE_LIMIT_VM="))
+	assert.NoError(this.T(), LoadError)
+	assert.Equal(this.T(), map[string]config.LimitConfig{
+		"authentication": {Requests: 10, Period: time.Minute},
+	}, Config.RateLimit.Limits)
+
+	for _, Invalid := range []string{"10", "10/0", "-1/60", "ten/60"} {
+		_, ParseError := config.ParseLimit(Invalid)
+		assert.Error(this.T(), ParseError, Invalid)
+	}
+}
+
+func (this *ConfigTestSuite) TestPrecedence() {
+	// Flags Override the Environment, which Overrides the File, which Overrides the Defaults
+	Path := this.WriteFile("config.yaml", `
+application:
+  host: file-host
+  port: 9000
+mfa:
+  issuer: FileIssuer
+database:
+  port: 6432
+`)
+	Config, LoadError := config.LoadFrom([]string{"--config", Path, "--application-port", "9100", "migrate", "status"},
+		append(this.Environment, "APPLICATION_HOST=env-host", "APPLICATION_PORT=9200", "MFA_ISSUER="))
+	assert.NoError(this.T(), LoadError)
+
+	assert.Equal(this.T(), Path, Config.File)
+	assert.Equal(this.T(), 9100, Config.Application.Port)
+	assert.Equal(this.T(), "env-host", Config.Application.Host)
+	assert.Equal(this.T(), 6432, Config.Database.Port)
+	assert.Equal(this.T(), "FileIssuer", Config.Mfa.Issuer, "Empty Variable should not Override the File")
+	assert.Equal(this.T(), 3000, Config.Application.FrontPort)
+	assert.Equal(this.T(), []string{"migrate", "status"}, Config.Arguments)
+}
+
+func (this *ConfigTestSuite) TestTomlFile() {
+	Path := this.WriteFile("config.toml", `
+[oidc]
+issuer_url = "https://sso.example.com"
+client_id = "infrastructure"
+redirect_url = "https://app.example.com/customer/oidc/callback/"
+scopes = ["openid", "email"]
+`)
+	Config, LoadError := config.LoadFrom(nil, append(this.Environment, config.ConfigFileKey+"="+Path))
+	assert.NoError(this.T(), LoadError)
+	assert.True(this.T(), Config.Oidc.IsEnabled())
+	assert.Equal(this.T(), []string{"openid", "email"}, Config.Oidc.Scopes)
+
+	_, LoadError = config.LoadFrom([]string{"--config", this.WriteFile("config.json", "{}")}, this.Environment)
+	assert.ErrorIs(this.T(), LoadError, config.ErrUnsupportedFormat)
+}
+
+func (this *ConfigTestSuite) TestIncompleteOidc() {
+	_, LoadError := config.LoadFrom(nil, append(this.Environment, "OIDC_CLIENT_ID=infrastructure", "OIDC_REDIRECT_URL=callback"))
+	Problems := this.GetProblems(LoadError)
+	assert.Contains(this.T(), Problems, "OIDC_ISSUER_URL is Required, once the Single Sign On is Enabled")
+	assert.Contains(this.T(), Problems, "OIDC_REDIRECT_URL should be an Absolute URL, got `callback`")
+}
+
+func (this *ConfigTestSuite) TestSecretFiles() {
+	Secret := this.WriteFile("jwt", strings.Repeat("s", config.MinSecretKeyLength)+"\n")
+	Environment := append([]string{"JWT_SECRET_KEY_FILE=" + Secret}, this.Environment[1:]...)
+
+	Config, LoadError := config.LoadFrom(nil, Environment)
+	assert.NoError(this.T(), LoadError)
+	assert.Equal(this.T(), strings.Repeat("s", config.MinSecretKeyLength), Config.Authentication.SecretKey, "Trailing Newline should be Trimmed")
+	assert.NotContains(this.T(), Config.String(), Config.Authentication.SecretKey, "Secrets should not be Printed")
+
+	_, LoadError = config.LoadFrom(nil, append(this.Environment, "JWT_SECRET_KEY_FILE="+Secret))
+	assert.Contains(this.T(), LoadError.Error(), "Only one of the JWT_SECRET_KEY and the JWT_SECRET_KEY_FILE")
+
+	_, LoadError = config.LoadFrom(nil, append(this.Environment, "DATABASE_PASSWORD_FILE=/nonexistent/password"))
+	assert.Contains(this.T(), LoadError.Error(), "Failed to Read DATABASE_PASSWORD_FILE")
+}
+
+func (this *ConfigTestSuite) TestEndpoints() {
+	Password := this.WriteFile("password", "european-secret")
+	Environment := append([]string{}, this.Environment[:len(this.Environment)-1]...)
+	Config, LoadError := config.LoadFrom(nil, append(Environment,
+		`VSPHERE_ENDPOINTS=[{"Name": "vc-1", "Region": "eu", "Host": "10.0.0.1", "Credentials": "VSPHERE_EU"},
+			{"Region": "us", "Host": "10.0.1.1"}]`,
+		"VSPHERE_EU_USERNAME=administrator", "VSPHERE_EU_PASSWORD_FILE="+Password))
+	assert.NoError(this.T(), LoadError)
+
+	Endpoints := Config.Vsphere.Endpoints
+	assert.Equal(this.T(), []string{"eu", "us"}, []string{Endpoints[0].Region, Endpoints[1].Region})
+	assert.Equal(this.T(), "administrator", Endpoints[0].Username)
+	assert.Equal(this.T(), "european-secret", Endpoints[0].Password)
+	assert.Equal(this.T(), "10.0.1.1", Endpoints[1].Name, "Endpoint should be Named after the Host by Default")
+
+	for _, Invalid := range []string{
+		`[]`, `{}`, `[{"Name": "vc-1", "Host": "10.0.0.1"}]`,
+		`[{"Region": "eu", "Host": "10.0.0.1"}, {"Region": "eu", "Host": "10.0.0.2"}]`,
+	} {
+		_, LoadError = config.LoadFrom(nil, append(this.Environment, "VSPHERE_ENDPOINTS="+Invalid))
+		assert.Error(this.T(), LoadError, Invalid)
+	}
+}
+
+func (this *ConfigTestSuite) TestYamlEndpoints() {
+	// Endpoints can be Specified as the List of the Sections in the File
+	Path := this.WriteFile("config.yml", `
+vsphere:
+  endpoints:
+    - Name: vc-1
+      Region: eu
+      Host: 10.0.0.1
+`)
+	Config, LoadError := config.LoadFrom([]string{"--config", Path}, this.Environment)
+	assert.NoError(this.T(), LoadError)
+	assert.Len(this.T(), Config.Vsphere.Endpoints, 1)
+	assert.Equal(this.T(), "eu", Config.Vsphere.Endpoints[0].Region)
+}
@@ -0,0 +1,257 @@
+package deploy_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/deploy"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/network"
+	storage_config "github.com/LovePelmeni/Infrastructure/storage_config"
+	"github.com/LovePelmeni/Infrastructure/tests/fakedb"
+	"github.com/LovePelmeni/Infrastructure/webhooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+type DeployTestSuite struct {
+	suite.Suite
+	Model          *simulator.Model
+	Server         *simulator.Server
+	Manager        *deploy.VirtualMachineManager
+	VirtualMachine *object.VirtualMachine
+}
+
+func TestDeploySuite(t *testing.T) {
+	suite.Run(t, new(DeployTestSuite))
+}
+
+func (this *DeployTestSuite) SetupTest() {
+	this.Model = simulator.VPX()
+	this.Model.Datacenter = 2
+	assert.NoError(this.T(), this.Model.Create())
+	this.Server = this.Model.Service.NewServer()
+
+	Context, CancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer CancelFunc()
+	Client, ClientError := govmomi.NewClient(Context, this.Server.URL, true)
+	assert.NoError(this.T(), ClientError)
+	this.Manager = deploy.NewVirtualMachineManager(*Client.Client)
+
+	VirtualMachine, FindError := find.NewFinder(Client.Client).VirtualMachine(Context, "/DC0/vm/DC0_H0_VM0")
+	assert.NoError(this.T(), FindError)
+	this.VirtualMachine = VirtualMachine
+}
+
+func (this *DeployTestSuite) TearDownTest() {
+	this.Server.Close()
+	this.Model.Remove()
+}
+
+func (this *DeployTestSuite) TestClonePlacement() {
+	Folder, FolderError := this.Manager.FindClonePlacement(this.VirtualMachine, "/DC0/vm", "Folder")
+	assert.NoError(this.T(), FolderError)
+	assert.Equal(this.T(), "Folder", Folder.Type)
+
+	Datastore, DatastoreError := this.Manager.FindClonePlacement(this.VirtualMachine, "/DC0/datastore/LocalDS_0", "Datastore")
+	assert.NoError(this.T(), DatastoreError)
+	assert.Equal(this.T(), "Datastore", Datastore.Type)
+
+	// Items of the other Datacenters can't be Used, even though they Exist
+	_, FolderError = this.Manager.FindClonePlacement(this.VirtualMachine, "/DC1/vm", "Folder")
+	assert.ErrorIs(this.T(), FolderError, deploy.ErrPlacementOutsideDatacenter)
+
+	// Item should be of the Requested Type
+	_, FolderError = this.Manager.FindClonePlacement(this.VirtualMachine, "/DC0/datastore/LocalDS_0", "Folder")
+	assert.Error(this.T(), FolderError)
+	_, FolderError = this.Manager.FindClonePlacement(this.VirtualMachine, "/DC0/vm/Missing", "Folder")
+	assert.Error(this.T(), FolderError)
+}
+
+func (this *DeployTestSuite) TestCloneSpec() {
+	IPAddress := network.NewVirtualMachineIPAddress("10.0.0.5", "255.255.255.0", "10.0.0.1", "web-copy")
+	Spec := deploy.NewVirtualMachineCloneSpec("web-copy", nil, nil, false, *IPAddress)
+
+	Clone, CloneError := this.Manager.ReplicateVirtualMachine(this.VirtualMachine, *Spec)
+	assert.NoError(this.T(), CloneError)
+	assert.Equal(this.T(), "/DC0/vm/web-copy", Clone.InventoryPath, "Copy should be Placed in the Folder of the Source by Default")
+
+	// Invalid Address is being Rejected before the Clone Task is Started
+	Spec = deploy.NewVirtualMachineCloneSpec("web-broken", nil, nil, false, *network.NewVirtualMachineIPAddress("10.0.0", "", "", "web"))
+	_, CloneError = this.Manager.ReplicateVirtualMachine(this.VirtualMachine, *Spec)
+	assert.Error(this.T(), CloneError)
+}
+
+func (this *DeployTestSuite) TestDiscard() {
+	Spec := deploy.NewVirtualMachineCloneSpec("web-orphan", nil, nil, false, *network.NewVirtualMachineIPAddress("10.0.0.6", "", "", "web-orphan"))
+	Clone, CloneError := this.Manager.ReplicateVirtualMachine(this.VirtualMachine, *Spec)
+	assert.NoError(this.T(), CloneError)
+
+	// Copy is Powered On, so it has to be Powered Off before it's Destroyed
+	assert.NoError(this.T(), this.Manager.DiscardVirtualMachine(Clone))
+
+	Context, CancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer CancelFunc()
+	Reference, _ := object.NewSearchIndex(&this.Manager.VimClient).FindByInventoryPath(Context, "/DC0/vm/web-orphan")
+	assert.Nil(this.T(), Reference)
+}
+
+func (this *DeployTestSuite) TestResizeCapacity() {
+	// Memory is being Requested in Megabytes, so the Host of the Simulator fits 2 GB, but not 4 TB
+	ResizeSpec := deploy.NewVirtualMachineResizeSpec(0, 2048, 0)
+	assert.NoError(this.T(), this.Manager.ValidateResizeCapacity(this.VirtualMachine, types.VirtualMachineConfigSpec{MemoryMB: 2048}, *ResizeSpec))
+
+	ResizeSpec = deploy.NewVirtualMachineResizeSpec(0, 4*1024*1024, 0)
+	assert.Error(this.T(), this.Manager.ValidateResizeCapacity(this.VirtualMachine, types.VirtualMachineConfigSpec{MemoryMB: 4 * 1024 * 1024}, *ResizeSpec))
+
+	ResizeSpec = deploy.NewVirtualMachineResizeSpec(0, 2048, 0)
+	ResizeSpec.MaxMemoryUsage = 1024
+	assert.Error(this.T(), this.Manager.ValidateResizeCapacity(this.VirtualMachine, types.VirtualMachineConfigSpec{MemoryMB: 2048}, *ResizeSpec),
+		"Memory should not Exceed the Limit of the Virtual Machine")
+}
+
+func (this *DeployTestSuite) AttachDataDisk(CapacityInKB int) int32 {
+	// Attaches the Disk the same way the Deploy does and Returns it's Device Key
+	Context, CancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer CancelFunc()
+
+	Devices, DeviceError := this.VirtualMachine.Device(Context)
+	assert.NoError(this.T(), DeviceError)
+	Controller, ControllerError := Devices.FindDiskController("scsi")
+	assert.NoError(this.T(), ControllerError)
+
+	Datastore, _ := find.NewFinder(&this.Manager.VimClient).Datastore(Context, "/DC0/datastore/LocalDS_0")
+	DiskSpec, DiskError := storage_config.NewVirtualMachineStorageManager().SetupStorageDisk(
+		*storage_config.NewVirtualMachineStorage(CapacityInKB), *Datastore)
+	assert.NoError(this.T(), DiskError)
+	Devices.AssignController(DiskSpec.Device, Controller)
+	assert.NoError(this.T(), this.Manager.ReconfigureVirtualMachine(this.VirtualMachine, types.VirtualMachineConfigSpec{
+		DeviceChange: []types.BaseVirtualDeviceConfigSpec{DiskSpec},
+	}))
+
+	Updated, _ := this.VirtualMachine.Device(Context)
+	Disks := Updated.SelectByType((*types.VirtualDisk)(nil))
+	return Disks[len(Disks)-1].GetVirtualDevice().Key
+}
+
+func (this *DeployTestSuite) GetDisks() map[int32]int64 {
+	// Returns Capacity of the Disks of the Virtual Machine by their Keys
+	Context, CancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer CancelFunc()
+	Devices, _ := this.VirtualMachine.Device(Context)
+	Disks := make(map[int32]int64)
+	for _, Disk := range Devices.SelectByType((*types.VirtualDisk)(nil)) {
+		Disks[Disk.GetVirtualDevice().Key] = Disk.(*types.VirtualDisk).CapacityInKB
+	}
+	return Disks
+}
+
+func (this *DeployTestSuite) TestResizeDataDisk() {
+	DiskKey := this.AttachDataDisk(1024 * 1024)
+	Disks := this.GetDisks()
+	assert.Len(this.T(), Disks, 2)
+
+	// Primary Disk of the Template is being Kept, only the Data Disk is being Grown
+	ResizeSpec := deploy.NewVirtualMachineResizeSpec(0, 0, 2*1024*1024)
+	ResizeSpec.DiskKey = DiskKey
+	PowerCycled, ResizeError := this.Manager.ResizeVirtualMachine(this.VirtualMachine, *ResizeSpec)
+	assert.NoError(this.T(), ResizeError)
+	assert.False(this.T(), PowerCycled, "Disk should be Grown on the Running Virtual Machine")
+
+	Resized := this.GetDisks()
+	for Key, Capacity := range Disks {
+		if Key == DiskKey {
+			assert.Equal(this.T(), int64(2*1024*1024), Resized[Key])
+		} else {
+			assert.Equal(this.T(), Capacity, Resized[Key], "Primary Disk should not be Changed")
+		}
+	}
+
+	// Virtual Machine, the Data Disk of which is Unknown, can't be Resized
+	ResizeSpec = deploy.NewVirtualMachineResizeSpec(0, 0, 4*1024*1024)
+	_, ResizeError = this.Manager.ResizeVirtualMachine(this.VirtualMachine, *ResizeSpec)
+	assert.Error(this.T(), ResizeError)
+	assert.Equal(this.T(), Resized, this.GetDisks())
+}
+
+func (this *DeployTestSuite) TestResizePowerCycle() {
+	// CPU Hot Add is not Enabled, so the Guest OS is being Shut Down and the Virtual Machine is being Started back
+	PowerCycled, ResizeError := this.Manager.ResizeVirtualMachine(this.VirtualMachine, *deploy.NewVirtualMachineResizeSpec(2, 0, 0))
+	assert.NoError(this.T(), ResizeError)
+	assert.True(this.T(), PowerCycled)
+
+	Context, CancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer CancelFunc()
+	var MoVirtualMachine mo.VirtualMachine
+	assert.NoError(this.T(), this.VirtualMachine.Properties(Context, this.VirtualMachine.Reference(),
+		[]string{"config.hardware.numCPU", "runtime.powerState"}, &MoVirtualMachine))
+	assert.Equal(this.T(), int32(2), MoVirtualMachine.Config.Hardware.NumCPU)
+	assert.Equal(this.T(), types.VirtualMachinePowerStatePoweredOn, MoVirtualMachine.Runtime.PowerState)
+
+	// Guest OS of the Powered Off Virtual Machine can't be Shut Down
+	Task, _ := this.VirtualMachine.PowerOff(Context)
+	assert.NoError(this.T(), Task.Wait(Context))
+	assert.ErrorIs(this.T(), this.Manager.ShutdownGuestVirtualMachine(this.VirtualMachine), deploy.ErrPowerCycleRequired)
+}
+
+func (this *DeployTestSuite) TestLifecycleEvents() {
+	// Record of the Virtual Machine and the Webhook of it's Owner, that is Subscribed to every Event
+	var Database *fakedb.Database
+	models.Database, Database = fakedb.New(func(Query string, Args []driver.Value) fakedb.Result {
+		switch {
+		case strings.HasPrefix(Query, `SELECT * FROM "virtual_machines"`):
+			return fakedb.Result{Columns: []string{"id", "owner_id", "item_path"},
+				Rows: [][]driver.Value{{int64(1), int64(7), "/DC0/vm/DC0_H0_VM0"}}}
+		case strings.HasPrefix(Query, `SELECT * FROM "webhooks"`):
+			return fakedb.Result{Columns: []string{"id", "owner_id", "url", "event_types"},
+				Rows: [][]driver.Value{{int64(1), int64(7), "https://cmdb.example.com/", ""}}}
+		}
+		return fakedb.Result{}
+	})
+	GetEvents := func() []string {
+		Events := []string{}
+		for _, Statement := range Database.Statements {
+			if !strings.HasPrefix(Statement.Query, `INSERT INTO "webhook_deliveries"`) {
+				continue
+			}
+			for _, Value := range Statement.Args {
+				if Payload, IsString := Value.(string); IsString && strings.HasPrefix(Payload, "{") {
+					var Event webhooks.Payload
+					assert.NoError(this.T(), json.Unmarshal([]byte(Payload), &Event))
+					assert.Equal(this.T(), 1, Event.VirtualMachine.Id)
+					assert.Equal(this.T(), map[string]interface{}{"JobId": float64(5)}, Event.Data)
+					Events = append(Events, Event.Type)
+				}
+			}
+		}
+		return Events
+	}
+
+	// Virtual Machines, that have not been Received by the Record, are not being Reported
+	assert.NoError(this.T(), this.Manager.ShutdownVirtualMachine(this.VirtualMachine))
+	assert.Empty(this.T(), GetEvents())
+
+	this.Manager.EventData["JobId"] = 5
+	VirtualMachine, FindError := this.Manager.GetVirtualMachine("1")
+	assert.NoError(this.T(), FindError)
+	assert.NoError(this.T(), this.Manager.StartVirtualMachine(VirtualMachine))
+
+	// Power Cycle of the Resize is being Reported as well
+	PowerCycled, ResizeError := this.Manager.ResizeVirtualMachine(VirtualMachine, *deploy.NewVirtualMachineResizeSpec(2, 0, 0))
+	assert.NoError(this.T(), ResizeError)
+	assert.True(this.T(), PowerCycled)
+
+	assert.NoError(this.T(), this.Manager.DiscardVirtualMachine(VirtualMachine))
+	assert.Equal(this.T(), []string{webhooks.EventStarted, webhooks.EventStopped, webhooks.EventStarted,
+		webhooks.EventStopped, webhooks.EventDestroyed}, GetEvents())
+}
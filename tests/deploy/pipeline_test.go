@@ -0,0 +1,165 @@
+package deploy_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/LovePelmeni/Infrastructure/deploy"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PipelineTestSuite struct {
+	suite.Suite
+	Calls     []string            // Do and Undo Calls of the Steps in the Order, they have been Made
+	Snapshots []models.Deployment // Progress of the Deployment, every time it has been Stored
+}
+
+func TestPipelineSuite(t *testing.T) {
+	suite.Run(t, new(PipelineTestSuite))
+}
+
+func (this *PipelineTestSuite) SetupTest() {
+	this.Calls = nil
+	this.Snapshots = nil
+}
+
+func (this *PipelineTestSuite) NewStep(Name string, Failing bool, FailingUndo bool) deploy.DeployStep {
+	// Returns Fake Step, that Records it's Calls
+	return deploy.NewDeployStep(Name, func() error {
+		this.Calls = append(this.Calls, "Do "+Name)
+		if Failing {
+			return errors.New("Step is Broken")
+		}
+		return nil
+	}, func() error {
+		this.Calls = append(this.Calls, "Undo "+Name)
+		if FailingUndo {
+			return errors.New("Undo is Broken")
+		}
+		return nil
+	})
+}
+
+func (this *PipelineTestSuite) NewPipeline(Deployment *models.Deployment, Steps ...deploy.DeployStep) *deploy.DeployPipeline {
+	Pipeline := deploy.NewDeployPipeline(Deployment, &struct{ IPAddress string }{IPAddress: "10.0.0.5"}, Steps...)
+	Pipeline.Store = func(Deployment *models.Deployment) error {
+		this.Snapshots = append(this.Snapshots, *Deployment)
+		return nil
+	}
+	return Pipeline
+}
+
+func (this *PipelineTestSuite) TestExecute() {
+	Deployment := models.NewDeployment(1, 1, "{}")
+	Pipeline := this.NewPipeline(Deployment,
+		this.NewStep("Resources", false, false), this.NewStep("Disk", false, false), this.NewStep("Network", false, false))
+
+	assert.NoError(this.T(), Pipeline.Execute())
+	assert.Equal(this.T(), []string{"Do Resources", "Do Disk", "Do Network"}, this.Calls)
+	assert.Equal(this.T(), models.DeploymentStateCompleted, Deployment.State)
+	assert.Equal(this.T(), 3, Deployment.CompletedSteps)
+	assert.Empty(this.T(), Deployment.CurrentStep)
+	assert.JSONEq(this.T(), `{"IPAddress": "10.0.0.5"}`, Deployment.Context)
+
+	// Progress is being Stored before and after every Step
+	assert.Len(this.T(), this.Snapshots, 7)
+	assert.Equal(this.T(), "Disk", this.Snapshots[2].CurrentStep)
+	assert.Equal(this.T(), 1, this.Snapshots[2].CompletedSteps)
+	assert.Equal(this.T(), 2, this.Snapshots[3].CompletedSteps)
+}
+
+func (this *PipelineTestSuite) TestRollback() {
+	Deployment := models.NewDeployment(1, 1, "{}")
+	Pipeline := this.NewPipeline(Deployment,
+		this.NewStep("Resources", false, false), this.NewStep("Disk", false, false),
+		this.NewStep("Network", true, false), this.NewStep("Ssh", false, false))
+
+	ExecuteError := Pipeline.Execute()
+	assert.ErrorContains(this.T(), ExecuteError, "Step Network has Failed")
+
+	// Failed Step and the Completed ones are being Undone in the Reversed Order, the Following ones are not
+	assert.Equal(this.T(), []string{"Do Resources", "Do Disk", "Do Network", "Undo Network", "Undo Disk", "Undo Resources"}, this.Calls)
+	assert.Equal(this.T(), models.DeploymentStateRolledBack, Deployment.State)
+	assert.Equal(this.T(), 0, Deployment.CompletedSteps)
+	assert.Equal(this.T(), ExecuteError.Error(), Deployment.Error)
+}
+
+func (this *PipelineTestSuite) TestRollbackFailure() {
+	Deployment := models.NewDeployment(1, 1, "{}")
+	Pipeline := this.NewPipeline(Deployment,
+		this.NewStep("Resources", false, false), this.NewStep("Disk", false, true), this.NewStep("Network", true, false))
+
+	assert.ErrorContains(this.T(), Pipeline.Execute(), "Failed to Undo Step Disk")
+	assert.Equal(this.T(), []string{"Do Resources", "Do Disk", "Do Network", "Undo Network", "Undo Disk"}, this.Calls)
+	assert.Equal(this.T(), models.DeploymentStateRollbackFailed, Deployment.State)
+
+	// Step, that has not been Undone, Remains Completed, so the Rollback can be Retried
+	assert.Equal(this.T(), 2, Deployment.CompletedSteps)
+	assert.Equal(this.T(), "Disk", Deployment.CurrentStep)
+
+	// Retried Rollback does not Undo the Step, that has been Undone already
+	this.Calls = nil
+	Pipeline.Steps[1] = this.NewStep("Disk", false, false)
+	assert.ErrorContains(this.T(), Pipeline.Rollback(errors.New("Step Network has Failed")), "Step Network has Failed")
+	assert.Equal(this.T(), []string{"Undo Disk", "Undo Resources"}, this.Calls)
+	assert.Equal(this.T(), models.DeploymentStateRolledBack, Deployment.State)
+}
+
+func (this *PipelineTestSuite) TestResume() {
+	// Deployment has been Interrupted, while the Disk Step has been Executed
+	Deployment := models.NewDeployment(1, 1, "{}")
+	Deployment.CompletedSteps = 1
+	Deployment.CurrentStep = "Disk"
+
+	Pipeline := this.NewPipeline(Deployment,
+		this.NewStep("Resources", false, false), this.NewStep("Disk", false, false), this.NewStep("Network", false, false))
+	assert.NoError(this.T(), Pipeline.Execute())
+	assert.Equal(this.T(), []string{"Do Disk", "Do Network"}, this.Calls)
+	assert.Equal(this.T(), models.DeploymentStateCompleted, Deployment.State)
+}
+
+func (this *PipelineTestSuite) TestResumeRollback() {
+	// Deployment has been Interrupted during the Rollback, so it's being Finished instead of Executing the Steps
+	Deployment := models.NewDeployment(1, 1, "{}")
+	Deployment.CompletedSteps = 2
+	Deployment.State = models.DeploymentStateRollingBack
+	Deployment.Error = "Superseded by the Job 2"
+
+	Pipeline := this.NewPipeline(Deployment,
+		this.NewStep("Resources", false, false), this.NewStep("Disk", false, false), this.NewStep("Network", false, false))
+	assert.EqualError(this.T(), Pipeline.Execute(), "Superseded by the Job 2")
+	assert.Equal(this.T(), []string{"Undo Disk", "Undo Resources"}, this.Calls)
+	assert.Equal(this.T(), models.DeploymentStateRolledBack, Deployment.State)
+}
+
+func (this *PipelineTestSuite) TestRollbackInterruptedStep() {
+	// Deployment has been Interrupted, while the Disk Step has been Executed, and Superseded by the other one
+	Deployment := models.NewDeployment(1, 1, "{}")
+	Deployment.CompletedSteps = 1
+	Deployment.CurrentStep = "Disk"
+
+	Pipeline := this.NewPipeline(Deployment,
+		this.NewStep("Resources", false, false), this.NewStep("Disk", false, false), this.NewStep("Network", false, false))
+	assert.EqualError(this.T(), Pipeline.Rollback(errors.New("Superseded by the Job 2")), "Superseded by the Job 2")
+
+	// Interrupted Step could have Attached the Disk already, so it's being Undone along with the Completed one
+	assert.Equal(this.T(), []string{"Undo Disk", "Undo Resources"}, this.Calls)
+	assert.Equal(this.T(), models.DeploymentStateRolledBack, Deployment.State)
+	assert.Equal(this.T(), 0, Deployment.CompletedSteps)
+}
+
+func (this *PipelineTestSuite) TestContextCredentials() {
+	// Root Password is not being Persisted along with the Outputs of the Steps
+	Deployment := models.NewDeployment(1, 1, "{}")
+	Context := &deploy.DeployContext{DiskKey: 2001, SshType: models.TypeByRootCredentials,
+		SshInfo: `{"Username": "root", "Password": "secret"}`}
+	Pipeline := deploy.NewDeployPipeline(Deployment, Context, this.NewStep("Ssh", false, false))
+	Pipeline.Store = func(Deployment *models.Deployment) error { return nil }
+
+	assert.NoError(this.T(), Pipeline.Execute())
+	assert.NotContains(this.T(), Deployment.Context, "secret")
+	assert.NotContains(this.T(), Deployment.Context, "SshInfo")
+	assert.Contains(this.T(), Deployment.Context, `"DiskKey":2001`)
+}
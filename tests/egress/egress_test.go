@@ -0,0 +1,59 @@
+package egress_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/LovePelmeni/Infrastructure/egress"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type EgressTestSuite struct {
+	suite.Suite
+}
+
+func TestEgressSuite(t *testing.T) {
+	suite.Run(t, new(EgressTestSuite))
+}
+
+func (this *EgressTestSuite) SetupTest() {
+	egress.DefaultResolver = egress.StaticResolver{
+		"public.example.com":  {"93.184.216.34", "2606:2800:220:1::1"},
+		"rebound.example.com": {"93.184.216.34", "::ffff:127.0.0.1"},
+	}
+}
+
+func (this *EgressTestSuite) TearDownTest() {
+	egress.AllowPrivateAddresses = false
+	egress.DefaultResolver = net.DefaultResolver
+}
+
+func (this *EgressTestSuite) TestForbiddenAddresses() {
+	for _, Address := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.0.1", "169.254.169.254", "fe80::1",
+		"fd00::1", "0.0.0.0", "::", "100.64.0.1", "224.0.0.1", "255.255.255.255", "::ffff:10.0.0.1",
+	} {
+		assert.True(this.T(), egress.IsForbiddenIP(net.ParseIP(Address)), Address)
+	}
+	for _, Address := range []string{"93.184.216.34", "8.8.8.8", "2606:2800:220:1::1"} {
+		assert.False(this.T(), egress.IsForbiddenIP(net.ParseIP(Address)), Address)
+	}
+}
+
+func (this *EgressTestSuite) TestValidateHost() {
+	assert.NoError(this.T(), egress.ValidateHost("public.example.com"))
+	assert.NoError(this.T(), egress.ValidateHost("8.8.8.8"))
+	assert.ErrorIs(this.T(), egress.ValidateHost("rebound.example.com"), egress.ErrForbiddenAddress)
+	assert.ErrorIs(this.T(), egress.ValidateHost("10.0.0.1"), egress.ErrForbiddenAddress)
+	assert.ErrorIs(this.T(), egress.ValidateHost("unknown.example.com"), egress.ErrUnresolvableHost)
+
+	egress.AllowPrivateAddresses = true
+	assert.NoError(this.T(), egress.ValidateHost("10.0.0.1"))
+}
+
+func (this *EgressTestSuite) TestControl() {
+	assert.ErrorIs(this.T(), egress.Control("tcp", "127.0.0.1:80", nil), egress.ErrForbiddenAddress)
+	assert.ErrorIs(this.T(), egress.Control("tcp6", "[fe80::1]:443", nil), egress.ErrForbiddenAddress)
+	assert.NoError(this.T(), egress.Control("tcp", "93.184.216.34:443", nil))
+}
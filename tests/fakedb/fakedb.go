@@ -0,0 +1,161 @@
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Package consists of the Fake SQL Driver for the Tests, that Records the Statements, the Database receives,
+// and Responds with the Rows, Returned by the Responder of the Test, so the Queries can be Tested without the Postgres Server
+// Transactions are being Recorded as the `BEGIN`, `COMMIT` and `ROLLBACK` Statements
+
+type Statement struct {
+	Query string
+	Args  []driver.Value
+}
+
+type Result struct {
+	// Response of the Fake Database to the Statement
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+	Error        error
+}
+
+// Responds to the Statement, the Database has received, Empty Result is being Returned, if it's nil
+type Responder func(Query string, Args []driver.Value) Result
+
+type Database struct {
+	Mutex      sync.Mutex
+	Statements []Statement
+	Responder  Responder
+}
+
+func New(Responder Responder) (*gorm.DB, *Database) {
+	// Returns Gorm Connection with the Postgres Dialect, that Sends the Statements to the Fake Database
+	Fake := &Database{Responder: Responder}
+	Connection, _ := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(Fake)}),
+		&gorm.Config{Logger: logger.Discard, DisableAutomaticPing: true})
+	return Connection, Fake
+}
+
+func (this *Database) GetQueries() []string {
+	// Returns Statements, the Database has received, in the Order they have been Sent
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	Queries := []string{}
+	for _, Statement := range this.Statements {
+		Queries = append(Queries, Statement.Query)
+	}
+	return Queries
+}
+
+func (this *Database) Reset() {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.Statements = nil
+}
+
+func (this *Database) Respond(Query string, Args []driver.NamedValue) Result {
+	Values := []driver.Value{}
+	for _, Arg := range Args {
+		Values = append(Values, Arg.Value)
+	}
+	this.Mutex.Lock()
+	this.Statements = append(this.Statements, Statement{Query: Query, Args: Values})
+	this.Mutex.Unlock()
+
+	if this.Responder == nil {
+		return Result{}
+	}
+	return this.Responder(Query, Values)
+}
+
+// Connector of the `database/sql`
+
+func (this *Database) Connect(Context context.Context) (driver.Conn, error) {
+	return &Connection{Database: this}, nil
+}
+
+func (this *Database) Driver() driver.Driver {
+	return this
+}
+
+func (this *Database) Open(Name string) (driver.Conn, error) {
+	return &Connection{Database: this}, nil
+}
+
+type Connection struct {
+	Database *Database
+}
+
+func (this *Connection) Prepare(Query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (this *Connection) Close() error {
+	return nil
+}
+
+func (this *Connection) Begin() (driver.Tx, error) {
+	this.Database.Respond("BEGIN", nil)
+	return this, nil
+}
+
+func (this *Connection) BeginTx(Context context.Context, Options driver.TxOptions) (driver.Tx, error) {
+	return this.Begin()
+}
+
+func (this *Connection) Commit() error {
+	return this.Database.Respond("COMMIT", nil).Error
+}
+
+func (this *Connection) Rollback() error {
+	return this.Database.Respond("ROLLBACK", nil).Error
+}
+
+func (this *Connection) ExecContext(Context context.Context, Query string, Args []driver.NamedValue) (driver.Result, error) {
+	Result := this.Database.Respond(strings.TrimSpace(Query), Args)
+	if Result.Error != nil {
+		return nil, Result.Error
+	}
+	return driver.RowsAffected(Result.RowsAffected), nil
+}
+
+func (this *Connection) QueryContext(Context context.Context, Query string, Args []driver.NamedValue) (driver.Rows, error) {
+	Result := this.Database.Respond(strings.TrimSpace(Query), Args)
+	if Result.Error != nil {
+		return nil, Result.Error
+	}
+	return &Rows{Result: Result}, nil
+}
+
+type Rows struct {
+	Result Result
+	Index  int
+}
+
+func (this *Rows) Columns() []string {
+	return this.Result.Columns
+}
+
+func (this *Rows) Close() error {
+	return nil
+}
+
+func (this *Rows) Next(Destination []driver.Value) error {
+	if this.Index >= len(this.Result.Rows) {
+		return io.EOF
+	}
+	copy(Destination, this.Result.Rows[this.Index])
+	this.Index++
+	return nil
+}
@@ -0,0 +1,176 @@
+package fakeredis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis"
+)
+
+// Package consists of the Fake Redis Server for the Tests, that Speaks the RESP Protocol and Keeps the Strings in Memory
+// Lua Scripts can't be Executed, so the Test Registers the Go Implementation of every Script, it relies on
+// Scripts, that have not been Registered, are being Rejected, the same way the Unavailable Redis does
+
+// Executes the Script against the Values of the Server, Returns nil, string, int64 or error
+type Script func(Values map[string]string, Keys []string, Args []string) interface{}
+
+type Server struct {
+	Mutex    sync.Mutex
+	Values   map[string]string
+	Scripts  map[string]Script
+	Listener net.Listener
+}
+
+func NewServer() *Server {
+	// Starts the Server on the Random Local Port
+	Listener, ListenError := net.Listen("tcp", "127.0.0.1:0")
+	if ListenError != nil {
+		panic(ListenError)
+	}
+	Server := &Server{
+		Values:   make(map[string]string),
+		Scripts:  make(map[string]Script),
+		Listener: Listener,
+	}
+	go Server.Serve()
+	return Server
+}
+
+func (this *Server) GetClient() *redis.Client {
+	return redis.NewClient(&redis.Options{Addr: this.Listener.Addr().String(), MaxRetries: 0})
+}
+
+func (this *Server) RegisterScript(Source string, Implementation Script) {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.Scripts[Source] = Implementation
+}
+
+func (this *Server) Close() {
+	this.Listener.Close()
+}
+
+func (this *Server) Serve() {
+	for {
+		Connection, AcceptError := this.Listener.Accept()
+		if AcceptError != nil {
+			return
+		}
+		go this.Handle(Connection)
+	}
+}
+
+func (this *Server) Handle(Connection net.Conn) {
+	defer Connection.Close()
+	Reader := bufio.NewReader(Connection)
+	for {
+		Command, ReadError := ReadCommand(Reader)
+		if ReadError != nil {
+			return
+		}
+		if _, WriteError := Connection.Write(Encode(this.Execute(Command))); WriteError != nil {
+			return
+		}
+	}
+}
+
+func ReadCommand(Reader *bufio.Reader) ([]string, error) {
+	// Reads the Array of the Bulk Strings, the Client Sends the Commands as
+	Header, ReadError := Reader.ReadString('\n')
+	if ReadError != nil {
+		return nil, ReadError
+	}
+	if !strings.HasPrefix(Header, "*") {
+		return nil, errors.New("Inline Commands are not Supported")
+	}
+	Length, ParseError := strconv.Atoi(strings.TrimSpace(Header[1:]))
+	if ParseError != nil {
+		return nil, ParseError
+	}
+	Command := make([]string, 0, Length)
+	for Index := 0; Index < Length; Index++ {
+		Size, ReadError := Reader.ReadString('\n')
+		if ReadError != nil {
+			return nil, ReadError
+		}
+		BulkLength, ParseError := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(Size, "$")))
+		if ParseError != nil {
+			return nil, ParseError
+		}
+		Bulk := make([]byte, BulkLength+2)
+		if _, ReadError := io.ReadFull(Reader, Bulk); ReadError != nil {
+			return nil, ReadError
+		}
+		Command = append(Command, string(Bulk[:BulkLength]))
+	}
+	return Command, nil
+}
+
+func Encode(Reply interface{}) []byte {
+	switch Value := Reply.(type) {
+	case nil:
+		return []byte("$-1\r\n")
+	case error:
+		return []byte("-" + Value.Error() + "\r\n")
+	case int64:
+		return []byte(":" + strconv.FormatInt(Value, 10) + "\r\n")
+	case bool:
+		if Value {
+			return []byte(":1\r\n")
+		}
+		return []byte("$-1\r\n")
+	default:
+		Serialized := fmt.Sprint(Value)
+		return []byte("$" + strconv.Itoa(len(Serialized)) + "\r\n" + Serialized + "\r\n")
+	}
+}
+
+func (this *Server) Execute(Command []string) interface{} {
+	if len(Command) == 0 {
+		return errors.New("ERR empty command")
+	}
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+
+	Args := Command[1:]
+	switch strings.ToUpper(Command[0]) {
+	case "PING":
+		return "PONG"
+	case "GET":
+		if Value, Exists := this.Values[Args[0]]; Exists {
+			return Value
+		}
+		return nil
+	case "SET":
+		// Expiration is not being Tracked, the Tests do not Outlive the Keys
+		this.Values[Args[0]] = Args[1]
+		return "OK"
+	case "EXISTS", "DEL":
+		var Number int64
+		for _, Key := range Args {
+			if _, Exists := this.Values[Key]; Exists {
+				Number++
+				if strings.ToUpper(Command[0]) == "DEL" {
+					delete(this.Values, Key)
+				}
+			}
+		}
+		return Number
+	case "EVAL":
+		Implementation, Exists := this.Scripts[Args[0]]
+		if !Exists {
+			return errors.New("ERR Script is not Registered in the Fake Redis")
+		}
+		KeysNumber, _ := strconv.Atoi(Args[1])
+		return Implementation(this.Values, Args[2:2+KeysNumber], Args[2+KeysNumber:])
+	case "EVALSHA":
+		return errors.New("NOSCRIPT No matching script")
+	}
+	return fmt.Errorf("ERR unknown command `%s`", Command[0])
+}
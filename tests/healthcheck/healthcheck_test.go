@@ -0,0 +1,132 @@
+package healthcheck_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/healthcheck"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+type HealthCheckTestSuite struct {
+	suite.Suite
+}
+
+func TestHealthCheckSuite(t *testing.T) {
+	suite.Run(t, new(HealthCheckTestSuite))
+}
+
+func NewVirtualMachine(PowerState types.VirtualMachinePowerState) mo.VirtualMachine {
+	var VirtualMachine mo.VirtualMachine
+	VirtualMachine.Summary.Runtime.PowerState = PowerState
+	VirtualMachine.Summary.QuickStats.OverallCpuUsage = 1200
+	VirtualMachine.Summary.QuickStats.GuestHeartbeatStatus = types.ManagedEntityStatusGreen
+	VirtualMachine.Summary.Config.NumCpu = 2
+	VirtualMachine.Summary.Storage = &types.VirtualMachineStorageSummary{Committed: 1024}
+	return VirtualMachine
+}
+
+func (this *HealthCheckTestSuite) TestPoweredOffVirtualMachine() {
+	// Boot Time is not being Reported for the Powered off Virtual Machines
+	VirtualMachine := NewVirtualMachine(types.VirtualMachinePowerStatePoweredOff)
+	Manager := healthcheck.NewVirtualMachineHealthCheckManager(&VirtualMachine)
+	assert.NotPanics(this.T(), func() { Manager.GetAliveMetrics() })
+	assert.NotPanics(this.T(), func() { Manager.GetHostSystemHealthMetrics() }, "Guest Summary is Optional")
+	assert.Equal(this.T(), int32(1200), Manager.GetCpuMetrics().OverallCpuUsage)
+}
+
+func (this *HealthCheckTestSuite) TestDatacenter() {
+	assert.Equal(this.T(), "Datacenter", healthcheck.GetDatacenter("/Datacenter/vm/Server"))
+	assert.Equal(this.T(), "", healthcheck.GetDatacenter(""))
+}
+
+func (this *HealthCheckTestSuite) TestSamples() {
+	Records := []models.VirtualMachine{
+		{ID: 1, OwnerId: 10, ItemPath: "/DC0/vm/First", ManagedObjectId: "vm-1"},
+		{ID: 2, OwnerId: 20, ItemPath: "/DC1/vm/Missing", ManagedObjectId: "vm-2"},
+	}
+	Samples := healthcheck.GetSamples(Records, map[string]mo.VirtualMachine{
+		"vm-1": NewVirtualMachine(types.VirtualMachinePowerStatePoweredOn),
+	})
+	assert.Len(this.T(), Samples, len(healthcheck.VirtualMachineGauges))
+
+	// Only the Virtual Machines, Found in the Inventory, are being Reported
+	Values := map[string]float64{}
+	for Index, Gauge := range healthcheck.VirtualMachineGauges {
+		assert.Len(this.T(), Samples[Index], 1)
+		assert.Equal(this.T(), []string{"1", "10", "DC0"}, Samples[Index][0].LabelValues)
+		Values[Gauge.Gauge.Name] = Samples[Index][0].Value
+	}
+	assert.Equal(this.T(), float64(1200), Values["virtual_machine_cpu_usage_mhz"])
+	assert.Equal(this.T(), float64(2), Values["virtual_machine_cpu_count"])
+	assert.Equal(this.T(), float64(1024), Values["virtual_machine_storage_committed_bytes"])
+	assert.Equal(this.T(), float64(1), Values["virtual_machine_powered_on"])
+	assert.Equal(this.T(), float64(1), Values["virtual_machine_guest_heartbeat_healthy"])
+}
+
+func (this *HealthCheckTestSuite) TestHistorySamples() {
+	Timestamp := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	History := healthcheck.GetHistorySamples([]models.VirtualMachine{
+		{ID: 1, ManagedObjectId: "vm-1"}, {ID: 2, ManagedObjectId: "vm-2"},
+	}, map[string]mo.VirtualMachine{"vm-1": NewVirtualMachine(types.VirtualMachinePowerStatePoweredOn)}, Timestamp)
+
+	assert.Len(this.T(), History, 1)
+	assert.Equal(this.T(), 1, History[0].VirtualMachineId)
+	assert.Equal(this.T(), 0, History[0].Resolution, "Raw Samples have no Resolution")
+	assert.Equal(this.T(), Timestamp, History[0].CollectedAt)
+	assert.Equal(this.T(), float64(1200), History[0].CpuUsage)
+	assert.Equal(this.T(), float64(1), History[0].PoweredOn)
+	assert.Equal(this.T(), 1, History[0].Samples)
+}
+
+func (this *HealthCheckTestSuite) TestHistoryQueryDefaults() {
+	Now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	Query, QueryError := healthcheck.NewHistoryQuery("", "", "", Now)
+	assert.NoError(this.T(), QueryError)
+	assert.Equal(this.T(), Now.Add(-24*time.Hour), Query.From)
+	assert.Equal(this.T(), Now, Query.To)
+
+	// Resolution is being Chosen, so the Last Day of the Raw Samples Fits into the Points Limit
+	assert.Equal(this.T(), time.Duration(0), Query.Source)
+	assert.Equal(this.T(), 87*time.Second, Query.Resolution)
+
+	// Range, that is Older than the Raw Samples, is being Served by the Rollups
+	Query, QueryError = healthcheck.NewHistoryQuery("", "2023-12-20T12:00:00Z", "", Now)
+	assert.NoError(this.T(), QueryError)
+	assert.Equal(this.T(), 5*time.Minute, Query.Source)
+	assert.Equal(this.T(), 5*time.Minute, Query.Resolution)
+}
+
+func (this *HealthCheckTestSuite) TestHistoryQueryResolution() {
+	Now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Recent Range with the Fine Resolution is being Served by the Raw Samples
+	Query, QueryError := healthcheck.NewHistoryQuery("2024-01-01T11:00:00Z", "", "90", Now)
+	assert.NoError(this.T(), QueryError)
+	assert.Equal(this.T(), time.Duration(0), Query.Source)
+	assert.Equal(this.T(), 90*time.Second, Query.Resolution)
+
+	// Raw Samples of the last Week are not Retained anymore, Resolution is being Rounded up to the Rollup one
+	Query, QueryError = healthcheck.NewHistoryQuery("2023-12-25T12:00:00Z", "2023-12-26T12:00:00Z", "7m", Now)
+	assert.NoError(this.T(), QueryError)
+	assert.Equal(this.T(), 5*time.Minute, Query.Source)
+	assert.Equal(this.T(), 10*time.Minute, Query.Resolution)
+}
+
+func (this *HealthCheckTestSuite) TestInvalidHistoryQuery() {
+	Now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, Invalid := range [][3]string{
+		{"yesterday", "", ""},
+		{"2024-01-01T12:00:00Z", "2024-01-01T11:00:00Z", ""},
+		{"", "", "10s"},
+		{"", "", "fast"},
+		{"2023-01-01T00:00:00Z", "", "5m"}, // Too many Points
+	} {
+		_, QueryError := healthcheck.NewHistoryQuery(Invalid[0], Invalid[1], Invalid[2], Now)
+		assert.Error(this.T(), QueryError, Invalid)
+	}
+}
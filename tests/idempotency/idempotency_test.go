@@ -0,0 +1,80 @@
+package idempotency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/LovePelmeni/Infrastructure/idempotency"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+}
+
+func TestIdempotencySuite(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
+
+func (this *IdempotencyTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (this *IdempotencyTestSuite) TestRecordKeyScope() {
+	Key := idempotency.GetRecordKey("customer:1", http.MethodPost, "/vm/initialize/", "key")
+	assert.Equal(this.T(), Key, idempotency.GetRecordKey("customer:1", http.MethodPost, "/vm/initialize/", "key"))
+
+	assert.NotEqual(this.T(), Key, idempotency.GetRecordKey("customer:2", http.MethodPost, "/vm/initialize/", "key"), "Customers should not Share the Keys")
+	assert.NotEqual(this.T(), Key, idempotency.GetRecordKey("customer:1", http.MethodPut, "/vm/initialize/", "key"))
+	assert.NotEqual(this.T(), Key, idempotency.GetRecordKey("customer:1", http.MethodPost, "/vm/clone/", "key"))
+	assert.NotEqual(this.T(), Key, idempotency.GetRecordKey("customer:1", http.MethodPost, "/vm/initialize/", "other"))
+}
+
+func (this *IdempotencyTestSuite) TestFingerprint() {
+	Form := url.Values{"Name": {"vm"}, "ProjectId": {"1"}}
+	Fingerprint := idempotency.GetFingerprint("VirtualMachineId=1", Form, nil)
+	assert.Equal(this.T(), Fingerprint, idempotency.GetFingerprint("VirtualMachineId=1", url.Values{"ProjectId": {"1"}, "Name": {"vm"}}, nil))
+	assert.NotEqual(this.T(), Fingerprint, idempotency.GetFingerprint("VirtualMachineId=2", Form, nil))
+	assert.NotEqual(this.T(), Fingerprint, idempotency.GetFingerprint("VirtualMachineId=1", url.Values{"Name": {"other"}, "ProjectId": {"1"}}, nil))
+	assert.NotEqual(this.T(), Fingerprint, idempotency.GetFingerprint("VirtualMachineId=1", Form, []byte(`{"Name": "vm"}`)))
+}
+
+func (this *IdempotencyTestSuite) TestIsStorable() {
+	for _, StatusCode := range []int{http.StatusOK, http.StatusAccepted, http.StatusBadRequest, http.StatusForbidden} {
+		assert.True(this.T(), idempotency.IsStorable(StatusCode), StatusCode)
+	}
+	for _, StatusCode := range []int{http.StatusConflict, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		assert.False(this.T(), idempotency.IsStorable(StatusCode), StatusCode)
+	}
+}
+
+func (this *IdempotencyTestSuite) TestRecordAndReplay() {
+	// Response, Captured by the Recorder, should be Replayed as is
+	Original := httptest.NewRecorder()
+	OriginalContext, _ := gin.CreateTestContext(Original)
+	Recorder := idempotency.NewResponseRecorder(OriginalContext.Writer)
+	OriginalContext.Writer = Recorder
+
+	OriginalContext.Header("X-RateLimit-Remaining", "5")
+	OriginalContext.JSON(http.StatusAccepted, gin.H{"JobId": 1})
+
+	Record := idempotency.NewRecord("fingerprint")
+	assert.Equal(this.T(), idempotency.StateProcessing, Record.State)
+	Record.Complete(Recorder.Status(), Recorder.Header(), Recorder.Body.Bytes())
+	assert.Equal(this.T(), idempotency.StateCompleted, Record.State)
+	assert.Empty(this.T(), Record.Header.Get("X-RateLimit-Remaining"), "Rate Limit Headers should not be Stored")
+
+	Replayed := httptest.NewRecorder()
+	ReplayedContext, _ := gin.CreateTestContext(Replayed)
+	Record.Replay(ReplayedContext)
+
+	assert.True(this.T(), ReplayedContext.IsAborted())
+	assert.Equal(this.T(), http.StatusAccepted, Replayed.Code)
+	assert.Equal(this.T(), Original.Body.String(), Replayed.Body.String())
+	assert.Equal(this.T(), Original.Header().Get("Content-Type"), Replayed.Header().Get("Content-Type"))
+	assert.Equal(this.T(), "true", Replayed.Header().Get(idempotency.ReplayedHeaderName))
+}
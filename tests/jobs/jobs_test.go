@@ -0,0 +1,228 @@
+package jobs_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/LovePelmeni/Infrastructure/exceptions"
+	"github.com/LovePelmeni/Infrastructure/jobs"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/tests/fakedb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type JobsTestSuite struct {
+	suite.Suite
+	Mutex    sync.Mutex
+	Jobs     map[int]models.Job // Records of the Jobs, the Fake Database Responds with
+	Observed []models.Job       // Every Stored State of the Jobs in the Order they have been Stored
+	Pool     *jobs.JobWorkerPool
+}
+
+func TestJobsSuite(t *testing.T) {
+	suite.Run(t, new(JobsTestSuite))
+}
+
+func (this *JobsTestSuite) SetupSuite() {
+	models.SubscribeJobs(func(Job models.Job) {
+		this.Mutex.Lock()
+		defer this.Mutex.Unlock()
+		this.Observed = append(this.Observed, Job)
+	})
+}
+
+func (this *JobsTestSuite) SetupTest() {
+	this.Jobs = make(map[int]models.Job)
+	this.Observed = nil
+	models.Database, _ = fakedb.New(this.Respond)
+
+	this.Pool = jobs.NewJobWorkerPool(1, 10)
+	this.Pool.RegisterHandler(models.JobTypeStart, func(Job *models.Job) (interface{}, error) {
+		return map[string]string{"Status": "Started"}, nil
+	})
+	this.Pool.RegisterHandler(models.JobTypeShutdown, func(Job *models.Job) (interface{}, error) {
+		return nil, errors.New("Virtual Machine is not Responding")
+	})
+	this.Pool.RegisterHandler(models.JobTypeReboot, func(Job *models.Job) (interface{}, error) {
+		panic("unexpected state")
+	})
+	this.Pool.RegisterResumableHandler(models.JobTypeDeploy, func(Job *models.Job) (interface{}, error) {
+		return map[string]string{"Status": "Applied"}, nil
+	})
+}
+
+func JobRow(Job models.Job) []driver.Value {
+	return []driver.Value{int64(Job.ID), Job.Type, int64(Job.VirtualMachineId), int64(Job.OwnerId), Job.State, Job.Payload}
+}
+
+func InsertedValues(Query string, Args []driver.Value) map[string]driver.Value {
+	// Returns Values of the `INSERT INTO "table" ("column", ...) VALUES ($1, ...)` Statement by the Columns
+	Columns := strings.Split(Query[strings.Index(Query, "(")+1:strings.Index(Query, ")")], ",")
+	Values := make(map[string]driver.Value)
+	for Index, Column := range Columns {
+		Values[strings.Trim(Column, `" `)] = Args[Index]
+	}
+	return Values
+}
+
+func (this *JobsTestSuite) Respond(Query string, Args []driver.Value) fakedb.Result {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	Columns := []string{"id", "type", "virtual_machine_id", "owner_id", "state", "payload"}
+
+	switch {
+	case strings.HasPrefix(Query, `INSERT INTO "jobs"`):
+		// Job is being Stored before it's Queued, so the Worker can Find it right away
+		Inserted := InsertedValues(Query, Args)
+		Job := models.Job{ID: len(this.Jobs) + 1, Type: Inserted["type"].(string), State: Inserted["state"].(string)}
+		this.Jobs[Job.ID] = Job
+		return fakedb.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(Job.ID)}}}
+
+	case strings.HasPrefix(Query, `SELECT * FROM "jobs" WHERE id = `):
+		Job, Exists := this.Jobs[int(Args[0].(int64))]
+		if !Exists {
+			return fakedb.Result{Columns: Columns}
+		}
+		return fakedb.Result{Columns: Columns, Rows: [][]driver.Value{JobRow(Job)}}
+
+	case strings.HasPrefix(Query, `SELECT * FROM "jobs" WHERE state IN `):
+		Rows := [][]driver.Value{}
+		for Id := 1; Id <= len(this.Jobs); Id++ {
+			if State := this.Jobs[Id].State; State == models.JobStateQueued || State == models.JobStateRunning {
+				Rows = append(Rows, JobRow(this.Jobs[Id]))
+			}
+		}
+		return fakedb.Result{Columns: Columns, Rows: Rows}
+
+	case strings.HasPrefix(Query, `UPDATE "jobs"`):
+		return fakedb.Result{RowsAffected: 1}
+	}
+	return fakedb.Result{}
+}
+
+func (this *JobsTestSuite) Store(Jobs ...models.Job) {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	for _, Job := range Jobs {
+		this.Jobs[Job.ID] = Job
+	}
+}
+
+func (this *JobsTestSuite) GetStates(JobId int) []string {
+	// Returns States, the Job has been Stored with, in the Order they have been Stored
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	States := []string{}
+	for _, Job := range this.Observed {
+		if Job.ID == JobId {
+			States = append(States, Job.State)
+		}
+	}
+	return States
+}
+
+func (this *JobsTestSuite) GetLast(JobId int) models.Job {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	var Last models.Job
+	for _, Job := range this.Observed {
+		if Job.ID == JobId {
+			Last = Job
+		}
+	}
+	return Last
+}
+
+func (this *JobsTestSuite) Enqueue(Type string) *models.Job {
+	Job, _ := models.NewJob(Type, 3, 7, nil)
+	assert.NoError(this.T(), this.Pool.Enqueue(Job))
+	return Job
+}
+
+func (this *JobsTestSuite) TestExecute() {
+	Job := this.Enqueue(models.JobTypeStart)
+	assert.Equal(this.T(), 1, Job.ID)
+	assert.Equal(this.T(), Job.ID, <-this.Pool.Queue, "Stored Job should be Queued")
+
+	this.Pool.Execute(Job.ID)
+	assert.Equal(this.T(), []string{models.JobStateQueued, models.JobStateRunning, models.JobStateSucceeded}, this.GetStates(Job.ID))
+
+	Finished := this.GetLast(Job.ID)
+	assert.Equal(this.T(), 100, Finished.Progress)
+	assert.JSONEq(this.T(), `{"Status": "Started"}`, Finished.Result)
+	assert.Empty(this.T(), Finished.Error)
+}
+
+func (this *JobsTestSuite) TestFailures() {
+	Failed := this.Enqueue(models.JobTypeShutdown)
+	Panicked := this.Enqueue(models.JobTypeReboot)
+	Unknown := this.Enqueue(models.JobTypeResize)
+
+	for _, Job := range []*models.Job{Failed, Panicked, Unknown} {
+		this.Pool.Execute(<-this.Pool.Queue)
+		assert.Equal(this.T(), models.JobStateFailed, this.GetLast(Job.ID).State)
+	}
+	assert.Equal(this.T(), "Virtual Machine is not Responding", this.GetLast(Failed.ID).Error)
+	assert.Equal(this.T(), "Job Handler Panicked: unexpected state", this.GetLast(Panicked.ID).Error)
+	assert.Equal(this.T(), exceptions.JobHandlerDoesNotExist(models.JobTypeResize).Error(), this.GetLast(Unknown.ID).Error)
+	assert.NotContains(this.T(), this.GetStates(Unknown.ID), models.JobStateRunning, "Job without the Handler should not be Started")
+}
+
+func (this *JobsTestSuite) TestQueueOverflow() {
+	this.Pool = jobs.NewJobWorkerPool(1, 0)
+	Job, _ := models.NewJob(models.JobTypeStart, 3, 7, nil)
+	assert.EqualError(this.T(), this.Pool.Enqueue(Job), exceptions.JobQueueOverflow().Error())
+	assert.Equal(this.T(), models.JobStateFailed, this.GetLast(Job.ID).State)
+}
+
+func (this *JobsTestSuite) TestRestart() {
+	// Jobs, that have not been Finished by the Previous Run of the Server
+	this.Store(
+		models.Job{ID: 1, Type: models.JobTypeDeploy, State: models.JobStateRunning},
+		models.Job{ID: 2, Type: models.JobTypeStart, State: models.JobStateRunning},
+		models.Job{ID: 3, Type: models.JobTypeStart, State: models.JobStateQueued},
+		models.Job{ID: 4, Type: models.JobTypeStart, State: models.JobStateSucceeded},
+	)
+
+	// Pool without the Workers, so the Restored Queue can be Inspected
+	this.Pool.WorkersNumber = 0
+	this.Pool.Start()
+	defer this.Pool.Stop()
+
+	// Resumable Job is being Queued again, while the other Interrupted one can't be Restarted Safely
+	assert.Equal(this.T(), 1, <-this.Pool.Queue)
+	assert.Equal(this.T(), 3, <-this.Pool.Queue)
+	assert.Len(this.T(), this.Pool.Queue, 0)
+
+	Interrupted := this.GetLast(2)
+	assert.Equal(this.T(), models.JobStateFailed, Interrupted.State)
+	assert.Equal(this.T(), "Job has been Interrupted by the Server Restart", Interrupted.Error)
+	assert.Empty(this.T(), this.GetStates(4), "Finished Job should not be Touched")
+}
+
+func (this *JobsTestSuite) TestWorkers() {
+	// Workers Execute the Queued Jobs, Stop Waits for them to be Finished
+	this.Pool.WorkersNumber = 2
+	this.Pool.Start()
+	First, Second := this.Enqueue(models.JobTypeStart), this.Enqueue(models.JobTypeDeploy)
+	this.Pool.Stop()
+
+	assert.Equal(this.T(), models.JobStateSucceeded, this.GetLast(First.ID).State)
+	assert.Equal(this.T(), models.JobStateSucceeded, this.GetLast(Second.ID).State)
+}
+
+func (this *JobsTestSuite) TestEnqueueAfterStop() {
+	// Jobs, Enqueued after the Stop (e.g by the Reconciler or the Late Request), are being Rejected instead of Panicking
+	this.Pool.WorkersNumber = 1
+	this.Pool.Start()
+	this.Pool.Stop()
+	this.Pool.Stop()
+
+	Job, _ := models.NewJob(models.JobTypeStart, 3, 7, nil)
+	assert.EqualError(this.T(), this.Pool.Enqueue(Job), exceptions.JobPoolStopped().Error())
+	assert.Empty(this.T(), this.Jobs, "Rejected Job should not be Stored")
+}
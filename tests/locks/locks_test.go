@@ -0,0 +1,66 @@
+package locks_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/jobs"
+	"github.com/LovePelmeni/Infrastructure/locks"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LocksTestSuite struct {
+	suite.Suite
+}
+
+func TestLocksSuite(t *testing.T) {
+	suite.Run(t, new(LocksTestSuite))
+}
+
+func (this *LocksTestSuite) TestKeys() {
+	assert.Equal(this.T(), "vm:lock:42", locks.GetLockKey(42))
+	assert.Equal(this.T(), "vm:lock:fence:42", locks.GetFenceKey(42))
+	assert.NotEqual(this.T(), locks.GetLockKey(42), locks.GetLockKey(43))
+}
+
+func (this *LocksTestSuite) TestNewLock() {
+	AcquiredAt := time.Now().Add(-time.Second).Truncate(time.Millisecond)
+	Lock := locks.NewLock(map[string]string{
+		"Token":      "7",
+		"HolderId":   "3",
+		"Holder":     "customer",
+		"Operation":  "Reboot",
+		"JobId":      "15",
+		"AcquiredAt": strconv.FormatInt(AcquiredAt.UnixNano()/int64(time.Millisecond), 10),
+	}, 30*time.Second)
+
+	assert.Equal(this.T(), int64(7), Lock.Token)
+	assert.Equal(this.T(), 3, Lock.HolderId)
+	assert.Equal(this.T(), "customer", Lock.Holder)
+	assert.Equal(this.T(), "Reboot", Lock.Operation)
+	assert.Equal(this.T(), 15, Lock.JobId)
+	assert.True(this.T(), AcquiredAt.Equal(Lock.AcquiredAt))
+	assert.WithinDuration(this.T(), time.Now().Add(30*time.Second), Lock.ExpiresAt, time.Second)
+}
+
+func (this *LocksTestSuite) TestNewLockWithoutJob() {
+	// The Job is being Attached after the Lock has been Acquired
+	Lock := locks.NewLock(map[string]string{"Token": "1", "Operation": "Deploy"}, time.Minute)
+	assert.Zero(this.T(), Lock.JobId)
+	assert.Equal(this.T(), int64(1), Lock.Token)
+}
+
+func (this *LocksTestSuite) TestDisabledLocker() {
+	// Pool without the Locker does not Lock the Virtual Machines, so nothing is being Reported as the Holder
+	Pool := jobs.NewJobWorkerPool(1, 1)
+	Lock, LockError := Pool.GetLock(42)
+	assert.NoError(this.T(), LockError)
+	assert.Nil(this.T(), Lock)
+
+	Lease, LeaseError := Pool.AcquireLock(&models.Job{Type: models.JobTypeReboot, VirtualMachineId: 42, OwnerId: 3})
+	assert.NoError(this.T(), LeaseError)
+	assert.Nil(this.T(), Lease)
+}
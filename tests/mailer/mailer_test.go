@@ -0,0 +1,61 @@
+package mailer_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LovePelmeni/Infrastructure/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MailerTestSuite struct {
+	suite.Suite
+}
+
+func TestMailerSuite(t *testing.T) {
+	suite.Run(t, new(MailerTestSuite))
+}
+
+func (this *MailerTestSuite) TestMemoryMailer() {
+	Mailer := mailer.NewMemoryMailer()
+	mailer.SetMailer(Mailer)
+
+	assert.NoError(this.T(), mailer.Send("customer@example.com", "Confirm your Email Address", "Token"))
+	assert.NoError(this.T(), mailer.Send("other@example.com", "Reset your Password", "Token"))
+
+	Messages := Mailer.GetMessages("customer@example.com")
+	assert.Len(this.T(), Messages, 1)
+	assert.Equal(this.T(), "Confirm your Email Address", Messages[0].Subject)
+	assert.False(this.T(), Messages[0].SentAt.IsZero())
+}
+
+func (this *MailerTestSuite) TestFileMailer() {
+	Path := filepath.Join(this.T().TempDir(), "Mails.json")
+	Mailer := mailer.NewFileMailer(Path)
+
+	assert.NoError(this.T(), Mailer.Send(mailer.NewMessage("customer@example.com", "Subject", "First")))
+	assert.NoError(this.T(), Mailer.Send(mailer.NewMessage("customer@example.com", "Subject", "Second")))
+
+	File, OpenError := os.Open(Path)
+	assert.NoError(this.T(), OpenError)
+	defer File.Close()
+
+	Bodies := []string{}
+	Scanner := bufio.NewScanner(File)
+	for Scanner.Scan() {
+		var Message mailer.Message
+		assert.NoError(this.T(), json.Unmarshal(Scanner.Bytes(), &Message))
+		Bodies = append(Bodies, Message.Body)
+	}
+	assert.Equal(this.T(), []string{"First", "Second"}, Bodies)
+}
+
+func (this *MailerTestSuite) TestSmtpMailerRejectsHeaderInjection() {
+	Mailer := mailer.NewSmtpMailer("localhost", "25", "support@example.com", "password")
+	SendError := Mailer.Send(mailer.NewMessage("customer@example.com\r\nBcc: other@example.com", "Subject", "Body"))
+	assert.Equal(this.T(), mailer.ErrInvalidHeader, SendError)
+}
@@ -0,0 +1,147 @@
+package main_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/idempotency"
+	"github.com/LovePelmeni/Infrastructure/middlewares"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/server"
+	"github.com/LovePelmeni/Infrastructure/tests/fakedb"
+	"github.com/LovePelmeni/Infrastructure/tests/fakeredis"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vmware/govmomi/simulator"
+)
+
+type RouterTestSuite struct {
+	suite.Suite
+	Redis    *fakeredis.Server
+	Model    *simulator.Model
+	vCenter  *simulator.Server
+	Database *fakedb.Database
+	Router   *gin.Engine
+	Token    string
+}
+
+func TestRouterSuite(t *testing.T) {
+	suite.Run(t, new(RouterTestSuite))
+}
+
+func (this *RouterTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+
+	// Redis Script of the Idempotency Store, that Stores the Record, unless the Key has been Used already
+	this.Redis = fakeredis.NewServer()
+	this.Redis.RegisterScript(idempotency.BeginScript, func(Values map[string]string, Keys []string, Args []string) interface{} {
+		if Record, Exists := Values[Keys[0]]; Exists {
+			return Record
+		}
+		Values[Keys[0]] = Args[0]
+		return nil
+	})
+	middlewares.RedisClient = this.Redis.GetClient()
+
+	// Customer is the Owner of the Project, the Virtual Machine is being Created in
+	models.Database, this.Database = fakedb.New(func(Query string, Args []driver.Value) fakedb.Result {
+		if strings.Contains(Query, `FROM "memberships"`) {
+			return fakedb.Result{Columns: []string{"id", "organization_id", "customer_id", "role"},
+				Rows: [][]driver.Value{{int64(1), int64(1), int64(7), models.RoleOwner}}}
+		}
+		return fakedb.Result{}
+	})
+
+	authentication.Configure(config.AuthenticationConfig{SecretKey: "secret", AccessTokenLifetime: time.Hour})
+	Token, TokenError := authentication.CreateJwtToken(7, "customer", "customer@example.com")
+	assert.NoError(this.T(), TokenError)
+	this.Token = Token
+
+	this.Model = simulator.VPX()
+	assert.NoError(this.T(), this.Model.Create())
+	this.vCenter = this.Model.Service.NewServer()
+
+	Pool := vsphere.NewSessionPool(this.vCenter.URL, time.Minute, vsphere.NewCircuitBreaker(3, time.Minute))
+	Pool.Insecure = true
+	Pool.Endpoint.Region = "eu"
+	Context, CancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer CancelFunc()
+	assert.NoError(this.T(), Pool.Connect(Context))
+	vsphere.Registry = vsphere.NewEndpointRegistry(nil, time.Minute, 3, time.Minute)
+	vsphere.Registry.Add(Pool)
+
+	this.Router = server.NewServer(&config.Config{}).NewRouter()
+}
+
+func (this *RouterTestSuite) TearDownSuite() {
+	this.vCenter.Close()
+	this.Model.Remove()
+	this.Redis.Close()
+}
+
+func (this *RouterTestSuite) Send(Path string, Key string, Form url.Values) *httptest.ResponseRecorder {
+	Request := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(Form.Encode()))
+	Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Request.Header.Set("Authorization", "Bearer "+this.Token)
+	Request.Header.Set(idempotency.HeaderName, Key)
+	Recorder := httptest.NewRecorder()
+	this.Router.ServeHTTP(Recorder, Request)
+	return Recorder
+}
+
+func (this *RouterTestSuite) TestIdempotentInitialization() {
+	// The Form is being Parsed by the Policy Check before the Idempotency Middleware, so the Body has been Consumed by then
+	Form := url.Values{"ProjectId": {"1"}, "VirtualMachineName": {"web"}, "ResourceRequirements": {"invalid"}}
+
+	Response := this.Send("/vm/initialize/", "initialize-1", Form)
+	assert.Equal(this.T(), http.StatusBadRequest, Response.Code)
+	assert.Empty(this.T(), Response.Header().Get(idempotency.ReplayedHeaderName))
+	assert.True(this.T(), strings.Contains(strings.Join(this.Database.GetQueries(), "\n"), `FROM "memberships"`),
+		"Request should Pass through the Policy Check")
+
+	Replayed := this.Send("/vm/initialize/", "initialize-1", Form)
+	assert.Equal(this.T(), http.StatusBadRequest, Replayed.Code)
+	assert.Equal(this.T(), "true", Replayed.Header().Get(idempotency.ReplayedHeaderName))
+	assert.Equal(this.T(), Response.Body.String(), Replayed.Body.String())
+
+	// The Key, Reused with the other Form, is being Rejected, instead of Replaying the Response to the other Request
+	Form.Set("VirtualMachineName", "db")
+	Reused := this.Send("/vm/initialize/", "initialize-1", Form)
+	assert.Equal(this.T(), http.StatusUnprocessableEntity, Reused.Code)
+
+	var Body map[string]string
+	assert.NoError(this.T(), json.Unmarshal(Reused.Body.Bytes(), &Body))
+	assert.Contains(this.T(), Body["Error"], "Idempotency Key has already been Used")
+}
+
+func (this *RouterTestSuite) TestIdempotentClone() {
+	Form := url.Values{"ProjectId": {"1"}, "Region": {"eu"}, "VirtualMachineName": {"copy"}}
+
+	this.Send("/vm/clone/", "clone-1", Form)
+	Form.Set("VirtualMachineName", "other")
+	Reused := this.Send("/vm/clone/", "clone-1", Form)
+	assert.Equal(this.T(), http.StatusUnprocessableEntity, Reused.Code)
+}
+
+func (this *RouterTestSuite) TestCustomerAuthorization() {
+	// Customer Routes are being Rejected by the Authorization Middleware, before the Controller Reads the Credentials
+	for _, Route := range [][2]string{{http.MethodDelete, "/customer/delete/"}, {http.MethodGet, "/customer/get/profile/"}} {
+		this.Database.Reset()
+		Recorder := httptest.NewRecorder()
+		this.Router.ServeHTTP(Recorder, httptest.NewRequest(Route[0], Route[1], nil))
+		assert.Equal(this.T(), http.StatusForbidden, Recorder.Code, Route[1])
+		assert.JSONEq(this.T(), `{"Error": "You are Not Authorized"}`, Recorder.Body.String(), Route[1])
+		assert.Empty(this.T(), this.Database.GetQueries(), Route[1])
+	}
+}
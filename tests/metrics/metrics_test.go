@@ -0,0 +1,124 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LovePelmeni/Infrastructure/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
+
+func Write(Metric metrics.Metric) string {
+	var Buffer bytes.Buffer
+	Metric.Write(&Buffer)
+	return Buffer.String()
+}
+
+func (this *MetricsTestSuite) TestCounter() {
+	Counter := metrics.NewCounterVec("requests_total", "Number of the Requests", "method", "status")
+	Counter.Inc("GET", "200")
+	Counter.Add(2, "GET", "200")
+	Counter.Inc("POST", "500")
+
+	assert.Equal(this.T(), float64(3), Counter.Get("GET", "200"))
+	assert.Equal(this.T(), `# HELP requests_total Number of the Requests
+# TYPE requests_total counter
+requests_total{method="GET",status="200"} 3
+requests_total{method="POST",status="500"} 1
+`, Write(Counter))
+
+	assert.Panics(this.T(), func() { Counter.Inc("GET") }, "Every Label should have the Value")
+}
+
+func (this *MetricsTestSuite) TestEscaping() {
+	Gauge := metrics.NewGaugeVec("escaped", "Line\nBreak and \\ Backslash", "path")
+	Gauge.Set(1.5, `C:\vm "main"`+"\n")
+	assert.Equal(this.T(), `# HELP escaped Line\nBreak and \\ Backslash
+# TYPE escaped gauge
+escaped{path="C:\\vm \"main\"\n"} 1.5
+`, Write(Gauge))
+}
+
+func (this *MetricsTestSuite) TestGaugeReplace() {
+	Gauge := metrics.NewGaugeVec("memory", "Memory", "vm_id")
+	Gauge.Set(10, "1")
+	Gauge.Set(20, "2")
+
+	// Series, that are not being Reported anymore, Disappear
+	Gauge.Replace([]metrics.GaugeValue{{LabelValues: []string{"2"}, Value: 25}, {LabelValues: []string{"3"}, Value: 30}})
+	_, Exists := Gauge.Get("1")
+	assert.False(this.T(), Exists)
+	Value, Exists := Gauge.Get("2")
+	assert.True(this.T(), Exists)
+	assert.Equal(this.T(), float64(25), Value)
+
+	Unlabelled := metrics.NewGaugeVec("timestamp", "Timestamp")
+	Unlabelled.Set(1700000000)
+	assert.Contains(this.T(), Write(Unlabelled), "\ntimestamp 1.7e+09\n")
+}
+
+func (this *MetricsTestSuite) TestHistogram() {
+	Histogram := metrics.NewHistogramVec("latency_seconds", "Latency", []float64{0.1, 1}, "route")
+	Histogram.Observe(0.05, "/ping/")
+	Histogram.Observe(0.1, "/ping/")
+	Histogram.Observe(0.5, "/ping/")
+	Histogram.Observe(3, "/ping/")
+
+	assert.Equal(this.T(), uint64(4), Histogram.GetCount("/ping/"))
+	assert.Equal(this.T(), `# HELP latency_seconds Latency
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/ping/",le="0.1"} 2
+latency_seconds_bucket{route="/ping/",le="1"} 3
+latency_seconds_bucket{route="/ping/",le="+Inf"} 4
+latency_seconds_sum{route="/ping/"} 3.65
+latency_seconds_count{route="/ping/"} 4
+`, Write(Histogram))
+}
+
+func (this *MetricsTestSuite) TestRegistry() {
+	Registry := metrics.NewRegistry()
+	Second := metrics.NewCounterVec("second_total", "Second")
+	First := metrics.NewCounterVec("first_total", "First")
+	Registry.Register(Second, First)
+	assert.Panics(this.T(), func() { Registry.Register(metrics.NewGaugeVec("first_total", "Duplicate")) })
+
+	First.Inc()
+	var Buffer bytes.Buffer
+	Registry.Write(&Buffer)
+	assert.Less(this.T(), strings.Index(Buffer.String(), "first_total"), strings.Index(Buffer.String(), "second_total"),
+		"Metrics should be Written in the Order of the Names")
+}
+
+func (this *MetricsTestSuite) TestHandler() {
+	gin.SetMode(gin.TestMode)
+	Registry := metrics.NewRegistry()
+	Registry.Register(metrics.NewCounterVec("requests_total", "Requests"))
+
+	Router := gin.New()
+	Router.GET("/metrics", Registry.Handler("scrape-token"))
+
+	Recorder := httptest.NewRecorder()
+	Router.ServeHTTP(Recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(this.T(), http.StatusUnauthorized, Recorder.Code)
+
+	Request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	Request.Header.Set("Authorization", "Bearer scrape-token")
+	Recorder = httptest.NewRecorder()
+	Router.ServeHTTP(Recorder, Request)
+	assert.Equal(this.T(), http.StatusOK, Recorder.Code)
+	assert.Equal(this.T(), metrics.ContentType, Recorder.Header().Get("Content-Type"))
+	assert.Contains(this.T(), Recorder.Body.String(), "# TYPE requests_total counter")
+}
@@ -0,0 +1,68 @@
+package mfa_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/mfa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// Secret of the RFC 6238 Test Vectors ("12345678901234567890"), Encoded with Base32
+var TestSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+type MfaTestSuite struct {
+	suite.Suite
+}
+
+func TestMfaSuite(t *testing.T) {
+	suite.Run(t, new(MfaTestSuite))
+}
+
+func (this *MfaTestSuite) TestGenerateCode() {
+	Vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for Timestamp, Expected := range Vectors {
+		Code, GenerateError := mfa.GenerateCode(TestSecret, mfa.GetTimeStep(time.Unix(Timestamp, 0)))
+		assert.NoError(this.T(), GenerateError)
+		assert.Equal(this.T(), Expected, Code)
+	}
+}
+
+func (this *MfaTestSuite) TestValidateCode() {
+	Now := time.Unix(1111111109, 0)
+	Code, _ := mfa.GenerateCode(TestSecret, mfa.GetTimeStep(Now))
+
+	Step, Valid := mfa.ValidateCode(TestSecret, Code, 0, Now.Add(mfa.Period*time.Second))
+	assert.True(this.T(), Valid, "Code of the Previous Period should be Accepted")
+	assert.Equal(this.T(), mfa.GetTimeStep(Now), Step)
+
+	_, Valid = mfa.ValidateCode(TestSecret, Code, Step, Now)
+	assert.False(this.T(), Valid, "Used Code should not be Accepted again")
+
+	_, Valid = mfa.ValidateCode(TestSecret, Code, 0, Now.Add(3*mfa.Period*time.Second))
+	assert.False(this.T(), Valid, "Expired Code should not be Accepted")
+}
+
+func (this *MfaTestSuite) TestProvisioningURI() {
+	Secret, GenerateError := mfa.GenerateSecret()
+	assert.NoError(this.T(), GenerateError)
+	assert.Len(this.T(), Secret, 32)
+
+	URI := mfa.GetProvisioningURI("customer@example.com", Secret)
+	assert.True(this.T(), strings.HasPrefix(URI, "otpauth://totp/"))
+	assert.Contains(this.T(), URI, "secret="+Secret)
+}
+
+func (this *MfaTestSuite) TestRecoveryCodes() {
+	Codes, Hashes, GenerateError := mfa.GenerateRecoveryCodes()
+	assert.NoError(this.T(), GenerateError)
+	assert.Len(this.T(), Codes, mfa.RecoveryCodesCount)
+	assert.Equal(this.T(), Hashes[0], mfa.HashRecoveryCode(" "+strings.ToUpper(Codes[0])+" "))
+}
@@ -0,0 +1,226 @@
+package migrations_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/migrations"
+	"github.com/LovePelmeni/Infrastructure/tests/fakedb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type MigrationsTestSuite struct {
+	suite.Suite
+	Applied  map[int64]string // Names of the Migrations, Recorded in the `schema_migrations` Table by the Versions
+	Replica  map[int64]string // Migrations, that are being Applied by the other Replica, while the Lock is being Awaited
+	Gorm     *gorm.DB
+	Database *fakedb.Database
+}
+
+func TestMigrationsSuite(t *testing.T) {
+	suite.Run(t, new(MigrationsTestSuite))
+}
+
+func (this *MigrationsTestSuite) SetupTest() {
+	this.Applied = make(map[int64]string)
+	this.Replica = make(map[int64]string)
+	this.Gorm, this.Database = fakedb.New(this.Respond)
+}
+
+func (this *MigrationsTestSuite) Respond(Query string, Args []driver.Value) fakedb.Result {
+	switch {
+	case strings.HasPrefix(Query, "SELECT pg_advisory_xact_lock"):
+		for Version, Name := range this.Replica {
+			this.Applied[Version] = Name
+		}
+		return fakedb.Result{Columns: []string{"pg_advisory_xact_lock"}, Rows: [][]driver.Value{{""}}}
+
+	case strings.HasPrefix(Query, `SELECT count(*) FROM "schema_migrations"`):
+		var Number int64
+		if _, Exists := this.Applied[Args[0].(int64)]; Exists {
+			Number = 1
+		}
+		return fakedb.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{Number}}}
+
+	case strings.HasPrefix(Query, `SELECT * FROM "schema_migrations"`):
+		Rows := [][]driver.Value{}
+		for Version := int64(1); Version <= 10; Version++ {
+			if Name, Exists := this.Applied[Version]; Exists {
+				Rows = append(Rows, []driver.Value{Version, Name, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+			}
+		}
+		return fakedb.Result{Columns: []string{"version", "name", "applied_at"}, Rows: Rows}
+
+	case strings.HasPrefix(Query, `INSERT INTO "schema_migrations"`):
+		this.Applied[Args[0].(int64)] = Args[1].(string)
+		return fakedb.Result{RowsAffected: 1}
+
+	case strings.HasPrefix(Query, `DELETE FROM "schema_migrations"`):
+		delete(this.Applied, Args[0].(int64))
+		return fakedb.Result{RowsAffected: 1}
+
+	case strings.HasPrefix(Query, "DROP TABLE broken"):
+		return fakedb.Result{Error: errors.New(`table "broken" is still referenced`)}
+	case strings.HasPrefix(Query, "CREATE TABLE broken"):
+		return fakedb.Result{Error: errors.New(`syntax error at or near "broken"`)}
+	}
+	return fakedb.Result{}
+}
+
+func (this *MigrationsTestSuite) NewMigrator(Migrations ...*migrations.Migration) *migrations.Migrator {
+	if len(Migrations) == 0 {
+		// Registered out of the Order on purpose
+		Migrations = []*migrations.Migration{
+			migrations.NewSQLMigration(3, "create_third", []string{"CREATE TABLE third ()"}, []string{"DROP TABLE third"}),
+			migrations.NewSQLMigration(1, "create_first", []string{"CREATE TABLE first ()"}, []string{"DROP TABLE first"}),
+			migrations.NewSQLMigration(2, "create_second", []string{"CREATE TABLE second ()"}, []string{"DROP TABLE second"}),
+		}
+	}
+	return migrations.NewMigrator(this.Gorm, Migrations)
+}
+
+func (this *MigrationsTestSuite) GetSchemaChanges() []string {
+	// Returns Statements of the Migrations, in the Order they have been Executed
+	Changes := []string{}
+	for _, Query := range this.Database.GetQueries() {
+		if strings.HasPrefix(Query, "CREATE TABLE") && !strings.Contains(Query, "schema_migrations") || strings.HasPrefix(Query, "DROP TABLE") {
+			Changes = append(Changes, Query)
+		}
+	}
+	return Changes
+}
+
+func (this *MigrationsTestSuite) TestUp() {
+	AppliedNumber, UpError := this.NewMigrator().Up(0)
+	assert.NoError(this.T(), UpError)
+	assert.Equal(this.T(), 3, AppliedNumber)
+	assert.Equal(this.T(), []string{"CREATE TABLE first ()", "CREATE TABLE second ()", "CREATE TABLE third ()"}, this.GetSchemaChanges())
+	assert.Equal(this.T(), map[int64]string{1: "create_first", 2: "create_second", 3: "create_third"}, this.Applied)
+
+	// Applied Migrations are being Skipped
+	this.Database.Reset()
+	AppliedNumber, UpError = this.NewMigrator().Up(0)
+	assert.NoError(this.T(), UpError)
+	assert.Equal(this.T(), 0, AppliedNumber)
+	assert.Empty(this.T(), this.GetSchemaChanges())
+}
+
+func (this *MigrationsTestSuite) TestUpToTarget() {
+	AppliedNumber, UpError := this.NewMigrator().Up(2)
+	assert.NoError(this.T(), UpError)
+	assert.Equal(this.T(), 2, AppliedNumber)
+	assert.Equal(this.T(), []string{"CREATE TABLE first ()", "CREATE TABLE second ()"}, this.GetSchemaChanges())
+
+	this.Database.Reset()
+	_, UpError = this.NewMigrator().Up(5)
+	assert.ErrorIs(this.T(), UpError, migrations.ErrUnknownVersion)
+	assert.Empty(this.T(), this.GetSchemaChanges())
+}
+
+func (this *MigrationsTestSuite) TestAdvisoryLock() {
+	// Every Migration is being Applied in it's own Transaction, once the Lock has been Acquired
+	_, UpError := this.NewMigrator().Up(0)
+	assert.NoError(this.T(), UpError)
+
+	Statements := this.Database.Statements[1:] // Creation of the `schema_migrations` Table
+	for Index := 0; Index < 3; Index++ {
+		Transaction := Statements[Index*6 : Index*6+6]
+		assert.Equal(this.T(), "BEGIN", Transaction[0].Query)
+		assert.Equal(this.T(), "SELECT pg_advisory_xact_lock($1)", Transaction[1].Query)
+		assert.Equal(this.T(), []driver.Value{int64(migrations.MigrationsLockKey)}, Transaction[1].Args)
+		assert.True(this.T(), strings.HasPrefix(Transaction[2].Query, `SELECT count(*) FROM "schema_migrations"`))
+		assert.True(this.T(), strings.HasPrefix(Transaction[4].Query, `INSERT INTO "schema_migrations"`))
+		assert.Equal(this.T(), "COMMIT", Transaction[5].Query)
+	}
+
+	// Migration, Applied by the other Replica, while the Lock has been Awaited, is not being Applied twice
+	this.SetupTest()
+	this.Replica[2] = "create_second"
+	AppliedNumber, UpError := this.NewMigrator().Up(0)
+	assert.NoError(this.T(), UpError)
+	assert.Equal(this.T(), 2, AppliedNumber)
+	assert.Equal(this.T(), []string{"CREATE TABLE first ()", "CREATE TABLE third ()"}, this.GetSchemaChanges())
+}
+
+func (this *MigrationsTestSuite) TestUpFailure() {
+	Migrator := this.NewMigrator(
+		migrations.NewSQLMigration(1, "create_first", []string{"CREATE TABLE first ()"}, nil),
+		migrations.NewSQLMigration(2, "create_broken", []string{"CREATE TABLE broken ()"}, nil),
+		migrations.NewSQLMigration(3, "create_third", []string{"CREATE TABLE third ()"}, nil),
+	)
+	AppliedNumber, UpError := Migrator.Up(0)
+	assert.Equal(this.T(), 1, AppliedNumber)
+	assert.EqualError(this.T(), UpError, `migration 2 (create_broken): syntax error at or near "broken"`)
+
+	// Failed Migration is being Rolled Back, the Following ones are not being Applied
+	assert.Equal(this.T(), map[int64]string{1: "create_first"}, this.Applied)
+	assert.Equal(this.T(), "ROLLBACK", this.Database.GetQueries()[len(this.Database.GetQueries())-1])
+	assert.NotContains(this.T(), this.GetSchemaChanges(), "CREATE TABLE third ()")
+}
+
+func (this *MigrationsTestSuite) TestDown() {
+	this.Applied = map[int64]string{1: "create_first", 2: "create_second", 3: "create_third"}
+
+	// Latest Migrations are being Reverted first
+	RevertedNumber, DownError := this.NewMigrator().Down(2)
+	assert.NoError(this.T(), DownError)
+	assert.Equal(this.T(), 2, RevertedNumber)
+	assert.Equal(this.T(), []string{"DROP TABLE third", "DROP TABLE second"}, this.GetSchemaChanges())
+	assert.Equal(this.T(), map[int64]string{1: "create_first"}, this.Applied)
+	assert.Contains(this.T(), this.Database.GetQueries(), "SELECT pg_advisory_xact_lock($1)")
+
+	// Migrations, that have not been Applied, are not being Counted
+	this.Database.Reset()
+	RevertedNumber, DownError = this.NewMigrator().Down(5)
+	assert.NoError(this.T(), DownError)
+	assert.Equal(this.T(), 1, RevertedNumber)
+	assert.Equal(this.T(), []string{"DROP TABLE first"}, this.GetSchemaChanges())
+	assert.Empty(this.T(), this.Applied)
+}
+
+func (this *MigrationsTestSuite) TestDownFailure() {
+	this.Applied = map[int64]string{1: "create_first", 2: "create_broken"}
+	Migrator := this.NewMigrator(
+		migrations.NewSQLMigration(1, "create_first", []string{"CREATE TABLE first ()"}, []string{"DROP TABLE first"}),
+		migrations.NewSQLMigration(2, "create_broken", []string{"CREATE TABLE broken ()"}, []string{"DROP TABLE broken"}),
+	)
+	RevertedNumber, DownError := Migrator.Down(2)
+	assert.Equal(this.T(), 0, RevertedNumber)
+	assert.EqualError(this.T(), DownError, `migration 2 (create_broken): table "broken" is still referenced`)
+	assert.Len(this.T(), this.Applied, 2, "Record of the Migration should be Kept, when it has not been Reverted")
+
+	// Migration without the Down Step can't be Reverted
+	Migrator = this.NewMigrator(&migrations.Migration{Version: 1, Name: "create_first", Up: Migrator.Migrations[0].Up})
+	_, DownError = Migrator.Down(1)
+	assert.EqualError(this.T(), DownError, "migration 1 (create_first): migration 1 (create_first) can not be Reverted")
+	assert.Contains(this.T(), this.Applied, int64(1))
+}
+
+func (this *MigrationsTestSuite) TestStatus() {
+	this.Applied = map[int64]string{1: "create_first", 3: "create_third"}
+	Statuses, StatusError := this.NewMigrator().Status()
+	assert.NoError(this.T(), StatusError)
+	assert.Len(this.T(), Statuses, 3)
+	for Index, Applied := range []bool{true, false, true} {
+		assert.Equal(this.T(), int64(Index+1), Statuses[Index].Version)
+		assert.Equal(this.T(), Applied, Statuses[Index].Applied)
+		assert.Equal(this.T(), Applied, Statuses[Index].AppliedAt != nil)
+	}
+}
+
+func (this *MigrationsTestSuite) TestRegisteredMigrations() {
+	// Registered Migrations should have Unique Versions in the Ascending Order, along with the Down Step
+	for Index, Migration := range migrations.Migrations {
+		if Index > 0 {
+			assert.Greater(this.T(), Migration.Version, migrations.Migrations[Index-1].Version, Migration.Name)
+		}
+		assert.NotEmpty(this.T(), Migration.Name)
+		assert.NotNil(this.T(), Migration.Up, Migration.Name)
+		assert.NotNil(this.T(), Migration.Down, Migration.Name)
+	}
+}
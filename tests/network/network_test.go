@@ -0,0 +1,55 @@
+package network_test
+
+import (
+	"testing"
+
+	"github.com/LovePelmeni/Infrastructure/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+type NetworkTestSuite struct {
+	suite.Suite
+}
+
+func TestNetworkSuite(t *testing.T) {
+	suite.Run(t, new(NetworkTestSuite))
+}
+
+func (this *NetworkTestSuite) TestValidate() {
+	assert.NoError(this.T(), network.NewVirtualMachineIPAddress("10.0.0.5", "255.255.255.0", "10.0.0.1", "web-1").Validate())
+	assert.NoError(this.T(), network.NewVirtualMachineIPAddress("10.0.0.5", "", "", "web").Validate(), "Netmask and Gateway are Optional")
+
+	assert.ErrorIs(this.T(), network.NewVirtualMachineIPAddress("10.0.0.256", "", "", "web").Validate(), network.ErrInvalidIPAddress)
+	assert.ErrorIs(this.T(), network.NewVirtualMachineIPAddress("fe80::1", "", "", "web").Validate(), network.ErrInvalidIPAddress)
+	assert.ErrorIs(this.T(), network.NewVirtualMachineIPAddress("10.0.0.5", "255.0.255.0", "", "web").Validate(), network.ErrInvalidNetmask)
+	assert.ErrorIs(this.T(), network.NewVirtualMachineIPAddress("10.0.0.5", "mask", "", "web").Validate(), network.ErrInvalidNetmask)
+	assert.ErrorIs(this.T(), network.NewVirtualMachineIPAddress("10.0.0.5", "", "gateway", "web").Validate(), network.ErrInvalidGateway)
+	assert.ErrorIs(this.T(), network.NewVirtualMachineIPAddress("10.0.0.5", "255.255.255.0", "10.0.1.1", "web").Validate(),
+		network.ErrInvalidGateway, "Gateway should be within the Subnet")
+	assert.ErrorIs(this.T(), network.NewVirtualMachineIPAddress("10.0.0.5", "", "", "web.example.com").Validate(), network.ErrInvalidHostname)
+	assert.ErrorIs(this.T(), network.NewVirtualMachineIPAddress("10.0.0.5", "", "", "").Validate(), network.ErrInvalidHostname)
+}
+
+func (this *NetworkTestSuite) TestCustomization() {
+	_, SetupError := network.NewVirtualMachinePublicNetworkManager().SetupPublicNetwork(
+		*network.NewVirtualMachineIPAddress("not-an-ip", "", "", "web"))
+	assert.ErrorIs(this.T(), SetupError, network.ErrInvalidIPAddress, "Invalid Address should not Reach the vSphere")
+
+	Specification, SetupError := network.NewVirtualMachinePublicNetworkManager().SetupPublicNetwork(
+		*network.NewVirtualMachineIPAddress("10.0.0.5", "255.255.255.0", "", "web"))
+	assert.NoError(this.T(), SetupError)
+	assert.Empty(this.T(), Specification.NicSettingMap[0].Adapter.Gateway)
+}
+
+func (this *NetworkTestSuite) TestDynamicCustomization() {
+	// Fixed Address is being Released on Rollback of the Deploy, the Hostname of the Guest is being Kept
+	Specification, SetupError := network.NewVirtualMachinePublicNetworkManager().SetupDynamicNetwork("web.example.com")
+	assert.NoError(this.T(), SetupError)
+	assert.IsType(this.T(), &types.CustomizationDhcpIpGenerator{}, Specification.NicSettingMap[0].Adapter.Ip)
+	assert.Equal(this.T(), "web", Specification.Identity.(*types.CustomizationLinuxPrep).HostName.(*types.CustomizationFixedName).Name)
+
+	_, SetupError = network.NewVirtualMachinePublicNetworkManager().SetupDynamicNetwork("")
+	assert.ErrorIs(this.T(), SetupError, network.ErrInvalidHostname)
+}
@@ -0,0 +1,244 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	customer_rest "github.com/LovePelmeni/Infrastructure/customer_rest"
+	"github.com/LovePelmeni/Infrastructure/middlewares"
+	"github.com/LovePelmeni/Infrastructure/oidc"
+	"github.com/LovePelmeni/Infrastructure/tests/fakeredis"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	TestClientId = "infrastructure"
+	TestKeyId    = "test-key"
+)
+
+// Local Fake Identity Provider, that Signs ID Tokens with the Generated RSA Key
+type FakeIssuer struct {
+	Server        *httptest.Server
+	Key           *rsa.PrivateKey
+	CodeChallenge string // Challenge of the Pending Authorization Code
+	Claims        jwt.MapClaims
+}
+
+func NewFakeIssuer() *FakeIssuer {
+	Key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	Issuer := &FakeIssuer{Key: Key}
+
+	Mux := http.NewServeMux()
+	Mux.HandleFunc("/.well-known/openid-configuration", func(Writer http.ResponseWriter, Request *http.Request) {
+		json.NewEncoder(Writer).Encode(map[string]string{
+			"issuer":                 Issuer.Server.URL,
+			"authorization_endpoint": Issuer.Server.URL + "/authorize",
+			"token_endpoint":         Issuer.Server.URL + "/token",
+			"jwks_uri":               Issuer.Server.URL + "/keys",
+		})
+	})
+	Mux.HandleFunc("/keys", func(Writer http.ResponseWriter, Request *http.Request) {
+		json.NewEncoder(Writer).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": TestKeyId,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(Key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(Key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	Mux.HandleFunc("/token", func(Writer http.ResponseWriter, Request *http.Request) {
+		Request.ParseForm()
+		if oidc.GetCodeChallenge(Request.PostForm.Get("code_verifier")) != Issuer.CodeChallenge {
+			Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(Writer).Encode(map[string]string{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"id_token":     Issuer.Sign(Issuer.Claims, TestKeyId),
+		})
+	})
+	Issuer.Server = httptest.NewServer(Mux)
+	return Issuer
+}
+
+func (this *FakeIssuer) Sign(Claims jwt.MapClaims, KeyId string) string {
+	Token := jwt.NewWithClaims(jwt.SigningMethodRS256, Claims)
+	Token.Header["kid"] = KeyId
+	Signed, _ := Token.SignedString(this.Key)
+	return Signed
+}
+
+func (this *FakeIssuer) GetClaims(Nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            this.Server.URL,
+		"sub":            "subject",
+		"aud":            []string{TestClientId},
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          Nonce,
+		"email":          "customer@example.com",
+		"email_verified": true,
+	}
+}
+
+type OidcTestSuite struct {
+	suite.Suite
+	Issuer   *FakeIssuer
+	Provider *oidc.Provider
+}
+
+func TestOidcSuite(t *testing.T) {
+	suite.Run(t, new(OidcTestSuite))
+}
+
+func (this *OidcTestSuite) SetupTest() {
+	this.Issuer = NewFakeIssuer()
+	Provider, ProviderError := oidc.NewProvider(context.Background(), oidc.NewConfig(
+		this.Issuer.Server.URL, TestClientId, "secret", "http://localhost/customer/oidc/callback/", nil))
+	assert.NoError(this.T(), ProviderError)
+	this.Provider = Provider
+}
+
+func (this *OidcTestSuite) TearDownTest() {
+	this.Issuer.Server.Close()
+}
+
+func (this *OidcTestSuite) TestAuthorizationURL() {
+	AuthorizationURL, ParseError := url.Parse(this.Provider.GetAuthorizationURL("state", "nonce", "verifier"))
+	assert.NoError(this.T(), ParseError)
+	assert.Equal(this.T(), "/authorize", AuthorizationURL.Path)
+	assert.Equal(this.T(), oidc.GetCodeChallenge("verifier"), AuthorizationURL.Query().Get("code_challenge"))
+	assert.Equal(this.T(), "S256", AuthorizationURL.Query().Get("code_challenge_method"))
+	assert.Equal(this.T(), "openid email profile", AuthorizationURL.Query().Get("scope"))
+}
+
+func (this *OidcTestSuite) TestExchangeCodeAndVerify() {
+	this.Issuer.CodeChallenge = oidc.GetCodeChallenge("verifier")
+	this.Issuer.Claims = this.Issuer.GetClaims("nonce")
+
+	_, ExchangeError := this.Provider.ExchangeCode(context.Background(), "code", "other-verifier")
+	assert.Error(this.T(), ExchangeError, "Code can't be Exchanged without the PKCE Verifier")
+
+	Tokens, ExchangeError := this.Provider.ExchangeCode(context.Background(), "code", "verifier")
+	assert.NoError(this.T(), ExchangeError)
+
+	Claims, VerifyError := this.Provider.VerifyIdToken(context.Background(), Tokens.IdToken, "nonce")
+	assert.NoError(this.T(), VerifyError)
+	assert.Equal(this.T(), "customer@example.com", Claims.Email)
+	assert.True(this.T(), Claims.EmailVerified)
+}
+
+func (this *OidcTestSuite) TestVerifyIdTokenRejectsInvalidTokens() {
+	Expired := this.Issuer.GetClaims("nonce")
+	Expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	OtherAudience := this.Issuer.GetClaims("nonce")
+	OtherAudience["aud"] = "other-client"
+
+	OtherIssuer := this.Issuer.GetClaims("nonce")
+	OtherIssuer["iss"] = "https://other.example.com"
+
+	Tokens := map[string]string{
+		"Expired":        this.Issuer.Sign(Expired, TestKeyId),
+		"Other Audience": this.Issuer.Sign(OtherAudience, TestKeyId),
+		"Other Issuer":   this.Issuer.Sign(OtherIssuer, TestKeyId),
+		"Other Nonce":    this.Issuer.Sign(this.Issuer.GetClaims("other-nonce"), TestKeyId),
+		"Unknown Key":    this.Issuer.Sign(this.Issuer.GetClaims("nonce"), "unknown-key"),
+	}
+	for Name, Token := range Tokens {
+		_, VerifyError := this.Provider.VerifyIdToken(context.Background(), Token, "nonce")
+		assert.Error(this.T(), VerifyError, Name)
+	}
+}
+
+func (this *OidcTestSuite) TestStateBinding() {
+	Request, RequestError := oidc.NewAuthorizationRequest()
+	assert.NoError(this.T(), RequestError)
+	assert.NotEqual(this.T(), Request.State, Request.GetStateBinding(), "Cookie should not Contain the State itself")
+
+	Other, _ := oidc.NewAuthorizationRequest()
+	assert.True(this.T(), oidc.IsStateBound(Request.State, Request.GetStateBinding()))
+	assert.False(this.T(), oidc.IsStateBound(Request.State, Other.GetStateBinding()))
+	assert.False(this.T(), oidc.IsStateBound(Request.State, ""))
+	assert.False(this.T(), oidc.IsStateBound("", (&oidc.AuthorizationRequest{}).GetStateBinding()))
+}
+
+func (this *OidcTestSuite) TestCallbackRequiresStateCookie() {
+	Redis := fakeredis.NewServer()
+	defer Redis.Close()
+	Redis.RegisterScript(oidc.PopScript, func(Values map[string]string, Keys []string, Args []string) interface{} {
+		Value, Exists := Values[Keys[0]]
+		if !Exists {
+			return nil
+		}
+		delete(Values, Keys[0])
+		return Value
+	})
+	middlewares.RedisClient = Redis.GetClient()
+	oidc.Configure(config.OidcConfig{IssuerURL: this.Issuer.Server.URL, ClientId: TestClientId,
+		ClientSecret: "secret", RedirectURL: "http://localhost/customer/oidc/callback/"})
+	defer oidc.Configure(config.OidcConfig{})
+
+	gin.SetMode(gin.TestMode)
+	Router := gin.New()
+	Router.GET("/customer/oidc/login/", customer_rest.OidcLoginRestController)
+	Router.GET("/customer/oidc/callback/", customer_rest.OidcCallbackRestController)
+	Send := func(Path string, Cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		Request := httptest.NewRequest(http.MethodGet, Path, nil)
+		for _, Cookie := range Cookies {
+			Request.AddCookie(Cookie)
+		}
+		Recorder := httptest.NewRecorder()
+		Router.ServeHTTP(Recorder, Request)
+		return Recorder
+	}
+	Login := func() (string, *http.Cookie) {
+		Response := Send("/customer/oidc/login/")
+		assert.Equal(this.T(), http.StatusFound, Response.Code)
+		Location, _ := url.Parse(Response.Header().Get("Location"))
+		Cookies := Response.Result().Cookies()
+		assert.Len(this.T(), Cookies, 1)
+		return Location.Query().Get("state"), Cookies[0]
+	}
+	Callback := func(State string, Cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		return Send("/customer/oidc/callback/?code=code&state="+url.QueryEscape(State), Cookies...)
+	}
+
+	State, Cookie := Login()
+	assert.Equal(this.T(), oidc.StateCookieName, Cookie.Name)
+	assert.Equal(this.T(), oidc.StateCookiePath, Cookie.Path)
+	assert.True(this.T(), Cookie.HttpOnly)
+	assert.Equal(this.T(), http.SameSiteLaxMode, Cookie.SameSite)
+	assert.True(this.T(), oidc.IsStateBound(State, Cookie.Value))
+
+	// State of the Attacker's Login, Passed to the Customer's Browser, is not being Accepted
+	_, OtherCookie := Login()
+	for _, Cookies := range [][]*http.Cookie{nil, {OtherCookie}} {
+		Response := Callback(State, Cookies...)
+		assert.Equal(this.T(), http.StatusBadRequest, Response.Code)
+		assert.Contains(this.T(), Response.Body.String(), oidc.ErrStateMismatch.Error())
+	}
+	Exists, _ := middlewares.RedisClient.Exists(oidc.GetAuthorizationRequestKey(State)).Result()
+	assert.Equal(this.T(), int64(1), Exists, "Rejected Callback should not Consume the Login Attempt")
+
+	// The Code Exchange Fails, since the Fake Provider does not Expect it, but the State has been Accepted and Consumed
+	Response := Callback(State, Cookie)
+	assert.Equal(this.T(), http.StatusBadGateway, Response.Code)
+	Exists, _ = middlewares.RedisClient.Exists(oidc.GetAuthorizationRequestKey(State)).Result()
+	assert.Equal(this.T(), int64(0), Exists)
+}
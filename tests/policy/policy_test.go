@@ -0,0 +1,56 @@
+package policy_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PolicyTestSuite struct {
+	suite.Suite
+}
+
+func TestPolicySuite(t *testing.T) {
+	suite.Run(t, new(PolicyTestSuite))
+}
+
+func (this *PolicyTestSuite) TestRolePermissions() {
+	assert.True(this.T(), policy.IsAllowed(models.RoleViewer, policy.ActionView))
+	assert.False(this.T(), policy.IsAllowed(models.RoleViewer, policy.ActionOperate), "Viewer has Read Only Access")
+
+	assert.True(this.T(), policy.IsAllowed(models.RoleOperator, policy.ActionOperate))
+	assert.False(this.T(), policy.IsAllowed(models.RoleOperator, policy.ActionManage), "Operator can't Remove Virtual Machines")
+
+	assert.True(this.T(), policy.IsAllowed(models.RoleAdmin, policy.ActionManage))
+	assert.False(this.T(), policy.IsAllowed(models.RoleAdmin, policy.ActionAdminister))
+
+	assert.True(this.T(), policy.IsAllowed(models.RoleOwner, policy.ActionAdminister))
+	assert.False(this.T(), policy.IsAllowed("Unknown", policy.ActionView))
+}
+
+func (this *PolicyTestSuite) TestRequestAction() {
+	assert.Equal(this.T(), policy.ActionView, policy.GetRequestAction(http.MethodGet, "/vm/get/list/"))
+	assert.Equal(this.T(), policy.ActionOperate, policy.GetRequestAction(http.MethodPost, "/vm/start/"))
+	assert.Equal(this.T(), policy.ActionManage, policy.GetRequestAction(http.MethodDelete, "/vm/remove/"))
+	assert.Equal(this.T(), policy.ActionOperate, policy.GetRequestAction(http.MethodGet, "/ssh/get/ssh/certificate/"))
+}
+
+func (this *PolicyTestSuite) TestAssignRole() {
+	assert.True(this.T(), policy.CanAssignRole(models.RoleAdmin, models.RoleOperator))
+	assert.False(this.T(), policy.CanAssignRole(models.RoleAdmin, models.RoleOwner), "Only Owners can Manage other Owners")
+	assert.True(this.T(), policy.CanAssignRole(models.RoleOwner, models.RoleOwner))
+	assert.False(this.T(), policy.CanAssignRole(models.RoleOperator, models.RoleViewer))
+}
+
+func (this *PolicyTestSuite) TestRequiredScope() {
+	assert.Equal(this.T(), authentication.ScopeVirtualMachinesRead, policy.GetRequiredScope(http.MethodGet, "/vm/get/list/"))
+	assert.Equal(this.T(), authentication.ScopeVirtualMachinesWrite, policy.GetRequiredScope(http.MethodPost, "/vm/initialize/"))
+	assert.Equal(this.T(), authentication.ScopeVirtualMachinesWrite, policy.GetRequiredScope(http.MethodPost, "/host/system/start/"))
+	assert.Equal(this.T(), authentication.ScopeSshRead, policy.GetRequiredScope(http.MethodGet, "/ssh/get/ssh/certificate/"))
+	assert.Empty(this.T(), policy.GetRequiredScope(http.MethodPost, "/customer/api-keys/create/"), "API Keys can't Manage other API Keys")
+}
@@ -0,0 +1,40 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
+
+func (this *RateLimitTestSuite) TestConfigure() {
+	defer func(Overrides map[string]ratelimit.Limit) { ratelimit.Overrides = Overrides }(ratelimit.Overrides)
+	Default := ratelimit.NewLimit(5, time.Minute)
+
+	ratelimit.Configure(config.RateLimitConfig{
+		MaxFailedLogins: int(ratelimit.MaxFailedLogins),
+		BaseLockout:     ratelimit.BaseLockout,
+		MaxLockout:      ratelimit.MaxLockout,
+		Limits:          map[string]config.LimitConfig{"tests": {Requests: 100, Period: time.Second}},
+	})
+	assert.Equal(this.T(), ratelimit.NewLimit(100, time.Second), ratelimit.GetLimit("tests", Default))
+	assert.Equal(this.T(), Default, ratelimit.GetLimit("other", Default), "Limit, that has not been Overridden, should fall back to the Default one")
+}
+
+func (this *RateLimitTestSuite) TestLockoutDuration() {
+	assert.Zero(this.T(), ratelimit.GetLockoutDuration(ratelimit.MaxFailedLogins-1))
+	assert.Equal(this.T(), ratelimit.BaseLockout, ratelimit.GetLockoutDuration(ratelimit.MaxFailedLogins))
+	assert.Equal(this.T(), 4*ratelimit.BaseLockout, ratelimit.GetLockoutDuration(ratelimit.MaxFailedLogins+2), "Lockout should be Doubled with every Failed Login")
+	assert.Equal(this.T(), ratelimit.MaxLockout, ratelimit.GetLockoutDuration(ratelimit.MaxFailedLogins+1000))
+}
@@ -0,0 +1,109 @@
+package reconciler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/reconciler"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vmware/govmomi/simulator"
+)
+
+type ReconcilerTestSuite struct {
+	suite.Suite
+	Now       time.Time
+	Inventory map[string]reconciler.InventoryItem
+}
+
+func TestReconcilerSuite(t *testing.T) {
+	suite.Run(t, new(ReconcilerTestSuite))
+}
+
+func (this *ReconcilerTestSuite) SetupTest() {
+	this.Now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	this.Inventory = map[string]reconciler.InventoryItem{
+		"vm-1": {ManagedObjectId: "vm-1", ItemPath: "/DC0/vm/web", PowerState: "poweredOn", IPAddress: "10.0.0.5"},
+		"vm-2": {ManagedObjectId: "vm-2", ItemPath: "/DC0/vm/db", PowerState: "poweredOff"},
+		"vm-3": {ManagedObjectId: "vm-3", ItemPath: "/DC0/vm/manual", PowerState: "poweredOn"},
+		"vm-4": {ManagedObjectId: "vm-4", ItemPath: "/DC0/vm/legacy", PowerState: "poweredOn"},
+	}
+}
+
+func (this *ReconcilerTestSuite) TestMatching() {
+	Reconciliation := reconciler.NewReconciliation("eu", []models.VirtualMachine{
+		// Matched by the Managed Object ID, even though it has been Moved
+		{ID: 1, ManagedObjectId: "vm-1", ItemPath: "/DC0/vm/old/web", Region: "eu"},
+		// Record, that has not been Reconciled yet, is being Matched by the Item Path
+		{ID: 2, ItemPath: "/DC0/vm/db", IPAddress: "10.0.0.7"},
+	}, this.Inventory, this.Now)
+
+	assert.Equal(this.T(), map[string]interface{}{
+		"reconciled_at": this.Now, "orphaned": false, "managed_object_id": "vm-1", "power_state": "poweredOn",
+		"item_path": "/DC0/vm/web", "ip_address": "10.0.0.5",
+	}, Reconciliation.Updates[1])
+
+	// Region is being Stored for the Records of the Default Region, Unknown IP Address does not Override the Stored one
+	assert.Equal(this.T(), map[string]interface{}{
+		"reconciled_at": this.Now, "orphaned": false, "managed_object_id": "vm-2", "power_state": "poweredOff", "region": "eu",
+	}, Reconciliation.Updates[2])
+}
+
+func (this *ReconcilerTestSuite) TestOrphans() {
+	Reconciliation := reconciler.NewReconciliation("eu", []models.VirtualMachine{
+		{ID: 1, ManagedObjectId: "vm-9", ItemPath: "/DC0/vm/removed", Region: "eu"},
+		{ID: 2, ManagedObjectId: "vm-1", ItemPath: "/DC0/vm/web", Region: "eu", Orphaned: true},
+	}, this.Inventory, this.Now)
+
+	assert.Equal(this.T(), map[string]interface{}{"reconciled_at": this.Now, "orphaned": true}, Reconciliation.Updates[1],
+		"Record of the Removed Virtual Machine should only be Flagged")
+	assert.Equal(this.T(), false, Reconciliation.Updates[2]["orphaned"], "Virtual Machine, that has Reappeared, is not an Orphan anymore")
+}
+
+func (this *ReconcilerTestSuite) TestUnmanaged() {
+	Reconciliation := reconciler.NewReconciliation("eu", []models.VirtualMachine{
+		{ID: 1, ManagedObjectId: "vm-1", ItemPath: "/DC0/vm/web", Region: "eu"},
+		{ID: 2, ItemPath: "/DC0/vm/db", Region: "eu"},
+	}, this.Inventory, this.Now)
+
+	assert.Equal(this.T(), []models.UnmanagedVirtualMachine{
+		{Region: "eu", ManagedObjectId: "vm-3", ItemPath: "/DC0/vm/manual", PowerState: "poweredOn", DetectedAt: this.Now},
+		{Region: "eu", ManagedObjectId: "vm-4", ItemPath: "/DC0/vm/legacy", PowerState: "poweredOn", DetectedAt: this.Now},
+	}, Reconciliation.Unmanaged)
+
+	// Every Virtual Machine is Unmanaged, while the Region does not have any Records
+	Reconciliation = reconciler.NewReconciliation("eu", nil, this.Inventory, this.Now)
+	assert.Len(this.T(), Reconciliation.Unmanaged, 4)
+	assert.Empty(this.T(), Reconciliation.Updates)
+}
+
+func (this *ReconcilerTestSuite) TestInventory() {
+	Model := simulator.VPX()
+	defer Model.Remove()
+	assert.NoError(this.T(), Model.Create())
+
+	Server := Model.Service.NewServer()
+	defer Server.Close()
+
+	Pool := vsphere.NewSessionPool(Server.URL, time.Minute, vsphere.NewCircuitBreaker(3, time.Minute))
+	Pool.Insecure = true
+
+	Context, CancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer CancelFunc()
+	assert.NoError(this.T(), Pool.Connect(Context))
+
+	Inventory, InventoryError := reconciler.NewInventoryReconciler(nil, time.Minute).GetInventory(Context, Pool)
+	assert.NoError(this.T(), InventoryError)
+	assert.Len(this.T(), Inventory, len(simulator.Map.All("VirtualMachine")))
+
+	// Inventory Paths are being Built from the Parents of the Virtual Machines
+	Paths := make(map[string]string)
+	for ManagedObjectId, Item := range Inventory {
+		assert.Equal(this.T(), ManagedObjectId, Item.ManagedObjectId)
+		Paths[Item.ItemPath] = Item.PowerState
+	}
+	assert.Equal(this.T(), "poweredOn", Paths["/DC0/vm/DC0_H0_VM0"])
+}
@@ -0,0 +1,87 @@
+package resource_config_test
+
+import (
+	"testing"
+
+	resource_config "github.com/LovePelmeni/Infrastructure/resource_config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+type ResourceConfigTestSuite struct {
+	suite.Suite
+	Hardware types.VirtualHardware
+	Config   types.VirtualMachineConfigInfo
+}
+
+func TestResourceConfigSuite(t *testing.T) {
+	suite.Run(t, new(ResourceConfigTestSuite))
+}
+
+func (this *ResourceConfigTestSuite) SetupTest() {
+	this.Hardware = types.VirtualHardware{NumCPU: 2, MemoryMB: 2048}
+	this.Config = types.VirtualMachineConfigInfo{
+		CpuHotAddEnabled:    types.NewBool(true),
+		MemoryHotAddEnabled: types.NewBool(true),
+	}
+}
+
+func (this *ResourceConfigTestSuite) TestUnchangedResources() {
+	Delta, DeltaError := resource_config.SetupResourcesDelta(this.Hardware, this.Config, true,
+		resource_config.NewVirtualMachineResources(2, 2048))
+	assert.NoError(this.T(), DeltaError)
+	assert.False(this.T(), Delta.Changed, "Resources are the same, Nothing should be Changed")
+	assert.Zero(this.T(), Delta.Specification.NumCPUs)
+	assert.Zero(this.T(), Delta.Specification.MemoryMB)
+}
+
+func (this *ResourceConfigTestSuite) TestHotAdd() {
+	Delta, DeltaError := resource_config.SetupResourcesDelta(this.Hardware, this.Config, true,
+		resource_config.NewVirtualMachineResources(4, 0))
+	assert.NoError(this.T(), DeltaError)
+	assert.True(this.T(), Delta.Changed)
+	assert.False(this.T(), Delta.RequiresPowerCycle, "CPU can be Hot Added")
+	assert.Equal(this.T(), int32(4), Delta.Specification.NumCPUs)
+	assert.Zero(this.T(), Delta.Specification.MemoryMB, "Memory has not been Requested")
+}
+
+func (this *ResourceConfigTestSuite) TestRemovalRequiresPowerCycle() {
+	Delta, DeltaError := resource_config.SetupResourcesDelta(this.Hardware, this.Config, true,
+		resource_config.NewVirtualMachineResources(1, 0))
+	assert.NoError(this.T(), DeltaError)
+	assert.True(this.T(), Delta.RequiresPowerCycle, "CPU can't be Hot Removed")
+
+	Delta, DeltaError = resource_config.SetupResourcesDelta(this.Hardware, this.Config, false,
+		resource_config.NewVirtualMachineResources(1, 0))
+	assert.NoError(this.T(), DeltaError)
+	assert.False(this.T(), Delta.RequiresPowerCycle, "Powered Off Virtual Machine does not need Power Cycle")
+}
+
+func (this *ResourceConfigTestSuite) TestDisabledHotAddRequiresPowerCycle() {
+	this.Config.MemoryHotAddEnabled = types.NewBool(false)
+	Delta, DeltaError := resource_config.SetupResourcesDelta(this.Hardware, this.Config, true,
+		resource_config.NewVirtualMachineResources(0, 4096))
+	assert.NoError(this.T(), DeltaError)
+	assert.Equal(this.T(), int64(4096), Delta.Specification.MemoryMB, "Memory is Requested in Megabytes")
+	assert.True(this.T(), Delta.RequiresPowerCycle)
+}
+
+func (this *ResourceConfigTestSuite) TestMemoryInMegabytes() {
+	Specification, SetupError := resource_config.SetupResources(resource_config.NewVirtualMachineResources(2, 512))
+	assert.NoError(this.T(), SetupError)
+	assert.Equal(this.T(), int64(512), Specification.MemoryMB)
+
+	// Removing Memory Requires Power Cycle
+	Delta, DeltaError := resource_config.SetupResourcesDelta(this.Hardware, this.Config, true,
+		resource_config.NewVirtualMachineResources(0, 1024))
+	assert.NoError(this.T(), DeltaError)
+	assert.Equal(this.T(), int64(1024), Delta.Specification.MemoryMB)
+	assert.True(this.T(), Delta.RequiresPowerCycle)
+}
+
+func (this *ResourceConfigTestSuite) TestNegativeResources() {
+	_, DeltaError := resource_config.SetupResourcesDelta(this.Hardware, this.Config, true,
+		resource_config.NewVirtualMachineResources(-1, 0))
+	assert.Error(this.T(), DeltaError)
+}
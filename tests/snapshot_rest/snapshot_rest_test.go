@@ -0,0 +1,302 @@
+package snapshot_rest_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/exceptions"
+	"github.com/LovePelmeni/Infrastructure/jobs"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/snapshot_rest"
+	"github.com/LovePelmeni/Infrastructure/tests/fakedb"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vmware/govmomi/simulator"
+)
+
+type SnapshotRestTestSuite struct {
+	suite.Suite
+	Mutex     sync.Mutex
+	Snapshots map[int]models.Snapshot // Records of the Snapshots, the Fake Database Responds with
+	Database  *fakedb.Database
+	Model     *simulator.Model
+	vCenter   *simulator.Server
+	Token     string
+}
+
+func TestSnapshotRestSuite(t *testing.T) {
+	suite.Run(t, new(SnapshotRestTestSuite))
+}
+
+func (this *SnapshotRestTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+
+	authentication.Configure(config.AuthenticationConfig{SecretKey: "secret", AccessTokenLifetime: time.Hour})
+	Token, TokenError := authentication.CreateJwtToken(7, "customer", "customer@example.com")
+	assert.NoError(this.T(), TokenError)
+	this.Token = Token
+
+	this.Model = simulator.VPX()
+	assert.NoError(this.T(), this.Model.Create())
+	this.vCenter = this.Model.Service.NewServer()
+
+	Pool := vsphere.NewSessionPool(this.vCenter.URL, time.Minute, vsphere.NewCircuitBreaker(3, time.Minute))
+	Pool.Insecure = true
+	Pool.Endpoint.Region = "eu"
+	Context, CancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer CancelFunc()
+	assert.NoError(this.T(), Pool.Connect(Context))
+	vsphere.Registry = vsphere.NewEndpointRegistry(nil, time.Minute, 3, time.Minute)
+	vsphere.Registry.Add(Pool)
+}
+
+func (this *SnapshotRestTestSuite) TearDownSuite() {
+	this.vCenter.Close()
+	this.Model.Remove()
+}
+
+func (this *SnapshotRestTestSuite) SetupTest() {
+	this.Snapshots = map[int]models.Snapshot{
+		1: {ID: 1, VirtualMachineId: 1, OwnerId: 7, Name: "before-upgrade", SnapshotRef: "snapshot-1"},
+		2: {ID: 2, VirtualMachineId: 2, OwnerId: 9, Name: "foreign", SnapshotRef: "snapshot-2"},
+	}
+	models.Database, this.Database = fakedb.New(this.Respond)
+
+	// Jobs are not being Executed by the Workers, so the Queue can be Inspected
+	jobs.WorkerPool.Queue = make(chan int, 10)
+}
+
+func SnapshotRow(Snapshot models.Snapshot) []driver.Value {
+	return []driver.Value{int64(Snapshot.ID), int64(Snapshot.VirtualMachineId), int64(Snapshot.OwnerId), Snapshot.Name, Snapshot.SnapshotRef}
+}
+
+func InsertedValues(Query string, Args []driver.Value) map[string]driver.Value {
+	// Returns Values of the `INSERT INTO "table" ("column", ...) VALUES ($1, ...)` Statement by the Columns
+	Columns := strings.Split(Query[strings.Index(Query, "(")+1:strings.Index(Query, ")")], ",")
+	Values := make(map[string]driver.Value)
+	for Index, Column := range Columns {
+		Values[strings.Trim(Column, `" `)] = Args[Index]
+	}
+	return Values
+}
+
+func (this *SnapshotRestTestSuite) Respond(Query string, Args []driver.Value) fakedb.Result {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	Columns := []string{"id", "virtual_machine_id", "owner_id", "name", "snapshot_ref"}
+
+	switch {
+	// The Only Virtual Machine, the Customer has Access to, is the First one
+	case strings.Contains(Query, `SELECT "region" FROM "virtual_machines"`):
+		return fakedb.Result{Columns: []string{"region"}, Rows: [][]driver.Value{{"eu"}}}
+	case strings.HasPrefix(Query, `SELECT * FROM "virtual_machines" WHERE id = `):
+		if fmt.Sprint(Args[0]) != "1" {
+			return fakedb.Result{Columns: []string{"id"}}
+		}
+		return fakedb.Result{Columns: []string{"id", "virtual_machine_name", "item_path", "region"},
+			Rows: [][]driver.Value{{int64(1), "web", "/DC0/vm/DC0_H0_VM0", "eu"}}}
+
+	case strings.HasPrefix(Query, `INSERT INTO "jobs"`):
+		return fakedb.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(15)}}}
+
+	case strings.HasPrefix(Query, `INSERT INTO "snapshots"`):
+		Inserted := InsertedValues(Query, Args)
+		Snapshot := models.Snapshot{ID: len(this.Snapshots) + 1, VirtualMachineId: int(Inserted["virtual_machine_id"].(int64)),
+			OwnerId: int(Inserted["owner_id"].(int64)), Name: Inserted["name"].(string), SnapshotRef: Inserted["snapshot_ref"].(string)}
+		this.Snapshots[Snapshot.ID] = Snapshot
+		return fakedb.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(Snapshot.ID)}}}
+
+	case strings.HasPrefix(Query, `SELECT * FROM "snapshots" WHERE id = `):
+		var Snapshot models.Snapshot
+		Exists := false
+		for _, Stored := range this.Snapshots {
+			if fmt.Sprint(Stored.ID) == fmt.Sprint(Args[0]) && fmt.Sprint(Stored.VirtualMachineId) == fmt.Sprint(Args[1]) {
+				Snapshot, Exists = Stored, true
+			}
+		}
+		if !Exists {
+			return fakedb.Result{Columns: Columns}
+		}
+		return fakedb.Result{Columns: Columns, Rows: [][]driver.Value{SnapshotRow(Snapshot)}}
+
+	case strings.HasPrefix(Query, `SELECT * FROM "snapshots" WHERE virtual_machine_id = `):
+		Rows := [][]driver.Value{}
+		for Id := len(this.Snapshots); Id > 0; Id-- {
+			if Snapshot := this.Snapshots[Id]; fmt.Sprint(Snapshot.VirtualMachineId) == fmt.Sprint(Args[0]) {
+				Rows = append(Rows, SnapshotRow(Snapshot))
+			}
+		}
+		return fakedb.Result{Columns: Columns, Rows: Rows}
+
+	case strings.HasPrefix(Query, `DELETE FROM "snapshots"`):
+		delete(this.Snapshots, int(Args[0].(int64)))
+		return fakedb.Result{RowsAffected: 1}
+
+	case strings.HasPrefix(Query, `UPDATE "jobs"`):
+		return fakedb.Result{RowsAffected: 1}
+	}
+	return fakedb.Result{}
+}
+
+func (this *SnapshotRestTestSuite) Send(Controller gin.HandlerFunc, Query url.Values, Form url.Values) (*httptest.ResponseRecorder, map[string]interface{}) {
+	Request := httptest.NewRequest(http.MethodPost, "/snapshots/?"+Query.Encode(), strings.NewReader(Form.Encode()))
+	Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Request.Header.Set("Authorization", "Bearer "+this.Token)
+
+	Recorder := httptest.NewRecorder()
+	Context, _ := gin.CreateTestContext(Recorder)
+	Context.Request = Request
+	Controller(Context)
+
+	var Response map[string]interface{}
+	assert.NoError(this.T(), json.Unmarshal(Recorder.Body.Bytes(), &Response))
+	return Recorder, Response
+}
+
+func (this *SnapshotRestTestSuite) GetQueuedJob() models.Job {
+	// Returns the Job, the Controller has Stored and Queued
+	assert.Len(this.T(), jobs.WorkerPool.Queue, 1)
+	assert.Equal(this.T(), 15, <-jobs.WorkerPool.Queue)
+
+	for _, Statement := range this.Database.Statements {
+		if strings.HasPrefix(Statement.Query, `INSERT INTO "jobs"`) {
+			Inserted := InsertedValues(Statement.Query, Statement.Args)
+			return models.Job{ID: 15, Type: Inserted["type"].(string), VirtualMachineId: int(Inserted["virtual_machine_id"].(int64)),
+				OwnerId: int(Inserted["owner_id"].(int64)), Payload: fmt.Sprint(Inserted["payload"])}
+		}
+	}
+	this.T().Fatal("Job has not been Stored")
+	return models.Job{}
+}
+
+func (this *SnapshotRestTestSuite) TestList() {
+	Response, Body := this.Send(snapshot_rest.ListSnapshotsRestController, url.Values{"VirtualMachineId": {"1"}}, nil)
+	assert.Equal(this.T(), http.StatusOK, Response.Code)
+	assert.Len(this.T(), Body["QuerySet"], 1, "Snapshots of the other Virtual Machines should not be Listed")
+	assert.Contains(this.T(), this.Database.GetQueries()[1], "ORDER BY created_at DESC")
+
+	// Virtual Machine of the Project, the Customer is not Member of
+	Response, Body = this.Send(snapshot_rest.ListSnapshotsRestController, url.Values{"VirtualMachineId": {"2"}}, nil)
+	assert.Equal(this.T(), http.StatusBadRequest, Response.Code)
+	assert.Equal(this.T(), "Virtual Machine Does Not Exist", Body["Error"])
+	assert.Contains(this.T(), this.Database.GetQueries()[0], "project_id IN (SELECT projects.id FROM")
+
+	Request := httptest.NewRequest(http.MethodGet, "/snapshots/?VirtualMachineId=1", nil)
+	Recorder := httptest.NewRecorder()
+	Context, _ := gin.CreateTestContext(Recorder)
+	Context.Request = Request
+	snapshot_rest.ListSnapshotsRestController(Context)
+	assert.Equal(this.T(), http.StatusForbidden, Recorder.Code)
+}
+
+func (this *SnapshotRestTestSuite) TestCreate() {
+	for _, Form := range []url.Values{
+		{},
+		{"Name": {strings.Repeat("s", 81)}},
+		{"Name": {"nightly"}, "Memory": {"sometimes"}},
+	} {
+		Response, _ := this.Send(snapshot_rest.CreateSnapshotRestController, url.Values{"VirtualMachineId": {"1"}}, Form)
+		assert.Equal(this.T(), http.StatusBadRequest, Response.Code, Form.Encode())
+	}
+	assert.Len(this.T(), jobs.WorkerPool.Queue, 0, "Invalid Snapshot should not be Queued")
+
+	Response, Body := this.Send(snapshot_rest.CreateSnapshotRestController, url.Values{"VirtualMachineId": {"1"}},
+		url.Values{"Name": {"nightly"}, "Description": {"Before the Upgrade"}, "Memory": {"true"}})
+	assert.Equal(this.T(), http.StatusAccepted, Response.Code)
+	assert.Equal(this.T(), float64(15), Body["JobId"])
+
+	Job := this.GetQueuedJob()
+	assert.Equal(this.T(), models.JobTypeCreateSnapshot, Job.Type)
+	assert.Equal(this.T(), 1, Job.VirtualMachineId)
+	assert.Equal(this.T(), 7, Job.OwnerId)
+	assert.JSONEq(this.T(), `{"Name": "nightly", "Description": "Before the Upgrade", "Memory": true, "Quiesce": false}`, Job.Payload)
+}
+
+func (this *SnapshotRestTestSuite) TestRevert() {
+	// Snapshot of the other Virtual Machine can't be Reached through the Accessible one
+	for _, SnapshotId := range []string{"2", "3"} {
+		Response, Body := this.Send(snapshot_rest.RevertSnapshotRestController,
+			url.Values{"VirtualMachineId": {"1"}, "SnapshotId": {SnapshotId}}, nil)
+		assert.Equal(this.T(), http.StatusNotFound, Response.Code)
+		assert.Equal(this.T(), "Snapshot Does Not Exist", Body["Error"])
+	}
+
+	Response, _ := this.Send(snapshot_rest.RevertSnapshotRestController,
+		url.Values{"VirtualMachineId": {"1"}, "SnapshotId": {"1"}}, url.Values{"SuppressPowerOn": {"maybe"}})
+	assert.Equal(this.T(), http.StatusBadRequest, Response.Code)
+	assert.Len(this.T(), jobs.WorkerPool.Queue, 0)
+
+	Response, _ = this.Send(snapshot_rest.RevertSnapshotRestController,
+		url.Values{"VirtualMachineId": {"1"}, "SnapshotId": {"1"}}, url.Values{"SuppressPowerOn": {"true"}})
+	assert.Equal(this.T(), http.StatusAccepted, Response.Code)
+
+	Job := this.GetQueuedJob()
+	assert.Equal(this.T(), models.JobTypeRevertSnapshot, Job.Type)
+	assert.JSONEq(this.T(), `{"SnapshotId": 1, "SuppressPowerOn": true}`, Job.Payload)
+}
+
+func (this *SnapshotRestTestSuite) TestRemove() {
+	Response, _ := this.Send(snapshot_rest.RemoveSnapshotRestController,
+		url.Values{"VirtualMachineId": {"1"}, "SnapshotId": {"2"}}, nil)
+	assert.Equal(this.T(), http.StatusNotFound, Response.Code)
+
+	Response, _ = this.Send(snapshot_rest.RemoveSnapshotRestController,
+		url.Values{"VirtualMachineId": {"1"}, "SnapshotId": {"1"}}, nil)
+	assert.Equal(this.T(), http.StatusAccepted, Response.Code)
+
+	Job := this.GetQueuedJob()
+	assert.Equal(this.T(), models.JobTypeRemoveSnapshot, Job.Type)
+	assert.JSONEq(this.T(), `{"SnapshotId": 1, "SuppressPowerOn": false}`, Job.Payload)
+}
+
+func (this *SnapshotRestTestSuite) TestJobHandlers() {
+	// Snapshot is being Taken, Reverted to and Removed on the Simulated vSphere, along with it's Record
+	Job, _ := models.NewJob(models.JobTypeCreateSnapshot, 1, 7, snapshot_rest.CreateSnapshotPayload{Name: "nightly"})
+	Result, CreationError := snapshot_rest.CreateSnapshotJobHandler(Job)
+	assert.NoError(this.T(), CreationError)
+	assert.Equal(this.T(), gin.H{"Status": "Created", "SnapshotId": 3}, Result)
+
+	Created := this.Snapshots[3]
+	assert.Equal(this.T(), "nightly", Created.Name)
+	assert.Equal(this.T(), 1, Created.VirtualMachineId)
+	assert.Equal(this.T(), 7, Created.OwnerId)
+	assert.NotEmpty(this.T(), Created.SnapshotRef)
+
+	Job, _ = models.NewJob(models.JobTypeRevertSnapshot, 1, 7, snapshot_rest.SnapshotOperationPayload{SnapshotId: 3})
+	Result, RevertError := snapshot_rest.RevertSnapshotJobHandler(Job)
+	assert.NoError(this.T(), RevertError)
+	assert.Equal(this.T(), gin.H{"Status": "Reverted", "SnapshotId": 3}, Result)
+
+	Job, _ = models.NewJob(models.JobTypeRemoveSnapshot, 1, 7, snapshot_rest.SnapshotOperationPayload{SnapshotId: 3})
+	Result, RemoveError := snapshot_rest.RemoveSnapshotJobHandler(Job)
+	assert.NoError(this.T(), RemoveError)
+	assert.Equal(this.T(), gin.H{"Status": "Removed"}, Result)
+	assert.NotContains(this.T(), this.Snapshots, 3, "Record of the Removed Snapshot should be Deleted")
+
+	// Snapshot does not Exist on the vSphere anymore
+	Job, _ = models.NewJob(models.JobTypeRevertSnapshot, 1, 7, snapshot_rest.SnapshotOperationPayload{SnapshotId: 3})
+	_, RevertError = snapshot_rest.RevertSnapshotJobHandler(Job)
+	assert.EqualError(this.T(), RevertError, exceptions.ItemDoesNotExist().Error())
+}
+
+func (this *SnapshotRestTestSuite) TestJobHandlerFailure() {
+	// Record of the Snapshot, that has been Removed outside of the Platform, is being Kept, when the vSphere Fails to Remove it
+	Job, _ := models.NewJob(models.JobTypeRemoveSnapshot, 1, 7, snapshot_rest.SnapshotOperationPayload{SnapshotId: 1})
+	_, RemoveError := snapshot_rest.RemoveSnapshotJobHandler(Job)
+	assert.EqualError(this.T(), RemoveError, exceptions.SnapshotFailure().Error())
+	assert.Contains(this.T(), this.Snapshots, 1)
+	assert.NotContains(this.T(), strings.Join(this.Database.GetQueries(), "\n"), `DELETE FROM "snapshots"`)
+}
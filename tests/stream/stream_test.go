@@ -0,0 +1,200 @@
+package stream_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/middlewares"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/stream"
+	"github.com/LovePelmeni/Infrastructure/stream_rest"
+	"github.com/LovePelmeni/Infrastructure/tests/fakedb"
+	"github.com/LovePelmeni/Infrastructure/tests/fakeredis"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+)
+
+type StreamTestSuite struct {
+	suite.Suite
+	Broker *stream.Broker
+}
+
+func TestStreamSuite(t *testing.T) {
+	suite.Run(t, new(StreamTestSuite))
+}
+
+func (this *StreamTestSuite) SetupTest() {
+	this.Broker = stream.NewBroker(nil)
+}
+
+func NewEvent(Type string, ProjectId int, OwnerId int) stream.Event {
+	Event, _ := stream.NewEvent(Type, 1, ProjectId, OwnerId, stream.PowerState{PowerState: "poweredOn"})
+	return Event
+}
+
+func (this *StreamTestSuite) TestDelivery() {
+	Member := stream.NewSubscription(1, []int{10})
+	Stranger := stream.NewSubscription(2, []int{20})
+	this.Broker.Subscribe(Member)
+	this.Broker.Subscribe(Stranger)
+
+	// Events of the Virtual Machines are being Delivered to the Members of the Project
+	this.Broker.Publish(NewEvent(stream.EventPowerState, 10, 0))
+	assert.Len(this.T(), Member.Events, 1)
+	assert.Len(this.T(), Stranger.Events, 0)
+
+	// Events of the Jobs are being Delivered to their Owners only
+	this.Broker.Publish(NewEvent(stream.EventJob, 0, 2))
+	assert.Len(this.T(), Member.Events, 1)
+	assert.Len(this.T(), Stranger.Events, 1)
+
+	Received := <-Member.Events
+	var Data stream.PowerState
+	assert.NoError(this.T(), json.Unmarshal(Received.Data, &Data))
+	assert.Equal(this.T(), "poweredOn", Data.PowerState)
+}
+
+func (this *StreamTestSuite) TestProjectsRefresh() {
+	Subscription := stream.NewSubscription(1, nil)
+	assert.False(this.T(), Subscription.Accepts(NewEvent(stream.EventHealth, 10, 0)))
+	Subscription.SetProjects([]int{10})
+	assert.True(this.T(), Subscription.Accepts(NewEvent(stream.EventHealth, 10, 0)))
+}
+
+func (this *StreamTestSuite) TestSlowSubscription() {
+	// Events, that do not Fit into the Buffer, are being Dropped instead of Blocking the Broker
+	Subscription := stream.NewSubscription(1, []int{10})
+	this.Broker.Subscribe(Subscription)
+	for Index := 0; Index < stream.BufferSize+5; Index++ {
+		this.Broker.Deliver(NewEvent(stream.EventHealth, 10, 0))
+	}
+	assert.Len(this.T(), Subscription.Events, stream.BufferSize)
+	assert.Equal(this.T(), float64(5), stream.DroppedEvents.Get(stream.EventHealth))
+}
+
+func (this *StreamTestSuite) TestStop() {
+	Subscription := stream.NewSubscription(1, []int{10})
+	this.Broker.Subscribe(Subscription)
+	this.Broker.Stop()
+
+	_, Open := <-Subscription.Events
+	assert.False(this.T(), Open, "Streams should be Finished, once the Broker is Stopped")
+	assert.NotPanics(this.T(), func() { this.Broker.Unsubscribe(Subscription) })
+}
+
+func (this *StreamTestSuite) TestJobEvent() {
+	Subscription := stream.NewSubscription(7, nil)
+	this.Broker.Subscribe(Subscription)
+	this.Broker.ObserveJob(models.Job{ID: 3, OwnerId: 7, VirtualMachineId: 1, State: models.JobStateRunning, Progress: 30})
+
+	Received := <-Subscription.Events
+	var Job models.Job
+	assert.NoError(this.T(), json.Unmarshal(Received.Data, &Job))
+	assert.Equal(this.T(), 30, Job.Progress)
+}
+
+func (this *StreamTestSuite) TestWatchPowerState() {
+	Model := simulator.VPX()
+	defer Model.Remove()
+	assert.NoError(this.T(), Model.Create())
+	Server := Model.Service.NewServer()
+	defer Server.Close()
+
+	Context, CancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer CancelFunc()
+	Client, ClientError := govmomi.NewClient(Context, Server.URL, true)
+	assert.NoError(this.T(), ClientError)
+
+	VirtualMachine, FindError := find.NewFinder(Client.Client).VirtualMachine(Context, "/DC0/vm/DC0_H0_VM0")
+	assert.NoError(this.T(), FindError)
+
+	Changes := make(chan [2]string, 10)
+	WatchContext, StopWatch := context.WithCancel(Context)
+	Stopped := make(chan error, 1)
+	go func() {
+		Stopped <- stream.WatchPowerState(WatchContext, Client.Client, func(ManagedObjectId string, State string) {
+			Changes <- [2]string{ManagedObjectId, State}
+		})
+	}()
+	time.Sleep(500 * time.Millisecond) // Current States are not being Reported
+
+	Task, PowerError := VirtualMachine.PowerOff(Context)
+	assert.NoError(this.T(), PowerError)
+	assert.NoError(this.T(), Task.Wait(Context))
+
+	select {
+	case Change := <-Changes:
+		assert.Equal(this.T(), [2]string{VirtualMachine.Reference().Value, "poweredOff"}, Change)
+	case <-Context.Done():
+		this.T().Fatal("Power State Change has not been Reported")
+	}
+	assert.Len(this.T(), Changes, 0, "Only the Changed Virtual Machine should be Reported")
+
+	StopWatch()
+	select {
+	case <-Stopped:
+	case <-Context.Done():
+		this.T().Fatal("Watch has not been Stopped")
+	}
+}
+
+func (this *StreamTestSuite) OpenStream(Lifetime time.Duration, OnOpen func(Credentials *authentication.JwtToken)) string {
+	// Opens the Stream of the Customer with the Token of the Lifetime passed, Returns the Body, once the Stream has been Closed
+	Redis := fakeredis.NewServer()
+	defer Redis.Close()
+	middlewares.RedisClient = Redis.GetClient()
+	models.Database, _ = fakedb.New(nil)
+
+	authentication.Configure(config.AuthenticationConfig{SecretKey: "secret", AccessTokenLifetime: Lifetime})
+	Token, _ := authentication.CreateJwtToken(1, "customer", "customer@example.com")
+	Credentials, _ := authentication.GetCustomerJwtCredentials(Token)
+
+	gin.SetMode(gin.TestMode)
+	Router := gin.New()
+	Router.GET("/stream/events/", stream_rest.StreamEventsRestController)
+	Request := httptest.NewRequest(http.MethodGet, "/stream/events/", nil)
+	Request.Header.Set("Authorization", "Bearer "+Token)
+	Recorder := httptest.NewRecorder()
+
+	Closed := make(chan struct{})
+	go func() {
+		Router.ServeHTTP(Recorder, Request)
+		close(Closed)
+	}()
+	OnOpen(Credentials)
+
+	select {
+	case <-Closed:
+	case <-time.After(5 * time.Second):
+		this.T().Fatal("Stream has not been Closed")
+	}
+	return Recorder.Body.String()
+}
+
+func (this *StreamTestSuite) TestStreamClosedOnRevocation() {
+	defer func(Interval time.Duration) { stream_rest.RefreshInterval = Interval }(stream_rest.RefreshInterval)
+	stream_rest.RefreshInterval = 20 * time.Millisecond
+
+	Body := this.OpenStream(time.Hour, func(Credentials *authentication.JwtToken) {
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(this.T(), authentication.RevokeAccessToken(middlewares.RedisClient, Credentials))
+	})
+	assert.Contains(this.T(), Body, "event:"+stream_rest.EventUnauthorized)
+	assert.Contains(this.T(), Body, "Token has been Revoked")
+}
+
+func (this *StreamTestSuite) TestStreamClosedOnExpiry() {
+	Body := this.OpenStream(time.Second, func(Credentials *authentication.JwtToken) {})
+	assert.Contains(this.T(), Body, "event:"+stream_rest.EventUnauthorized)
+	assert.Contains(this.T(), Body, "Access Token has Expired")
+}
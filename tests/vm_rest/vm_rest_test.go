@@ -0,0 +1,162 @@
+package vm_rest_test
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/tests/fakedb"
+	"github.com/LovePelmeni/Infrastructure/vm_rest"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type VmRestTestSuite struct {
+	suite.Suite
+	Database *fakedb.Database
+
+	Limit   int64 // Virtual Machines Limit of the Customer
+	Owned   int64 // Number of the Virtual Machines, the Customer Owns
+	Pending int64 // Number of the Jobs, that are Creating Virtual Machines of the Customer
+}
+
+func TestVmRestSuite(t *testing.T) {
+	suite.Run(t, new(VmRestTestSuite))
+}
+
+func (this *VmRestTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	this.Limit, this.Owned, this.Pending = 3, 0, 0
+	models.Database, this.Database = fakedb.New(this.Respond)
+}
+
+func (this *VmRestTestSuite) Respond(Query string, Args []driver.Value) fakedb.Result {
+	Count := func(Number int64) fakedb.Result {
+		return fakedb.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{Number}}}
+	}
+	switch {
+	case strings.HasPrefix(Query, `SELECT * FROM "customers"`):
+		return fakedb.Result{Columns: []string{"id", "virtual_machines_limit"}, Rows: [][]driver.Value{{int64(7), this.Limit}}}
+	case strings.HasPrefix(Query, `SELECT count(*) FROM "virtual_machines"`):
+		return Count(this.Owned)
+	case strings.HasPrefix(Query, `SELECT count(*) FROM "jobs"`):
+		return Count(this.Pending)
+	case strings.HasPrefix(Query, `INSERT INTO "jobs"`):
+		return fakedb.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(15)}}}
+	case strings.Contains(Query, `FROM "virtual_machines"`):
+		return fakedb.Result{Columns: []string{"id", "virtual_machine_name"},
+			Rows: [][]driver.Value{{int64(1), "web"}, {int64(2), "db"}}}
+	}
+	return fakedb.Result{}
+}
+
+func (this *VmRestTestSuite) TestLimit() {
+	this.Owned, this.Pending = 1, 1
+	Job, _ := models.NewJob(models.JobTypeInitialize, 0, 7, nil)
+	_, CreationError := Job.Create()
+	assert.NoError(this.T(), CreationError)
+	assert.Equal(this.T(), 15, Job.ID)
+
+	// Customer's Row is being Locked, so the Virtual Machines are being Counted and the Job is being Stored within the same Transaction
+	Queries := this.Database.GetQueries()
+	assert.Len(this.T(), Queries, 6)
+	assert.Equal(this.T(), "BEGIN", Queries[0])
+	assert.Contains(this.T(), Queries[1], "FOR UPDATE")
+	assert.True(this.T(), strings.HasPrefix(Queries[4], `INSERT INTO "jobs"`))
+	assert.Equal(this.T(), "COMMIT", Queries[5])
+
+	// Job is not being Stored, once the Limit has been Reached
+	this.Database.Reset()
+	this.Pending = 2
+	Job, _ = models.NewJob(models.JobTypeClone, 1, 7, nil)
+	_, CreationError = Job.Create()
+	assert.ErrorIs(this.T(), CreationError, models.ErrVirtualMachinesLimitExceeded)
+	assert.Contains(this.T(), CreationError.Error(), "You can Own up to 3 Virtual Machines")
+	assert.Equal(this.T(), "ROLLBACK", this.Database.GetQueries()[len(this.Database.GetQueries())-1])
+	assert.NotContains(this.T(), strings.Join(this.Database.GetQueries(), "\n"), "INSERT")
+
+	// Jobs, that does not Create Virtual Machines are not Limited
+	this.Database.Reset()
+	Job, _ = models.NewJob(models.JobTypeStart, 1, 7, nil)
+	_, CreationError = Job.Create()
+	assert.NoError(this.T(), CreationError)
+	assert.NotContains(this.T(), strings.Join(this.Database.GetQueries(), "\n"), "FOR UPDATE")
+}
+
+func (this *VmRestTestSuite) TestLimitResponse() {
+	this.Owned = 3
+	Recorder := httptest.NewRecorder()
+	Context, _ := gin.CreateTestContext(Recorder)
+	Context.Request = httptest.NewRequest(http.MethodPost, "/vm/initialize/", nil)
+
+	vm_rest.EnqueueVirtualMachineJob(Context, models.JobTypeInitialize, 0, 7, vm_rest.InitializeVirtualMachinePayload{})
+	assert.Equal(this.T(), http.StatusConflict, Recorder.Code)
+
+	var Response map[string]string
+	assert.NoError(this.T(), json.Unmarshal(Recorder.Body.Bytes(), &Response))
+	assert.Contains(this.T(), Response["Error"], "Virtual Machines Limit has been Exceeded")
+}
+
+func (this *VmRestTestSuite) TestPagination() {
+	Page, PageError := vm_rest.GetVirtualMachinesPage(models.Database.Model(&models.VirtualMachine{}), url.Values{
+		"Page": {"3"}, "PageSize": {"10"}, "Sort": {"-VirtualMachineName"}, "Name": {"web"}, "CreatedAfter": {"2024-01-01"},
+	})
+	assert.NoError(this.T(), PageError)
+	assert.Equal(this.T(), 3, Page.Page)
+	assert.Equal(this.T(), 10, Page.PageSize)
+	assert.Len(this.T(), Page.QuerySet, 2)
+	assert.Equal(this.T(), "web", Page.QuerySet[0].VirtualMachineName)
+
+	Queries := this.Database.GetQueries()
+	assert.Len(this.T(), Queries, 2)
+	assert.Contains(this.T(), Queries[0], "count(*)")
+	assert.NotContains(this.T(), Queries[0], "LIMIT", "Total should not be Limited by the Page")
+	assert.Contains(this.T(), Queries[1], "virtual_machine_name ILIKE $1 AND created_at >= $2")
+	assert.Contains(this.T(), Queries[1], "ORDER BY virtual_machine_name DESC, id DESC LIMIT 10 OFFSET 20")
+	assert.Equal(this.T(), "%web%", this.Database.Statements[1].Args[0])
+}
+
+func (this *VmRestTestSuite) TestDefaultPagination() {
+	Page, PageError := vm_rest.GetVirtualMachinesPage(models.Database.Model(&models.VirtualMachine{}), url.Values{})
+	assert.NoError(this.T(), PageError)
+	assert.Equal(this.T(), 1, Page.Page)
+	assert.Equal(this.T(), vm_rest.DefaultPageSize, Page.PageSize)
+	assert.Contains(this.T(), this.Database.GetQueries()[1], "ORDER BY created_at DESC, id DESC LIMIT 20")
+}
+
+func (this *VmRestTestSuite) TestInvalidPagination() {
+	for _, Query := range []url.Values{
+		{"Page": {"0"}},
+		{"Page": {"first"}},
+		{"PageSize": {"0"}},
+		{"PageSize": {"101"}},
+		{"Sort": {"-Password"}},
+		{"CreatedBefore": {"01.01.2024"}},
+	} {
+		_, PageError := vm_rest.GetVirtualMachinesPage(models.Database.Model(&models.VirtualMachine{}), Query)
+		assert.Error(this.T(), PageError, Query.Encode())
+	}
+	assert.Empty(this.T(), this.Database.GetQueries(), "Invalid Requests should not Reach the Database")
+}
+
+func (this *VmRestTestSuite) TestResizeWithoutDataDisk() {
+	// Virtual Machine has not been Deployed with the Data Disk, so there is no Disk to Grow
+	Recorder := httptest.NewRecorder()
+	Context, _ := gin.CreateTestContext(Recorder)
+	Context.Request = httptest.NewRequest(http.MethodPatch, "/vm/resources/?VirtualMachineId=1",
+		strings.NewReader(url.Values{"DiskCapacityInKB": {"2097152"}}.Encode()))
+	Context.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Context.Set(authentication.CredentialsContextKey, &authentication.JwtToken{UserId: 7})
+
+	vm_rest.ResizeVirtualMachineRestController(Context)
+	assert.Equal(this.T(), http.StatusBadRequest, Recorder.Code)
+	assert.Contains(this.T(), Recorder.Body.String(), "Virtual Machine does not have the Data Disk to Grow")
+	assert.NotContains(this.T(), strings.Join(this.Database.GetQueries(), "\n"), `INSERT INTO "jobs"`)
+}
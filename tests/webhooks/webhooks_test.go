@@ -0,0 +1,186 @@
+package webhooks_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/alerting"
+	"github.com/LovePelmeni/Infrastructure/egress"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/webhooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WebhooksTestSuite struct {
+	suite.Suite
+	Now time.Time
+}
+
+func TestWebhooksSuite(t *testing.T) {
+	suite.Run(t, new(WebhooksTestSuite))
+}
+
+func (this *WebhooksTestSuite) SetupTest() {
+	this.Now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	webhooks.MaxAttempts = 3
+	webhooks.BackoffBase = 30 * time.Second
+	webhooks.BackoffMax = 5 * time.Minute
+
+	// Sink of the Deliveries Listens on the Loopback Address
+	egress.AllowPrivateAddresses = true
+	egress.DefaultResolver = egress.StaticResolver{
+		"cmdb.example.com":     {"93.184.216.34"},
+		"internal.example.com": {"93.184.216.34", "10.0.0.5"},
+	}
+}
+
+func (this *WebhooksTestSuite) TearDownTest() {
+	egress.AllowPrivateAddresses = false
+	egress.DefaultResolver = net.DefaultResolver
+}
+
+func (this *WebhooksTestSuite) NewDelivery(Webhook models.Webhook) *models.WebhookDelivery {
+	Deliveries, DeliveriesError := webhooks.GetDeliveries([]models.Webhook{Webhook}, webhooks.NewPayload(
+		webhooks.EventStarted, models.VirtualMachine{ID: 1, VirtualMachineName: "web", OwnerId: 2, ProjectId: 3}, nil, this.Now))
+	assert.NoError(this.T(), DeliveriesError)
+	assert.Len(this.T(), Deliveries, 1)
+	return &Deliveries[0]
+}
+
+func (this *WebhooksTestSuite) TestSignature() {
+	// Known Value of the `echo -n '1704110400.{}' | openssl dgst -sha256 -hmac secret`
+	assert.Equal(this.T(), "sha256=f3817c196e58b728e07e8a3b5204ec984ab8d8c5baa0ad0e15947c0d29839e35", webhooks.Sign("secret", 1704110400, []byte("{}")))
+	assert.NotEqual(this.T(), webhooks.Sign("secret", 1, []byte("{}")), webhooks.Sign("secret", 2, []byte("{}")), "Timestamp should be Signed")
+	assert.NotEqual(this.T(), webhooks.Sign("secret", 1, []byte("{}")), webhooks.Sign("other", 1, []byte("{}")))
+}
+
+func (this *WebhooksTestSuite) TestBackoff() {
+	assert.Equal(this.T(), 30*time.Second, webhooks.GetBackoff(1))
+	assert.Equal(this.T(), time.Minute, webhooks.GetBackoff(2))
+	assert.Equal(this.T(), 4*time.Minute, webhooks.GetBackoff(4))
+	assert.Equal(this.T(), 5*time.Minute, webhooks.GetBackoff(5))
+	assert.Equal(this.T(), 5*time.Minute, webhooks.GetBackoff(100))
+}
+
+func (this *WebhooksTestSuite) TestValidation() {
+	EventTypes, ParseError := webhooks.ParseEventTypes(" vm.created, vm.destroyed ")
+	assert.NoError(this.T(), ParseError)
+	assert.Equal(this.T(), []string{"vm.created", "vm.destroyed"}, EventTypes)
+
+	EventTypes, ParseError = webhooks.ParseEventTypes("")
+	assert.NoError(this.T(), ParseError)
+	assert.Empty(this.T(), EventTypes, "Empty Filter stands for every Event")
+
+	_, ParseError = webhooks.ParseEventTypes("vm.created,vm.exploded")
+	assert.ErrorIs(this.T(), ParseError, webhooks.ErrUnknownEventType)
+
+	assert.NoError(this.T(), webhooks.ValidateURL("https://cmdb.example.com/hooks"))
+	assert.ErrorIs(this.T(), webhooks.ValidateURL("/hooks"), webhooks.ErrInvalidURL)
+	assert.ErrorIs(this.T(), webhooks.ValidateURL("ftp://cmdb.example.com"), webhooks.ErrInvalidURL)
+}
+
+func (this *WebhooksTestSuite) TestInternalURL() {
+	egress.AllowPrivateAddresses = false
+	for _, URL := range []string{
+		"http://127.0.0.1:8001/customer/",
+		"http://[::1]/hooks",
+		"http://169.254.169.254/latest/meta-data/",
+		"https://192.168.1.10/hooks",
+		"https://internal.example.com/hooks", // One of the Addresses of the Host is a Private one
+	} {
+		assert.ErrorIs(this.T(), webhooks.ValidateURL(URL), egress.ErrForbiddenAddress, URL)
+	}
+	assert.ErrorIs(this.T(), webhooks.ValidateURL("https://unknown.example.com/hooks"), egress.ErrUnresolvableHost)
+
+	// Host, that has been Validated, can be Pointed to the Internal Address later, so the Dispatcher Checks it once again
+	Sink := alerting.NewHttpSink()
+	defer Sink.Close()
+	Webhook := models.Webhook{ID: 1, URL: Sink.GetURL(), Secret: "secret"}
+	Delivery := this.NewDelivery(Webhook)
+
+	webhooks.Attempt(webhooks.NewClient(), Webhook, Delivery, this.Now)
+	assert.Equal(this.T(), models.DeliveryStatePending, Delivery.State)
+	assert.Contains(this.T(), Delivery.Error, egress.ErrForbiddenAddress.Error())
+	assert.Empty(this.T(), Sink.GetRequests())
+}
+
+func (this *WebhooksTestSuite) TestDeliveries() {
+	Payload := webhooks.NewPayload(webhooks.EventDestroyed, models.VirtualMachine{ID: 1}, nil, this.Now)
+	Deliveries, DeliveriesError := webhooks.GetDeliveries([]models.Webhook{
+		*models.NewWebhook(1, "https://a.example.com", "secret", nil),
+		*models.NewWebhook(1, "https://b.example.com", "secret", []string{webhooks.EventCreated}),
+		*models.NewWebhook(1, "https://c.example.com", "secret", []string{webhooks.EventCreated, webhooks.EventDestroyed}),
+	}, Payload)
+	assert.NoError(this.T(), DeliveriesError)
+	assert.Len(this.T(), Deliveries, 2, "Webhook, that is not Subscribed to the Event, should be Skipped")
+
+	for _, Delivery := range Deliveries {
+		assert.Equal(this.T(), Payload.Id, Delivery.EventId)
+		assert.Equal(this.T(), models.DeliveryStatePending, Delivery.State)
+	}
+}
+
+func (this *WebhooksTestSuite) TestAttempt() {
+	Sink := alerting.NewHttpSink()
+	defer Sink.Close()
+	Webhook := models.Webhook{ID: 1, URL: Sink.GetURL() + "/hook", Secret: "secret"}
+	Delivery := this.NewDelivery(Webhook)
+	Delivery.ID = 5
+
+	webhooks.Attempt(webhooks.NewClient(), Webhook, Delivery, this.Now)
+	assert.Equal(this.T(), models.DeliveryStateSucceeded, Delivery.State)
+	assert.Equal(this.T(), http.StatusOK, Delivery.StatusCode)
+	assert.Equal(this.T(), 1, Delivery.Attempts)
+	assert.NotNil(this.T(), Delivery.DeliveredAt)
+
+	Requests := Sink.GetRequests()
+	assert.Len(this.T(), Requests, 1)
+	assert.Equal(this.T(), webhooks.EventStarted, Requests[0].Header.Get(webhooks.EventHeader))
+	assert.Equal(this.T(), "5", Requests[0].Header.Get(webhooks.DeliveryHeader))
+
+	// Receiver Verifies the Signature of the Body with the Timestamp from the Header
+	Timestamp, _ := strconv.ParseInt(Requests[0].Header.Get(webhooks.TimestampHeader), 10, 64)
+	assert.Equal(this.T(), this.Now.Unix(), Timestamp)
+	assert.Equal(this.T(), webhooks.Sign("secret", Timestamp, Requests[0].Body), Requests[0].Header.Get(webhooks.SignatureHeader))
+
+	var Payload webhooks.Payload
+	assert.NoError(this.T(), json.Unmarshal(Requests[0].Body, &Payload))
+	assert.Equal(this.T(), Delivery.EventId, Payload.Id)
+	assert.Equal(this.T(), "web", Payload.VirtualMachine.VirtualMachineName)
+}
+
+func (this *WebhooksTestSuite) TestRetries() {
+	Sink := alerting.NewHttpSink()
+	defer Sink.Close()
+	Sink.SetStatusCode(http.StatusServiceUnavailable)
+	Webhook := models.Webhook{ID: 1, URL: Sink.GetURL(), Secret: "secret"}
+	Delivery := this.NewDelivery(Webhook)
+
+	// Failed Attempts are being Retried with the Growing Delay
+	webhooks.Attempt(webhooks.NewClient(), Webhook, Delivery, this.Now)
+	assert.Equal(this.T(), models.DeliveryStatePending, Delivery.State)
+	assert.Equal(this.T(), http.StatusServiceUnavailable, Delivery.StatusCode)
+	assert.Equal(this.T(), this.Now.Add(30*time.Second), Delivery.NextAttemptAt)
+	assert.NotEmpty(this.T(), Delivery.Error)
+
+	webhooks.Attempt(webhooks.NewClient(), Webhook, Delivery, this.Now)
+	assert.Equal(this.T(), this.Now.Add(time.Minute), Delivery.NextAttemptAt)
+
+	// Delivery Fails, once it runs out of the Attempts
+	webhooks.Attempt(webhooks.NewClient(), Webhook, Delivery, this.Now)
+	assert.Equal(this.T(), models.DeliveryStateFailed, Delivery.State)
+	assert.Equal(this.T(), 3, Delivery.Attempts)
+	assert.Nil(this.T(), Delivery.DeliveredAt)
+
+	// Redirects are being Treated as the Failures
+	Sink.SetStatusCode(http.StatusFound)
+	Redirected := this.NewDelivery(Webhook)
+	webhooks.Attempt(webhooks.NewClient(), Webhook, Redirected, this.Now)
+	assert.Equal(this.T(), models.DeliveryStatePending, Redirected.State)
+	assert.Equal(this.T(), http.StatusFound, Redirected.StatusCode)
+}
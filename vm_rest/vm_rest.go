@@ -3,57 +3,42 @@ package vm_rest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"sync"
 
 	"net/http"
-
 	"net/url"
+
 	"os"
 
-	"reflect"
 	"strconv"
 	"time"
 
 	"github.com/LovePelmeni/Infrastructure/authentication"
 	"github.com/LovePelmeni/Infrastructure/deploy"
+	"github.com/LovePelmeni/Infrastructure/exceptions"
 	"github.com/LovePelmeni/Infrastructure/healthcheck"
+	"github.com/LovePelmeni/Infrastructure/jobs"
+	"github.com/LovePelmeni/Infrastructure/locks"
 	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/network"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/LovePelmeni/Infrastructure/parsers"
 	"github.com/LovePelmeni/Infrastructure/resources"
+	"github.com/LovePelmeni/Infrastructure/vsphere"
+	"github.com/LovePelmeni/Infrastructure/webhooks"
 
 	"github.com/gin-gonic/gin"
-	"github.com/vmware/govmomi"
+	"gorm.io/gorm"
 
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/property"
-	"github.com/vmware/govmomi/vapi/rest"
-	_ "github.com/vmware/govmomi/vapi/rest"
 	"github.com/vmware/govmomi/vim25/mo"
 )
 
-// Insfrastructure API Environment Variables
-var (
-	APIIp    = os.Getenv("VMWARE_SOURCE_IP")
-	Username = os.Getenv("VMWARE_SOURCE_USERNAME")
-	Password = os.Getenv("VMWARE_SOURCE_PASSWORD")
-
-	APIUrl = &url.URL{
-		Scheme: "https",
-		Path:   "/sdk/",
-		Host:   APIIp,
-		User:   url.UserPassword(Username, Password),
-	}
-)
-
-var (
-	Client *govmomi.Client
-)
-
 var (
 	Customer models.Customer
 )
@@ -74,26 +59,6 @@ func InitializeProductionLogger() {
 	Logger = zap.New(Core)
 }
 
-func init() {
-
-	var RestClient *rest.Client
-	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*10)
-	defer CancelFunc()
-
-	APIClient, ConnectionError := govmomi.NewClient(TimeoutContext, APIUrl, false)
-	switch {
-	case ConnectionError != nil:
-		Logger.Error("FAILED TO INITIALIZE CLIENT, DOES THE VMWARE HYPERVISOR ACTUALLY RUNNING?")
-
-	case ConnectionError == nil:
-		RestClient = rest.NewClient(APIClient.Client)
-		if FailedToLogin := RestClient.Login(TimeoutContext, APIUrl.User); FailedToLogin != nil {
-			Logger.Error("FAILED TO LOGIN TO THE VMWARE HYPERVISOR SERVER", zap.Error(FailedToLogin))
-		}
-	}
-	Client = APIClient
-}
-
 // Package which Contains Rest API Controllers, for Handling VM's Behaviour
 
 // VM Rest API Controllers
@@ -150,12 +115,15 @@ type VirtualMachineSchemaStructure struct {
 	Running   bool `json:"Running"`
 	Shutdown  bool `json:"Shutdown"`
 
+	// Operation, the Virtual Machine is Currently Locked by, nil if there is no one
+	Lock *locks.Lock `json:"Lock" xml:"Lock"`
+
 	// Owner Information
 
 	Ssh struct {
-		byRootCredentials bool   `json:"byRootCredentials" xml:"byRootCredentials"`
-		byRootCertificate bool   `json:"byRootCertificate" xml:"byRootCertificate"`
-		RootUsername      string `json"RootUsername" xml:"RootUsername"`
+		ByRootCredentials bool   `json:"byRootCredentials" xml:"byRootCredentials"`
+		ByRootCertificate bool   `json:"byRootCertificate" xml:"byRootCertificate"`
+		RootUsername      string `json:"RootUsername" xml:"RootUsername"`
 		RootPassword      string `json:"RootPassword" xml:"RootPassword"`
 		IpAddress         string `json:"IpAddress" xml:"IpAddress"`
 	} `json:"Ssh" xml:"Ssh"`
@@ -189,24 +157,34 @@ func GetCustomerVirtualMachine(RequestContext *gin.Context) {
 	var VirtualMachineDatabaseObject models.VirtualMachine
 	var CustomerDatabaseObject models.Customer
 
-	jwtCredentials, _ := authentication.GetCustomerJwtCredentials(
-		RequestContext.Request.Header.Get("jwt-token"))
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
 
 	// Initializing Base Parameters to perform the Action
 	CustomerId := jwtCredentials.UserId
 
 	VirtualMachineId := RequestContext.Query("VirtualMachineId")
-	models.Database.Model(&VirtualMachineDatabaseObject).Where(
-		"owner_id = ? AND id = ?", CustomerId, VirtualMachineId).Find(&VirtualMachineDatabaseObject)
+	models.Database.Model(&VirtualMachineDatabaseObject).Scopes(models.AccessibleBy(CustomerId)).Where(
+		"id = ?", VirtualMachineId).Find(&VirtualMachineDatabaseObject)
 
-	// Receiving the Customer
+	// Receiving the Customer, who Created the Virtual Machine
 	models.Database.Model(&models.Customer{}).Where(
-		"id = ?", CustomerId).Find(&CustomerDatabaseObject)
+		"id = ?", VirtualMachineDatabaseObject.OwnerId).Find(&CustomerDatabaseObject)
+
+	Client, ClientError := vsphere.Registry.GetClient(VirtualMachineDatabaseObject.Region)
+	if ClientError != nil {
+		RequestContext.JSON(http.StatusServiceUnavailable, gin.H{"Error": ClientError.Error()})
+		return
+	}
 
 	VirtualMachineManager := deploy.NewVirtualMachineManager(*Client.Client)
-	VirtualMachineInstance, FindError := VirtualMachineManager.GetVirtualMachine(VirtualMachineId, string(CustomerId))
-	if FindError != nil {
+	VirtualMachineInstance, FindError := VirtualMachineManager.GetVirtualMachine(VirtualMachineId)
+	if FindError != nil || VirtualMachineDatabaseObject.ID == 0 {
 		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Virtual Machine Does Not Exist"})
+		return
 	}
 
 	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*5)
@@ -230,15 +208,18 @@ func GetCustomerVirtualMachine(RequestContext *gin.Context) {
 	MemoryResourceUsage := healthManager.GetMemoryUsageMetrics().Active
 	StorageResourceUsage := healthManager.GetStorageUsageMetrics().Committed
 
+	// Receiving the Operation, that is being Performed on the Virtual Machine right now
+	Lock, LockError := jobs.WorkerPool.GetLock(VirtualMachineDatabaseObject.ID)
+	if LockError != nil {
+		Logger.Error("Failed to Receive Virtual Machine Lock", zap.Error(LockError))
+	}
+
 	VirtualMachine := VirtualMachineSchemaStructure{
 
 		VirtualMachineName: VirtualMachineDatabaseObject.VirtualMachineName,
 		VirtualMachineId:   strconv.Itoa(VirtualMachineDatabaseObject.ID),
 
-		CreatedAt: fmt.Sprintf("%s-%s-%s",
-			VirtualMachineDatabaseObject.CreatedAt.Day,
-			VirtualMachineDatabaseObject.CreatedAt.Month,
-			VirtualMachineDatabaseObject.CreatedAt.Year()),
+		CreatedAt: VirtualMachineDatabaseObject.CreatedAt.Format("02-01-2006"),
 
 		// Active Status
 
@@ -246,6 +227,8 @@ func GetCustomerVirtualMachine(RequestContext *gin.Context) {
 		Deploying: PowerState == "Deploying",
 		Shutdown:  PowerState == "Shutdown",
 
+		Lock: Lock,
+
 		Owner: struct {
 			Username string "json:\"Username\""
 			Email    string "json:\"Email\""
@@ -282,110 +265,347 @@ func GetCustomerVirtualMachine(RequestContext *gin.Context) {
 		},
 	}
 
-	switch reflect.ValueOf(VirtualMachine).IsNil() {
-	case false:
-		RequestContext.JSON(http.StatusOK,
-			gin.H{"VirtualMachine": VirtualMachine})
-	case true:
-		RequestContext.JSON(http.StatusBadRequest,
-			gin.H{"Error": "Virtual Machine Does Not Exist"})
-	}
+	RequestContext.JSON(http.StatusOK,
+		gin.H{"VirtualMachine": VirtualMachine})
 }
 
+var (
+	// Fields, the List of the Virtual Machines can be Sorted by
+	VirtualMachineSortFields = map[string]string{
+		"CreatedAt":          "created_at",
+		"VirtualMachineName": "virtual_machine_name",
+		"State":              "state",
+		"Datacenter":         "configuration::jsonb -> 'Datacenter' ->> 'DatacenterName'",
+	}
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
 func GetCustomerVirtualMachines(RequestContext *gin.Context) {
-	// Returns List of the VM's that Customer Owns
-	var VirtualMachineQuerySet []VirtualMachineSchemaStructure
-	var VirtualMachines []models.VirtualMachine
-	var VirtualMachineServerOwner models.Customer
-	jwtCredentials, _ := authentication.GetCustomerJwtCredentials(
-		RequestContext.Request.Header.Get("jwt-token"))
+	// Returns List of the VM's of the Projects, the Customer is Member of
+	// Supports Pagination (`Page`, `PageSize`), Filtering (`ProjectId`, `State`, `Name`, `Region`, `Datacenter`, `CreatedAfter`, `CreatedBefore`)
+	// and Sorting (`Sort`, Field Name with the Optional `-` Prefix for the Descending Order, e.g `-CreatedAt`)
 
-	CustomerId := jwtCredentials.UserId
-	Gorm := models.Database.Model(&Customer).Where(
-		"id = ?", CustomerId).Find(VirtualMachineServerOwner).Preload("Vms").Find(&VirtualMachines)
-	group := sync.WaitGroup{}
-
-	for _, VirtualMachineServer := range VirtualMachines {
-
-		go func() {
-			group.Add(1)
-			VirtualMachine := VirtualMachineSchemaStructure{
-				// Virtual Machine Server Metadata
-				VirtualMachineName: VirtualMachineServer.VirtualMachineName,
-				VirtualMachineId:   strconv.Itoa(VirtualMachineServer.ID),
-
-				// Resources Configuration
-				Owner: struct {
-					Username string "json:\"Username\""
-					Email    string "json:\"Email\""
-					City     string "json:\"City\""
-					Country  string "json:\"Country\""
-					ZipCode  string "json:\"ZipCode\""
-					Street   string "json:\"Street\""
-				}{
-					Username: VirtualMachineServerOwner.Username,
-					Email:    VirtualMachineServerOwner.Email,
-					City:     VirtualMachineServerOwner.City,
-					Country:  VirtualMachineServerOwner.Country,
-					Street:   VirtualMachineServerOwner.Street,
-				},
-			}
-			VirtualMachineQuerySet = append(VirtualMachineQuerySet, VirtualMachine)
-			group.Done()
-		}()
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
 	}
-	group.Wait()
-	switch Gorm.Error {
+
+	VirtualMachinesPage, QueryError := GetVirtualMachinesPage(
+		models.Database.Model(&models.VirtualMachine{}).Scopes(models.AccessibleBy(jwtCredentials.UserId)),
+		RequestContext.Request.URL.Query())
+
+	switch QueryError {
 	case nil:
-		RequestContext.JSON(http.StatusOK,
-			gin.H{"QuerySet": VirtualMachines})
+		RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": VirtualMachinesPage.QuerySet,
+			"Total": VirtualMachinesPage.Total, "Page": VirtualMachinesPage.Page, "PageSize": VirtualMachinesPage.PageSize})
 	default:
-		Logger.Error("Failed to Receive All Customer Virtual Machines", zap.Error(Gorm.Error))
 		RequestContext.JSON(http.StatusBadRequest,
-			gin.H{"Error": fmt.Sprintf("%s", Gorm.Error)})
+			gin.H{"Error": fmt.Sprintf("%s", QueryError)})
+	}
+}
+
+type VirtualMachinesPage struct {
+	// Page of the Virtual Machines List along with the Total Number of the Virtual Machines, that Match the Filters
+	QuerySet []models.VirtualMachine
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+func GetVirtualMachinesPage(QuerySet *gorm.DB, Query url.Values) (*VirtualMachinesPage, error) {
+	// Returns the Page of the Virtual Machines of the Query Set, Filtered and Sorted using the Query Params of the List Request
+
+	Page, PageError := strconv.Atoi(GetQueryValue(Query, "Page", "1"))
+	PageSize, PageSizeError := strconv.Atoi(GetQueryValue(Query, "PageSize", strconv.Itoa(DefaultPageSize)))
+	if PageError != nil || PageSizeError != nil || Page < 1 || PageSize < 1 || PageSize > MaxPageSize {
+		return nil, fmt.Errorf("Invalid Pagination, Page Size should be between 1 and %v", MaxPageSize)
+	}
+
+	// Filtering Virtual Machines
+	if ProjectId := Query.Get("ProjectId"); len(ProjectId) != 0 {
+		QuerySet = QuerySet.Where("project_id = ?", ProjectId)
+	}
+
+	if State := Query.Get("State"); len(State) != 0 {
+		QuerySet = QuerySet.Where("state = ?", State)
+	}
+	if Name := Query.Get("Name"); len(Name) != 0 {
+		QuerySet = QuerySet.Where("virtual_machine_name ILIKE ?", "%"+Name+"%")
+	}
+	if Region := Query.Get("Region"); len(Region) != 0 {
+		QuerySet = QuerySet.Scopes(models.InRegions(vsphere.Registry.GetRecordRegions(Region)...))
+	}
+	if Datacenter := Query.Get("Datacenter"); len(Datacenter) != 0 {
+		QuerySet = QuerySet.Where("configuration::jsonb -> 'Datacenter' ->> 'DatacenterName' = ?", Datacenter)
+	}
+
+	for _, Filter := range [][2]string{{"CreatedAfter", "created_at >= ?"}, {"CreatedBefore", "created_at <= ?"}} {
+		if Value := Query.Get(Filter[0]); len(Value) != 0 {
+			CreatedAt, ParseError := time.Parse("2006-01-02", Value)
+			if ParseError != nil {
+				return nil, fmt.Errorf("Invalid %s Date, Expected Format is YYYY-MM-DD", Filter[0])
+			}
+			QuerySet = QuerySet.Where(Filter[1], CreatedAt)
+		}
+	}
+
+	// Sorting Virtual Machines
+	Sort := GetQueryValue(Query, "Sort", "-CreatedAt")
+	Order := "ASC"
+	if len(Sort) != 0 && Sort[0] == '-' {
+		Sort, Order = Sort[1:], "DESC"
+	}
+	SortField, Valid := VirtualMachineSortFields[Sort]
+	if !Valid {
+		return nil, errors.New("Invalid Sort Field")
+	}
+
+	VirtualMachinesPage := &VirtualMachinesPage{QuerySet: []models.VirtualMachine{}, Page: Page, PageSize: PageSize}
+	if Counted := QuerySet.Session(&gorm.Session{}).Count(&VirtualMachinesPage.Total); Counted.Error != nil {
+		Logger.Error("Failed to Count Customer Virtual Machines", zap.Error(Counted.Error))
+		return nil, Counted.Error
+	}
+	if Gorm := QuerySet.Omit("ssh_key").Order(fmt.Sprintf("%s %s, id %s", SortField, Order, Order)).
+		Offset((Page - 1) * PageSize).Limit(PageSize).Find(&VirtualMachinesPage.QuerySet); Gorm.Error != nil {
+		Logger.Error("Failed to Receive All Customer Virtual Machines", zap.Error(Gorm.Error))
+		return nil, Gorm.Error
+	}
+	return VirtualMachinesPage, nil
+}
+
+func GetQueryValue(Query url.Values, Key string, Default string) string {
+	// Returns Value of the Query Param or the Default one, if it has not been Passed
+	if Values, Exists := Query[Key]; Exists && len(Values) != 0 {
+		return Values[0]
 	}
+	return Default
 }
 
 // Virtual Machine Rest API Endpoints
 
+// Every Mutating Operation on the Virtual Machine Server is being Executed in the Background by the Job Worker Pool
+// Rest Controllers only Validate the Input, Enqueue the Job and Return it's ID, so the Customer can Poll the Status
+// using `GetVirtualMachineJobRestController`
+
+type InitializeVirtualMachinePayload struct {
+	// Payload of the Job, that Initializes new Virtual Machine Server
+	ProjectId            int    `json:"ProjectId"`
+	Region               string `json:"Region"` // Region of the vSphere Endpoint, the Virtual Machine is being Created at
+	VirtualMachineName   string `json:"VirtualMachineName"`
+	ResourceRequirements string `json:"ResourceRequirements"`
+	DatacenterConfig     string `json:"DatacenterConfig"`
+}
+
+type DeployVirtualMachinePayload struct {
+	// Payload of the Job, that Applies Custom Configuration to the Initialized Virtual Machine Server
+	VirtualMachineConfiguration string `json:"VirtualMachineConfiguration"`
+}
+
+type ResizeVirtualMachinePayload struct {
+	// Payload of the Job, that Changes Resources of the Virtual Machine Server, Zero Values are being Left as they are
+	CpuNum            int32 `json:"CpuNum"`
+	MemoryInMegabytes int64 `json:"MemoryInMegabytes"`
+	DiskCapacityInKB  int64 `json:"DiskCapacityInKB"`
+}
+
+type CloneVirtualMachinePayload struct {
+	// Payload of the Job, that Creates a Copy of the Existing Virtual Machine Server
+	VirtualMachineName string `json:"VirtualMachineName"`
+	Linked             bool   `json:"Linked"`
+	DatastoreItemPath  string `json:"DatastoreItemPath"` // Optional, Source Datastore is used by Default
+	FolderItemPath     string `json:"FolderItemPath"`    // Optional, Source Folder is used by Default
+	IP                 string `json:"IP"`
+	Netmask            string `json:"Netmask"`
+	Gateway            string `json:"Gateway"`
+	Hostname           string `json:"Hostname"`
+}
+
+func init() {
+	InitializeProductionLogger()
+
+	// Registering Handlers for the Virtual Machine Operation Jobs
+	jobs.WorkerPool.RegisterHandler(models.JobTypeInitialize, InitializeVirtualMachineJobHandler)
+	jobs.WorkerPool.RegisterResumableHandler(models.JobTypeDeploy, DeployVirtualMachineJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeStart, StartVirtualMachineJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeReboot, RebootVirtualMachineJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeShutdown, ShutdownVirtualMachineJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeRemove, RemoveVirtualMachineJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeClone, CloneVirtualMachineJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeResize, ResizeVirtualMachineJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeStartGuestOS, StartGuestOSJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeRebootGuestOS, RebootGuestOSJobHandler)
+	jobs.WorkerPool.RegisterHandler(models.JobTypeShutdownGuestOS, ShutdownGuestOSJobHandler)
+}
+
+func EnqueueVirtualMachineJob(RequestContext *gin.Context, JobType string, VirtualMachineId int, OwnerId int, Payload interface{}) {
+	// Enqueues new Job and Responds with `202 Accepted` and the ID of the Job
+	// Responds with `409 Conflict` and the Current Lock, if the Virtual Machine is Performing other Operation
+	// or if the Job would Exceed the Virtual Machines Limit of the Customer
+
+	NewJob, JobError := models.NewJob(JobType, VirtualMachineId, OwnerId, Payload)
+	if JobError != nil {
+		RequestContext.JSON(http.StatusBadRequest,
+			gin.H{"Error": "Invalid Configuration has been Passed"})
+		return
+	}
+
+	EnqueueError := jobs.WorkerPool.Enqueue(NewJob)
+	if errors.Is(EnqueueError, locks.ErrLocked) {
+		Lock, _ := jobs.WorkerPool.GetLock(VirtualMachineId)
+		RequestContext.JSON(http.StatusConflict,
+			gin.H{"Error": EnqueueError.Error(), "Lock": Lock})
+		return
+	}
+	if errors.Is(EnqueueError, models.ErrVirtualMachinesLimitExceeded) {
+		RequestContext.JSON(http.StatusConflict, gin.H{"Error": EnqueueError.Error()})
+		return
+	}
+	if EnqueueError != nil {
+		Logger.Error("Failed to Enqueue Virtual Machine Job",
+			zap.String("Type", JobType), zap.Error(EnqueueError))
+		RequestContext.JSON(http.StatusServiceUnavailable,
+			gin.H{"Error": "Failed to Schedule the Operation, Try a bit Later"})
+		return
+	}
+	RequestContext.JSON(http.StatusAccepted, gin.H{"JobId": NewJob.ID, "Status": NewJob.State})
+}
+
+func EnqueueVirtualMachineOperationJob(RequestContext *gin.Context, JobType string) {
+	// Enqueues Job, that does not require any Payload, besides the Virtual Machine Itself (Power Operations, Removal etc...)
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	VirtualMachineId, ParseError := strconv.Atoi(RequestContext.Query("VirtualMachineId"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Virtual Machine ID"})
+		return
+	}
+
+	var VirtualMachine models.VirtualMachine
+	models.Database.Model(&models.VirtualMachine{}).Where(
+		"id = ?", VirtualMachineId).Scopes(models.AccessibleBy(jwtCredentials.UserId)).Find(&VirtualMachine)
+
+	if VirtualMachine.ID == 0 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Virtual Machine Does Not Exist"})
+		return
+	}
+	EnqueueVirtualMachineJob(RequestContext, JobType, VirtualMachine.ID, jwtCredentials.UserId, nil)
+}
+
+func GetVirtualMachineJobRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns the Current State of the Virtual Machine Job
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	var Job models.Job
+	models.Database.Model(&models.Job{}).Where("id = ?", RequestContext.Param("JobId")).Where(
+		"owner_id = ? OR virtual_machine_id IN (?)", jwtCredentials.UserId,
+		models.Database.Model(&models.VirtualMachine{}).Select("id").Scopes(models.AccessibleBy(jwtCredentials.UserId))).Find(&Job)
+
+	if Job.ID == 0 {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Job Does Not Exist"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Job": Job})
+}
+
 func InitializeVirtualMachineRestController(RequestContext *gin.Context) {
 
 	//Rest Controller, that Initializes New Empty Virtual Machine + Load Balancer
 
 	// Receiving Extra Info, that is going to be Necessary to Initialize New VM Server
 
-	JwtCookie, _ := authentication.GetCustomerJwtCredentials(
-		RequestContext.Request.Header.Get("Authorization"))
+	JwtCookie, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	// Project, the Virtual Machine is going to Belong to, Role of the Customer in it has been Checked by the `PolicyCheckMiddleware`
+	ProjectId, ParseError := strconv.Atoi(RequestContext.PostForm("ProjectId"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Project ID"})
+		return
+	}
 
-	VirtualMachineName := RequestContext.PostForm("VirtualMachineName")
-	CustomerId := JwtCookie.UserId
+	// Region, the Virtual Machine is going to be Created at, Default one is used, if it has not been Specified
+	Pool, RegionError := vsphere.Registry.Get(RequestContext.PostForm("Region"))
+	if RegionError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": RegionError.Error()})
+		return
+	}
 
-	// Initilizing Resource Requirements Instance, that will be used to pick up Appropriate Hardware Instances of the Choosed Datacenter, based on this Requirements
-	DatacenterResourceRequirements, InvalidError := resources.NewDatacenterResourceRequirements(
-		RequestContext.PostForm("ResourceRequirements"))
+	Payload := InitializeVirtualMachinePayload{
+		ProjectId:            ProjectId,
+		Region:               Pool.Endpoint.Region,
+		VirtualMachineName:   RequestContext.PostForm("VirtualMachineName"),
+		ResourceRequirements: RequestContext.PostForm("ResourceRequirements"),
+		DatacenterConfig:     RequestContext.PostForm("DatacenterConfig"),
+	}
 
-	// On Parse Failure Returning Bad Request with Error Explanation
-	if InvalidError != nil {
+	// Validating the Configuration before Scheduling the Job, so the Customer receives the Parse Errors Immediately
+	if _, InvalidError := resources.NewDatacenterResourceRequirements(Payload.ResourceRequirements); InvalidError != nil || len(Payload.VirtualMachineName) == 0 {
 		RequestContext.JSON(http.StatusBadRequest,
 			gin.H{"Error": "Invalid Configuration has been Passed."})
 		return
 	}
 
-	// Initializing Hardware Configuration Based on the Resource Requirements
-	DatacenterConfig, ParseError := parsers.NewHardwareConfig(RequestContext.PostForm("DatacenterConfig"))
-	if ParseError != nil {
+	if _, ParseError := parsers.NewHardwareConfig(Payload.DatacenterConfig); ParseError != nil {
 		RequestContext.JSON(http.StatusBadRequest,
 			gin.H{"Error": "Failed to Initialize New Virtual Server, Invalid Configuration has been Passed"})
 		return
 	}
+	EnqueueVirtualMachineJob(RequestContext, models.JobTypeInitialize, 0, JwtCookie.UserId, Payload)
+}
+
+func InitializeVirtualMachineJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Initializes New Empty Virtual Machine
+
+	var Payload InitializeVirtualMachinePayload
+	if DecodeError := Job.DecodePayload(&Payload); DecodeError != nil {
+		return nil, DecodeError
+	}
+	CustomerId := Job.OwnerId
+	VirtualMachineName := Payload.VirtualMachineName
+
+	Client, ClientError := vsphere.Registry.GetClient(Payload.Region)
+	if ClientError != nil {
+		return nil, ClientError
+	}
+	RestClient, ClientError := vsphere.Registry.GetRestClient(Payload.Region)
+	if ClientError != nil {
+		return nil, ClientError
+	}
+
+	// Initilizing Resource Requirements Instance, that will be used to pick up Appropriate Hardware Instances of the Choosed Datacenter, based on this Requirements
+	DatacenterResourceRequirements, InvalidError := resources.NewDatacenterResourceRequirements(Payload.ResourceRequirements)
+	if InvalidError != nil {
+		return nil, InvalidError
+	}
+
+	// Initializing Hardware Configuration Based on the Resource Requirements
+	DatacenterConfig, ParseError := parsers.NewHardwareConfig(Payload.DatacenterConfig)
+	if ParseError != nil {
+		return nil, ParseError
+	}
 
 	// Receiving Datacenter Instance, based on Obtained Datacenter Config
 
 	Datacenter, FindError := DatacenterConfig.GetDatacenter(*Client.Client)
 	if FindError != nil {
-		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": FindError})
-		return
+		return nil, FindError
 	}
+	Job.UpdateProgress(10)
 
 	// Initializing Datacenter Manager, to pick up Compute Resources, based on the Requirements
 	DatacenterResourceManager := resources.NewDatacenterResourceManager(Client.Client)
@@ -394,93 +614,99 @@ func InitializeVirtualMachineRestController(RequestContext *gin.Context) {
 	ParsedResourceInstances, FindError := DatacenterResourceManager.GetComputeResources(Datacenter, *DatacenterResourceRequirements)
 
 	// Checking if Parsed Resource Instances is not Nil or Empty Slice....
-	if reflect.ValueOf(ParsedResourceInstances).IsNil() || FindError != nil {
-		RequestContext.JSON(
-			http.StatusBadGateway, gin.H{"Error": FindError.Error()})
-		return
+	if len(ParsedResourceInstances) == 0 || FindError != nil {
+		return nil, exceptions.NoResourceAvailable()
 	}
+	Job.UpdateProgress(30)
 
 	// Initializing New Virtual Server Instance...
 
 	InstanceDeployer := deploy.NewVirtualMachineManager(*Client.Client)
 	InitializedInstance, InitError := InstanceDeployer.InitializeNewVirtualMachine(
-		*Client.Client, VirtualMachineName,
+		*Client.Client, RestClient, VirtualMachineName,
 		ParsedResourceInstances["Datastore"].(*object.Datastore),
 		ParsedResourceInstances["Network"].(*object.Network),
 		ParsedResourceInstances["ClusterComputeResource"].(*object.ClusterComputeResource),
 		ParsedResourceInstances["Folder"].(*object.Folder),
 	)
 
-	switch InitError {
-	case nil:
-		// Creating New Virtual Machine Model ORM Object.... and store it into SQL DB
-
-		TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*1)
-		defer CancelFunc()
-
-		// Receiving IP Address of the Initialized Virtual Machine
-		IPAddress, IPError := InitializedInstance.WaitForIP(TimeoutContext)
-		if IPError != nil {
-			Logger.Error(
-				"Failed to Parse the IP Address of the Virtual Machine", zap.Error(IPError))
-			RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Initialize Virtual Machine"})
-			return
-		}
-		// Getting Initial Configuration for the new Virtual Machine, (only adding with hardware Configuration)
-		// All Customer Customization will be added after all.
-
-		NewVirtualMachineConfiguration := models.VirtualMachineConfiguration{
-
-			Metadata: struct {
-				VirtualMachineName    string "json:\"VirtualMachineId\" xml:\"VirtualMachineId\""
-				VirtualMachineOwnerId string "json:\"VmOwnerId\" xml:\"VmOwnerId\""
-			}{
-				VirtualMachineName:    VirtualMachineName,
-				VirtualMachineOwnerId: strconv.Itoa(CustomerId),
-			},
-
-			Datacenter: struct {
-				DatacenterName     string `json:"DatacenterName" xml:"DatacenterName"`
-				DatacenterItemPath string `json:"DatacenterItemPath" xml:"DatacenterItemPath"`
-			}{
-				DatacenterName:     Datacenter.Name,
-				DatacenterItemPath: object.NewReference(Client.Client, Datacenter.Reference()).(*object.Datacenter).InventoryPath,
-			},
-
-			Network: struct {
-				Name     string `json:"Name" xml:"Name"`
-				ItemPath string `json:"ItemPath" xml:"ItemPath"`
-			}{
-				Name:     ParsedResourceInstances["Network"].(*object.Network).Name(),
-				ItemPath: ParsedResourceInstances["Network"].(*object.Network).InventoryPath,
-			},
-		}
-		// Define Initial ORM Model Object for the Virtual Machine
+	if InitError != nil {
+		Logger.Error("Failed to Initialize New Virtual Server", zap.Error(InitError))
+		return nil, InitError
+	}
+	Job.UpdateProgress(70)
 
-		NewVirtualMachine := models.VirtualMachine{
+	// Creating New Virtual Machine Model ORM Object.... and store it into SQL DB
 
-			SshInfo:            models.SSHConfiguration{},
-			IPAddress:          IPAddress,
-			ItemPath:           InitializedInstance.InventoryPath,
-			Configuration:      NewVirtualMachineConfiguration,
-			OwnerId:            CustomerId,
-			VirtualMachineName: VirtualMachineName,
-		}
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Minute*1)
+	defer CancelFunc()
 
-		Created, CreationError := NewVirtualMachine.Create()
-		if CreationError != nil {
-			Created.Rollback()
-			Logger.Error("Failed to Create new Database VM Record", zap.Error(CreationError))
-		}
-		RequestContext.JSON(http.StatusCreated,
-			gin.H{"Status": "Initialized"})
+	// Receiving IP Address of the Initialized Virtual Machine
+	IPAddress, IPError := InitializedInstance.WaitForIP(TimeoutContext)
+	if IPError != nil {
+		Logger.Error(
+			"Failed to Parse the IP Address of the Virtual Machine", zap.Error(IPError))
+		return nil, IPError
+	}
+	Job.UpdateProgress(90)
 
-	default:
-		// In Worse Case returning Initialization Error...
-		Logger.Error("Failed to Initialize New Virtual Server, Error: %s", zap.Error(InitError))
-		RequestContext.JSON(http.StatusBadGateway,
-			gin.H{"Error": "Failed to Initialize new Virtual Server"})
+	// Getting Initial Configuration for the new Virtual Machine, (only adding with hardware Configuration)
+	// All Customer Customization will be added after all.
+
+	NewVirtualMachineConfiguration := models.VirtualMachineConfiguration{
+
+		Metadata: struct {
+			VirtualMachineName    string "json:\"VirtualMachineId\" xml:\"VirtualMachineId\""
+			VirtualMachineOwnerId string "json:\"VmOwnerId\" xml:\"VmOwnerId\""
+		}{
+			VirtualMachineName:    VirtualMachineName,
+			VirtualMachineOwnerId: strconv.Itoa(CustomerId),
+		},
+
+		Datacenter: struct {
+			DatacenterName     string `json:"DatacenterName" xml:"DatacenterName"`
+			DatacenterItemPath string `json:"DatacenterItemPath" xml:"DatacenterItemPath"`
+		}{
+			DatacenterName:     Datacenter.Name,
+			DatacenterItemPath: object.NewReference(Client.Client, Datacenter.Reference()).(*object.Datacenter).InventoryPath,
+		},
+
+		Network: struct {
+			Name     string `json:"Name" xml:"Name"`
+			ItemPath string `json:"ItemPath" xml:"ItemPath"`
+		}{
+			Name:     ParsedResourceInstances["Network"].(*object.Network).Name(),
+			ItemPath: ParsedResourceInstances["Network"].(*object.Network).InventoryPath,
+		},
 	}
+	// Define Initial ORM Model Object for the Virtual Machine
+
+	NewVirtualMachine := models.VirtualMachine{
+
+		SshInfo:            models.SSHConfiguration{},
+		IPAddress:          IPAddress,
+		ItemPath:           InitializedInstance.InventoryPath,
+		Configuration:      NewVirtualMachineConfiguration,
+		OwnerId:            CustomerId,
+		ProjectId:          Payload.ProjectId,
+		Region:             Payload.Region,
+		VirtualMachineName: VirtualMachineName,
+	}
+
+	if _, CreationError := NewVirtualMachine.Create(); CreationError != nil {
+		Logger.Error("Failed to Create new Database VM Record", zap.Error(CreationError))
+		// The Virtual Machine is not Managed without the Record, so it's being Destroyed instead of being Left Orphaned
+		if DiscardError := InstanceDeployer.DiscardVirtualMachine(InitializedInstance); DiscardError != nil {
+			Logger.Error("Failed to Destroy Unrecorded Virtual Machine",
+				zap.String("ItemPath", InitializedInstance.InventoryPath), zap.Error(DiscardError))
+		}
+		return nil, CreationError
+	}
+
+	// Attaching the Created Virtual Machine to the Job, so the Customer can find out it's ID
+	Job.VirtualMachineId = NewVirtualMachine.ID
+	webhooks.Emit(webhooks.EventCreated, NewVirtualMachine, gin.H{"JobId": Job.ID})
+	return gin.H{"Status": "Initialized", "VirtualMachineId": NewVirtualMachine.ID, "IPAddress": IPAddress}, nil
 }
 
 func DeployVirtualMachineRestController(RequestContext *gin.Context) {
@@ -491,274 +717,545 @@ func DeployVirtualMachineRestController(RequestContext *gin.Context) {
 	// Receiving Parsed Configuration of the Characteristics, that has been Provided by User
 	// Memory in Megabytes, Cpu Nums etc....
 
-	jwtCredentials, _ := authentication.GetCustomerJwtCredentials(
-		RequestContext.Request.Header.Get("Authorization"))
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
 
 	VmId := RequestContext.Query("VirtualMachineId")
 	VmOwnerId := jwtCredentials.UserId
 
 	// Parsing Custom Virtual Machine Configuration
-	Deployer := deploy.NewVirtualMachineManager(*Client.Client)
-	VmCustomConfig, ParseError := parsers.NewCustomConfig(RequestContext.PostForm("VirtualMachineConfiguration"))
-	if ParseError != nil {
-		RequestContext.JSON(http.StatusOK, gin.H{"Error": "Invalid Configuration has been Passed"})
+	Payload := DeployVirtualMachinePayload{
+		VirtualMachineConfiguration: RequestContext.PostForm("VirtualMachineConfiguration"),
+	}
+	if _, ParseError := parsers.NewCustomConfig(Payload.VirtualMachineConfiguration); ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Configuration has been Passed"})
 		return
 	}
 
-	// Receiving Virtual Machine from the Database and Converting into An API Instance...
-	VirtualMachine, FindError := Deployer.GetVirtualMachine(VmId, strconv.Itoa(VmOwnerId))
+	var VirtualMachine models.VirtualMachine
+	models.Database.Model(&models.VirtualMachine{}).Where(
+		"id = ?", VmId).Scopes(models.AccessibleBy(VmOwnerId)).Find(&VirtualMachine)
 
-	if FindError != nil {
+	if VirtualMachine.ID == 0 {
 		RequestContext.JSON(http.StatusBadRequest,
 			gin.H{"Error": "Virtual Server Does Not Exist"})
 		return
 	}
 
-	// Applying Converted Configuration to the Virtual Machine Instance
+	// New Configuration can't be Applied, while the Previous Deployment is still being Executed
+	if Deployment, Unfinished := models.GetUnfinishedDeployment(VirtualMachine.ID); Unfinished && Deployment.IsActive() {
+		RequestContext.JSON(http.StatusConflict, gin.H{
+			"Error": "Virtual Machine is being Deployed by the other Job", "JobId": Deployment.JobId})
+		return
+	}
+	EnqueueVirtualMachineJob(RequestContext, models.JobTypeDeploy, VirtualMachine.ID, VmOwnerId, Payload)
+}
 
-	VmInfo, ApplyError := Deployer.ApplyConfiguration(VirtualMachine, *VmCustomConfig)
+func DeployVirtualMachineJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Applies Custom Configuration to the Initialized Virtual Machine
 
-	switch ApplyError {
-	case nil:
+	var Payload DeployVirtualMachinePayload
+	if DecodeError := Job.DecodePayload(&Payload); DecodeError != nil {
+		return nil, DecodeError
+	}
 
-		// Updating Virtual Machine ORM Object with New Info
+	Client, ClientError := vsphere.Registry.GetClient(models.GetVirtualMachineRegion(Job.VirtualMachineId))
+	if ClientError != nil {
+		return nil, ClientError
+	}
 
-		var VirtualMachine models.VirtualMachine
-		var VirtualMachineCustomConfiguration models.VirtualMachineConfiguration
-		var VirtualMachineSshConfiguration models.SSHConfiguration
+	// Receiving Virtual Machine from the Database and Converting into An API Instance...
+	Deployer := deploy.NewVirtualMachineManager(*Client.Client)
+	VirtualMachineInstance, FindError := Deployer.GetVirtualMachine(strconv.Itoa(Job.VirtualMachineId))
 
-		VirtualMachineSshConfiguration = models.SSHConfiguration{
-			Type:                 VmInfo.SshType,
-			SshCredentialsMethod: *models.NewSshCredentialsInfo(),
-			SshPublicKeyMethod:   *models.NewSshPublicKeyInfo(),
-			VirtualMachineId:     VirtualMachine.ID,
+	if FindError != nil {
+		return nil, FindError
+	}
+
+	// Resuming the Deployment, if it has been Interrupted along with this Job, Otherwise Starting the new one
+	// Unfinished Deployment of the other Job is being Rolled Back first, so the Configuration of the Payload is not being Lost
+	Deployment, Unfinished := models.GetUnfinishedDeployment(Job.VirtualMachineId)
+	switch {
+	case Unfinished && Deployment.JobId == Job.ID:
+		Logger.Info("Resuming Interrupted Deployment", zap.Int("Deployment ID", Deployment.ID),
+			zap.String("Step", Deployment.CurrentStep), zap.String("State", Deployment.State))
+
+	case Unfinished && Deployment.IsActive():
+		return nil, fmt.Errorf("Virtual Machine is being Deployed by the Job %v", Deployment.JobId)
+
+	default:
+		if Unfinished {
+			Logger.Info("Rolling Back Unfinished Deployment", zap.Int("Deployment ID", Deployment.ID),
+				zap.String("Step", Deployment.CurrentStep), zap.String("State", Deployment.State))
+			if RollbackError := Deployer.RollbackDeployment(VirtualMachineInstance, Deployment,
+				fmt.Errorf("Superseded by the Job %v", Job.ID)); RollbackError != nil {
+				return nil, RollbackError
+			}
+		}
+		Deployment = models.NewDeployment(Job.VirtualMachineId, Job.ID, Payload.VirtualMachineConfiguration)
+		if _, CreationError := Deployment.Create(); CreationError != nil {
+			Logger.Error("Failed to Create Deployment Record", zap.Error(CreationError))
+			return nil, CreationError
 		}
+	}
 
-		json.Unmarshal(VmCustomConfig.ToJson(), &VirtualMachineCustomConfiguration)
-		models.Database.Model(&models.VirtualMachine{}).Where("id = ?").Find(&VirtualMachine)
+	VmCustomConfig, ParseError := parsers.NewCustomConfig(Deployment.Configuration)
+	if ParseError != nil {
+		return nil, ParseError
+	}
+	Job.UpdateProgress(10)
 
-		// Applying Custom Configuration, that Customer has been Specified Initially
+	// Applying Converted Configuration to the Virtual Machine Instance
 
-		VirtualMachine.Configuration.Disk = VirtualMachineCustomConfiguration.Disk
-		VirtualMachine.Configuration.Resources = VirtualMachineCustomConfiguration.Resources
-		VirtualMachine.Configuration.HostSystem = VirtualMachineCustomConfiguration.HostSystem
-		VirtualMachine.Configuration.ExtraTools.Tools = VirtualMachineCustomConfiguration.ExtraTools.Tools
-		VirtualMachine.SshInfo = VirtualMachineSshConfiguration
-		VirtualMachine.State = "Ready" // Changing Availability Status To Ready
+	VmInfo, ApplyError := Deployer.ApplyConfiguration(VirtualMachineInstance, *VmCustomConfig, Deployment)
+	if ApplyError != nil {
+		Logger.Error("Failed to Apply Configuration to the Virtual Machine", zap.Error(ApplyError))
+		return nil, ApplyError
+	}
+	Job.UpdateProgress(90)
 
-		// Saving the Object to the Database....
+	// Updating Virtual Machine ORM Object with New Info
 
-		Saved, Error := VirtualMachine.Save()
-		if Saved.Error != nil || Error != nil {
-			Logger.Error(
-				"Failed to Save Virtual Machine Database Record with Custom Configuration Resources",
-				zap.Error(Error))
-		}
+	var VirtualMachine models.VirtualMachine
+	var VirtualMachineCustomConfiguration models.VirtualMachineConfiguration
 
-		RequestContext.JSON(http.StatusOK, gin.H{"Status": "Applied",
-			"IPAddress": VmInfo.IPAddress, "SshInfo": VmInfo.SshInfo})
+	VirtualMachineSshConfiguration := models.SSHConfiguration{
+		Type:             VmInfo.SshType,
+		VirtualMachineId: Job.VirtualMachineId,
+	}
 
-	default:
-		Logger.Error("Failed to Apply Configuration to the Virtual Machine", zap.Error(ApplyError))
-		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": ApplyError})
+	switch VmInfo.SshType {
+	case models.TypeByRootCredentials:
+		var RootCredentials struct {
+			Username string `json:"Username"`
+			Password string `json:"Password"`
+		}
+		json.Unmarshal([]byte(VmInfo.SshInfo), &RootCredentials)
+		VirtualMachineSshConfiguration.SshCredentialsMethod = *models.NewSshCredentialsInfo(
+			RootCredentials.Username, RootCredentials.Password)
+
+	case models.TypeByRootCertificate:
+		var Certificate struct {
+			KeyContent []byte `json:"KeyContent"`
+			Filename   string `json:"Filename"`
+		}
+		json.Unmarshal([]byte(VmInfo.SshInfo), &Certificate)
+		VirtualMachineSshConfiguration.SshPublicKeyMethod = *models.NewSshPublicKeyInfo(
+			Certificate.KeyContent, Certificate.Filename)
 	}
 
+	json.Unmarshal(VmCustomConfig.ToJson(), &VirtualMachineCustomConfiguration)
+	models.Database.Model(&models.VirtualMachine{}).Where("id = ?", Job.VirtualMachineId).Find(&VirtualMachine)
+
+	// Applying Custom Configuration, that Customer has been Specified Initially
+
+	VirtualMachine.Configuration.Disk = VirtualMachineCustomConfiguration.Disk
+	VirtualMachine.Configuration.Resources = VirtualMachineCustomConfiguration.Resources
+	VirtualMachine.Configuration.HostSystem = VirtualMachineCustomConfiguration.HostSystem
+	VirtualMachine.Configuration.ExtraTools.Tools = VirtualMachineCustomConfiguration.ExtraTools.Tools
+	VirtualMachine.SshInfo = VirtualMachineSshConfiguration
+	VirtualMachine.DataDiskKey = VmInfo.DiskKey
+	VirtualMachine.State = models.StatusReady // Changing Availability Status To Ready
+
+	// Saving the Object to the Database....
+
+	if _, SaveError := VirtualMachine.Save(); SaveError != nil {
+		Logger.Error(
+			"Failed to Save Virtual Machine Database Record with Custom Configuration Resources",
+			zap.Error(SaveError))
+		return nil, SaveError
+	}
+	webhooks.Emit(webhooks.EventDeployed, VirtualMachine, gin.H{"JobId": Job.ID, "DeploymentId": Deployment.ID})
+	// SSH Credentials are not being Stored in the Result, since it's Readable by every Member of the Project,
+	// the Customer Receives them through the `/ssh/` Endpoints, that Require the Appropriate Role
+	return gin.H{"Status": "Applied", "DeploymentId": Deployment.ID, "IPAddress": VmInfo.IPAddress}, nil
 }
 
-func StartVirtualMachineRestController(RequestContext *gin.Context) {
-	// Rest Controller, that is Used to Start Virtual Machine Server
+func ResizeVirtualMachineRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Changes CPU, Memory and Disk Capacity of the Deployed Virtual Machine Server
+	// Only Resources, that has been Passed are going to be Changed
 
-	jwtCredentials, _ := authentication.GetCustomerJwtCredentials(RequestContext.Request.Header.Get("Authorization"))
-	VirtualMachineId := RequestContext.Query("VirtualMachineId")
-	CustomerId := jwtCredentials.UserId
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
 
-	// Updating the State of the Virtual Machine to `NotReady` in order to prevent other operations on this Virtual Machine
+	CpuNum, CpuError := strconv.ParseInt(RequestContext.DefaultPostForm("CpuNum", "0"), 10, 32)
+	Memory, MemoryError := strconv.ParseInt(RequestContext.DefaultPostForm("MemoryInMegabytes", "0"), 10, 64)
+	DiskCapacity, DiskError := strconv.ParseInt(RequestContext.DefaultPostForm("DiskCapacityInKB", "0"), 10, 64)
+
+	if CpuError != nil || MemoryError != nil || DiskError != nil || CpuNum < 0 || Memory < 0 || DiskCapacity < 0 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Resources has been Passed"})
+		return
+	}
+
+	if CpuNum == 0 && Memory == 0 && DiskCapacity == 0 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "No Resources to Change has been Passed"})
+		return
+	}
 
 	var VirtualMachine models.VirtualMachine
 	models.Database.Model(&models.VirtualMachine{}).Where(
-		"id = ?", VirtualMachineId).Find(&VirtualMachine)
+		"id = ?", RequestContext.Query("VirtualMachineId")).Scopes(models.AccessibleBy(jwtCredentials.UserId)).Find(&VirtualMachine)
 
-	VmManager := deploy.NewVirtualMachineManager(*Client.Client)
-	Vm, VmError := VmManager.GetVirtualMachine(VirtualMachineId, strconv.Itoa(CustomerId))
+	if VirtualMachine.ID == 0 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Virtual Machine Does Not Exist"})
+		return
+	}
 
-	if VmError != nil {
-		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "VM Does not Exist."})
+	// Only the Data Disk, Attached by the Deploy, can be Grown
+	if DiskCapacity != 0 && VirtualMachine.DataDiskKey == 0 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Virtual Machine does not have the Data Disk to Grow"})
+		return
 	}
-	StartedError := VmManager.StartVirtualMachine(Vm)
 
-	switch StartedError {
-	case nil:
-		RequestContext.JSON(http.StatusOK, gin.H{"Status": "Started"})
-	default:
-		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": StartedError})
+	Payload := ResizeVirtualMachinePayload{
+		CpuNum:            int32(CpuNum),
+		MemoryInMegabytes: Memory,
+		DiskCapacityInKB:  DiskCapacity,
 	}
+	EnqueueVirtualMachineJob(RequestContext, models.JobTypeResize, VirtualMachine.ID, jwtCredentials.UserId, Payload)
 }
 
-func RebootVirtualMachineRestController(RequestContext *gin.Context) {
-	// Rest Controller, that is Used to Reboot Virtual Machine Server
+func ResizeVirtualMachineJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Resizes the Virtual Machine and Updates it's Configuration
 
-	jwtCredentials, _ := authentication.GetCustomerJwtCredentials(RequestContext.Request.Header.Get("Authorization"))
-	VirtualMachineId := RequestContext.Query("VirtualMachineId")
-	CustomerId := jwtCredentials.UserId
-	NewVmManager := deploy.NewVirtualMachineManager(*Client.Client)
-	Vm, VmError := NewVmManager.GetVirtualMachine(VirtualMachineId, strconv.Itoa(CustomerId))
+	var Payload ResizeVirtualMachinePayload
+	if DecodeError := Job.DecodePayload(&Payload); DecodeError != nil {
+		return nil, DecodeError
+	}
 
-	if VmError != nil {
-		RequestContext.JSON(
-			http.StatusBadRequest, gin.H{"Error": "VM Does Not Exist."})
-		return
+	VmManager, VirtualMachineInstance, FindError := GetJobVirtualMachine(Job)
+	if FindError != nil {
+		return nil, FindError
 	}
 
-	Rebooted := NewVmManager.RebootVirtualMachine(Vm)
+	var VirtualMachine models.VirtualMachine
+	models.Database.Model(&models.VirtualMachine{}).Where("id = ?", Job.VirtualMachineId).Find(&VirtualMachine)
+	Job.UpdateProgress(10)
+
+	// Resources can't exceed the Limits, Specified in the Configuration of the Virtual Machine
+	ResizeSpec := deploy.NewVirtualMachineResizeSpec(Payload.CpuNum, Payload.MemoryInMegabytes, Payload.DiskCapacityInKB)
+	ResizeSpec.MaxCpuUsage = VirtualMachine.Configuration.Resources.MaxCpuUsage
+	ResizeSpec.MaxMemoryUsage = VirtualMachine.Configuration.Resources.MaxMemoryUsage
+	ResizeSpec.MaxStorageCapacity = VirtualMachine.Configuration.Resources.MaxStorageCapacity
+	ResizeSpec.DiskKey = VirtualMachine.DataDiskKey
+
+	PowerCycled, ResizeError := VmManager.ResizeVirtualMachine(VirtualMachineInstance, *ResizeSpec)
+	if ResizeError != nil {
+		return nil, ResizeError
+	}
+	Job.UpdateProgress(90)
 
-	switch Rebooted {
-	case true:
-		RequestContext.JSON(http.StatusOK, gin.H{"Status": "Rebooted"})
-	case false:
-		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": VmError})
+	if Payload.CpuNum != 0 {
+		VirtualMachine.Configuration.Resources.CpuNum = Payload.CpuNum
+	}
+	if Payload.MemoryInMegabytes != 0 {
+		VirtualMachine.Configuration.Resources.MemoryInMegabytes = Payload.MemoryInMegabytes
 	}
+	if Payload.DiskCapacityInKB != 0 {
+		VirtualMachine.Configuration.Disk.CapacityInKB = int(Payload.DiskCapacityInKB)
+	}
+
+	if _, SaveError := VirtualMachine.Save(); SaveError != nil {
+		Logger.Error("Failed to Save Resized Virtual Machine Configuration", zap.Error(SaveError))
+		return nil, SaveError
+	}
+	webhooks.Emit(webhooks.EventResized, VirtualMachine, gin.H{"JobId": Job.ID, "PowerCycled": PowerCycled})
+	return gin.H{"Status": "Resized", "PowerCycled": PowerCycled}, nil
 }
 
-func ShutdownVirtualMachineRestController(RequestContext *gin.Context) {
+func CloneVirtualMachineRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Creates a Copy of the Existing Virtual Machine Server
+	// The Copy Receives it's own IP Address and Hostname and Belongs to the same Project
 
-	// Rest Controller, that Is Used to Shutdown Virtual Machine Server
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
 
-	VirtualMachineId := RequestContext.Query("VirtualMachineId")
-	CustomerId := RequestContext.Query("CustomerId")
-	NewVmManager := deploy.NewVirtualMachineManager(*Client.Client)
+	Linked, ParseError := strconv.ParseBool(RequestContext.DefaultPostForm("Linked", "false"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Linked Flag has been Passed"})
+		return
+	}
 
-	Vm, FindError := NewVmManager.GetVirtualMachine(VirtualMachineId, CustomerId)
+	Payload := CloneVirtualMachinePayload{
+		VirtualMachineName: RequestContext.PostForm("VirtualMachineName"),
+		Linked:             Linked,
+		DatastoreItemPath:  RequestContext.PostForm("DatastoreItemPath"),
+		FolderItemPath:     RequestContext.PostForm("FolderItemPath"),
+		IP:                 RequestContext.PostForm("IP"),
+		Netmask:            RequestContext.PostForm("Netmask"),
+		Gateway:            RequestContext.PostForm("Gateway"),
+		Hostname:           RequestContext.PostForm("Hostname"),
+	}
 
-	if FindError != nil {
-		RequestContext.AbortWithStatusJSON(
-			http.StatusBadRequest, gin.H{"Error": "Server does Not Exist"})
+	if len(Payload.VirtualMachineName) == 0 || len(Payload.IP) == 0 || len(Payload.Hostname) == 0 {
+		RequestContext.JSON(http.StatusBadRequest,
+			gin.H{"Error": "Virtual Machine Name, IP Address and Hostname are Required"})
+		return
+	}
+	IPAddress := network.NewVirtualMachineIPAddress(Payload.IP, Payload.Netmask, Payload.Gateway, Payload.Hostname)
+	if ValidationError := IPAddress.Validate(); ValidationError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": ValidationError.Error()})
 		return
 	}
 
-	StartedError := NewVmManager.StartVirtualMachine(Vm)
-	switch {
-	case StartedError != nil:
-		RequestContext.JSON(http.StatusBadGateway,
-			gin.H{"Error": fmt.Sprintf("Failed to Start the Server, %s", StartedError)})
+	var VirtualMachine models.VirtualMachine
+	models.Database.Model(&models.VirtualMachine{}).Where(
+		"id = ?", RequestContext.Query("VirtualMachineId")).Scopes(models.AccessibleBy(jwtCredentials.UserId)).Find(&VirtualMachine)
 
-	case StartedError == nil:
-		RequestContext.JSON(http.StatusCreated, gin.H{"Operation": "Success"})
+	if VirtualMachine.ID == 0 {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Virtual Machine Does Not Exist"})
+		return
 	}
+	EnqueueVirtualMachineJob(RequestContext, models.JobTypeClone, VirtualMachine.ID, jwtCredentials.UserId, Payload)
 }
 
-func RemoveVirtualMachineRestController(RequestContext *gin.Context) {
-	// Rest Controller, that is Used for Destroying Virtual machines...
+func CloneVirtualMachineJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Clones the Virtual Machine and Records the Copy in the Database
 
-	jwtCredentials, _ := authentication.GetCustomerJwtCredentials(
-		RequestContext.Request.Header.Get("jwt-token"))
+	var Payload CloneVirtualMachinePayload
+	if DecodeError := Job.DecodePayload(&Payload); DecodeError != nil {
+		return nil, DecodeError
+	}
 
-	VirtualMachineId := RequestContext.Query("VirtualMachineId")
-	CustomerId := jwtCredentials.UserId
+	// The Copy is being Created at the Region of the Source Virtual Machine
+	VmManager, SourceVirtualMachine, FindError := GetJobVirtualMachine(Job)
+	if FindError != nil {
+		return nil, FindError
+	}
+	Client := &VmManager.VimClient
 
-	NewVmManager := deploy.NewVirtualMachineManager(*Client.Client)
-	Vm, FindError := NewVmManager.GetVirtualMachine(VirtualMachineId, strconv.Itoa(CustomerId))
+	// Receiving Target Location of the Copy, if it has been Specified, it should be within the Datacenter of the Source
 
-	if FindError != nil {
-		RequestContext.AbortWithStatusJSON(
-			http.StatusBadRequest, gin.H{"Error": "Server does Not Exist"})
-		return
+	var Datastore *object.Datastore
+	if len(Payload.DatastoreItemPath) != 0 {
+		DatastoreRef, DatastoreError := VmManager.FindClonePlacement(SourceVirtualMachine, Payload.DatastoreItemPath, "Datastore")
+		if DatastoreError != nil {
+			return nil, DatastoreError
+		}
+		Datastore = object.NewDatastore(Client, *DatastoreRef)
 	}
 
-	Started, StartedError := NewVmManager.DestroyVirtualMachine(Vm)
+	var Folder *object.Folder
+	if len(Payload.FolderItemPath) != 0 {
+		FolderRef, FolderError := VmManager.FindClonePlacement(SourceVirtualMachine, Payload.FolderItemPath, "Folder")
+		if FolderError != nil {
+			return nil, FolderError
+		}
+		Folder = object.NewFolder(Client, *FolderRef)
+	}
+	Job.UpdateProgress(10)
 
-	switch {
-	case StartedError != nil || Started != true:
-		RequestContext.JSON(http.StatusBadGateway,
-			gin.H{"Error": fmt.Sprintf("Failed to Start the Server, %s", StartedError)})
+	IPAddress := network.NewVirtualMachineIPAddress(Payload.IP, Payload.Netmask, Payload.Gateway, Payload.Hostname)
+	CloneSpec := deploy.NewVirtualMachineCloneSpec(Payload.VirtualMachineName, Datastore, Folder, Payload.Linked, *IPAddress)
 
-	case StartedError == nil && Started:
+	Clone, CloneError := VmManager.ReplicateVirtualMachine(SourceVirtualMachine, *CloneSpec)
+	if CloneError != nil {
+		return nil, CloneError
+	}
+	Job.UpdateProgress(80)
+
+	// Recording the Copy as a new Virtual Machine of the same Project
+	// The Copy Inherits Configuration and SSH Credentials of the Source, as the Disks are the same
 
-		var VirtualMachine models.VirtualMachine
-		models.Database.Model(&models.VirtualMachine{}).Where(
+	var SourceVirtualMachineObj models.VirtualMachine
+	models.Database.Model(&models.VirtualMachine{}).Where("id = ?", Job.VirtualMachineId).Find(&SourceVirtualMachineObj)
 
-			"id = ?", VirtualMachineId).Find(&VirtualMachine)
-		Deleted, Error := VirtualMachine.Delete()
+	Configuration := SourceVirtualMachineObj.Configuration
+	Configuration.Metadata.VirtualMachineName = Payload.VirtualMachineName
 
-		if Error != nil {
-			Deleted.Rollback()
-			Logger.Error(
-				"Failed to Delete Virtual Machine Object with ID: `%s`, Error: %s",
-				zap.String("Virtual Machine ID", VirtualMachineId), zap.Error(Error))
+	NewVirtualMachine := models.NewVirtualMachine(
+		Job.OwnerId,
+		Payload.VirtualMachineName,
+		&SourceVirtualMachineObj.SshInfo,
+		Clone.InventoryPath,
+		Payload.IP,
+		&Configuration,
+	)
+	NewVirtualMachine.State = SourceVirtualMachineObj.State
+	NewVirtualMachine.ProjectId = SourceVirtualMachineObj.ProjectId
+	NewVirtualMachine.Region = SourceVirtualMachineObj.Region
+
+	if _, CreationError := NewVirtualMachine.Create(); CreationError != nil {
+		Logger.Error("Failed to Create Database Record for the Cloned Virtual Machine", zap.Error(CreationError))
+		// The Copy is not Managed without the Record, so it's being Destroyed instead of being Left Orphaned
+		if DiscardError := VmManager.DiscardVirtualMachine(Clone); DiscardError != nil {
+			Logger.Error("Failed to Destroy Unrecorded Copy of the Virtual Machine",
+				zap.String("ItemPath", Clone.InventoryPath), zap.Error(DiscardError))
 		}
-		RequestContext.JSON(http.StatusCreated, gin.H{"Operation": "Success"})
+		return nil, CreationError
+	}
+	webhooks.Emit(webhooks.EventCreated, *NewVirtualMachine, gin.H{"JobId": Job.ID, "SourceVirtualMachineId": Job.VirtualMachineId})
+	return gin.H{"Status": "Cloned", "VirtualMachineId": NewVirtualMachine.ID, "IPAddress": Payload.IP}, nil
+}
+
+func GetJobVirtualMachine(Job *models.Job) (*deploy.VirtualMachineManager, *object.VirtualMachine, error) {
+	// Returns API Instance of the Virtual Machine, the Job is Associated with, using the Client of it's Region
+	Client, ClientError := vsphere.Registry.GetClient(models.GetVirtualMachineRegion(Job.VirtualMachineId))
+	if ClientError != nil {
+		return nil, nil, ClientError
+	}
+	VmManager := deploy.NewVirtualMachineManager(*Client.Client)
+	VmManager.EventData["JobId"] = Job.ID
+	VirtualMachine, FindError := VmManager.GetVirtualMachine(strconv.Itoa(Job.VirtualMachineId))
+	return VmManager, VirtualMachine, FindError
+}
+
+func StartVirtualMachineRestController(RequestContext *gin.Context) {
+	// Rest Controller, that is Used to Start Virtual Machine Server
+	EnqueueVirtualMachineOperationJob(RequestContext, models.JobTypeStart)
+}
+
+func StartVirtualMachineJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Starts Virtual Machine Server
+	VmManager, Vm, VmError := GetJobVirtualMachine(Job)
+	if VmError != nil {
+		return nil, VmError
+	}
+	if StartedError := VmManager.StartVirtualMachine(Vm); StartedError != nil {
+		return nil, StartedError
 	}
+	return gin.H{"Status": "Started"}, nil
+}
+
+func RebootVirtualMachineRestController(RequestContext *gin.Context) {
+	// Rest Controller, that is Used to Reboot Virtual Machine Server
+	EnqueueVirtualMachineOperationJob(RequestContext, models.JobTypeReboot)
+}
+
+func RebootVirtualMachineJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Reboots Virtual Machine Server
+	VmManager, Vm, VmError := GetJobVirtualMachine(Job)
+	if VmError != nil {
+		return nil, VmError
+	}
+	if Rebooted := VmManager.RebootVirtualMachine(Vm); !Rebooted {
+		return nil, errors.New("Failed to Reboot Virtual Machine")
+	}
+	return gin.H{"Status": "Rebooted"}, nil
+}
+
+func ShutdownVirtualMachineRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Is Used to Shutdown Virtual Machine Server
+	EnqueueVirtualMachineOperationJob(RequestContext, models.JobTypeShutdown)
+}
+
+func ShutdownVirtualMachineJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Shuts Down Virtual Machine Server
+	VmManager, Vm, VmError := GetJobVirtualMachine(Job)
+	if VmError != nil {
+		return nil, VmError
+	}
+	if ShutdownError := VmManager.ShutdownVirtualMachine(Vm); ShutdownError != nil {
+		return nil, ShutdownError
+	}
+	return gin.H{"Status": "Shutdown"}, nil
+}
+
+func RemoveVirtualMachineRestController(RequestContext *gin.Context) {
+	// Rest Controller, that is Used for Destroying Virtual machines...
+	EnqueueVirtualMachineOperationJob(RequestContext, models.JobTypeRemove)
+}
+
+func RemoveVirtualMachineJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Destroys Virtual Machine Server and Removes its Database Record
+	VmManager, Vm, VmError := GetJobVirtualMachine(Job)
+	if VmError != nil {
+		return nil, VmError
+	}
+
+	Destroyed, DestroyError := VmManager.DestroyVirtualMachine(Vm)
+	if DestroyError != nil {
+		return nil, DestroyError
+	}
+	if !Destroyed {
+		return nil, errors.New("Failed to Destroy Virtual Machine")
+	}
+	Job.UpdateProgress(80)
+
+	var VirtualMachine models.VirtualMachine
+	models.Database.Model(&models.VirtualMachine{}).Where(
+		"id = ?", Job.VirtualMachineId).Find(&VirtualMachine)
+
+	if _, DeleteError := VirtualMachine.Delete(); DeleteError != nil {
+		Logger.Error(
+			"Failed to Delete Virtual Machine Object",
+			zap.Int("Virtual Machine ID", Job.VirtualMachineId), zap.Error(DeleteError))
+		return nil, DeleteError
+	}
+
+	// Snapshots have been Destroyed along with the Virtual Machine
+	models.Database.Where("virtual_machine_id = ?", Job.VirtualMachineId).Delete(&models.Snapshot{})
+	return gin.H{"Status": "Removed"}, nil
 }
 
 func RebootGuestOSRestController(RequestContext *gin.Context) {
 	// Rest Controller, that allows to Reboot Operational System of the Virtual Machine
-	jwtCredentials, _ := authentication.GetCustomerJwtCredentials(RequestContext.Request.Header.Get("jwt-token"))
-	VirtualMachineId := RequestContext.Query("VirtualMachineId")
-	VmOwnerId := jwtCredentials.UserId
+	EnqueueVirtualMachineOperationJob(RequestContext, models.JobTypeRebootGuestOS)
+}
 
-	VmManager := deploy.NewVirtualMachineManager(*Client.Client)
-	VirtualMachine, FindError := VmManager.GetVirtualMachine(VirtualMachineId, strconv.Itoa(VmOwnerId))
+func RebootGuestOSJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Reboots Operational System of the Virtual Machine
+	_, VirtualMachine, FindError := GetJobVirtualMachine(Job)
 	if FindError != nil {
-		RequestContext.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"Error": "Virtual Machine Server not found"})
-		return
+		return nil, FindError
 	}
 	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*20)
 	defer CancelFunc()
 
-	RebootedError := VirtualMachine.RebootGuest(TimeoutContext)
-	if RebootedError != nil {
-		Logger.Error("Failed to Reboot OS on Virtual Machine Server, Error: %s", zap.Error(RebootedError))
-		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Reboot Operational System"})
-		return
+	if RebootedError := VirtualMachine.RebootGuest(TimeoutContext); RebootedError != nil {
+		Logger.Error("Failed to Reboot OS on Virtual Machine Server", zap.Error(RebootedError))
+		return nil, RebootedError
 	}
-	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Rebooted"})
+	webhooks.EmitVirtualMachine(webhooks.EventRebooted, Job.VirtualMachineId, gin.H{"JobId": Job.ID, "GuestOS": true})
+	return gin.H{"Status": "Rebooted"}, nil
 }
 
 func StartGuestOSRestController(RequestContext *gin.Context) {
 	// Rest Controller, that allows to Start Operational System on the Virtual machine
+	EnqueueVirtualMachineOperationJob(RequestContext, models.JobTypeStartGuestOS)
+}
 
-	jwtCredentials, _ := authentication.GetCustomerJwtCredentials(RequestContext.Request.Header.Get("Authorization"))
-	VirtualMachineId := RequestContext.Query("VirtualMachineId")
-	VmOwnerId := jwtCredentials.UserId
-
-	VmManager := deploy.NewVirtualMachineManager(*Client.Client)
-	VirtualMachine, FindError := VmManager.GetVirtualMachine(VirtualMachineId, strconv.Itoa(VmOwnerId))
+func StartGuestOSJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Starts Operational System on the Virtual Machine
+	VmManager, VirtualMachine, FindError := GetJobVirtualMachine(Job)
 	if FindError != nil {
-		RequestContext.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"Error": "Virtual Machine Server not found"})
-		return
+		return nil, FindError
 	}
-	// Starting Virtual Machine...
-	RebootedError := VmManager.StartVirtualMachine(VirtualMachine)
-	if RebootedError != nil {
-		Logger.Error("Failed to Reboot OS on Virtual Machine Server, Error: %s", zap.Error(RebootedError))
-		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to Reboot Operational System"})
-		return
+	VmManager.EventData["GuestOS"] = true
+	if StartedError := VmManager.StartVirtualMachine(VirtualMachine); StartedError != nil {
+		Logger.Error("Failed to Start OS on Virtual Machine Server", zap.Error(StartedError))
+		return nil, StartedError
 	}
-	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Started"})
+	return gin.H{"Status": "Started"}, nil
 }
 
 func ShutdownGuestOsRestController(RequestContext *gin.Context) {
 	// Rest Controller, that allows to Shutdown Operational System on the Virtual Machine
-	jwtCredentials, _ := authentication.GetCustomerJwtCredentials(RequestContext.Request.Header.Get("Authorization"))
-	VirtualMachineId := RequestContext.Query("VirtualMachineId")
-	VmOwnerId := jwtCredentials.UserId
+	EnqueueVirtualMachineOperationJob(RequestContext, models.JobTypeShutdownGuestOS)
+}
 
-	VmManager := deploy.NewVirtualMachineManager(*Client.Client)
-	VirtualMachine, FindError := VmManager.GetVirtualMachine(VirtualMachineId, strconv.Itoa(VmOwnerId))
+func ShutdownGuestOSJobHandler(Job *models.Job) (interface{}, error) {
+	// Job Handler, that Shuts Down Operational System on the Virtual Machine
+	_, VirtualMachine, FindError := GetJobVirtualMachine(Job)
 	if FindError != nil {
-		RequestContext.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"Error": "Virtual Machine Server not found"})
-		return
+		return nil, FindError
 	}
 	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), time.Second*20)
 	defer CancelFunc()
 
-	RebootedError := VirtualMachine.ShutdownGuest(TimeoutContext)
-	if RebootedError != nil {
-		Logger.Error("Failed to Shutdown OS on Virtual Machine Server, Error: %s", zap.Error(RebootedError))
-		RequestContext.JSON(http.StatusBadGateway, gin.H{"Error": "Failed to shutdown Operational System"})
-		return
+	if ShutdownError := VirtualMachine.ShutdownGuest(TimeoutContext); ShutdownError != nil {
+		Logger.Error("Failed to Shutdown OS on Virtual Machine Server", zap.Error(ShutdownError))
+		return nil, ShutdownError
 	}
-	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Shutdowned"})
+	webhooks.EmitVirtualMachine(webhooks.EventStopped, Job.VirtualMachineId, gin.H{"JobId": Job.ID, "GuestOS": true})
+	return gin.H{"Status": "Shutdowned"}, nil
 }
@@ -0,0 +1,636 @@
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/metrics"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Pools of the vSphere Sessions (SOAP and REST / vAPI), one per Region, Shared by all the Packages
+// Sessions are being Kept Alive in the Background and Logged in again, once the vSphere Forgets them
+// The Circuit Breaker of every Region Tracks the Availability of it's vSphere, so the Requests can be Rejected without Dialing it,
+// and the Unavailable Region does not Affect the other ones
+
+var (
+	Logger *zap.Logger
+)
+
+var (
+	ConnectTimeout = 10 * time.Second
+)
+
+var (
+	ErrUnavailable   = errors.New("vSphere Is Currently Not Available, Try a bit Later")
+	ErrUnknownRegion = errors.New("Region Does Not Exist")
+)
+
+var (
+	Registry *EndpointRegistry
+)
+
+var (
+	// Requests to the vSphere by the Region, API (soap or rest) and the Method
+	Requests        = metrics.NewCounterVec("vsphere_requests_total", "Number of the Requests to the vSphere", "region", "api", "method")
+	RequestErrors   = metrics.NewCounterVec("vsphere_request_errors_total", "Number of the Failed Requests to the vSphere", "region", "api", "method")
+	RequestDuration = metrics.NewHistogramVec("vsphere_request_duration_seconds", "Latency of the Requests to the vSphere, including Re-Logins",
+		metrics.DefaultBuckets, "region", "api", "method")
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("VsphereLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+	metrics.Register(Requests, RequestErrors, RequestDuration)
+}
+
+func ObserveRequest(Region string, Api string, Method string, StartedAt time.Time, Failed bool) {
+	Requests.Inc(Region, Api, Method)
+	if Failed {
+		RequestErrors.Inc(Region, Api, Method)
+	}
+	RequestDuration.Observe(time.Since(StartedAt).Seconds(), Region, Api, Method)
+}
+
+func GetSoapMethod(Request soap.HasFault) string {
+	// Returns Name of the SOAP Method, e.g `RetrievePropertiesEx` for the `*methods.RetrievePropertiesExBody`
+	return strings.TrimSuffix(reflect.TypeOf(Request).Elem().Name(), "Body")
+}
+
+func Configure(Config config.VsphereConfig) {
+	// Creates the Session Pools of the Endpoints, the First one Serves the Default Region
+	Endpoints := make([]Endpoint, 0, len(Config.Endpoints))
+	for _, Endpoint := range Config.Endpoints {
+		Endpoints = append(Endpoints, NewEndpoint(Endpoint))
+	}
+	Registry = NewEndpointRegistry(Endpoints, Config.KeepAliveInterval, Config.BreakerFailures, Config.BreakerCooldown)
+}
+
+// Endpoints
+
+type Endpoint struct {
+	// vSphere Endpoint (vCenter), that Serves the Virtual Machines of the Region
+	Name     string
+	Region   string
+	Host     string
+	Username string `json:"-"`
+	Password string `json:"-"`
+}
+
+func NewEndpoint(Config config.EndpointConfig) Endpoint {
+	return Endpoint{
+		Name:     Config.Name,
+		Region:   Config.Region,
+		Host:     Config.Host,
+		Username: Config.Username,
+		Password: Config.Password,
+	}
+}
+
+func (this Endpoint) GetURL() *url.URL {
+	// Returns SDK URL of the Endpoint
+	return &url.URL{
+		Scheme: "https",
+		Path:   "/sdk/",
+		Host:   this.Host,
+		User:   url.UserPassword(this.Username, this.Password),
+	}
+}
+
+// Circuit Breaker
+
+const (
+	StateClosed   = "Closed"   // vSphere is Available, Requests are being Passed
+	StateOpen     = "Open"     // vSphere has Failed too many times, Requests are being Rejected
+	StateHalfOpen = "HalfOpen" // Cooldown has Passed, single Probe Request is being Passed to Check the vSphere
+)
+
+type CircuitBreaker struct {
+	// Tracks Failures of the Requests to the vSphere, Opens after the Number of the Failures in a row
+	Mutex     sync.Mutex
+	State     string
+	Failures  int
+	Threshold int
+	Cooldown  time.Duration
+	OpenedAt  time.Time
+	ProbedAt  time.Time // Time, the Probe Request has been Passed in the Half Open State
+	Now       func() time.Time
+}
+
+func NewCircuitBreaker(Threshold int, Cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		State:     StateClosed,
+		Threshold: Threshold,
+		Cooldown:  Cooldown,
+		Now:       time.Now,
+	}
+}
+
+func (this *CircuitBreaker) GetState() string {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	if this.State == StateOpen && this.Now().Sub(this.OpenedAt) >= this.Cooldown {
+		return StateHalfOpen
+	}
+	return this.State
+}
+
+func (this *CircuitBreaker) Allow() bool {
+	// Returns true, if the Request can be Passed to the vSphere
+	// Once the Cooldown has Passed, only one Probe Request is being Passed, until it Succeeds or Fails
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+
+	Now := this.Now()
+	switch this.State {
+	case StateClosed:
+		return true
+	case StateOpen:
+		if Now.Sub(this.OpenedAt) < this.Cooldown {
+			return false
+		}
+		this.State = StateHalfOpen
+	case StateHalfOpen:
+		// The Probe has not Reported back, Letting the next one through
+		if Now.Sub(this.ProbedAt) < this.Cooldown {
+			return false
+		}
+	}
+	this.ProbedAt = Now
+	return true
+}
+
+func (this *CircuitBreaker) RecordSuccess() {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	if this.State != StateClosed {
+		Logger.Info("vSphere Circuit Breaker has been Closed")
+	}
+	this.State = StateClosed
+	this.Failures = 0
+}
+
+func (this *CircuitBreaker) RecordFailure() {
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.Failures++
+	if this.State == StateHalfOpen || this.Failures >= this.Threshold {
+		if this.State == StateClosed {
+			Logger.Warn("vSphere Circuit Breaker has been Opened", zap.Int("Failures", this.Failures))
+		}
+		this.State = StateOpen
+		this.OpenedAt = this.Now()
+	}
+}
+
+// Session Pool
+
+func IsNotAuthenticated(Error error) bool {
+	// Returns true, if the vSphere has Rejected the Request, because the Session has Expired
+	if soap.IsSoapFault(Error) {
+		_, NotAuthenticated := soap.ToSoapFault(Error).VimFault().(types.NotAuthenticated)
+		return NotAuthenticated
+	}
+	if soap.IsVimFault(Error) {
+		_, NotAuthenticated := soap.ToVimFault(Error).(*types.NotAuthenticated)
+		return NotAuthenticated
+	}
+	return false
+}
+
+func IsConnectionFailure(Error error) bool {
+	// Returns true, if the Request has not Reached the vSphere, Faults mean, that the vSphere is Available
+	if Error == nil || soap.IsSoapFault(Error) || soap.IsVimFault(Error) {
+		return false
+	}
+	return !errors.Is(Error, context.Canceled)
+}
+
+func IsSessionRequest(Request soap.HasFault) bool {
+	switch Request.(type) {
+	case *methods.LoginBody, *methods.LoginByTokenBody, *methods.LogoutBody:
+		return true
+	default:
+		return false
+	}
+}
+
+type SessionRoundTripper struct {
+	// SOAP Round Tripper, that Logs in again and Repeats the Request, once the Session has Expired
+	RoundTripper soap.RoundTripper
+	Pool         *SessionPool
+}
+
+func (this *SessionRoundTripper) RoundTrip(Context context.Context, Request, Response soap.HasFault) (Error error) {
+	defer func(StartedAt time.Time) {
+		ObserveRequest(this.Pool.Endpoint.Region, "soap", GetSoapMethod(Request), StartedAt, Error != nil)
+	}(time.Now())
+
+	Generation := this.Pool.GetGeneration()
+	Error = this.RoundTripper.RoundTrip(Context, Request, Response)
+
+	if IsNotAuthenticated(Error) && !IsSessionRequest(Request) {
+		if LoginError := this.Pool.Relogin(Context, Generation); LoginError != nil {
+			Logger.Error("Failed to Login to the vSphere again", zap.Error(LoginError))
+			return Error
+		}
+		// The Fault of the first Attempt is not being Overwritten by the Successful Response
+		Body := reflect.ValueOf(Response).Elem()
+		Body.Set(reflect.Zero(Body.Type()))
+		Error = this.RoundTripper.RoundTrip(Context, Request, Response)
+	}
+	this.Pool.Record(Error)
+	return Error
+}
+
+type RestRoundTripper struct {
+	// REST Round Tripper, that Logs in again and Repeats the Request, once the vAPI Session has Expired
+	Transport http.RoundTripper
+	Pool      *SessionPool
+}
+
+func (this *RestRoundTripper) RoundTrip(Request *http.Request) (*http.Response, error) {
+	StartedAt := time.Now()
+	Response, Error := this.RoundTripSession(Request)
+	ObserveRequest(this.Pool.Endpoint.Region, "rest", Request.Method, StartedAt,
+		Error != nil || Response.StatusCode >= http.StatusInternalServerError)
+	return Response, Error
+}
+
+func (this *RestRoundTripper) RoundTripSession(Request *http.Request) (*http.Response, error) {
+	Response, Error := this.Transport.RoundTrip(Request)
+	switch {
+	case Error != nil:
+		this.Pool.Record(Error)
+		return nil, Error
+	case Response.StatusCode != http.StatusUnauthorized:
+		this.Pool.Record(nil)
+		return Response, nil
+	case strings.HasSuffix(Request.URL.Path, "/session"):
+		return Response, nil
+	case Request.Body != nil && Request.GetBody == nil:
+		return Response, nil // The Body can't be Sent twice
+	}
+
+	SessionId := Request.Header.Get("vmware-api-session-id")
+	if LoginError := this.Pool.ReloginRest(Request.Context(), SessionId); LoginError != nil {
+		Logger.Error("Failed to Login to the vSphere REST API again", zap.Error(LoginError))
+		return Response, nil
+	}
+	Response.Body.Close()
+
+	Repeated := Request.Clone(Request.Context())
+	if Request.GetBody != nil {
+		if Repeated.Body, Error = Request.GetBody(); Error != nil {
+			return nil, Error
+		}
+	}
+	Repeated.Header.Set("vmware-api-session-id", this.Pool.RestClient.SessionID())
+	Response, Error = this.Transport.RoundTrip(Repeated)
+	this.Pool.Record(Error)
+	return Response, Error
+}
+
+type SessionPool struct {
+	// Pool of the vSphere Sessions, the Clients are being Created once and stay the same after the Re-Logins,
+	// so they can be Safely Stored by the Callers
+
+	Endpoint  Endpoint
+	URL       *url.URL
+	Interval  time.Duration
+	Breaker   *CircuitBreaker
+	Insecure  bool
+	LastUsed  time.Time
+	Mutex     sync.RWMutex
+	LoginLock sync.Mutex
+
+	Client     *govmomi.Client
+	RestClient *rest.Client
+	Generation int // Increased with every Re-Login of the SOAP Session
+
+	Stopped chan struct{}
+	Group   sync.WaitGroup
+}
+
+func NewSessionPool(URL *url.URL, Interval time.Duration, Breaker *CircuitBreaker) *SessionPool {
+	return &SessionPool{
+		URL:      URL,
+		Interval: Interval,
+		Breaker:  Breaker,
+		Stopped:  make(chan struct{}),
+	}
+}
+
+func (this *SessionPool) Connect(Context context.Context) error {
+	// Creates the Clients and Logs in, the Round Trippers of the Clients are being Wrapped,
+	// so the Expired Sessions are being Restored Transparently
+
+	SoapClient := soap.NewClient(this.URL, this.Insecure)
+	VimClient, ConnectionError := vim25.NewClient(Context, SoapClient)
+	if ConnectionError != nil {
+		this.Record(ConnectionError)
+		return ConnectionError
+	}
+	VimClient.RoundTripper = &SessionRoundTripper{RoundTripper: VimClient.RoundTripper, Pool: this}
+	Client := &govmomi.Client{Client: VimClient, SessionManager: session.NewManager(VimClient)}
+
+	if LoginError := Client.Login(Context, this.URL.User); LoginError != nil {
+		return LoginError
+	}
+
+	RestClient := rest.NewClient(VimClient)
+	RestClient.Transport = &RestRoundTripper{Transport: RestClient.Transport, Pool: this}
+	if LoginError := RestClient.Login(Context, this.URL.User); LoginError != nil {
+		// vAPI is not being Exposed by the Standalone ESXi Hosts, so SOAP Session is Enough
+		Logger.Warn("Failed to Login to the vSphere REST API", zap.Error(LoginError))
+	}
+
+	this.Mutex.Lock()
+	defer this.Mutex.Unlock()
+	this.Client = Client
+	this.RestClient = RestClient
+	this.LastUsed = time.Now()
+	Logger.Info("vSphere Session has been Established", zap.String("Region", this.Endpoint.Region), zap.String("Host", this.URL.Host))
+	return nil
+}
+
+func (this *SessionPool) GetClient() (*govmomi.Client, error) {
+	// Returns SOAP Client of the vSphere, if it is Available
+	this.Mutex.RLock()
+	defer this.Mutex.RUnlock()
+	if this.Client == nil || this.Breaker.GetState() == StateOpen {
+		return nil, ErrUnavailable
+	}
+	return this.Client, nil
+}
+
+func (this *SessionPool) GetRestClient() (*rest.Client, error) {
+	// Returns REST / vAPI Client of the vSphere, if it is Available
+	this.Mutex.RLock()
+	defer this.Mutex.RUnlock()
+	if this.RestClient == nil || this.Breaker.GetState() == StateOpen {
+		return nil, ErrUnavailable
+	}
+	return this.RestClient, nil
+}
+
+func (this *SessionPool) IsAvailable() bool {
+	// Returns true, if the Request to the vSphere can be Performed, does not Dial the vSphere
+	this.Mutex.RLock()
+	Connected := this.Client != nil
+	this.Mutex.RUnlock()
+	return Connected && this.Breaker.Allow()
+}
+
+func (this *SessionPool) GetGeneration() int {
+	this.Mutex.RLock()
+	defer this.Mutex.RUnlock()
+	return this.Generation
+}
+
+func (this *SessionPool) Relogin(Context context.Context, Generation int) error {
+	// Logs in to the vSphere again, unless the other Request has already done it after the Generation passed
+	this.LoginLock.Lock()
+	defer this.LoginLock.Unlock()
+
+	this.Mutex.RLock()
+	Client, Current := this.Client, this.Generation
+	this.Mutex.RUnlock()
+
+	if Current != Generation {
+		return nil
+	}
+	if LoginError := Client.Login(Context, this.URL.User); LoginError != nil {
+		return LoginError
+	}
+	this.Mutex.Lock()
+	this.Generation++
+	this.Mutex.Unlock()
+	Logger.Info("vSphere Session has been Restored", zap.String("Region", this.Endpoint.Region))
+	return nil
+}
+
+func (this *SessionPool) ReloginRest(Context context.Context, SessionId string) error {
+	// Logs in to the vSphere REST API again, unless the other Request has already done it
+	this.LoginLock.Lock()
+	defer this.LoginLock.Unlock()
+
+	if this.RestClient.SessionID() != SessionId {
+		return nil
+	}
+	return this.RestClient.Login(Context, this.URL.User)
+}
+
+func (this *SessionPool) Record(Error error) {
+	// Reports the Result of the Request to the Circuit Breaker
+	if IsConnectionFailure(Error) {
+		this.Breaker.RecordFailure()
+		return
+	}
+	if Error == nil || !errors.Is(Error, context.Canceled) {
+		this.Breaker.RecordSuccess()
+		this.Mutex.Lock()
+		this.LastUsed = time.Now()
+		this.Mutex.Unlock()
+	}
+}
+
+func (this *SessionPool) KeepAlive(Context context.Context) error {
+	// Sends the Request to the vSphere, so the Sessions do not Expire, Connects, if it has not been done yet
+	this.Mutex.RLock()
+	Client, RestClient := this.Client, this.RestClient
+	this.Mutex.RUnlock()
+
+	if Client == nil {
+		return this.Connect(Context)
+	}
+	if _, TimeError := methods.GetCurrentTime(Context, Client.Client); TimeError != nil {
+		return TimeError
+	}
+	if Session, SessionError := RestClient.Session(Context); SessionError == nil && Session == nil {
+		return this.ReloginRest(Context, RestClient.SessionID())
+	}
+	return nil
+}
+
+func (this *SessionPool) Start() {
+	// Starts Keep Alive Loop in the Background
+	// Idle Sessions are being Refreshed every Interval, Unavailable vSphere is being Probed every Cooldown
+
+	this.Group.Add(1)
+	go func() {
+		defer this.Group.Done()
+		Ticker := time.NewTicker(this.Breaker.Cooldown)
+		defer Ticker.Stop()
+
+		for {
+			this.Mutex.RLock()
+			Idle := time.Since(this.LastUsed) >= this.Interval || this.Client == nil
+			this.Mutex.RUnlock()
+
+			if Idle || this.Breaker.GetState() != StateClosed {
+				TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), ConnectTimeout)
+				if KeepAliveError := this.KeepAlive(TimeoutContext); KeepAliveError != nil {
+					Logger.Error("Failed to Keep vSphere Session Alive",
+						zap.String("Region", this.Endpoint.Region), zap.Error(KeepAliveError))
+				}
+				CancelFunc()
+			}
+			select {
+			case <-this.Stopped:
+				return
+			case <-Ticker.C:
+			}
+		}
+	}()
+	Logger.Info("vSphere Session Pool has been Started",
+		zap.String("Region", this.Endpoint.Region), zap.Duration("Interval", this.Interval))
+}
+
+func (this *SessionPool) Stop() {
+	// Stops Keep Alive Loop and Logs out of the vSphere
+	close(this.Stopped)
+	this.Group.Wait()
+
+	this.Mutex.RLock()
+	Client, RestClient := this.Client, this.RestClient
+	this.Mutex.RUnlock()
+	if Client == nil {
+		return
+	}
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), ConnectTimeout)
+	defer CancelFunc()
+	RestClient.Logout(TimeoutContext)
+	Client.Logout(TimeoutContext)
+	Logger.Info("vSphere Session Pool has been Stopped", zap.String("Region", this.Endpoint.Region))
+}
+
+// Endpoint Registry
+
+type EndpointRegistry struct {
+	// Session Pools of the Endpoints by their Regions, every Pool has it's own Circuit Breaker
+	Pools    map[string]*SessionPool
+	Regions  []string      // Regions in the Order of the Configuration, the First one is the Default Region
+	Cooldown time.Duration // Time, the Circuit Breakers stay Open for
+}
+
+func NewEndpointRegistry(Endpoints []Endpoint, Interval time.Duration, Failures int, Cooldown time.Duration) *EndpointRegistry {
+	Registry := &EndpointRegistry{Pools: make(map[string]*SessionPool), Cooldown: Cooldown}
+	for _, Endpoint := range Endpoints {
+		Pool := NewSessionPool(Endpoint.GetURL(), Interval, NewCircuitBreaker(Failures, Cooldown))
+		Pool.Endpoint = Endpoint
+		Registry.Add(Pool)
+	}
+	return Registry
+}
+
+func (this *EndpointRegistry) Add(Pool *SessionPool) {
+	this.Pools[Pool.Endpoint.Region] = Pool
+	this.Regions = append(this.Regions, Pool.Endpoint.Region)
+}
+
+func (this *EndpointRegistry) GetDefaultRegion() string {
+	return this.Regions[0]
+}
+
+func (this *EndpointRegistry) IsDefaultRegion(Region string) bool {
+	// Virtual Machines, Created before the Regions have been Introduced, does not have any Region and Belong to the Default one
+	return len(Region) == 0 || Region == this.GetDefaultRegion()
+}
+
+func (this *EndpointRegistry) GetRecordRegions(Region string) []string {
+	// Returns Values of the Region, the Database Records of the Virtual Machines of the Region can have
+	if this.IsDefaultRegion(Region) {
+		return []string{this.GetDefaultRegion(), ""}
+	}
+	return []string{Region}
+}
+
+func (this *EndpointRegistry) Get(Region string) (*SessionPool, error) {
+	// Returns Session Pool of the Region, Empty Region stands for the Default one
+	if len(Region) == 0 {
+		Region = this.GetDefaultRegion()
+	}
+	Pool, Exists := this.Pools[Region]
+	if !Exists {
+		return nil, ErrUnknownRegion
+	}
+	return Pool, nil
+}
+
+func (this *EndpointRegistry) GetPools() []*SessionPool {
+	// Returns Session Pools of all the Regions in the Order of the Configuration
+	Pools := make([]*SessionPool, 0, len(this.Regions))
+	for _, Region := range this.Regions {
+		Pools = append(Pools, this.Pools[Region])
+	}
+	return Pools
+}
+
+func (this *EndpointRegistry) GetClient(Region string) (*govmomi.Client, error) {
+	// Returns SOAP Client of the Region, if it is Available
+	Pool, RegionError := this.Get(Region)
+	if RegionError != nil {
+		return nil, RegionError
+	}
+	return Pool.GetClient()
+}
+
+func (this *EndpointRegistry) GetRestClient(Region string) (*rest.Client, error) {
+	// Returns REST / vAPI Client of the Region, if it is Available
+	Pool, RegionError := this.Get(Region)
+	if RegionError != nil {
+		return nil, RegionError
+	}
+	return Pool.GetRestClient()
+}
+
+func (this *EndpointRegistry) IsAnyAvailable() bool {
+	// Returns true, if at least one of the Regions can Serve the Request
+	for _, Pool := range this.GetPools() {
+		if Pool.IsAvailable() {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *EndpointRegistry) Start() {
+	for _, Pool := range this.GetPools() {
+		Pool.Start()
+	}
+}
+
+func (this *EndpointRegistry) Stop() {
+	for _, Pool := range this.GetPools() {
+		Pool.Stop()
+	}
+}
@@ -0,0 +1,177 @@
+package webhook_rest
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/LovePelmeni/Infrastructure/authentication"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/LovePelmeni/Infrastructure/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of Rest API Controllers, for Managing the Webhooks of the Customer and Inspecting their Deliveries
+// Webhooks Receive the Lifecycle Events of the Virtual Machines of the Projects, the Customer is Member of
+
+var (
+	Logger *zap.Logger
+)
+
+const (
+	MaxDeliveries = 100 // Number of the Latest Deliveries, Returned by the Delivery Log Controller
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("WebhookRestLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+}
+
+func GetCustomerWebhook(RequestContext *gin.Context) (*models.Webhook, bool) {
+	// Returns the Customer's Webhook, specified in the `WebhookId` Param, Responds with the Error and Returns false, if it does not Exist
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return nil, false
+	}
+	WebhookId, ParseError := strconv.Atoi(RequestContext.Request.FormValue("WebhookId"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Webhook ID"})
+		return nil, false
+	}
+	Webhook, Exists := models.GetCustomerWebhook(jwtCredentials.UserId, WebhookId)
+	if !Exists {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Webhook Does Not Exist"})
+		return nil, false
+	}
+	return Webhook, true
+}
+
+func CreateWebhookRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Registers new Webhook of the Customer, Comma Separated `EventTypes` Filter the Events,
+	// the Webhook is Subscribed to, Empty ones stand for every Event
+	// Secret of the Signatures is being Returned only once, so the Customer should Store it
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+
+	URL := RequestContext.PostForm("URL")
+	if ValidationError := webhooks.ValidateURL(URL); ValidationError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": ValidationError.Error()})
+		return
+	}
+	EventTypes, ParseError := webhooks.ParseEventTypes(RequestContext.PostForm("EventTypes"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": ParseError.Error()})
+		return
+	}
+
+	Secret, SecretError := authentication.GenerateRandomToken()
+	if SecretError != nil {
+		Logger.Error("Failed to Generate Webhook Secret", zap.Error(SecretError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Create Webhook"})
+		return
+	}
+	Webhook := models.NewWebhook(jwtCredentials.UserId, URL, Secret, EventTypes)
+	if _, CreationError := Webhook.Create(); CreationError != nil {
+		Logger.Error("Failed to Create Webhook", zap.Error(CreationError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Create Webhook"})
+		return
+	}
+	RequestContext.JSON(http.StatusCreated, gin.H{"Webhook": Webhook, "Secret": Secret})
+}
+
+func ListWebhooksRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns Webhooks of the Customer along with the Event Types, they can be Subscribed to
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+	Webhooks, WebhooksError := models.GetCustomerWebhooks(jwtCredentials.UserId)
+	if WebhooksError != nil {
+		Logger.Error("Failed to Receive Webhooks", zap.Error(WebhooksError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Receive Webhooks"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": Webhooks, "EventTypes": webhooks.EventTypes})
+}
+
+func RemoveWebhookRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Removes the Webhook along with it's Delivery Log, Pending Deliveries are not being Sent anymore
+
+	Webhook, Exists := GetCustomerWebhook(RequestContext)
+	if !Exists {
+		return
+	}
+	if _, DeleteError := Webhook.Delete(); DeleteError != nil {
+		Logger.Error("Failed to Remove Webhook", zap.Error(DeleteError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Remove Webhook"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"Status": "Removed"})
+}
+
+func ListWebhookDeliveriesRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Returns the Latest Deliveries of the Webhook along with the Results of their Latest Attempts
+
+	Webhook, Exists := GetCustomerWebhook(RequestContext)
+	if !Exists {
+		return
+	}
+	Deliveries, DeliveriesError := models.GetWebhookDeliveries(Webhook.ID, MaxDeliveries)
+	if DeliveriesError != nil {
+		Logger.Error("Failed to Receive Webhook Deliveries", zap.Error(DeliveriesError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Receive Webhook Deliveries"})
+		return
+	}
+	RequestContext.JSON(http.StatusOK, gin.H{"QuerySet": Deliveries})
+}
+
+func RedeliverWebhookRestController(RequestContext *gin.Context) {
+	// Rest Controller, that Schedules the Delivery of the same Event once again, e.g after the Endpoint has been Fixed
+	// Event ID remains the same, so the Endpoint can Skip the Events, it has already Processed
+
+	jwtCredentials, JwtError := authentication.GetRequestCredentials(RequestContext)
+	if JwtError != nil {
+		RequestContext.JSON(http.StatusForbidden, gin.H{"Error": "You are Not Authorized"})
+		return
+	}
+	DeliveryId, ParseError := strconv.Atoi(RequestContext.Request.FormValue("DeliveryId"))
+	if ParseError != nil {
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Invalid Delivery ID"})
+		return
+	}
+	Delivery, Exists := models.GetCustomerWebhookDelivery(jwtCredentials.UserId, DeliveryId)
+	if !Exists {
+		RequestContext.JSON(http.StatusNotFound, gin.H{"Error": "Webhook Delivery Does Not Exist"})
+		return
+	}
+
+	Redelivery, RedeliveryError := webhooks.Redeliver(*Delivery)
+	if RedeliveryError != nil {
+		Logger.Error("Failed to Redeliver Webhook Event", zap.Error(RedeliveryError))
+		RequestContext.JSON(http.StatusBadRequest, gin.H{"Error": "Failed to Redeliver Webhook Event"})
+		return
+	}
+	RequestContext.JSON(http.StatusCreated, gin.H{"Delivery": Redelivery})
+}
@@ -0,0 +1,391 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LovePelmeni/Infrastructure/config"
+	"github.com/LovePelmeni/Infrastructure/egress"
+	"github.com/LovePelmeni/Infrastructure/metrics"
+	"github.com/LovePelmeni/Infrastructure/models"
+	"github.com/google/uuid"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Package consists of the Outbound Webhooks, that Notify the Systems of the Customers (e.g CMDB) about the Lifecycle of the Virtual Machines
+// Events are being Stored as the Deliveries of every Matching Webhook and Sent by the Dispatcher in the Background,
+// Failed Deliveries are being Retried with the Exponential Backoff, until they run out of the Attempts
+// Every Payload is being Signed with the HMAC-SHA256 of the Webhook Secret, so the Receiver can Verify it
+// Events are being Emitted by the Operations of the `deploy.VirtualMachineManager` and by the Job Handlers of the `vm_rest`,
+// once the Record of the Virtual Machine has been Saved, so the Payload Reflects the Stored State
+
+var (
+	Logger *zap.Logger
+)
+
+const (
+	EventCreated   = "vm.created"
+	EventDeployed  = "vm.deployed"
+	EventStarted   = "vm.started"
+	EventRebooted  = "vm.rebooted"
+	EventStopped   = "vm.stopped"
+	EventResized   = "vm.resized"
+	EventDestroyed = "vm.destroyed"
+)
+
+var (
+	EventTypes = []string{EventCreated, EventDeployed, EventStarted, EventRebooted, EventStopped, EventResized, EventDestroyed}
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature" // `sha256=<Hex HMAC of the "<Timestamp>.<Body>">`
+	TimestampHeader = "X-Webhook-Timestamp" // Unix Time of the Attempt, so the Receiver can Reject the Replayed Requests
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+var (
+	Timeout      = 10 * time.Second
+	MaxAttempts  = 8
+	BackoffBase  = 30 * time.Second
+	BackoffMax   = time.Hour
+	PollInterval = 5 * time.Second
+	BatchSize    = 50 // Deliveries, Claimed by the Dispatcher at once
+	MaxErrorSize = 1000
+)
+
+var (
+	ErrUnknownEventType = errors.New("Unknown Event Type")
+	ErrInvalidURL       = errors.New("Invalid Webhook URL, Absolute HTTP or HTTPS URL is Expected")
+)
+
+var (
+	DeliveryAttempts = metrics.NewCounterVec("webhook_delivery_attempts_total", "Number of the Webhook Delivery Attempts", "status")
+)
+
+func InitializeProductionLogger() {
+
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.ISO8601TimeEncoder
+	fileEncoder := zapcore.NewJSONEncoder(config)
+	file, _ := os.OpenFile("WebhooksLog.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logWriter := zapcore.AddSync(file)
+
+	Core := zapcore.NewTee(zapcore.NewCore(fileEncoder, logWriter, zapcore.DebugLevel))
+	Logger = zap.New(Core)
+}
+
+func init() {
+	InitializeProductionLogger()
+	metrics.Register(DeliveryAttempts)
+}
+
+func Configure(Config config.WebhooksConfig) {
+	Timeout = Config.Timeout
+	MaxAttempts = Config.MaxAttempts
+	BackoffBase = Config.BackoffBase
+	BackoffMax = Config.BackoffMax
+	PollInterval = Config.PollInterval
+}
+
+func ValidateURL(Value string) error {
+	// Checks, that the URL is Absolute and it's Host is not an Internal one
+	Parsed, ParseError := url.Parse(Value)
+	if ParseError != nil || (Parsed.Scheme != "http" && Parsed.Scheme != "https") || len(Parsed.Hostname()) == 0 {
+		return ErrInvalidURL
+	}
+	if HostError := egress.ValidateHost(Parsed.Hostname()); HostError != nil {
+		return fmt.Errorf("Invalid Webhook URL, %w", HostError)
+	}
+	return nil
+}
+
+func ParseEventTypes(Value string) ([]string, error) {
+	// Parses Comma Separated Event Types, Empty Value stands for every Event
+	Parsed := []string{}
+	for _, EventType := range strings.Split(Value, ",") {
+		EventType = strings.TrimSpace(EventType)
+		if len(EventType) == 0 {
+			continue
+		}
+		Known := false
+		for _, Supported := range EventTypes {
+			Known = Known || EventType == Supported
+		}
+		if !Known {
+			return nil, fmt.Errorf("%w `%s`, Supported ones are %s", ErrUnknownEventType, EventType, strings.Join(EventTypes, ", "))
+		}
+		Parsed = append(Parsed, EventType)
+	}
+	return Parsed, nil
+}
+
+func Sign(Secret string, Timestamp int64, Body []byte) string {
+	// Returns the Signature of the Payload, Timestamp is being Signed along with the Body, so it can't be Replaced
+	Mac := hmac.New(sha256.New, []byte(Secret))
+	Mac.Write([]byte(strconv.FormatInt(Timestamp, 10) + "."))
+	Mac.Write(Body)
+	return "sha256=" + hex.EncodeToString(Mac.Sum(nil))
+}
+
+func GetBackoff(Attempts int) time.Duration {
+	// Returns the Delay after the Failed Attempt, that Doubles after every Attempt up to the `BackoffMax`
+	Backoff := BackoffBase
+	for Attempt := 1; Attempt < Attempts && Backoff < BackoffMax; Attempt++ {
+		Backoff *= 2
+	}
+	if Backoff > BackoffMax {
+		return BackoffMax
+	}
+	return Backoff
+}
+
+// EVENTS
+
+type VirtualMachineInfo struct {
+	Id                 int    `json:"Id"`
+	VirtualMachineName string `json:"VirtualMachineName"`
+	OwnerId            int    `json:"OwnerId"`
+	ProjectId          int    `json:"ProjectId"`
+	Region             string `json:"Region"`
+	State              string `json:"State"`
+	IPAddress          string `json:"IPAddress"`
+	ItemPath           string `json:"ItemPath"`
+}
+
+type Payload struct {
+	// Body of the Webhook Request
+	Id             string             `json:"Id"` // Same for the Redeliveries, so the Receiver can Skip the Duplicates
+	Type           string             `json:"Type"`
+	CreatedAt      time.Time          `json:"CreatedAt"`
+	VirtualMachine VirtualMachineInfo `json:"VirtualMachine"`
+	Data           interface{}        `json:"Data,omitempty"`
+}
+
+func NewPayload(EventType string, VirtualMachine models.VirtualMachine, Data interface{}, CreatedAt time.Time) Payload {
+	return Payload{
+		Id:        uuid.NewString(),
+		Type:      EventType,
+		CreatedAt: CreatedAt.UTC(),
+		VirtualMachine: VirtualMachineInfo{
+			Id:                 VirtualMachine.ID,
+			VirtualMachineName: VirtualMachine.VirtualMachineName,
+			OwnerId:            VirtualMachine.OwnerId,
+			ProjectId:          VirtualMachine.ProjectId,
+			Region:             VirtualMachine.Region,
+			State:              VirtualMachine.State,
+			IPAddress:          VirtualMachine.IPAddress,
+			ItemPath:           VirtualMachine.ItemPath,
+		},
+		Data: Data,
+	}
+}
+
+func GetDeliveries(Webhooks []models.Webhook, Payload Payload) ([]models.WebhookDelivery, error) {
+	// Returns Deliveries of the Payload to the Webhooks, that are Subscribed to it's Event Type
+	Serialized, EncodeError := json.Marshal(Payload)
+	if EncodeError != nil {
+		return nil, EncodeError
+	}
+	Deliveries := []models.WebhookDelivery{}
+	for _, Webhook := range Webhooks {
+		if Webhook.Accepts(Payload.Type) {
+			Deliveries = append(Deliveries, *models.NewWebhookDelivery(Webhook.ID, Payload.Id, Payload.Type, string(Serialized)))
+		}
+	}
+	return Deliveries, nil
+}
+
+func Emit(EventType string, VirtualMachine models.VirtualMachine, Data interface{}) {
+	// Schedules the Deliveries of the Event to the Webhooks, Failures are being Logged,
+	// since the Operation on the Virtual Machine has already been Made
+	Webhooks, WebhooksError := models.GetVirtualMachineWebhooks(VirtualMachine)
+	if WebhooksError != nil {
+		Logger.Error("Failed to Receive Webhooks", zap.Int("VirtualMachineId", VirtualMachine.ID), zap.Error(WebhooksError))
+		return
+	}
+	Deliveries, DeliveriesError := GetDeliveries(Webhooks, NewPayload(EventType, VirtualMachine, Data, time.Now()))
+	if DeliveriesError != nil || len(Deliveries) == 0 {
+		return
+	}
+	if Created := models.Database.Create(&Deliveries); Created.Error != nil {
+		Logger.Error("Failed to Schedule Webhook Deliveries", zap.String("Event", EventType),
+			zap.Int("VirtualMachineId", VirtualMachine.ID), zap.Error(Created.Error))
+	}
+}
+
+func EmitVirtualMachine(EventType string, VirtualMachineId int, Data interface{}) {
+	// Schedules the Deliveries of the Event of the Virtual Machine, that is being Received from the Database
+	var VirtualMachine models.VirtualMachine
+	if Found := models.Database.Model(&models.VirtualMachine{}).Where("id = ?", VirtualMachineId).Find(&VirtualMachine); Found.Error != nil || VirtualMachine.ID == 0 {
+		Logger.Error("Failed to Find Virtual Machine of the Webhook Event", zap.Int("VirtualMachineId", VirtualMachineId))
+		return
+	}
+	Emit(EventType, VirtualMachine, Data)
+}
+
+func Redeliver(Delivery models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	// Schedules new Delivery of the same Event, the Log of the Previous one is being Kept
+	Redelivery := models.NewWebhookDelivery(Delivery.WebhookId, Delivery.EventId, Delivery.EventType, Delivery.Payload)
+	_, CreationError := Redelivery.Create()
+	return Redelivery, CreationError
+}
+
+// DISPATCHER
+
+func NewClient() *http.Client {
+	// Returns Client, that does not Follow the Redirects, so they are being Treated as the Failures
+	// Addresses of the Webhooks are being Checked once again on every Connection, since their DNS Records might have been Changed
+	return &http.Client{
+		Transport: egress.NewTransport(),
+		CheckRedirect: func(Request *http.Request, Via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func Attempt(Client *http.Client, Webhook models.Webhook, Delivery *models.WebhookDelivery, Now time.Time) {
+	// Sends the Delivery and Records the Result of the Attempt, Delivery is being Failed, once it runs out of the Attempts
+
+	Delivery.Attempts++
+	Delivery.StatusCode, Delivery.Error = 0, ""
+	SendError := Send(Client, Webhook, *Delivery, Now, &Delivery.StatusCode)
+
+	switch {
+	case SendError == nil:
+		Delivery.State, Delivery.DeliveredAt = models.DeliveryStateSucceeded, &Now
+		DeliveryAttempts.Inc("succeeded")
+		return
+	case Delivery.Attempts >= MaxAttempts:
+		Delivery.State = models.DeliveryStateFailed
+	default:
+		Delivery.NextAttemptAt = Now.Add(GetBackoff(Delivery.Attempts))
+	}
+	Delivery.Error = SendError.Error()
+	if len(Delivery.Error) > MaxErrorSize {
+		Delivery.Error = Delivery.Error[:MaxErrorSize]
+	}
+	DeliveryAttempts.Inc("failed")
+}
+
+func Send(Client *http.Client, Webhook models.Webhook, Delivery models.WebhookDelivery, Now time.Time, StatusCode *int) error {
+	TimeoutContext, CancelFunc := context.WithTimeout(context.Background(), Timeout)
+	defer CancelFunc()
+
+	Body := []byte(Delivery.Payload)
+	Request, RequestError := http.NewRequestWithContext(TimeoutContext, http.MethodPost, Webhook.URL, bytes.NewReader(Body))
+	if RequestError != nil {
+		return RequestError
+	}
+	Request.Header.Set("Content-Type", "application/json")
+	Request.Header.Set(SignatureHeader, Sign(Webhook.Secret, Now.Unix(), Body))
+	Request.Header.Set(TimestampHeader, strconv.FormatInt(Now.Unix(), 10))
+	Request.Header.Set(EventHeader, Delivery.EventType)
+	Request.Header.Set(DeliveryHeader, strconv.Itoa(Delivery.ID))
+
+	Response, ResponseError := Client.Do(Request)
+	if ResponseError != nil {
+		return ResponseError
+	}
+	defer Response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(Response.Body, 1<<20))
+
+	*StatusCode = Response.StatusCode
+	if Response.StatusCode < http.StatusOK || Response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("Webhook has Responded with the Status %v", Response.StatusCode)
+	}
+	return nil
+}
+
+type Dispatcher struct {
+	// Background Loop, that Sends the Due Deliveries, every Replica runs it's own one
+	Client  *http.Client
+	Stopped chan struct{}
+	Group   sync.WaitGroup
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{Client: NewClient(), Stopped: make(chan struct{})}
+}
+
+func (this *Dispatcher) Start() {
+	this.Group.Add(1)
+	go func() {
+		defer this.Group.Done()
+		Ticker := time.NewTicker(PollInterval)
+		defer Ticker.Stop()
+
+		for {
+			if DispatchError := this.Dispatch(); DispatchError != nil {
+				Logger.Error("Failed to Dispatch Webhook Deliveries", zap.Error(DispatchError))
+			}
+			select {
+			case <-this.Stopped:
+				return
+			case <-Ticker.C:
+			}
+		}
+	}()
+	Logger.Info("Webhook Dispatcher has been Started", zap.Duration("Interval", PollInterval))
+}
+
+func (this *Dispatcher) Stop() {
+	close(this.Stopped)
+	this.Group.Wait()
+	Logger.Info("Webhook Dispatcher has been Stopped")
+}
+
+func (this *Dispatcher) Dispatch() error {
+	// Claims the Due Deliveries and Sends them one by one, the Deliveries, that have not been Sent before the Lease Expires
+	// (e.g because of the Crash of the Replica), are being Claimed again
+	Claimed, ClaimError := models.ClaimWebhookDeliveries(BatchSize, time.Duration(BatchSize+1)*Timeout)
+	if ClaimError != nil || len(Claimed) == 0 {
+		return ClaimError
+	}
+
+	WebhookIds := make([]int, 0, len(Claimed))
+	for _, Delivery := range Claimed {
+		WebhookIds = append(WebhookIds, Delivery.WebhookId)
+	}
+	Webhooks, WebhooksError := models.GetWebhooks(WebhookIds)
+	if WebhooksError != nil {
+		return WebhooksError
+	}
+	ById := make(map[int]models.Webhook, len(Webhooks))
+	for _, Webhook := range Webhooks {
+		ById[Webhook.ID] = Webhook
+	}
+
+	for Index := range Claimed {
+		select {
+		case <-this.Stopped:
+			return nil // Rest of the Deliveries are being Claimed again, once the Lease Expires
+		default:
+		}
+		Webhook, Exists := ById[Claimed[Index].WebhookId]
+		if !Exists {
+			continue // Webhook has been Removed along with the Deliveries
+		}
+		Attempt(this.Client, Webhook, &Claimed[Index], time.Now())
+		if _, SaveError := Claimed[Index].Save(); SaveError != nil {
+			Logger.Error("Failed to Save Webhook Delivery", zap.Int("DeliveryId", Claimed[Index].ID), zap.Error(SaveError))
+		}
+	}
+	return nil
+}